@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"fmt"
+
+	sous "github.com/opentable/sous/lib"
+)
+
+// PlanRectify computes the changes that `sous rectify` would make, without
+// making them.
+type PlanRectify struct {
+	Resolver *sous.Resolver
+	State    *sous.State
+	// Result is the computed plan, set by Do.
+	Result *sous.Plan
+}
+
+// Do implements Action on PlanRectify.
+func (pr *PlanRectify) Do() error {
+	gdm, err := pr.State.Deployments()
+	if err != nil {
+		return err
+	}
+	pr.Result, err = pr.Resolver.Plan(gdm, pr.State.Defs.Clusters)
+	return err
+}
+
+// PlanUpdate computes the changes that `sous deploy` would make to the GDM,
+// without making them.
+type PlanUpdate struct {
+	Manifest      *sous.Manifest
+	StateReader   sous.StateReader
+	ResolveFilter *sous.ResolveFilter
+	// Result is the computed plan, set by Do.
+	Result *sous.Plan
+}
+
+// Do implements Action on PlanUpdate.
+func (pu *PlanUpdate) Do() error {
+	mid := pu.Manifest.ID()
+
+	sid, err := pu.ResolveFilter.SourceID(mid)
+	if err != nil {
+		return err
+	}
+	did, err := pu.ResolveFilter.DeploymentID(mid)
+	if err != nil {
+		return err
+	}
+
+	state, err := pu.StateReader.ReadState()
+	if err != nil {
+		return err
+	}
+	if _, ok := state.Manifests.Get(mid); !ok {
+		return fmt.Errorf("no manifest found for %q - try 'sous init' first", mid)
+	}
+	prior, err := state.Deployments()
+	if err != nil {
+		return err
+	}
+
+	updated := state.Clone()
+	gdm, err := updated.Deployments()
+	if err != nil {
+		return err
+	}
+	if err := updateState(updated, gdm, sid, did); err != nil {
+		return err
+	}
+	post, err := updated.Deployments()
+	if err != nil {
+		return err
+	}
+
+	pu.Result = sous.PlanChanges(prior, post)
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/git"
+	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/logging"
@@ -27,6 +28,12 @@ type Server struct {
 	*config.Config
 	ServerHandler http.Handler
 	*sous.AutoResolver
+	QueueSet *sous.R11nQueueSet
+	// Snapshotter takes periodic snapshots of the GDM, if not nil.
+	Snapshotter *sous.Snapshotter
+	// StorageVerifier checks the secondary storage of the GDM against the
+	// primary, if not nil.
+	StorageVerifier *storage.StorageVerifier
 }
 
 // Do runs the server.
@@ -35,6 +42,19 @@ func (ss *Server) Do() error {
 		return err
 	}
 
+	if ss.QueueSet != nil {
+		if err := ss.QueueSet.Resume(); err != nil {
+			reportServerMessage(fmt.Sprintf("Failed to resume deploy queues: %s", err), ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
+		}
+	}
+
+	if ss.Snapshotter != nil {
+		ss.Snapshotter.Start()
+	}
+	if ss.StorageVerifier != nil {
+		ss.StorageVerifier.Start()
+	}
+
 	reportServerMessage("Starting scheduled GDM resolution.  Filtering the GDM to resolve on this server", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
 	if ss.AutoResolver != nil {
@@ -45,6 +65,9 @@ func (ss *Server) Do() error {
 
 	reportServerMessage("Sous Server Running", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
+	if auth := ss.Config.Auth; auth.TLSCertFile != "" {
+		return server.RunTLS(ss.ListenAddr, ss.ServerHandler, auth.TLSCertFile, auth.TLSKeyFile, auth.TLSClientCAFile)
+	}
 	return server.Run(ss.ListenAddr, ss.ServerHandler)
 }
 
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+const planFlagHelp = "show the changes that would be made, without making them"
+
+const planFormatFlagHelp = "format of the -plan output: text or json"
+
+// planResult renders p in format as the result of a command.
+func planResult(p *sous.Plan, format string) cmdr.Result {
+	switch format {
+	default:
+		return cmdr.UsageErrorf("unknown -format %q: expected text or json", format)
+	case "json":
+		b, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		return cmdr.SuccessData(append(b, '\n'))
+	case "text":
+		out := &bytes.Buffer{}
+		if err := p.Render(out); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		return cmdr.SuccessData(out.Bytes())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/cmdr"
 )
@@ -16,6 +17,10 @@ type (
 		config.PolicyFlags       `inject:"optional"`
 
 		*sous.BuildManager
+		SousGraph *graph.SousGraph
+		CLI       *CLI
+
+		remote bool
 	}
 )
 
@@ -26,6 +31,14 @@ const sousBuildHelp = `build your project
 build builds the project in your current directory by default. If you pass it a
 path, it will instead build the project at that path.
 
+With -platforms, projects built from a Dockerfile are built for each of the
+platforms listed, using docker buildx, and pushed as a single multi-platform
+image.
+
+With -remote, the Sous server builds the project instead of the local Docker
+daemon, and its output is shown as it goes. The server clones the project, so
+only the pushed revision is built: local changes are not.
+
 args: [path]
 `
 
@@ -33,6 +46,8 @@ args: [path]
 func (sb *SousBuild) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &sb.DeployFilterFlags, SourceFlagsHelp)
 	fs.BoolVar(&sb.PolicyFlags.Strict, "strict", false, "require that the build be pristine")
+	fs.StringVar(&sb.PolicyFlags.Platforms, "platforms", "", "comma separated platforms to build a multi-platform image for, e.g. linux/amd64,linux/arm64")
+	fs.BoolVar(&sb.remote, "remote", false, "have the Sous server build the project instead of local Docker")
 	//fs.BoolVar(&sb.PolicyFlags.ForceClone, "force-clone", false, "force a shallow clone of the codebase before build")
 	// above is commented prior to impl.
 }
@@ -55,6 +70,10 @@ func (sb *SousBuild) Execute(args []string) cmdr.Result {
 		}
 	}
 
+	if sb.remote {
+		return sb.buildRemotely()
+	}
+
 	result, err := sb.BuildManager.Build()
 
 	if err != nil {
@@ -62,3 +81,19 @@ func (sb *SousBuild) Execute(args []string) cmdr.Result {
 	}
 	return cmdr.Success(result)
 }
+
+func (sb *SousBuild) buildRemotely() cmdr.Result {
+	req, err := sb.BuildManager.BuildConfig.NewBuildRequest()
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	rb, err := sb.SousGraph.GetRemoteBuilder(sb.CLI.Out)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	result, err := rb.Build(req)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	return cmdr.Success(result)
+}
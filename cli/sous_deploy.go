@@ -18,6 +18,8 @@ type SousDeploy struct {
 	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
 	dryrunOption      string
 	waitStable        bool
+	plan              bool
+	format            string
 }
 
 func init() { TopLevelCommands["deploy"] = &SousDeploy{} }
@@ -28,6 +30,9 @@ usage: sous deploy -cluster <name> -tag <semver>
 
 sous deploy will deploy the version tag for this application in the named
 cluster.
+
+With -plan, sous deploy shows the change it would make to the GDM instead,
+without making it. Use -format json for output suitable for other tools.
 `
 
 // Help returns the help string for this command.
@@ -42,11 +47,24 @@ func (sd *SousDeploy) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sd.dryrunOption, "dry-run", "none",
 		"prevent rectify from actually changing things - "+
 			"values are none,scheduler,registry,both")
+	fs.BoolVar(&sd.plan, "plan", false, planFlagHelp)
+	fs.StringVar(&sd.format, "format", "text", planFormatFlagHelp)
 }
 
 // Execute fulfills the cmdr.Executor interface.
 func (sd *SousDeploy) Execute(args []string) cmdr.Result {
 	otplFlags := config.OTPLFlags{}
+	if sd.plan {
+		plan, err := sd.SousGraph.GetPlanUpdate(sd.DeployFilterFlags, otplFlags)
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		if err := plan.Do(); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		return planResult(plan.Result, sd.format)
+	}
+
 	update, err := sd.SousGraph.GetUpdate(sd.DeployFilterFlags, otplFlags)
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
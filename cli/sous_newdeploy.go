@@ -102,50 +102,55 @@ func (sd *SousNewDeploy) Execute(args []string) cmdr.Result {
 	}
 
 	if location := updateResponse.Location(); location != "" {
-		fmt.Printf("Deployment queued: %s\n", location)
-		client, err := restful.NewClient("", sd.LogSink, nil)
-		if err != nil {
-			return cmdr.InternalErrorf("Failed to create polling client: %s", err)
-		}
-		pollTime := sd.Config.PollIntervalForClient
-
-		messages.ReportLogFieldsMessageToConsole("\n", logging.InformationLevel, sd.LogSink)
-
-		var p *mpb.Progress
-		var bar *mpb.Bar
-		if terminal.IsTerminal(int(os.Stdin.Fd())) {
-			p = mpb.New()
-			// initialize bar with dynamic total and initial total guess = 80
-			bar = p.AddBar(100,
-				// indicate that total is dynamic
-				mpb.BarDynamicTotal(),
-				// trigger total auto increment by 1, when 18 % remains till bar completion
-				mpb.BarAutoIncrTotal(18, 1),
-				mpb.PrependDecorators(
-					decor.CountersNoUnit("%d / %d", 12, 0),
-				),
-				mpb.AppendDecorators(
-					decor.Percentage(5, 0),
-				),
-			)
-		}
-
-		result := PollDeployQueue(location, client, pollTime, bar, sd.LogSink)
-
-		if terminal.IsTerminal(int(os.Stdin.Fd())) && bar != nil && p != nil {
-			bar.SetTotal(100, true)
-			bar.Incr(100)
-			bar.Complete()
-			p.Wait()
-			p.RemoveBar(bar)
-		}
-		return result
+		return waitForDeployment(location, sd.Config.PollIntervalForClient, sd.LogSink)
 	}
 	return cmdr.Successf("Desired version for %q already %q",
 		sd.TargetDeploymentID, sd.DeployFilterFlags.Tag)
 
 }
 
+// waitForDeployment polls the rectification at location until it completes,
+// showing progress if attached to a terminal.
+func waitForDeployment(location string, pollTime int, log logging.LogSink) cmdr.Result {
+	fmt.Printf("Deployment queued: %s\n", location)
+	client, err := restful.NewClient("", log, nil)
+	if err != nil {
+		return cmdr.InternalErrorf("Failed to create polling client: %s", err)
+	}
+
+	messages.ReportLogFieldsMessageToConsole("\n", logging.InformationLevel, log)
+
+	var p *mpb.Progress
+	var bar *mpb.Bar
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		p = mpb.New()
+		// initialize bar with dynamic total and initial total guess = 80
+		bar = p.AddBar(100,
+			// indicate that total is dynamic
+			mpb.BarDynamicTotal(),
+			// trigger total auto increment by 1, when 18 % remains till bar completion
+			mpb.BarAutoIncrTotal(18, 1),
+			mpb.PrependDecorators(
+				decor.CountersNoUnit("%d / %d", 12, 0),
+			),
+			mpb.AppendDecorators(
+				decor.Percentage(5, 0),
+			),
+		)
+	}
+
+	result := PollDeployQueue(location, client, pollTime, bar, log)
+
+	if terminal.IsTerminal(int(os.Stdin.Fd())) && bar != nil && p != nil {
+		bar.SetTotal(100, true)
+		bar.Incr(100)
+		bar.Complete()
+		p.Wait()
+		p.RemoveBar(bar)
+	}
+	return result
+}
+
 func timeTrack(start time.Time) string {
 	elapsed := time.Since(start)
 	return elapsed.String()
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+	"github.com/pkg/errors"
+)
+
+// SousPlumbingGCImages is the description of the `sous plumbing gc-images` command.
+type SousPlumbingGCImages struct {
+	State    *sous.State
+	Registry sous.Registry
+	// HTTPClient and Clients are the main Sous server, and the server of each
+	// cluster, which know the digests deployed and pinned.
+	HTTPClient graph.HTTPClient
+	Clients    graph.ClientBundle
+	graph.OutWriter
+	graph.ErrWriter
+	flags struct {
+		keep    int
+		delete  bool
+		orphans bool
+	}
+}
+
+func init() { PlumbingSubcommands["gc-images"] = &SousPlumbingGCImages{} }
+
+const sousPlumbingGCImagesHelp = `Finds, and optionally deletes, images which are no longer needed.
+
+usage: sous plumbing gc-images [-keep <n>] [-orphans] [-delete]
+
+An image is needed if it is deployed by the GDM, or is one of the most recent
+-keep versions of the source of a manifest, which may be rolled back to. The
+images of sources which no manifest refers to are kept too, unless -orphans is
+given, when they are all unneeded.
+
+By default, the unneeded images are only listed. With -delete, they are
+deleted from the docker registry by digest, which must allow deletes; the
+registry's own garbage collection then frees the space they used. Deleting a
+digest deletes every tag of it, so an image whose digest is also that of a
+needed image, or was pinned when another was deployed, is kept. Before
+deleting anything, the images deployed in each cluster and those of needed
+artifacts are fetched from the Sous servers, which pin the digests they deploy.
+`
+
+// Help prints the help
+func (*SousPlumbingGCImages) Help() string { return sousPlumbingGCImagesHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingGCImages) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&config.DeployFilterFlags{})
+}
+
+// AddFlags adds the flags for sous plumbing gc-images.
+func (sgc *SousPlumbingGCImages) AddFlags(fs *flag.FlagSet) {
+	fs.IntVar(&sgc.flags.keep, "keep", 5, "the number of recent versions of each manifest's source to keep")
+	fs.BoolVar(&sgc.flags.delete, "delete", false, "delete the unneeded images, rather than listing them")
+	fs.BoolVar(&sgc.flags.orphans, "orphans", false, "treat the images of sources which no manifest refers to as unneeded")
+}
+
+// Execute defines the behavior of `sous plumbing gc-images`
+func (sgc *SousPlumbingGCImages) Execute(args []string) cmdr.Result {
+	if sgc.flags.keep < 0 {
+		return cmdr.UsageErrorf("-keep must not be negative")
+	}
+	ac, collects := sgc.Registry.(sous.ArtifactCollector)
+	if sgc.flags.delete && !collects {
+		return cmdr.UsageErrorf("the registry in use cannot delete images")
+	}
+
+	if collects {
+		harvested := map[sous.SourceLocation]bool{}
+		for _, m := range sgc.State.Manifests.Snapshot() {
+			if harvested[m.Source] {
+				continue
+			}
+			harvested[m.Source] = true
+			if err := ac.HarvestArtifacts(m.Source); err != nil {
+				fmt.Fprintf(sgc.ErrWriter, "Unable to find images for %s: %s\n", m.Source, err)
+			}
+		}
+	}
+
+	known, err := sgc.Registry.ListSourceIDs()
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	unneeded, needed, err := sous.UnreferencedSourceIDs(sgc.State, known, sgc.flags.keep, sgc.flags.orphans)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+
+	if !sgc.flags.delete {
+		for _, sid := range unneeded {
+			fmt.Fprintln(sgc.OutWriter, sid)
+		}
+		return cmdr.Successf("%d of %d images are unneeded; use -delete to delete them.", len(unneeded), len(known))
+	}
+
+	images, err := sous.ServerImages(sgc.HTTPClient, sgc.Clients, needed)
+	if err != nil {
+		return cmdr.InternalErrorf("unable to find the images the servers need, so deleting none: %s", err)
+	}
+
+	deleted, kept, failed := 0, 0, 0
+	for _, sid := range unneeded {
+		err := deleteArtifact(ac, sid, needed, images)
+		if shared, is := errors.Cause(err).(*sous.SharedArtifactError); is {
+			fmt.Fprintf(sgc.ErrWriter, "Kept %s: %s\n", sid, shared)
+			kept++
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(sgc.ErrWriter, "Unable to delete %s: %s\n", sid, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(sgc.OutWriter, "Deleted %s\n", sid)
+		deleted++
+	}
+	if failed > 0 {
+		return cmdr.InternalErrorf("failed to delete %d of %d unneeded images", failed, len(unneeded))
+	}
+	return cmdr.Successf("Deleted %d unneeded images, and kept %d which share a needed digest.", deleted, kept)
+}
+
+// deleteArtifact deletes the artifact built from sid with ac, unless its
+// image has the digest of one of images, needed by the servers.
+func deleteArtifact(ac sous.ArtifactCollector, sid sous.SourceID, needed []sous.SourceID, images map[string]string) error {
+	art, err := ac.GetArtifact(sid)
+	if err != nil {
+		return err
+	}
+	if what, ok := images[sous.ImageDigest(art.Name)]; ok {
+		return &sous.SharedArtifactError{SourceID: sid, Image: art.Name, SharedWith: what}
+	}
+	return ac.DeleteArtifact(sid, needed)
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+)
+
+type gcTestCollector struct {
+	sous.Registry
+	images  map[string]string
+	deleted []sous.SourceID
+}
+
+func (c *gcTestCollector) GetArtifact(sid sous.SourceID) (*sous.BuildArtifact, error) {
+	name, ok := c.images[sid.Version.String()]
+	if !ok {
+		return nil, errors.Errorf("no image for %s", sid)
+	}
+	return &sous.BuildArtifact{Name: name, Type: "docker"}, nil
+}
+
+func (c *gcTestCollector) ListSourceIDs() ([]sous.SourceID, error) {
+	sids := []sous.SourceID{}
+	for v := range c.images {
+		sids = append(sids, sous.SourceID{Location: sous.SourceLocation{Repo: "github.com/example/project"}, Version: semv.MustParse(v)})
+	}
+	return sids, nil
+}
+
+func (c *gcTestCollector) HarvestArtifacts(sous.SourceLocation) error { return nil }
+
+// DeleteArtifact knows of no pins, as the pins are kept by the server.
+func (c *gcTestCollector) DeleteArtifact(sid sous.SourceID, needed []sous.SourceID) error {
+	c.deleted = append(c.deleted, sid)
+	return nil
+}
+
+func TestGCImages_KeepsDigestPinnedOnServer(t *testing.T) {
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"prod": &sous.Cluster{Name: "prod"}}
+	state.Manifests.Add(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "github.com/example/project"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"prod": {Version: semv.MustParse("2.0.0"), DeployConfig: sous.DeployConfig{NumInstances: 1}},
+		},
+	})
+
+	// 1.0.0 is unneeded, but the server pinned the tag of 2.0.0 to its
+	// digest when it deployed it.
+	reg := &gcTestCollector{images: map[string]string{
+		"1.0.0": "docker.example.com/project@sha256:aaa",
+		"2.0.0": "docker.example.com/project@sha256:bbb",
+		"3.0.0": "docker.example.com/project@sha256:ccc",
+	}}
+	deployed := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}},
+		Cluster:    "prod",
+	}
+	status := map[string]interface{}{
+		"Completed": &sous.ResolveStatus{Log: []sous.DiffResolution{{
+			DeploymentID: deployed,
+			Desc:         sous.StableDiff,
+			DeployState: &sous.DeployState{
+				BuildArtifact: &sous.BuildArtifact{Name: "docker.example.com/project@sha256:aaa", Tag: "docker.example.com/project:2.0.0"},
+			},
+		}}},
+	}
+	prod, prodCtrl := restfultest.NewHTTPClientSpy()
+	prodCtrl.MatchMethod("Retrieve", spies.AnyArgs, status, restfultest.DummyUpdater(), nil)
+	main, mainCtrl := restfultest.NewHTTPClientSpy()
+	mainCtrl.MatchMethod("Retrieve", spies.AnyArgs, nil, restfultest.DummyUpdater(), errors.New("404 Not Found"))
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	gc := &SousPlumbingGCImages{
+		State:      state,
+		Registry:   reg,
+		HTTPClient: graph.HTTPClient{HTTPClient: main},
+		Clients:    graph.ClientBundle{"prod": prod},
+		OutWriter:  graph.OutWriter(out),
+		ErrWriter:  graph.ErrWriter(errOut),
+	}
+	gc.flags.delete = true
+	gc.flags.orphans = true
+
+	res := gc.Execute(nil)
+	assert.Equal(t, 0, res.ExitCode(), "%v", res)
+	assert.Equal(t, []sous.SourceID{{Location: sous.SourceLocation{Repo: "github.com/example/project"}, Version: semv.MustParse("3.0.0")}}, reg.deleted)
+	assert.Contains(t, errOut.String(), "Kept github.com/example/project,1.0.0")
+	assert.Contains(t, errOut.String(), "deployed in prod")
+	assert.Len(t, prodCtrl.CallsTo("Retrieve"), 1)
+}
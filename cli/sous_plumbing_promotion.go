@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingPromotion is the description of the `sous plumbing promotion` command.
+type SousPlumbingPromotion struct {
+	graph.HTTPClient
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	User              sous.User
+	flags             struct {
+		pipeline, approve string
+		pause, resume     bool
+	}
+}
+
+func init() { PlumbingSubcommands["promotion"] = &SousPlumbingPromotion{} }
+
+const sousPlumbingPromotionHelp = `Pauses, resumes or approves promotions through a pipeline.
+
+usage: sous plumbing promotion -pipeline <name> (-pause | -resume | -approve <version> -repo <repo> -cluster <cluster>)
+
+Pausing a pipeline stops promotions through it until it is resumed, by
+setting Paused on the pipeline in the GDM. A promotion into a stage which
+requires approval is recorded in the Pending of the pipeline in the GDM, and
+waits until the version of the deployment in that cluster is approved with
+-approve; it is made after the next auto-resolve.
+`
+
+// Help prints the help
+func (*SousPlumbingPromotion) Help() string { return sousPlumbingPromotionHelp }
+
+// RegisterOn registers items on the DI graph
+func (spp *SousPlumbingPromotion) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&spp.DeployFilterFlags)
+}
+
+// AddFlags adds the flags for sous plumbing promotion.
+func (spp *SousPlumbingPromotion) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &spp.DeployFilterFlags, ManifestFilterFlagsHelp+ClusterFilterFlagsHelp)
+	fs.StringVar(&spp.flags.pipeline, "pipeline", "", "the name of the promotion pipeline")
+	fs.BoolVar(&spp.flags.pause, "pause", false, "pause promotions through the pipeline")
+	fs.BoolVar(&spp.flags.resume, "resume", false, "resume promotions through the pipeline")
+	fs.StringVar(&spp.flags.approve, "approve", "", "approve the promotion of the deployment to this version")
+}
+
+// Execute defines the behavior of `sous plumbing promotion`
+func (spp *SousPlumbingPromotion) Execute(args []string) cmdr.Result {
+	if spp.flags.pipeline == "" {
+		return cmdr.UsageErrorf("-pipeline is required")
+	}
+	q := map[string]string{"pipeline": spp.flags.pipeline}
+	switch {
+	case spp.flags.pause || spp.flags.resume:
+		if spp.flags.pause && spp.flags.resume {
+			return cmdr.UsageErrorf("-pause and -resume are mutually exclusive")
+		}
+		q["paused"] = strconv.FormatBool(spp.flags.pause)
+		if _, err := spp.Create("./promotion-pause", q, nil, spp.User.HTTPHeaders()); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		if spp.flags.pause {
+			return cmdr.Successf("Paused %q.", spp.flags.pipeline)
+		}
+		return cmdr.Successf("Resumed %q.", spp.flags.pipeline)
+	case spp.flags.approve != "":
+		f := spp.DeployFilterFlags
+		if f.Repo == "" || f.Cluster == "" {
+			return cmdr.UsageErrorf("-approve needs -repo and -cluster")
+		}
+		q["repo"], q["offset"], q["flavor"], q["cluster"] = f.Repo, f.Offset, f.Flavor, f.Cluster
+		q["version"] = spp.flags.approve
+		if _, err := spp.Create("./promotion-approval", q, nil, spp.User.HTTPHeaders()); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		return cmdr.Successf("Approved promotion of %s to %s.", f.Repo, spp.flags.approve)
+	}
+	return cmdr.UsageErrorf("one of -pause, -resume or -approve is required")
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingRestore is the description of the `sous plumbing restore` command.
+type SousPlumbingRestore struct {
+	graph.HTTPClient
+	User sous.User
+}
+
+func init() { PlumbingSubcommands["restore"] = &SousPlumbingRestore{} }
+
+const sousPlumbingRestoreHelp = `Restores the GDM from a snapshot.
+
+usage: sous plumbing restore <id|time>
+
+The snapshot is named either by its ID, or by an RFC3339 time, which names the
+latest snapshot taken at or before that time; see 'sous plumbing snapshot
+list'. The restore is recorded in the GDM's history as made by you. A
+snapshot of the GDM it replaces is taken first, so that the restore can itself
+be undone. Restoring changes to the definitions needs an admin.
+`
+
+// Help prints the help
+func (*SousPlumbingRestore) Help() string { return sousPlumbingRestoreHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingRestore) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing restore`
+func (spr *SousPlumbingRestore) Execute(args []string) cmdr.Result {
+	if len(args) != 1 {
+		return cmdr.UsageErrorf("usage: sous plumbing restore <id|time>")
+	}
+	res, err := spr.Create("./gdm/restore", map[string]string{"at": args[0]}, nil, spr.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	return cmdr.Successf("Restored GDM from %s. The GDM it replaced is snapshot %s.", args[0], snapshotAt(res.Location()))
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/dto"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+type (
+	// SousPlumbingSnapshot is the description of the `sous plumbing snapshot` command.
+	SousPlumbingSnapshot struct{}
+
+	// SousPlumbingSnapshotList is the description of the `sous plumbing snapshot list` command.
+	SousPlumbingSnapshotList struct {
+		graph.HTTPClient
+		flags struct {
+			limit int
+		}
+	}
+
+	// SousPlumbingSnapshotShow is the description of the `sous plumbing snapshot show` command.
+	SousPlumbingSnapshotShow struct {
+		graph.HTTPClient
+	}
+
+	// SousPlumbingSnapshotDiff is the description of the `sous plumbing snapshot diff` command.
+	SousPlumbingSnapshotDiff struct {
+		graph.HTTPClient
+		StateReader graph.StateReader
+	}
+
+	// SousPlumbingSnapshotTake is the description of the `sous plumbing snapshot take` command.
+	SousPlumbingSnapshotTake struct {
+		graph.HTTPClient
+		User sous.User
+	}
+)
+
+// SnapshotSubcommands collects the subcommands of `sous plumbing snapshot`.
+var SnapshotSubcommands = cmdr.Commands{
+	"list": &SousPlumbingSnapshotList{},
+	"show": &SousPlumbingSnapshotShow{},
+	"diff": &SousPlumbingSnapshotDiff{},
+	"take": &SousPlumbingSnapshotTake{},
+}
+
+func init() { PlumbingSubcommands["snapshot"] = &SousPlumbingSnapshot{} }
+
+const sousPlumbingSnapshotHelp = `Lists, shows, compares and takes snapshots of the GDM.
+
+The server takes a snapshot of the GDM every SOUS_SNAPSHOT_INTERVAL minutes
+if it has changed, and whenever asked to. Snapshots are named either by their
+ID, or by an RFC3339 time, which names the latest snapshot taken at or before
+that time. Use 'sous plumbing restore' to restore the GDM from one.
+`
+
+// Help prints the help
+func (*SousPlumbingSnapshot) Help() string { return sousPlumbingSnapshotHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingSnapshot) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Subcommands implements Subcommander for SousPlumbingSnapshot
+func (*SousPlumbingSnapshot) Subcommands() cmdr.Commands {
+	return SnapshotSubcommands
+}
+
+// Execute defines the behavior of `sous plumbing snapshot`
+func (*SousPlumbingSnapshot) Execute(args []string) cmdr.Result {
+	err := cmdr.UsageErrorf("usage: sous plumbing snapshot command")
+	err.Tip = "try `sous help plumbing snapshot` for a list of commands"
+	return err
+}
+
+const sousPlumbingSnapshotListHelp = `Lists the snapshots of the GDM, newest first.
+
+usage: sous plumbing snapshot list [-limit <n>]
+`
+
+// Help prints the help
+func (*SousPlumbingSnapshotList) Help() string { return sousPlumbingSnapshotListHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingSnapshotList) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// AddFlags adds the flags for sous plumbing snapshot list.
+func (spl *SousPlumbingSnapshotList) AddFlags(fs *flag.FlagSet) {
+	fs.IntVar(&spl.flags.limit, "limit", 50, "list at most this many snapshots (0 for all)")
+}
+
+// Execute defines the behavior of `sous plumbing snapshot list`
+func (spl *SousPlumbingSnapshotList) Execute(args []string) cmdr.Result {
+	snapshots := &dto.SnapshotsResponse{}
+	qs := map[string]string{"limit": strconv.Itoa(spl.flags.limit)}
+	if _, err := spl.Retrieve("./gdm/snapshots", qs, snapshots, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tREASON\tUSER")
+	for _, info := range snapshots.Snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			info.ID, info.Time.Format(time.RFC3339), info.Reason, historyUser(info.User))
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
+
+const sousPlumbingSnapshotShowHelp = `Shows the deployments in a snapshot of the GDM.
+
+usage: sous plumbing snapshot show <id|time>
+`
+
+// Help prints the help
+func (*SousPlumbingSnapshotShow) Help() string { return sousPlumbingSnapshotShowHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingSnapshotShow) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing snapshot show`
+func (sps *SousPlumbingSnapshotShow) Execute(args []string) cmdr.Result {
+	if len(args) != 1 {
+		return cmdr.UsageErrorf("usage: sous plumbing snapshot show <id|time>")
+	}
+	ss, err := retrieveSnapshot(sps.HTTPClient, args[0])
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	deps, err := ss.State().Deployments()
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "Snapshot %s taken %s (%s) %s\n\n",
+		ss.ID, ss.Time.Format(time.RFC3339), ss.Reason, historyUser(ss.User))
+	sous.DumpDeployments(out, deps)
+	return cmdr.SuccessData(out.Bytes())
+}
+
+const sousPlumbingSnapshotDiffHelp = `Lists the differences between two snapshots of the GDM.
+
+usage: sous plumbing snapshot diff <id|time> [<id|time>]
+
+Without a second snapshot, compares the first with the current GDM.
+`
+
+// Help prints the help
+func (*SousPlumbingSnapshotDiff) Help() string { return sousPlumbingSnapshotDiffHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingSnapshotDiff) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing snapshot diff`
+func (spd *SousPlumbingSnapshotDiff) Execute(args []string) cmdr.Result {
+	if len(args) < 1 || len(args) > 2 {
+		return cmdr.UsageErrorf("usage: sous plumbing snapshot diff <id|time> [<id|time>]")
+	}
+	from, err := retrieveSnapshot(spd.HTTPClient, args[0])
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	var to *sous.State
+	if len(args) == 2 {
+		ss, err := retrieveSnapshot(spd.HTTPClient, args[1])
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		to = ss.State()
+	} else if to, err = spd.StateReader.ReadState(); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	for _, d := range sous.DiffStates(from.State(), to) {
+		fmt.Fprintln(out, d)
+	}
+	return cmdr.SuccessData(out.Bytes())
+}
+
+const sousPlumbingSnapshotTakeHelp = `Takes a snapshot of the GDM now.
+
+usage: sous plumbing snapshot take
+`
+
+// Help prints the help
+func (*SousPlumbingSnapshotTake) Help() string { return sousPlumbingSnapshotTakeHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingSnapshotTake) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing snapshot take`
+func (spt *SousPlumbingSnapshotTake) Execute(args []string) cmdr.Result {
+	res, err := spt.Create("./gdm/snapshot", nil, nil, spt.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	return cmdr.Successf("Took snapshot %s.", snapshotAt(res.Location()))
+}
+
+// retrieveSnapshot gets the snapshot named by ref from the server.
+func retrieveSnapshot(client graph.HTTPClient, ref string) (*sous.StateSnapshot, error) {
+	ss := &sous.StateSnapshot{}
+	if _, err := client.Retrieve("./gdm/snapshot", map[string]string{"at": ref}, ss, nil); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// snapshotAt returns the snapshot ID in a snapshot's location.
+func snapshotAt(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return u.Query().Get("at")
+}
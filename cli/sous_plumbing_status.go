@@ -2,7 +2,10 @@ package cli
 
 import (
 	"flag"
+	"fmt"
+	"time"
 
+	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
 	"github.com/opentable/sous/util/cmdr"
@@ -12,20 +15,26 @@ import (
 type SousPlumbingStatus struct {
 	SousGraph *graph.SousGraph
 	Config    graph.LocalSousConfig
+	CLI       *CLI
 
 	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	watch             bool
 }
 
 func init() { PlumbingSubcommands["status"] = &SousPlumbingStatus{} }
 
 // Help implements Command on SousPlumbingStatus.
 func (*SousPlumbingStatus) Help() string {
-	return `reports the status of a given deployment`
+	return `reports the status of a given deployment
+
+With -watch, the status is reported again each time the GDM changes, until
+interrupted.`
 }
 
 // AddFlags implements cmdr.AddFlags on SousPlumbingStatus.
 func (sps *SousPlumbingStatus) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &sps.DeployFilterFlags, DeployFilterFlagsHelp)
+	fs.BoolVar(&sps.watch, "watch", false, "report the status again whenever the GDM changes")
 }
 
 // Execute implements cmdr.Executor on SousPlumbingStatus.
@@ -38,9 +47,36 @@ func (sps *SousPlumbingStatus) Execute(args []string) cmdr.Result {
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
 	}
+	if sps.watch {
+		return sps.watchStatus(poll)
+	}
 	if err := poll.Do(); err != nil {
 		return cmdr.EnsureErrorResult(err)
 	}
 	return cmdr.Success()
 
 }
+
+func (sps *SousPlumbingStatus) watchStatus(poll actions.Action) cmdr.Result {
+	w, err := sps.SousGraph.GetGDMWatcher()
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	changes, err := w.Watch(nil)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	for {
+		if err := poll.Do(); err != nil {
+			fmt.Fprintf(sps.CLI.Err, "%s\n", err)
+		} else {
+			fmt.Fprintf(sps.CLI.Out, "Deployments resolved.\n")
+		}
+		fmt.Fprintf(sps.CLI.Out, "Waiting for the GDM to change...\n")
+		change, ok := <-changes
+		if !ok {
+			return cmdr.InternalErrorf("stopped watching the GDM")
+		}
+		fmt.Fprintf(sps.CLI.Out, "GDM changed at %s.\n", change.Time.Format(time.RFC3339))
+	}
+}
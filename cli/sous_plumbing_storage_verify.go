@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/ext/storage"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingStorageVerify is the description of the `sous plumbing storage-verify` command.
+type SousPlumbingStorageVerify struct {
+	graph.HTTPClient
+	User  sous.User
+	flags struct {
+		repair bool
+	}
+}
+
+func init() { PlumbingSubcommands["storage-verify"] = &SousPlumbingStorageVerify{} }
+
+const sousPlumbingStorageVerifyHelp = `Compares the server's Postgres storage of the GDM with its primary.
+
+usage: sous plumbing storage-verify [-repair]
+
+Every write to the GDM is made to both the primary storage (usually git) and
+the secondary (usually Postgres), but a failed write to the secondary is only
+logged. This lists each manifest deployed to the server's cluster which
+differs between the primary and the server's Postgres database: missing from
+Postgres, only in Postgres, or modified, with the differences, and any
+differences in the definitions Postgres stores.
+
+With -repair, which only admins may use, the Postgres database is then
+rewritten from the primary.
+
+If SOUS_STORAGE_VERIFY_INTERVAL is set, the server also checks every that many
+minutes, reporting the divergences as metrics, and repairs them if
+SOUS_STORAGE_VERIFY_REPAIR is set.
+`
+
+// Help prints the help
+func (*SousPlumbingStorageVerify) Help() string { return sousPlumbingStorageVerifyHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousPlumbingStorageVerify) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// AddFlags adds the flags for sous plumbing storage-verify.
+func (spv *SousPlumbingStorageVerify) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&spv.flags.repair, "repair", false, "rewrite the secondary storage from the primary if they differ")
+}
+
+// Execute defines the behavior of `sous plumbing storage-verify`
+func (spv *SousPlumbingStorageVerify) Execute(args []string) cmdr.Result {
+	if spv.flags.repair {
+		if _, err := spv.Create("./storage/repair", nil, nil, spv.User.HTTPHeaders()); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+	}
+	report := &storage.StorageReport{}
+	if _, err := spv.Retrieve("./storage/verify", nil, report, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	if report.Consistent() {
+		if spv.flags.repair {
+			return cmdr.Successf("Secondary storage repaired; all %d manifests deployed to %s agree.", report.Manifests, report.Cluster)
+		}
+		return cmdr.Successf("Secondary storage agrees with the primary for all %d manifests deployed to %s.", report.Manifests, report.Cluster)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "Secondary storage differs from the primary in %d of %d manifests deployed to %s",
+		len(report.Divergences), report.Manifests, report.Cluster)
+	if len(report.Defs) > 0 {
+		fmt.Fprintf(out, ", and in the definitions:\n\n")
+		for _, d := range report.Defs {
+			fmt.Fprintf(out, "  %s\n", d)
+		}
+	} else {
+		fmt.Fprintf(out, ":\n")
+	}
+	if len(report.Divergences) > 0 {
+		fmt.Fprintln(out)
+		w := &tabwriter.Writer{}
+		w.Init(out, 2, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MANIFEST\tDIVERGENCE\tDIFFS")
+		for _, d := range report.Divergences {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", d.ManifestID, d.Kind, strings.Join(d.Diffs, "; "))
+		}
+		w.Flush()
+	}
+	if !spv.flags.repair {
+		fmt.Fprintln(out, "\nRun with -repair to rewrite the secondary storage from the primary.")
+	}
+	return cmdr.SuccessData(out.Bytes())
+}
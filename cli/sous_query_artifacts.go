@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"flag"
+
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
 	"github.com/opentable/sous/lib"
@@ -11,6 +13,9 @@ import (
 type SousQueryArtifacts struct {
 	*sous.RegistryDumper
 	graph.ErrWriter
+	flags struct {
+		sbom bool
+	}
 }
 
 func init() { QuerySubcommands["artifacts"] = &SousQueryArtifacts{} }
@@ -19,6 +24,8 @@ const sousQueryArtifactsHelp = `Lists the images that Sous is currently aware of
 
 Note that Sous may discover more images after attempting a rectify
 
+With -sbom, lists the packages recorded in the software bill of materials of
+each image instead.
 `
 
 func (*SousQueryArtifacts) RegisterOn(psy Addable) {
@@ -26,11 +33,19 @@ func (*SousQueryArtifacts) RegisterOn(psy Addable) {
 	psy.Add(&config.DeployFilterFlags{})
 }
 
+// AddFlags adds the flags for sous query artifacts.
+func (sqa *SousQueryArtifacts) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&sqa.flags.sbom, "sbom", false, "list the packages in the SBOM of each image")
+}
+
 // Help prints the help
 func (*SousQueryArtifacts) Help() string { return sousQueryArtifactsHelp }
 
 // Execute defines the behavior of `sous query gdm`
 func (sqa *SousQueryArtifacts) Execute(args []string) cmdr.Result {
+	if sqa.flags.sbom {
+		return ProduceResult(sqa.RegistryDumper.SBOMsAsTable(sqa.ErrWriter))
+	}
 	err := sqa.RegistryDumper.AsTable(sqa.ErrWriter)
 	return ProduceResult(err)
 }
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousQueryHistory is the description of the `sous query history` command.
+type SousQueryHistory struct {
+	graph.HTTPClient
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	ResolveFilter     *sous.ResolveFilter
+	flags             struct {
+		limit int
+	}
+}
+
+func init() { QuerySubcommands["history"] = &SousQueryHistory{} }
+
+const sousQueryHistoryHelp = `The history of changes to the GDM, newest first.
+
+Each change is listed with the user who made it, when, the manifest and
+clusters affected, a description of the change, and the outcome of the first
+rectification of each affected cluster afterwards.
+
+The usual -repo, -offset, -flavor and -cluster flags restrict the listing to
+matching manifests and clusters.
+`
+
+// Help prints the help
+func (*SousQueryHistory) Help() string { return sousQueryHistoryHelp }
+
+// RegisterOn registers items on the DI graph
+func (sqh *SousQueryHistory) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&sqh.DeployFilterFlags)
+}
+
+// AddFlags adds the flags for sous query history.
+func (sqh *SousQueryHistory) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sqh.DeployFilterFlags, RectifyFilterFlagsHelp,
+		map[string]interface{}{"offset": "*", "flavor": "*"})
+	fs.IntVar(&sqh.flags.limit, "limit", 50, "show at most this many changes (0 for all)")
+}
+
+// Execute defines the behavior of `sous query history`
+func (sqh *SousQueryHistory) Execute(args []string) cmdr.Result {
+	qs := historyQuery(sqh.ResolveFilter)
+	qs["limit"] = strconv.Itoa(sqh.flags.limit)
+
+	history := &dto.HistoryResponse{}
+	if _, err := sqh.Retrieve("./history", qs, history, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tMANIFEST\tCHANGE\tOUTCOMES\tDIFFS")
+	for _, e := range history.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Format(time.RFC3339), historyUser(e.User), e.ManifestID, e.Kind,
+			historyOutcomes(e), strings.Join(e.Diffs, "; "))
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
+
+// historyQuery converts rf into query values for the /history resource.
+func historyQuery(rf *sous.ResolveFilter) map[string]string {
+	qs := map[string]string{}
+	if rf == nil {
+		return qs
+	}
+	for name, matcher := range map[string]sous.ResolveFieldMatcher{
+		"repo":    rf.Repo,
+		"offset":  rf.Offset,
+		"flavor":  rf.Flavor,
+		"cluster": rf.Cluster,
+	} {
+		if v, err := matcher.Value(); err == nil {
+			qs[name] = v
+		}
+	}
+	return qs
+}
+
+func historyUser(u sous.User) string {
+	if u.Email == "" {
+		return u.Name
+	}
+	return u.Email
+}
+
+// historyOutcomes summarises the outcome for each cluster affected by e.
+func historyOutcomes(e sous.HistoryEntry) string {
+	clusters := append([]string{}, e.Clusters...)
+	sort.Strings(clusters)
+	var outcomes []string
+	for _, c := range clusters {
+		o, has := e.Outcomes[c]
+		switch {
+		case !has:
+			outcomes = append(outcomes, c+":pending")
+		case o.Resolution.Error != nil:
+			outcomes = append(outcomes, c+":failed")
+		default:
+			outcomes = append(outcomes, fmt.Sprintf("%s:%s", c, o.Resolution.Desc))
+		}
+	}
+	return strings.Join(outcomes, ",")
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousQueryPromotions is the description of the `sous query promotions` command.
+type SousQueryPromotions struct {
+	graph.HTTPClient
+}
+
+func init() { QuerySubcommands["promotions"] = &SousQueryPromotions{} }
+
+const sousQueryPromotionsHelp = `The promotion pipelines in the GDM, and any promotions awaiting approval.
+
+Use 'sous plumbing promotion' to pause, resume or approve promotions.
+`
+
+// Help prints the help
+func (*SousQueryPromotions) Help() string { return sousQueryPromotionsHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousQueryPromotions) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&config.DeployFilterFlags{})
+}
+
+// Execute defines the behavior of `sous query promotions`
+func (sqp *SousQueryPromotions) Execute(args []string) cmdr.Result {
+	promotions := &dto.PromotionsResponse{}
+	if _, err := sqp.Retrieve("./promotions", nil, promotions, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PIPELINE\tSTAGES\tPAUSED\tAWAITING APPROVAL")
+	for _, p := range promotions.Promotions {
+		var stages, pending []string
+		for _, s := range p.Stages {
+			stages = append(stages, s.Cluster)
+		}
+		for _, pp := range p.Pending {
+			pending = append(pending, fmt.Sprintf("%s %s->%s", pp.DeploymentID, pp.From, pp.To))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", p.Name, strings.Join(stages, ","), p.Paused, strings.Join(pending, "; "))
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
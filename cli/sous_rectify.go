@@ -13,6 +13,8 @@ import (
 type SousRectify struct {
 	Config            graph.LocalSousConfig
 	dryrun            string
+	plan              bool
+	format            string
 	SousGraph         *graph.SousGraph
 	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
 	SourceHostChooser sous.SourceHostChooser
@@ -34,7 +36,11 @@ Use with great care.
 Because of the hazard involved in doing complete rectification at the command
 line, sous rectify requires the -all flag to consider the whole tree. This is
 almost certainly not what you want. Even if it is, you certainly want to trial
-your rectifies with -dry-run=scheduler first.
+your rectifies with -plan or -dry-run=scheduler first.
+
+-plan lists the deployments that would be created, modified and deleted, and
+the fields that would change in each, without changing anything. Use
+-format json for output suitable for other tools.
 
 Note: by default this command will query a live docker registry and make
 changes to live Singularity clusters.
@@ -51,6 +57,8 @@ func (sr *SousRectify) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sr.dryrun, "dry-run", "none",
 		"prevent rectify from actually changing things - "+
 			"values are none,scheduler,registry,both")
+	fs.BoolVar(&sr.plan, "plan", false, planFlagHelp)
+	fs.StringVar(&sr.format, "format", "text", planFormatFlagHelp)
 }
 
 // Execute fulfils the cmdr.Executor interface.
@@ -70,6 +78,17 @@ func (sr *SousRectify) Execute(args []string) cmdr.Result {
 			"(Or -all if you really mean to rectify the whole world; see 'sous help rectify'.)")
 	}
 
+	if sr.plan {
+		plan, err := sr.SousGraph.GetPlanRectify(sr.dryrun, sr.DeployFilterFlags)
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		if err := plan.Do(); err != nil {
+			return EnsureErrorResult(err)
+		}
+		return planResult(plan.Result, sr.format)
+	}
+
 	rectify, err := sr.SousGraph.GetRectify(sr.dryrun, sr.DeployFilterFlags)
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
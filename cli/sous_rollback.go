@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/samsalisbury/semv"
+)
+
+// SousRollback is the command description for `sous rollback`.
+type SousRollback struct {
+	DeployFilterFlags  config.DeployFilterFlags `inject:"optional"`
+	HTTPClient         *graph.ClusterSpecificHTTPClient
+	TargetDeploymentID graph.TargetDeploymentID
+	Historian          sous.DeployHistorian
+	Registry           sous.Registry
+	LogSink            graph.LogSink
+	User               sous.User
+	graph.LocalSousConfig
+	to         string
+	waitStable bool
+}
+
+func init() { TopLevelCommands["rollback"] = &SousRollback{} }
+
+const sousRollbackHelp = `redeploys the previous known-good version into a particular cluster
+
+usage: sous rollback -cluster <name> [-to <version>]
+
+sous rollback looks up the versions of this application previously deployed
+successfully in the named cluster, and deploys the most recent one which
+differs from the version currently intended for it. Only versions still
+present in the registry are considered.
+
+Use -to to choose a specific version instead.`
+
+// Help returns the help string for this command.
+func (sr *SousRollback) Help() string { return sousRollbackHelp }
+
+// AddFlags adds the flags for sous rollback.
+func (sr *SousRollback) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sr.DeployFilterFlags, ManifestFilterFlagsHelp+ClusterFilterFlagsHelp)
+
+	fs.StringVar(&sr.to, "to", "",
+		"the version to roll back to (default: the previous successfully deployed version)")
+	fs.BoolVar(&sr.waitStable, "wait-stable", true,
+		"wait for the deploy to complete before returning (otherwise, use --wait-stable=false)")
+}
+
+// RegisterOn adds flag options to the graph.
+func (sr *SousRollback) RegisterOn(psy Addable) {
+	psy.Add(&sr.DeployFilterFlags)
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute rolls the target deployment back.
+func (sr *SousRollback) Execute(args []string) cmdr.Result {
+	did := sous.DeploymentID(sr.TargetDeploymentID)
+
+	d := server.SingleDeploymentBody{}
+	q := sr.TargetDeploymentID.QueryMap()
+	q["force"] = "false"
+
+	updater, err := sr.HTTPClient.Retrieve("./single-deployment", q, &d, nil)
+	if err != nil {
+		return cmdr.InternalErrorf("Failed to retrieve current deployment: %s", err)
+	}
+	if d.Deployment == nil {
+		return cmdr.InternalErrorf("No deployment of %q found", sr.TargetDeploymentID)
+	}
+	current := d.Deployment.Version
+
+	target, err := sr.targetVersion(did, current)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	messages.ReportLogFieldsMessage("SousRollback.Execute rolling back",
+		logging.InformationLevel, sr.LogSink, did, current, target)
+
+	d.Deployment.Version = target
+
+	updateResponse, err := updater.Update(d, sr.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.InternalErrorf("Failed to update deployment: %s", err)
+	}
+
+	if !sr.waitStable {
+		return cmdr.Successf("Rollback of %q to %s requested of server. Exiting optimistically.", sr.TargetDeploymentID, target)
+	}
+
+	if location := updateResponse.Location(); location != "" {
+		return waitForDeployment(location, sr.Config.PollIntervalForClient, sr.LogSink)
+	}
+	return cmdr.Successf("Desired version for %q already %q", sr.TargetDeploymentID, target)
+}
+
+// targetVersion determines the version to roll back to, checking that it is
+// known to the registry.
+func (sr *SousRollback) targetVersion(did sous.DeploymentID, current semv.Version) (semv.Version, error) {
+	known, err := sr.Registry.ListSourceIDs()
+	if err != nil {
+		return semv.Version{}, err
+	}
+
+	if sr.to != "" {
+		to, err := semv.Parse(sr.to)
+		if err != nil {
+			return semv.Version{}, err
+		}
+		sid := sous.SourceID{Location: did.ManifestID.Source, Version: to}
+		if !sous.KnownSourceID(sid, known) {
+			// The registry's list may simply be stale; ask for the artifact directly.
+			if _, err := sr.Registry.GetArtifact(sid); err != nil {
+				return semv.Version{}, cmdr.UsageErrorf("version %s of %s is not in the registry: %s", to, did.ManifestID.Source, err)
+			}
+		}
+		return to, nil
+	}
+
+	deployed, err := sr.Historian.DeployedVersions(did)
+	if err != nil {
+		return semv.Version{}, err
+	}
+	return sous.RollbackVersion(did, current, deployed, known)
+}
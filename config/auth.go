@@ -0,0 +1,131 @@
+package config
+
+import "github.com/pkg/errors"
+
+// AuthConfig describes how the Sous server identifies the users making
+// requests, and which of them may change which manifests.
+type AuthConfig struct {
+	// Mode is how the server authenticates requests. It is one of:
+	//
+	//   header: trust the Sous-User-Name and Sous-User-Email headers sent by
+	//           the client (the default). Since any client can claim to be
+	//           anyone, Authorize cannot be used in this mode.
+	//   proxy:  trust ProxyNameHeader and ProxyEmailHeader, set by an
+	//           authenticating proxy in front of the server.
+	//   token:  look up bearer tokens in TokenFile.
+	//   tls:    use the name and email of a verified client certificate.
+	Mode string `env:"SOUS_AUTH_MODE"`
+	// ProxyNameHeader is the header naming the user in "proxy" mode.
+	ProxyNameHeader string `env:"SOUS_AUTH_PROXY_NAME_HEADER"`
+	// ProxyEmailHeader is the header carrying the user's email in "proxy"
+	// mode.
+	ProxyEmailHeader string `env:"SOUS_AUTH_PROXY_EMAIL_HEADER"`
+	// TokenFile is a YAML file mapping bearer tokens to users, each with a
+	// Name and Email, used in "token" mode.
+	TokenFile string `env:"SOUS_AUTH_TOKEN_FILE"`
+	// TLSCertFile and TLSKeyFile, if set, make the server listen for HTTPS.
+	TLSCertFile string `env:"SOUS_TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"SOUS_TLS_KEY_FILE"`
+	// TLSClientCAFile is the CA bundle used to verify client certificates,
+	// needed in "tls" mode.
+	TLSClientCAFile string `env:"SOUS_TLS_CLIENT_CA_FILE"`
+	// Authorize turns on checking that the user making a change to a manifest
+	// is one of its Owners, or an admin, and holds any role needed for the
+	// clusters changed. It needs a Mode other than "header".
+	Authorize bool `env:"SOUS_AUTH_AUTHORIZE"`
+	// Admins are the names or emails of users who may change any manifest.
+	// Servers which replace the deployments of their siblings' clusters
+	// authenticate with ClientAuth, and so the user it names should be an
+	// admin.
+	Admins []string
+	// ClusterDeployers maps cluster names to the names or emails of the users
+	// who may change deployments to that cluster. Clusters not listed may be
+	// changed by any owner of a manifest.
+	ClusterDeployers map[string][]string
+}
+
+// ClientAuthConfig is the credentials the client presents to the server,
+// which it needs when the server authenticates users in "token" or "tls" mode.
+type ClientAuthConfig struct {
+	// Token is sent to the server as a bearer token.
+	Token string `env:"SOUS_CLIENT_TOKEN"`
+	// CertFile and KeyFile are the client certificate, and its key.
+	CertFile string `env:"SOUS_CLIENT_CERT_FILE"`
+	KeyFile  string `env:"SOUS_CLIENT_KEY_FILE"`
+	// CAFile is a CA bundle used to verify the server's certificate, as well
+	// as the system's CAs.
+	CAFile string `env:"SOUS_CLIENT_CA_FILE"`
+}
+
+// Validate returns an error if this ClientAuthConfig is incomplete.
+func (c ClientAuthConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("CertFile and KeyFile must be set together")
+	}
+	return nil
+}
+
+// Validate returns an error if this AuthConfig is incomplete.
+func (c AuthConfig) Validate() error {
+	switch c.Mode {
+	default:
+		return errors.Errorf("unknown auth mode %q: expected header, proxy, token or tls", c.Mode)
+	case "", "header":
+	case "proxy":
+		if c.ProxyNameHeader == "" && c.ProxyEmailHeader == "" {
+			return errors.New("proxy auth mode needs ProxyNameHeader or ProxyEmailHeader")
+		}
+	case "token":
+		if c.TokenFile == "" {
+			return errors.New("token auth mode needs a TokenFile")
+		}
+	case "tls":
+		if c.TLSCertFile == "" || c.TLSKeyFile == "" || c.TLSClientCAFile == "" {
+			return errors.New("tls auth mode needs TLSCertFile, TLSKeyFile and TLSClientCAFile")
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLSCertFile and TLSKeyFile must be set together")
+	}
+	if c.Authorize && (c.Mode == "" || c.Mode == "header") {
+		return errors.New("Authorize needs an auth mode which does not trust the client's headers: proxy, token or tls")
+	}
+	return nil
+}
+
+// Equal tests the equality of two AuthConfigs.
+func (c AuthConfig) Equal(other AuthConfig) bool {
+	if c.Mode != other.Mode ||
+		c.ProxyNameHeader != other.ProxyNameHeader ||
+		c.ProxyEmailHeader != other.ProxyEmailHeader ||
+		c.TokenFile != other.TokenFile ||
+		c.TLSCertFile != other.TLSCertFile ||
+		c.TLSKeyFile != other.TLSKeyFile ||
+		c.TLSClientCAFile != other.TLSClientCAFile ||
+		c.Authorize != other.Authorize {
+		return false
+	}
+	if len(c.Admins) != len(other.Admins) {
+		return false
+	}
+	for i := range c.Admins {
+		if c.Admins[i] != other.Admins[i] {
+			return false
+		}
+	}
+	if len(c.ClusterDeployers) != len(other.ClusterDeployers) {
+		return false
+	}
+	for cluster, users := range c.ClusterDeployers {
+		others, ok := other.ClusterDeployers[cluster]
+		if !ok || len(users) != len(others) {
+			return false
+		}
+		for i := range users {
+			if users[i] != others[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -4,5 +4,8 @@ type (
 	// PolicyFlags capture user intent about the processing of a build
 	PolicyFlags struct {
 		ForceClone, Strict bool
+		// Platforms is a comma separated list of the platforms to build
+		// images for, e.g. "linux/amd64,linux/arm64".
+		Platforms string
 	}
 )
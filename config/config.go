@@ -8,6 +8,8 @@ import (
 	"path"
 
 	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/secrets"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
@@ -28,6 +30,9 @@ type (
 		Server string `env:"SOUS_SERVER"`
 		// Database contains configuration for the local Postgresql DB.
 		Database storage.PostgresConfig
+		// ObjectStore contains configuration for storing the GDM in an
+		// S3-compatible object store, as well as or instead of in git.
+		ObjectStore storage.ObjectStoreConfig
 		// SiblingURLs is a temporary measure for setting up a distributed cluster
 		// of sous servers. Each server must be configured with accessible URLs for
 		// all the servers in production, as named by cluster.
@@ -38,6 +43,22 @@ type (
 		BuildStateDir string `env:"SOUS_BUILD_STATE_DIR"`
 		// Docker is the Docker configuration.
 		Docker docker.Config
+		// Kubernetes is the configuration used for clusters of kind
+		// "kubernetes".
+		Kubernetes kubernetes.Config
+		// Secrets is the configuration of the provider of secrets referred to
+		// in deployment environments.
+		Secrets secrets.Config
+		// Auth is the configuration of authentication and authorization on the
+		// Sous server.
+		Auth AuthConfig
+		// ClientAuth is the credentials this client presents to the Sous
+		// server, and that the server presents to its siblings.
+		ClientAuth ClientAuthConfig
+		// WebhookHosts are the hosts the server may send webhook
+		// notifications to. A host beginning with a dot allows any of its
+		// subdomains. Webhooks to any other host are refused.
+		WebhookHosts []string
 		// Logging is the logging configuration.
 		Logging logging.Config
 		// User identifies the user of this client.
@@ -47,6 +68,26 @@ type (
 		MaxHTTPConcurrencySingularity int `env:"MAX_HTTP_CONCURRENCY_SINGULARITY"`
 		// PollIntervalForClient is the maximum number of checks for client on SOUS Deploy
 		PollIntervalForClient int `env:"SOUS_POLL_INTERVAL_FOR_CLIENT"`
+		// BuildWorkers is the number of builds the server runs for clients at
+		// once, with `sous build -remote`. If it is 0, the server refuses
+		// remote builds.
+		BuildWorkers int `env:"SOUS_BUILD_WORKERS"`
+		// BuildRepoHosts are the hosts whose repos the server clones for
+		// remote builds, e.g. github.com. Repos on other hosts are refused.
+		BuildRepoHosts []string
+		// SnapshotInterval is the number of minutes between the server's
+		// snapshots of the GDM. Snapshots are only taken when the GDM has
+		// changed since the last one. If it is 0, snapshots are only taken
+		// when asked for.
+		SnapshotInterval int `env:"SOUS_SNAPSHOT_INTERVAL"`
+		// StorageVerifyInterval is the number of minutes between the server's
+		// checks that its local Postgres storage of the GDM agrees with the
+		// primary. If it is 0, the default, the storage is only checked when
+		// asked to.
+		StorageVerifyInterval int `env:"SOUS_STORAGE_VERIFY_INTERVAL"`
+		// StorageVerifyRepair has the server's periodic checks rewrite the
+		// secondary storage from the primary when they disagree.
+		StorageVerifyRepair bool `env:"SOUS_STORAGE_VERIFY_REPAIR"`
 	}
 )
 
@@ -76,15 +117,23 @@ func (c Config) Validate() error {
 	if err := c.Logging.Validate(); err != nil {
 		return errors.Wrapf(err, "Config.Logging")
 	}
+	if err := c.Auth.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Auth")
+	}
+	if err := c.ClientAuth.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.ClientAuth")
+	}
 	return nil
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
-		Docker: docker.DefaultConfig(),
+		Docker:                        docker.DefaultConfig(),
+		Kubernetes:                    kubernetes.DefaultConfig(),
 		MaxHTTPConcurrencySingularity: 10,
 		PollIntervalForClient:         600,
+		SnapshotInterval:              60,
 	}
 }
 
@@ -102,9 +151,21 @@ func (c *Config) Equal(other *Config) bool {
 	if c.Docker != other.Docker {
 		return false
 	}
+	if c.Kubernetes != other.Kubernetes {
+		return false
+	}
+	if c.Secrets != other.Secrets {
+		return false
+	}
 	if !c.Logging.Equal(other.Logging) {
 		return false
 	}
+	if !c.Auth.Equal(other.Auth) {
+		return false
+	}
+	if c.ClientAuth != other.ClientAuth {
+		return false
+	}
 	if len(c.SiblingURLs) != len(other.SiblingURLs) {
 		return false
 	}
@@ -76,6 +76,16 @@ func TestConfig_Validate(t *testing.T) {
 
 	cfg.Server = ""
 	checkValid()
+
+	cfg.Auth.Authorize = true
+	checkNotValid()
+
+	cfg.Auth.Mode = "header"
+	checkNotValid()
+
+	cfg.Auth.Mode = "token"
+	cfg.Auth.TokenFile = "/etc/sous/tokens.yaml"
+	checkValid()
 }
 
 func TestConfig_Equals(t *testing.T) {
@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// HistoryResponse is used by the server to return GDM history entries,
+// newest first.
+type HistoryResponse struct {
+	Entries []sous.HistoryEntry
+}
@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// NotificationsResponse is used by the server to return the log of recent
+// webhook deliveries.
+type NotificationsResponse struct {
+	Deliveries []sous.NotificationDelivery
+}
@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// PromotionsResponse is used by the server to return the status of each
+// promotion pipeline.
+type PromotionsResponse struct {
+	Promotions []sous.PromotionStatus
+}
@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// SnapshotsResponse is used by the server to describe the snapshots taken of
+// the GDM, newest first.
+type SnapshotsResponse struct {
+	Snapshots []sous.SnapshotInfo
+}
@@ -0,0 +1,230 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// A BuildCache lets builds reuse the work of earlier builds.
+	//
+	// Builds pass --cache-from an image built from the same Dockerfile, which
+	// is pulled from (and, if Push is set, pushed back to) the registry, so
+	// that unchanged layers need not be rebuilt.
+	//
+	// The result of each build is recorded under Dir, keyed on its SourceID
+	// and the hash of its Dockerfile. A later build with the same key, whose
+	// images are still present locally and in the NameCache, is skipped
+	// altogether. The advisories of the build's context (about tags or the
+	// workspace, say) are not recorded, since they can change while the key
+	// stays the same; the later build's own are added to its result by
+	// BuildResult.Contextualize.
+	//
+	// A nil *BuildCache caches nothing.
+	BuildCache struct {
+		RegistryHost string
+		// Dir is where build results are recorded. If empty, builds are never
+		// skipped.
+		Dir string
+		// Push is true if cache images are pushed to the registry after each
+		// build.
+		Push      bool
+		NameCache *NameCache
+		Log       logging.LogSink
+	}
+
+	// buildCacheKey identifies a build in a BuildCache.
+	buildCacheKey struct {
+		sid sous.SourceID
+		// dockerfileHash is the hex SHA-256 of the Dockerfile built.
+		dockerfileHash string
+		// platform is the platforms the build is for, or, for the image of
+		// a single platform, that platform.
+		platform string
+	}
+)
+
+// NewBuildCache returns a BuildCache configured by cfg, recording build
+// results in stateDir, or nil if cfg does not enable the build cache.
+func NewBuildCache(cfg Config, stateDir string, nc *NameCache, ls logging.LogSink) *BuildCache {
+	if !cfg.BuildCache {
+		return nil
+	}
+	bc := &BuildCache{
+		RegistryHost: cfg.RegistryHost,
+		Push:         cfg.BuildCachePush,
+		NameCache:    nc,
+		Log:          ls,
+	}
+	if stateDir != "" {
+		bc.Dir = filepath.Join(stateDir, "build-cache")
+	}
+	return bc
+}
+
+// key computes the key of building the Dockerfile at path in ctx.
+func (bc *BuildCache) key(ctx *sous.BuildContext, path string) (buildCacheKey, error) {
+	if bc == nil {
+		return buildCacheKey{}, nil
+	}
+	df, err := ioutil.ReadFile(ctx.Sh.Abs(path))
+	if err != nil {
+		return buildCacheKey{}, err
+	}
+	sum := sha256.Sum256(df)
+	return buildCacheKey{
+		sid:            ctx.Version(),
+		dockerfileHash: hex.EncodeToString(sum[:]),
+		platform:       strings.Join(ctx.Platforms, ","),
+	}, nil
+}
+
+// forPlatform returns the key of building the image for platform as part of
+// the build keyed by k.
+func (k buildCacheKey) forPlatform(platform string) buildCacheKey {
+	k.platform = platform
+	return k
+}
+
+// String returns a file name safe representation of k.
+func (k buildCacheKey) String() string {
+	s := k.sid.String() + "\x00" + k.sid.RevID() + "\x00" + k.dockerfileHash
+	if k.platform != "" {
+		s += "\x00" + k.platform
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// image returns the name of the image whose layers are reused by builds of
+// the same Dockerfile as k.
+func (bc *BuildCache) image(k buildCacheKey) string {
+	in := fullRepoName(bc.RegistryHost, k.sid.Location, "cache") + ":" + k.dockerfileHash[:16]
+	if k.platform != "" {
+		in += "-" + platformTag(k.platform)
+	}
+	return in
+}
+
+func (bc *BuildCache) path(k buildCacheKey) string {
+	return filepath.Join(bc.Dir, k.String()+".json")
+}
+
+// lookup returns the result recorded for k if the build can be skipped.
+// Builds of dirty workspaces are never skipped. The result has only the
+// advisories of its products, not of ctx.
+func (bc *BuildCache) lookup(ctx *sous.BuildContext, k buildCacheKey) (*sous.BuildResult, bool) {
+	if bc == nil || bc.Dir == "" || ctx.Source.DirtyWorkingTree {
+		return nil, false
+	}
+	miss := func() (*sous.BuildResult, bool) {
+		reportCacheMiss(bc.Log, k.sid, bc.image(k))
+		return nil, false
+	}
+
+	f, err := os.Open(bc.path(k))
+	if err != nil {
+		return miss()
+	}
+	defer f.Close()
+	br := &sous.BuildResult{}
+	if err := json.NewDecoder(f).Decode(br); err != nil {
+		reportCacheError(bc.Log, k.sid, err)
+		return nil, false
+	}
+
+	if bc.NameCache != nil {
+		if _, _, err := bc.NameCache.getImageNameFromCache(k.sid); err != nil {
+			return miss()
+		}
+	}
+	for _, p := range br.Products {
+		if err := ctx.Sh.Cmd("docker", "image", "inspect", p.ID).Succeed(); err != nil {
+			return miss()
+		}
+	}
+	reportCacheHit(bc.Log, k.sid, bc.image(k))
+	return br, true
+}
+
+// record stores br as the result of building k in ctx, without the
+// advisories of ctx.
+func (bc *BuildCache) record(ctx *sous.BuildContext, k buildCacheKey, br *sous.BuildResult) {
+	if bc == nil || bc.Dir == "" {
+		return
+	}
+	err := func() error {
+		if err := os.MkdirAll(bc.Dir, os.ModePerm); err != nil {
+			return err
+		}
+		b, err := json.Marshal(withoutAdvisories(br, ctx.Advisories))
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(bc.path(k), b, 0644)
+	}()
+	if err != nil {
+		reportCacheError(bc.Log, k.sid, err)
+	}
+}
+
+// withoutAdvisories returns a copy of br whose products lack advisories.
+func withoutAdvisories(br *sous.BuildResult, advisories []string) *sous.BuildResult {
+	omit := map[string]bool{}
+	for _, a := range advisories {
+		omit[a] = true
+	}
+	stripped := *br
+	stripped.Products = make([]*sous.BuildProduct, len(br.Products))
+	for i, p := range br.Products {
+		sp := *p
+		sp.Advisories = nil
+		for _, a := range p.Advisories {
+			if !omit[a] {
+				sp.Advisories = append(sp.Advisories, a)
+			}
+		}
+		stripped.Products[i] = &sp
+	}
+	return &stripped
+}
+
+// cacheFrom pulls the cache image for k, and returns the docker build
+// arguments which use it. The build proceeds without the cache image if it
+// cannot be pulled.
+func (bc *BuildCache) cacheFrom(ctx *sous.BuildContext, k buildCacheKey) []interface{} {
+	if bc == nil {
+		return nil
+	}
+	image := bc.image(k)
+	if err := ctx.Sh.Cmd("docker", "pull", image).Succeed(); err != nil {
+		messages.ReportLogFieldsMessage("No build cache image", logging.DebugLevel, bc.Log, image, err)
+		return nil
+	}
+	return []interface{}{"--cache-from", image}
+}
+
+// store tags imageID as the cache image for k, and pushes it if bc.Push is
+// set. Failures are logged, but do not fail the build.
+func (bc *BuildCache) store(ctx *sous.BuildContext, k buildCacheKey, imageID string) {
+	if bc == nil {
+		return
+	}
+	image := bc.image(k)
+	err := ctx.Sh.Cmd("docker", "tag", imageID, image).Succeed()
+	if err == nil && bc.Push {
+		err = ctx.Sh.Cmd("docker", "push", image).Succeed()
+	}
+	if err != nil {
+		reportCacheError(bc.Log, k.sid, err)
+	}
+}
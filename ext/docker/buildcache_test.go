@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyarly/spies"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildCacheTestContext(t *testing.T, dir string) (*sous.BuildContext, *shell.TestShellController) {
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	require.NoError(t, ioutil.WriteFile(dockerfile, []byte("FROM blah\n"), 0644))
+	sh, ctl := shell.NewTestShell()
+	ctl.MatchMethod("Abs", spies.AnyArgs, dockerfile)
+	return &sous.BuildContext{
+		Sh: sh,
+		Source: sous.SourceContext{
+			RemoteURL:      "github.com/example/project",
+			Revision:       "abc123",
+			NearestTagName: "1.2.3",
+		},
+	}, ctl
+}
+
+func TestBuildCache_Nil(t *testing.T) {
+	var bc *BuildCache
+	ctx := &sous.BuildContext{}
+	k, err := bc.key(ctx, "Dockerfile")
+	assert.NoError(t, err)
+	_, hit := bc.lookup(ctx, k)
+	assert.False(t, hit)
+	assert.Nil(t, bc.cacheFrom(ctx, k))
+	bc.store(ctx, k, "deadbeef")
+	bc.record(ctx, k, &sous.BuildResult{})
+}
+
+func TestNewBuildCache(t *testing.T) {
+	assert.Nil(t, NewBuildCache(Config{}, "/state", nil, logging.SilentLogSet()))
+	bc := NewBuildCache(Config{BuildCache: true, RegistryHost: "docker.example.com"}, "/state", nil, logging.SilentLogSet())
+	require.NotNil(t, bc)
+	assert.Equal(t, "/state/build-cache", bc.Dir)
+}
+
+func TestBuildCache_RecordAndLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-build-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, ctl := buildCacheTestContext(t, dir)
+	bc := &BuildCache{RegistryHost: "docker.example.com", Dir: filepath.Join(dir, "cache"), Log: logging.SilentLogSet()}
+	k, err := bc.key(ctx, "Dockerfile")
+	require.NoError(t, err)
+	assert.Regexp(t, `^docker.example.com/example/project-cache:[0-9a-f]{16}$`, bc.image(k))
+	assert.Regexp(t, `^docker.example.com/example/project-cache:[0-9a-f]{16}-linux-arm64$`, bc.image(k.forPlatform("linux/arm64")))
+	assert.NotEqual(t, k.String(), k.forPlatform("linux/arm64").String())
+
+	_, hit := bc.lookup(ctx, k)
+	assert.False(t, hit)
+
+	ctx.Advisories = []string{string(sous.TagNotHead)}
+	recorded := &sous.BuildResult{Products: []*sous.BuildProduct{{ID: "deadbeef", Advisories: []string{string(sous.TagNotHead), string(sous.NotService)}}}}
+	bc.record(ctx, k, recorded)
+	assert.Len(t, recorded.Products[0].Advisories, 2, "the recorded result should not change")
+	br, hit := bc.lookup(ctx, k)
+	require.True(t, hit)
+	assert.Equal(t, "deadbeef", br.Products[0].ID)
+	assert.Len(t, ctl.CmdsLike("docker", "image", "inspect", "deadbeef"), 1)
+
+	// Advisories of the context are those of the later build, not the first.
+	ctx.Advisories = []string{string(sous.EphemeralTag)}
+	br.Contextualize(ctx)
+	assert.Equal(t, []string{string(sous.NotService), string(sous.EphemeralTag)}, br.Products[0].Advisories)
+
+	ctx.Source.DirtyWorkingTree = true
+	_, hit = bc.lookup(ctx, k)
+	assert.False(t, hit, "dirty workspaces should always be built")
+	ctx.Source.DirtyWorkingTree = false
+
+	_, cctl := ctl.CmdFor("docker", "image", "inspect")
+	cctl.MatchMethod("Succeed", spies.AnyArgs, assert.AnError)
+	_, hit = bc.lookup(ctx, k)
+	assert.False(t, hit, "missing images should be rebuilt")
+}
+
+func TestBuildCache_CacheFromAndStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-build-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, ctl := buildCacheTestContext(t, dir)
+	bc := &BuildCache{RegistryHost: "docker.example.com", Push: true, Log: logging.SilentLogSet()}
+	k, err := bc.key(ctx, "Dockerfile")
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"--cache-from", bc.image(k)}, bc.cacheFrom(ctx, k))
+	bc.store(ctx, k, "deadbeef")
+	assert.Len(t, ctl.CmdsLike("docker", "tag", "deadbeef", bc.image(k)), 1)
+	assert.Len(t, ctl.CmdsLike("docker", "push", bc.image(k)), 1)
+
+	_, cctl := ctl.CmdFor("docker", "pull")
+	cctl.MatchMethod("Succeed", spies.AnyArgs, assert.AnError)
+	assert.Nil(t, bc.cacheFrom(ctx, k))
+}
@@ -8,9 +8,11 @@ import (
 
 	"github.com/nyarly/inlinefiles/templatestore"
 	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
 	"github.com/opentable/sous/util/shell"
+	"github.com/pkg/errors"
 )
 
 //go:generate inlinefiles --vfs=templateVFS tmpl/ templates_vfs.go
@@ -22,6 +24,8 @@ type (
 		DockerRegistryHost        string
 		SourceShell, ScratchShell shell.Shell
 		Pack                      sous.Buildpack
+		// Signer, if set, signs the images registered.
+		Signer *Signer
 	}
 	// BuildTarget represents a single target within a Build.
 	BuildTarget interface {
@@ -65,15 +69,40 @@ func (b *Builder) ApplyMetadata(br *sous.BuildResult) error {
 	return nil
 }
 
-// Register registers the build artifact to the the registry
+// Register registers the build artifact to the the registry. The images
+// built for particular platforms are combined into a multi-platform image,
+// which is registered in their place.
 func (b *Builder) Register(br *sous.BuildResult) error {
+	var prods []*sous.BuildProduct
+	multi := map[string]*sous.BuildProduct{}
+	platforms := map[string][]*sous.BuildProduct{}
 	for _, prod := range br.Products {
 		err := b.pushToRegistry(prod)
 		if err != nil {
 			return err
 		}
+		if prod.Platform == "" {
+			prods = append(prods, prod)
+			continue
+		}
+		if multi[prod.Kind] == nil {
+			multi[prod.Kind] = &sous.BuildProduct{Source: prod.Source, Kind: prod.Kind}
+			prods = append(prods, multi[prod.Kind])
+		}
+		platforms[prod.Kind] = append(platforms[prod.Kind], prod)
+	}
 
-		err = b.recordName(prod)
+	for _, prod := range prods {
+		if prod == multi[prod.Kind] {
+			err := b.pushManifestLists(prod, platforms[prod.Kind])
+			if err != nil {
+				return err
+			}
+		}
+		if err := b.sign(prod); err != nil {
+			return err
+		}
+		err := b.recordName(prod)
 		if err != nil {
 			return err
 		}
@@ -89,9 +118,47 @@ func (b *Builder) info(msg string) {
 	messages.ReportLogFieldsMessage(msg, logging.InformationLevel, logging.Log)
 }
 
+// sbomPackagesScript lists the packages installed in an image, one per line
+// as the tab separated name, version and type of each.
+const sbomPackagesScript = `
+if command -v dpkg-query >/dev/null 2>&1; then
+  dpkg-query -W -f='${Package}\t${Version}\tdeb\n'
+fi
+if command -v rpm >/dev/null 2>&1; then
+  rpm -qa --qf '%{NAME}\t%{VERSION}-%{RELEASE}\trpm\n'
+fi
+if [ -f /lib/apk/db/installed ]; then
+  awk -F: '/^P:/ { p = $2 } /^V:/ { print p "\t" $2 "\tapk" }' /lib/apk/db/installed
+fi
+`
+
+// generateSBOM lists the packages installed in the image built for bp, and
+// returns the SBOM for it. Images without a shell have no packages listed.
+func (b *Builder) generateSBOM(bp *sous.BuildProduct) *sous.SBOM {
+	sh := b.SourceShell.Clone()
+	sh.LongRunning(false)
+	listing, err := sh.Cmd("docker", "run", "--rm", "--entrypoint", "/bin/sh", bp.ID, "-c", sbomPackagesScript).Stdout()
+	if err != nil {
+		messages.ReportLogFieldsMessage("Unable to list packages for SBOM", logging.WarningLevel, logging.Log, bp.ID, err)
+		listing = ""
+	}
+	dirty := false
+	for _, a := range bp.Advisories {
+		if a == string(sous.DirtyWS) {
+			dirty = true
+		}
+	}
+	return sous.NewSBOM(bp.Source, dirty, sous.ParseSBOMPackages(listing))
+}
+
 func (b *Builder) applyMetadata(bp *sous.BuildProduct) error {
 	bp.VersionName = b.VersionTag(bp.Source, bp.Kind)
 	bp.RevisionName = b.RevisionTag(bp.Source, bp.Kind, time.Now())
+	if bp.Platform != "" {
+		bp.VersionName += "-" + platformTag(bp.Platform)
+		bp.RevisionName += "-" + platformTag(bp.Platform)
+	}
+	bp.SBOM = b.generateSBOM(bp)
 
 	c := b.SourceShell.Cmd("docker", "build", "-t", bp.VersionName, "-t", bp.RevisionName, "-")
 	bf := b.metadataDockerfile(bp)
@@ -108,13 +175,22 @@ func (b *Builder) metadataDockerfile(bp *sous.BuildProduct) io.Reader {
 		panic(err)
 	}
 
+	labels := Labels(sv)
+	if bp.SBOM != nil {
+		sbom, err := bp.SBOM.EncodeLabel()
+		if err != nil {
+			panic(err)
+		}
+		labels[DockerSBOMLabel] = sbom
+	}
+
 	md.Execute(&bf, struct {
 		ImageID    string
 		Labels     map[string]string
 		Advisories []string
 	}{
 		bp.ID,
-		Labels(sv),
+		labels,
 		bp.Advisories,
 	})
 	return &bf
@@ -131,6 +207,43 @@ func (b *Builder) pushToRegistry(bp *sous.BuildProduct) error {
 	return verr
 }
 
+// pushManifestLists pushes manifest lists combining the images of ps, each
+// built for a different platform, as the multi-platform image mp.
+func (b *Builder) pushManifestLists(mp *sous.BuildProduct, ps []*sous.BuildProduct) error {
+	mp.VersionName = b.VersionTag(mp.Source, mp.Kind)
+	mp.RevisionName = b.RevisionTag(mp.Source, mp.Kind, time.Now())
+	mp.Advisories = ps[0].Advisories
+	mp.SBOM = ps[0].SBOM
+	for _, p := range ps {
+		if p.Platform == docker_registry.DefaultPlatform {
+			mp.SBOM = p.SBOM
+		}
+	}
+
+	for _, list := range []string{mp.VersionName, mp.RevisionName} {
+		create := []interface{}{"manifest", "create", "--amend", list}
+		for _, p := range ps {
+			create = append(create, p.VersionName)
+		}
+		if err := b.SourceShell.Run("docker", create...); err != nil {
+			return err
+		}
+		if err := b.SourceShell.Run("docker", "manifest", "push", "--purge", list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sign signs the pushed image of bp as built from its source.
+func (b *Builder) sign(bp *sous.BuildProduct) error {
+	if b.Signer == nil {
+		return nil
+	}
+	b.SourceShell.ConsoleEcho(fmt.Sprintf("[signing \"%s\" as built from \"%s\"]", bp.VersionName, bp.Source.String()))
+	return errors.Wrapf(b.Signer.Sign(bp.Source, bp.VersionName), "signing %s", bp.VersionName)
+}
+
 // recordName inserts metadata about the newly built image into our local name cache
 func (b *Builder) recordName(bp *sous.BuildProduct) error {
 	sv := bp.Source
@@ -140,6 +253,9 @@ func (b *Builder) recordName(bp *sous.BuildProduct) error {
 	for _, adv := range bp.Advisories {
 		qs = append(qs, sous.Quality{Name: adv, Kind: "advisory"})
 	}
+	if si, ok := b.ImageMapper.(sous.SBOMInserter); ok && bp.SBOM != nil {
+		return si.InsertWithSBOM(sv, in, "", qs, bp.SBOM)
+	}
 	return b.ImageMapper.Insert(sv, in, "", qs)
 }
 
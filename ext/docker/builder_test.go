@@ -2,10 +2,12 @@ package docker
 
 import (
 	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
 	"github.com/opentable/sous/util/shell"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -78,3 +80,63 @@ func TestBuilderApplyMetadata(t *testing.T) {
 
 	assert.Len(t, srcCtl.CmdsLike("docker", "push"), 4)
 }
+
+func TestBuilderRegisterMultiPlatform(t *testing.T) {
+	srcSh, srcCtl := shell.NewTestShell()
+	scratchSh, _ := shell.NewTestShell()
+	nc := sous.NewInserterSpy()
+
+	b, err := NewBuilder(nc, "docker.example.com", srcSh, scratchSh)
+	require.NoError(t, err)
+
+	sid := sous.MakeSourceID("github.com/opentable/test", "", "2.3.7+abcd")
+	br := &sous.BuildResult{
+		Products: []*sous.BuildProduct{
+			{Source: sid, Platform: "linux/amd64"},
+			{Source: sid, Platform: "linux/arm64/v8"},
+		},
+	}
+
+	require.NoError(t, b.ApplyMetadata(br))
+	assert.Equal(t, "docker.example.com/test:2.3.7-linux-arm64-v8", br.Products[1].VersionName)
+
+	require.NoError(t, b.Register(br))
+	assert.Len(t, srcCtl.CmdsLike("docker", "push"), 4)
+	assert.Len(t, srcCtl.CmdsLike("docker", "manifest", "create", "--amend", "docker.example.com/test:2.3.7",
+		"docker.example.com/test:2.3.7-linux-amd64", "docker.example.com/test:2.3.7-linux-arm64-v8"), 1)
+	assert.Len(t, srcCtl.CmdsLike("docker", "manifest", "push", "--purge"), 2)
+
+	inserts := nc.CallsTo("Insert")
+	require.Len(t, inserts, 1, "only the multi-platform image should be recorded")
+	assert.Equal(t, "docker.example.com/test:2.3.7", inserts[0].PassedArgs().String(1))
+}
+
+func TestBuilderRegisterSigns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-signing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	privPath, _ := writeKeyPair(t, dir, "builder")
+
+	srcSh, _ := shell.NewTestShell()
+	scratchSh, _ := shell.NewTestShell()
+	nc := sous.NewInserterSpy()
+	dc := docker_registry.NewDummyClient()
+	dc.FeedMetadata(docker_registry.Metadata{
+		Registry:      "docker.example.com",
+		CanonicalName: "test@sha256:012345678901234567890123456789ab012345678901234567890123456789ab",
+	})
+
+	b, err := NewBuilder(nc, "docker.example.com", srcSh, scratchSh)
+	require.NoError(t, err)
+	b.Signer, err = NewSigner(privPath, dc)
+	require.NoError(t, err)
+
+	sid := sous.MakeSourceID("github.com/opentable/test", "", "2.3.7+abcd")
+	br := &sous.BuildResult{Products: []*sous.BuildProduct{{Source: sid}}}
+	require.NoError(t, b.ApplyMetadata(br))
+	require.NoError(t, b.Register(br))
+
+	assert.Equal(t, "docker.example.com/test:2.3.7", dc.CallsTo("GetImageMetadata")[0].PassedArgs().String(0))
+	assert.Len(t, dc.CallsTo("PutSignature"), 1)
+	assert.Len(t, nc.CallsTo("Insert"), 1)
+}
@@ -1,5 +1,7 @@
 package docker
 
+import "strings"
+
 type Config struct {
 	RegistryHost string `env:"SOUS_DOCKER_REGISTRY_HOST"`
 	// DatabaseDriver is the name of the driver to use for local
@@ -8,6 +10,28 @@ type Config struct {
 	// DatabaseConnection is the database connection string for local
 	// persistence.
 	DatabaseConnection string `env:"SOUS_DOCKER_DB_CONN"`
+	// BuildCache enables the build cache (see BuildCache).
+	BuildCache bool `env:"SOUS_DOCKER_BUILD_CACHE"`
+	// BuildCachePush is true if build cache images should be pushed to the
+	// registry, so that they can be used by other machines.
+	BuildCachePush bool `env:"SOUS_DOCKER_BUILD_CACHE_PUSH"`
+	// BuildpackDir is a directory of executables, each of which is an
+	// ExternalBuildpack which may be selected to build projects.
+	BuildpackDir string `env:"SOUS_BUILDPACK_DIR"`
+	// SigningKey is the path of an unencrypted PEM file holding the ECDSA
+	// private key images are signed with when they are built. Images are
+	// not signed if it is empty.
+	SigningKey string `env:"SOUS_DOCKER_SIGNING_KEY"`
+	// RemoteSigningKey is the path of an unencrypted PEM file holding the
+	// ECDSA private key images built by the server for clients are signed
+	// with. It should differ from SigningKey, so that clusters can choose
+	// whether to trust images built from anything pushed to a repo. Remote
+	// builds are not signed if it is empty.
+	RemoteSigningKey string `env:"SOUS_DOCKER_REMOTE_SIGNING_KEY"`
+	// VerificationKeys is a comma separated list of the paths of PEM files
+	// holding the ECDSA public keys whose signatures are trusted, in
+	// clusters which require signatures.
+	VerificationKeys string `env:"SOUS_DOCKER_VERIFICATION_KEYS"`
 }
 
 // DefaultConfig builds a default configuration, which can be then overridden by
@@ -20,6 +44,17 @@ func DefaultConfig() Config {
 	}
 }
 
+// VerificationKeyPaths returns the paths listed in VerificationKeys.
+func (c Config) VerificationKeyPaths() []string {
+	var paths []string
+	for _, p := range strings.Split(c.VerificationKeys, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
 func (c Config) DBConfig() DBConfig {
 	return DBConfig{
 		Driver:     c.DatabaseDriver,
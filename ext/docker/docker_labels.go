@@ -5,4 +5,7 @@ const (
 	DockerPathLabel     = "com.opentable.sous.repo_offset"
 	DockerVersionLabel  = "com.opentable.sous.version"
 	DockerRevisionLabel = "com.opentable.sous.revision"
+	// DockerSBOMLabel holds the sous.SBOM of an image, encoded by
+	// sous.SBOM.EncodeLabel.
+	DockerSBOMLabel = "com.opentable.sous.sbom"
 )
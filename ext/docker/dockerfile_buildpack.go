@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/opentable/sous/lib"
@@ -14,6 +15,8 @@ import (
 // DockerfileBuildpack is a simple buildpack for building projects using
 // their own Dockerfile.
 type DockerfileBuildpack struct {
+	// Cache is the BuildCache used by builds, if any.
+	Cache    *BuildCache
 	detected *sous.DetectResult
 }
 
@@ -75,17 +78,56 @@ func (d *DockerfileBuildpack) Detect(c *sous.BuildContext) (*sous.DetectResult,
 	return result, nil
 }
 
-// Build implements Buildpack.Build
+// Build implements Buildpack.Build. If c lists Platforms, an image is built
+// for each of them, using docker buildx.
 func (d *DockerfileBuildpack) Build(c *sous.BuildContext) (*sous.BuildResult, error) {
-	dr := d.detected
 	start := time.Now()
+
+	key, err := d.Cache.key(c, filepath.Join(c.Source.OffsetDir, "Dockerfile"))
+	if err != nil {
+		return nil, err
+	}
+	if br, hit := d.Cache.lookup(c, key); hit {
+		return br, nil
+	}
+
+	br := &sous.BuildResult{}
+	if len(c.Platforms) == 0 {
+		id, err := d.build(c, key, "")
+		if err != nil {
+			return nil, err
+		}
+		br.Products = []*sous.BuildProduct{{ID: id}}
+	}
+	for _, p := range c.Platforms {
+		id, err := d.build(c, key.forPlatform(p), p)
+		if err != nil {
+			return nil, err
+		}
+		br.Products = append(br.Products, &sous.BuildProduct{ID: id, Platform: p})
+	}
+
+	br.Elapsed = time.Since(start)
+	d.Cache.record(c, key, br)
+	return br, nil
+}
+
+// build builds the image for platform, or for the platform of the Docker
+// daemon if platform is empty, and returns its ID.
+func (d *DockerfileBuildpack) build(c *sous.BuildContext, key buildCacheKey, platform string) (string, error) {
 	offset := c.Source.OffsetDir
 	if offset == "" {
 		offset = "."
 	}
 
 	cmd := []interface{}{"build", "--pull"}
-	r := dr.Data.(detectData)
+	if platform != "" {
+		// Images for other platforms are loaded into the local daemon one at a
+		// time, to be labelled and pushed like any other.
+		cmd = []interface{}{"buildx", "build", "--pull", "--load", "--quiet", "--platform", platform}
+	}
+	cmd = append(cmd, d.Cache.cacheFrom(c, key)...)
+	r := d.detected.Data.(detectData)
 	if r.HasAppVersionArg {
 		v := c.Version().Version
 		v.Meta = ""
@@ -99,16 +141,19 @@ func (d *DockerfileBuildpack) Build(c *sous.BuildContext) (*sous.BuildResult, er
 
 	output, err := c.Sh.Stdout("docker", cmd...)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	match := successfulBuildRE.FindStringSubmatch(string(output))
-	if match == nil {
-		return nil, fmt.Errorf("Couldn't find container id in:\n%s", output)
+	var id string
+	if platform != "" {
+		id = strings.TrimSpace(output)
+	} else if match := successfulBuildRE.FindStringSubmatch(string(output)); match != nil {
+		id = match[1]
+	}
+	if id == "" {
+		return "", fmt.Errorf("Couldn't find container id in:\n%s", output)
 	}
 
-	return &sous.BuildResult{
-		Elapsed:  time.Since(start),
-		Products: []*sous.BuildProduct{{ID: match[1]}},
-	}, nil
+	d.Cache.store(c, key, id)
+	return id, nil
 }
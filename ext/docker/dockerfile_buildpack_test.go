@@ -9,6 +9,8 @@ import (
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var detectTests = []struct {
@@ -106,6 +108,25 @@ func TestDetect(t *testing.T) {
 	}
 }
 
+func TestBuildPlatforms(t *testing.T) {
+	sh, ctl := shell.NewTestShell()
+	d := &DockerfileBuildpack{detected: &sous.DetectResult{Data: detectData{}}}
+	ctx := &sous.BuildContext{
+		Sh:        sh,
+		Source:    sous.SourceContext{RemoteURL: "github.com/example/project", NearestTagName: "1.2.3"},
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+	}
+	_, cctl := ctl.CmdFor("docker", "buildx", "build")
+	cctl.ResultSuccess("sha256:cabba9e\n", "")
+
+	br, err := d.Build(ctx)
+	require.NoError(t, err)
+	require.Len(t, br.Products, 2)
+	assert.Equal(t, "linux/arm64", br.Products[1].Platform)
+	assert.Equal(t, "sha256:cabba9e", br.Products[1].ID)
+	assert.Len(t, ctl.CmdsLike("docker", "buildx", "build", "--pull", "--load", "--quiet", "--platform", "linux/arm64"), 1)
+}
+
 func assertError(expectedErr string, actualErr error) error {
 	if actualErr == nil && expectedErr == "" {
 		return nil
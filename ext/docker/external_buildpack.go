@@ -0,0 +1,193 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An ExternalBuildpack is a Buildpack implemented by an executable, so
+	// that projects can be built in ways Sous doesn't know about.
+	//
+	// The executable is run in the project's root directory, first as
+	// "<path> detect" and then, if it is selected, as "<path> build". Each is
+	// sent an ExternalBuildRequest as JSON on stdin. The detect step must
+	// print an ExternalDetectResponse as JSON on stdout, and the build step an
+	// ExternalBuildResponse. Anything written to stderr is left to the
+	// console.
+	//
+	// The products of a build must be Docker images, which Sous will label
+	// and push like those of its built in buildpacks.
+	ExternalBuildpack struct {
+		// Path is the path to the executable.
+		Path     string
+		detected *ExternalDetectResponse
+	}
+
+	// An ExternalBuildRequest describes the project to an ExternalBuildpack.
+	ExternalBuildRequest struct {
+		// RootDir is the absolute path of the project's root directory.
+		RootDir string
+		// OffsetDir is the offset of the project being built within RootDir.
+		OffsetDir string
+		// Repo is the repository the project is built from.
+		Repo string
+		// Version and Revision are the version and revision being built, as
+		// they would be passed to Dockerfile builds in the APP_VERSION and
+		// APP_REVISION build arguments.
+		Version, Revision string
+		// DirtyWorkingTree is true if the project has uncommitted changes.
+		DirtyWorkingTree bool
+		// DetectData is the Data from the ExternalDetectResponse. It is only
+		// sent to the build step.
+		DetectData json.RawMessage `json:",omitempty"`
+	}
+
+	// An ExternalDetectResponse is the output of the detect step of an
+	// ExternalBuildpack.
+	ExternalDetectResponse struct {
+		// Compatible is true if the buildpack can build the project.
+		Compatible bool
+		// Priority is used to choose among compatible buildpacks: the built in
+		// Split and Dockerfile buildpacks have priorities of 20 and 10.
+		Priority int
+		// Description describes what will be built.
+		Description string
+		// Data is passed back to the build step as DetectData.
+		Data json.RawMessage `json:",omitempty"`
+	}
+
+	// An ExternalBuildResponse is the output of the build step of an
+	// ExternalBuildpack.
+	ExternalBuildResponse struct {
+		Products []ExternalBuildProduct
+	}
+
+	// An ExternalBuildProduct is a Docker image built by an
+	// ExternalBuildpack.
+	ExternalBuildProduct struct {
+		// ID is the Docker image ID.
+		ID string
+		// Kind is empty for a deployable image, or describes what else the
+		// image is for (e.g. "test").
+		Kind string
+		// Advisories are any advisories about the image.
+		Advisories []string
+	}
+)
+
+// NewExternalBuildpack returns an ExternalBuildpack implemented by the
+// executable at path.
+func NewExternalBuildpack(path string) *ExternalBuildpack {
+	return &ExternalBuildpack{Path: path}
+}
+
+// ExternalBuildpacks returns an ExternalBuildpack for each executable file in
+// dir, ordered by name.
+func ExternalBuildpacks(dir string) ([]*ExternalBuildpack, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading buildpack directory")
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	var ebps []*ExternalBuildpack
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		ebps = append(ebps, NewExternalBuildpack(filepath.Join(dir, fi.Name())))
+	}
+	return ebps, nil
+}
+
+// Name returns the name of this buildpack, which is the name of its
+// executable.
+func (ebp *ExternalBuildpack) Name() string {
+	return filepath.Base(ebp.Path)
+}
+
+func (ebp *ExternalBuildpack) request(ctx *sous.BuildContext) ExternalBuildRequest {
+	v := ctx.Version().Version
+	v.Meta = ""
+	return ExternalBuildRequest{
+		RootDir:          ctx.Sh.Dir(),
+		OffsetDir:        ctx.Source.OffsetDir,
+		Repo:             ctx.Source.RemoteURL,
+		Version:          v.String(),
+		Revision:         ctx.Version().RevID(),
+		DirtyWorkingTree: ctx.Source.DirtyWorkingTree,
+	}
+}
+
+// run runs step, sending req and decoding the response into rz.
+func (ebp *ExternalBuildpack) run(ctx *sous.BuildContext, step string, req ExternalBuildRequest, rz interface{}) error {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	cmd := ctx.Sh.Cmd(ebp.Path, step)
+	cmd.SetStdin(bytes.NewReader(in))
+	out, err := cmd.Stdout()
+	if err != nil {
+		return errors.Wrapf(err, "buildpack %s %s", ebp.Name(), step)
+	}
+	if err := json.Unmarshal([]byte(out), rz); err != nil {
+		return errors.Wrapf(err, "buildpack %s %s: parsing response", ebp.Name(), step)
+	}
+	return nil
+}
+
+// Detect implements Buildpack on ExternalBuildpack.
+func (ebp *ExternalBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, error) {
+	if _, err := os.Stat(ebp.Path); err != nil {
+		return nil, err
+	}
+	rz := &ExternalDetectResponse{}
+	if err := ebp.run(ctx, "detect", ebp.request(ctx), rz); err != nil {
+		return nil, err
+	}
+	ebp.detected = rz
+	return &sous.DetectResult{
+		Compatible:  rz.Compatible,
+		Description: rz.Description,
+		Data:        rz.Data,
+		Priority:    rz.Priority,
+	}, nil
+}
+
+// Build implements Buildpack on ExternalBuildpack.
+func (ebp *ExternalBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	start := time.Now()
+	req := ebp.request(ctx)
+	if ebp.detected != nil {
+		req.DetectData = ebp.detected.Data
+	}
+	rz := &ExternalBuildResponse{}
+	if err := ebp.run(ctx, "build", req, rz); err != nil {
+		return nil, err
+	}
+	if len(rz.Products) == 0 {
+		return nil, errors.Errorf("buildpack %s build: no products were built", ebp.Name())
+	}
+	br := &sous.BuildResult{}
+	for _, p := range rz.Products {
+		if p.ID == "" {
+			return nil, errors.Errorf("buildpack %s build: product has no image ID", ebp.Name())
+		}
+		advisories := append([]string{}, p.Advisories...)
+		if p.Kind != "" {
+			advisories = append(advisories, string(sous.NotService))
+		}
+		br.Products = append(br.Products, &sous.BuildProduct{ID: p.ID, Kind: p.Kind, Advisories: advisories})
+	}
+	br.Elapsed = time.Since(start)
+	return br, nil
+}
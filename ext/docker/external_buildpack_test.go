@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const externalTestBuildpack = `#!/bin/sh
+request=$(cat)
+case "$1" in
+detect)
+  echo '{"Compatible": true, "Priority": 30, "Data": {"lang": "go"}}' ;;
+build)
+  echo "$request" > "$(dirname "$0")/request.json"
+  echo '{"Products": [{"ID": "cafebabe"}, {"ID": "deadbeef", "Kind": "test"}]}' ;;
+esac
+`
+
+func TestExternalBuildpacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-buildpacks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "golang"), []byte(externalTestBuildpack), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a buildpack"), 0644))
+
+	ebps, err := ExternalBuildpacks(dir)
+	require.NoError(t, err)
+	require.Len(t, ebps, 1)
+	ebp := ebps[0]
+	assert.Equal(t, "golang", ebp.Name())
+
+	sh, err := shell.DefaultInDir(dir)
+	require.NoError(t, err)
+	ctx := &sous.BuildContext{
+		Sh: sh,
+		Source: sous.SourceContext{
+			RemoteURL:      "github.com/example/project",
+			Revision:       "abc123",
+			NearestTagName: "1.2.3",
+		},
+	}
+
+	dr, err := ebp.Detect(ctx)
+	require.NoError(t, err)
+	assert.True(t, dr.Compatible)
+	assert.Equal(t, 30, dr.Priority)
+
+	br, err := ebp.Build(ctx)
+	require.NoError(t, err)
+	require.Len(t, br.Products, 2)
+	assert.Equal(t, "cafebabe", br.Products[0].ID)
+	assert.Empty(t, br.Products[0].Advisories)
+	assert.Equal(t, "test", br.Products[1].Kind)
+	assert.Contains(t, br.Products[1].Advisories, string(sous.NotService))
+
+	reqJSON, err := ioutil.ReadFile(filepath.Join(dir, "request.json"))
+	require.NoError(t, err)
+	req := ExternalBuildRequest{}
+	require.NoError(t, json.Unmarshal(reqJSON, &req))
+	assert.Equal(t, dir, req.RootDir)
+	assert.Equal(t, "github.com/example/project", req.Repo)
+	assert.Equal(t, "abc123", req.Revision)
+	assert.JSONEq(t, `{"lang": "go"}`, string(req.DetectData))
+}
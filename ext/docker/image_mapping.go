@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
@@ -34,7 +35,9 @@ type (
 		DB                 *sql.DB
 		DockerRegistryHost string
 		Log                logging.LogSink
-		groomOnce          sync.Once
+		// Verifier, if set, verifies the signatures of artifacts.
+		Verifier  *Verifier
+		groomOnce sync.Once
 	}
 
 	imageName string
@@ -220,6 +223,7 @@ func (nc *NameCache) GetSourceID(a *sous.BuildArtifact) (sous.SourceID, error) {
 	}
 
 	qualities := qualitiesFromLabels(md.Labels)
+	sbom := sbomFromLabels(md.Labels)
 
 	fullCanon := nc.DockerRegistryHost + "/" + md.CanonicalName
 	mirrored := false
@@ -238,6 +242,11 @@ func (nc *NameCache) GetSourceID(a *sous.BuildArtifact) (sous.SourceID, error) {
 		messages.ReportLogFieldsMessage("Err recording", logging.DebugLevel, nc.Log, fullCanon, err)
 		return sid, err
 	}
+	if sbom != nil {
+		if err := nc.dbInsertSBOM(fullCanon, sbom); err != nil {
+			messages.ReportLogFieldsMessage("Err recording SBOM", logging.WarningLevel, nc.Log, fullCanon, err)
+		}
+	}
 
 	names := []string{}
 	for _, n := range md.AllNames {
@@ -308,6 +317,21 @@ func qualitiesFromLabels(lm map[string]string) []sous.Quality {
 	return qs
 }
 
+// sbomFromLabels returns the SBOM recorded in the labels of an image, if
+// there is one.
+func sbomFromLabels(lm map[string]string) *sous.SBOM {
+	label, ok := lm[DockerSBOMLabel]
+	if !ok {
+		return nil
+	}
+	sbom, err := sous.DecodeSBOMLabel(label)
+	if err != nil {
+		messages.ReportLogFieldsMessage("Unable to decode SBOM label", logging.WarningLevel, logging.Log, err)
+		return nil
+	}
+	return sbom
+}
+
 // GetCanonicalName returns the canonical name for an image given any known name
 func (nc *NameCache) GetCanonicalName(in string) (string, error) {
 	_, _, _, _, cn, err := nc.dbQueryOnName(in)
@@ -323,6 +347,159 @@ func (nc *NameCache) Insert(sid sous.SourceID, in, etag string, qs []sous.Qualit
 	return err
 }
 
+// InsertWithSBOM implements sous.SBOMInserter on NameCache: it is like
+// Insert, but also stores the SBOM of the image.
+func (nc *NameCache) InsertWithSBOM(sid sous.SourceID, in, etag string, qs []sous.Quality, sbom *sous.SBOM) error {
+	if err := nc.Insert(sid, in, etag, qs); err != nil {
+		return err
+	}
+	if sbom == nil {
+		return nil
+	}
+	return nc.dbInsertSBOM(in, sbom)
+}
+
+// GetSBOM implements sous.SBOMRegistry on NameCache. It returns nil if no
+// SBOM is known for the image built from sid.
+func (nc *NameCache) GetSBOM(sid sous.SourceID) (*sous.SBOM, error) {
+	cn, _, err := nc.dbQueryCNameforSourceID(sid)
+	if err != nil {
+		return nil, err
+	}
+	var js string
+	err = nc.DB.QueryRow("select docker_image_sbom.sbom "+
+		"from docker_image_sbom natural join docker_search_metadata "+
+		"where docker_search_metadata.canonicalName = $1", cn).Scan(&js)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sbom := &sous.SBOM{}
+	return sbom, json.Unmarshal([]byte(js), sbom)
+}
+
+// InspectArtifact implements sous.ArtifactInspector on NameCache. The
+// artifact's labels are read from the registry, and its build time from its
+// SBOM if it has one.
+func (nc *NameCache) InspectArtifact(sid sous.SourceID, art *sous.BuildArtifact) (*sous.ArtifactFacts, error) {
+	md, err := nc.RegistryClient.GetImageMetadata(art.Name, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting metadata for %s", art.Name)
+	}
+	facts := &sous.ArtifactFacts{SourceID: sid, Artifact: art, Labels: md.Labels, Platforms: md.Platforms}
+	if sbom, err := nc.GetSBOM(sid); err == nil && sbom != nil {
+		facts.Created = sbom.Built
+	}
+	facts.FromLabels()
+	return facts, nil
+}
+
+// PinArtifact implements sous.DigestPinner on NameCache. The first digest
+// each name is pinned to is recorded, so that if the name is pushed again,
+// the artifact stays pinned to the image first deployed.
+func (nc *NameCache) PinArtifact(sid sous.SourceID, art *sous.BuildArtifact) (*sous.BuildArtifact, error) {
+	if ref, err := reference.Parse(art.Name); err == nil {
+		if _, digested := ref.(reference.Digested); digested {
+			return art, nil
+		}
+	}
+	pinned := *art
+	pinned.Tag = art.Name
+
+	first, err := nc.dbQueryPinnedDigest(art.Name)
+	if err != nil {
+		return nil, err
+	}
+	md, err := nc.RegistryClient.GetImageMetadata(art.Name, "")
+	if err != nil {
+		if first == "" {
+			return nil, errors.Wrapf(err, "resolving digest of %s", art.Name)
+		}
+		messages.ReportLogFieldsMessage("Couldn't check digest of pinned image", logging.WarningLevel, nc.Log, art.Name, first, err)
+		pinned.Name = first
+		return &pinned, nil
+	}
+	current := md.Registry + "/" + md.CanonicalName
+
+	switch first {
+	case "":
+		if err := nc.dbInsertPinnedDigest(art.Name, current); err != nil {
+			return nil, err
+		}
+		first = current
+		if err := nc.dbAddNames(art.Name, []string{current}); err != nil {
+			messages.ReportLogFieldsMessage("Couldn't record pinned name", logging.DebugLevel, nc.Log, art.Name, current, err)
+		}
+	case current:
+	default:
+		messages.ReportLogFieldsMessage("Image was pushed again under a pinned tag: keeping the first digest",
+			logging.WarningLevel, nc.Log, sid, art.Name, first, current)
+	}
+	pinned.Name = first
+	return &pinned, nil
+}
+
+// VerifyArtifact implements sous.ArtifactVerifier on NameCache.
+func (nc *NameCache) VerifyArtifact(sid sous.SourceID, art *sous.BuildArtifact) error {
+	if nc.Verifier == nil || len(nc.Verifier.Keys) == 0 {
+		return errors.New("no verification keys are configured")
+	}
+	return nc.Verifier.Verify(sid, art.Name)
+}
+
+// HarvestArtifacts implements sous.ArtifactCollector on NameCache.
+func (nc *NameCache) HarvestArtifacts(sl sous.SourceLocation) error {
+	return nc.harvest(sl)
+}
+
+// DeleteArtifact implements sous.ArtifactCollector on NameCache. The image
+// built from sid is deleted from its registry by digest, and then from the
+// cache. Deleting a digest deletes every tag which refers to it, so nothing is
+// deleted if the digest is also that of one of needed, or was pinned under a
+// name other than those of sid.
+func (nc *NameCache) DeleteArtifact(sid sous.SourceID, needed []sous.SourceID) error {
+	cn, ins, err := nc.dbQueryCNameforSourceID(sid)
+	if err != nil {
+		return err
+	}
+	dgst := sous.ImageDigest(cn)
+
+	for _, n := range needed {
+		ncn, _, err := nc.dbQueryCNameforSourceID(n)
+		if _, unknown := errors.Cause(err).(NoImageNameFound); unknown {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if sous.ImageDigest(ncn) == dgst {
+			return &sous.SharedArtifactError{SourceID: sid, Image: cn, SharedWith: n.String()}
+		}
+	}
+
+	pins, err := nc.dbQueryPinsOfDigest(dgst)
+	if err != nil {
+		return err
+	}
+	own := map[string]bool{}
+	for _, in := range ins {
+		own[in] = true
+	}
+	for _, pin := range pins {
+		if !own[pin] {
+			return &sous.SharedArtifactError{SourceID: sid, Image: cn, SharedWith: "the image pinned for " + pin}
+		}
+	}
+
+	messages.ReportLogFieldsMessage("Deleting image", logging.InformationLevel, nc.Log, sid, cn)
+	if err := nc.RegistryClient.DeleteImage(cn); err != nil {
+		return errors.Wrapf(err, "deleting %s", cn)
+	}
+	return nc.dbDeleteCName(cn)
+}
+
 /*Harvesting source location*/
 //{
 //"message": "{\"Dir\":\"nested/there\",\"Repo\":\"https://github.com/opentable/wackadoo\"}"
@@ -440,6 +617,21 @@ var schema = []string{
 		", kind text not null" +
 		", constraint upsertable unique (metadata_id, quality, kind) on conflict ignore" +
 		");",
+
+	// the digest each tag was first pinned to when deployed
+	"create table docker_pinned_digest(" +
+		"name text not null" +
+		", digest_name text not null" +
+		", constraint pinnable unique (name) on conflict ignore" +
+		");",
+
+	// sboms are stored as JSON
+	"create table docker_image_sbom(" +
+		"metadata_id references docker_search_metadata" +
+		"    on delete cascade not null" +
+		", sbom text not null" +
+		", constraint upsertable unique (metadata_id) on conflict replace" +
+		");",
 }
 
 var schemaFingerprint = fingerPrintSchema(schema)
@@ -743,6 +935,70 @@ func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, quals []sous.Q
 	return nc.dbAddNamesForID(id, []string{in})
 }
 
+func (nc *NameCache) dbInsertSBOM(cn string, sbom *sous.SBOM) error {
+	js, err := json.Marshal(sbom)
+	if err != nil {
+		return err
+	}
+	_, err = nc.DB.Exec("insert into docker_image_sbom (metadata_id, sbom) "+
+		"select metadata_id, $1 from docker_search_metadata where canonicalName = $2", string(js), cn)
+	return errors.Wrapf(err, "inserting SBOM for %s", cn)
+}
+
+func (nc *NameCache) dbQueryPinnedDigest(in string) (string, error) {
+	var dn string
+	err := nc.DB.QueryRow("select digest_name from docker_pinned_digest where name = $1", in).Scan(&dn)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return dn, errors.Wrapf(err, "querying pinned digest of %s", in)
+}
+
+func (nc *NameCache) dbInsertPinnedDigest(in, dn string) error {
+	_, err := nc.DB.Exec("insert into docker_pinned_digest (name, digest_name) values ($1, $2)", in, dn)
+	return errors.Wrapf(err, "pinning %s to %s", in, dn)
+}
+
+// dbQueryPinsOfDigest returns the names which were pinned to dgst.
+func (nc *NameCache) dbQueryPinsOfDigest(dgst string) ([]string, error) {
+	rows, err := nc.DB.Query("select name, digest_name from docker_pinned_digest")
+	if err != nil {
+		return nil, errors.Wrapf(err, "querying pins of %s", dgst)
+	}
+	defer rows.Close()
+
+	var pins []string
+	for rows.Next() {
+		var in, dn string
+		if err := rows.Scan(&in, &dn); err != nil {
+			return nil, errors.Wrapf(err, "querying pins of %s", dgst)
+		}
+		if sous.ImageDigest(dn) == dgst {
+			pins = append(pins, in)
+		}
+	}
+	return pins, errors.Wrapf(rows.Err(), "querying pins of %s", dgst)
+}
+
+func (nc *NameCache) dbDeleteCName(cn string) error {
+	ids := "(select metadata_id from docker_search_metadata where canonicalName = $1)"
+	for _, del := range []string{
+		"delete from docker_pinned_digest where name in " +
+			"(select name from docker_search_name where metadata_id in " + ids + ")",
+		"delete from docker_pinned_digest where digest_name in " +
+			"(select name from docker_search_name where metadata_id in " + ids + ")",
+		"delete from docker_search_name where metadata_id in " + ids,
+		"delete from docker_image_qualities where metadata_id in " + ids,
+		"delete from docker_image_sbom where metadata_id in " + ids,
+		"delete from docker_search_metadata where canonicalName = $1",
+	} {
+		if _, err := nc.DB.Exec(del, cn); err != nil {
+			return errors.Wrapf(err, "forgetting %s", cn)
+		}
+	}
+	return nil
+}
+
 func (nc *NameCache) dbAddNamesForID(id int64, ins []string) error {
 	add, err := nc.DB.Prepare("insert or replace into docker_search_name " +
 		"(metadata_id, name) values ($1, $2)")
@@ -432,6 +432,151 @@ func TestRecordAdvisories(t *testing.T) {
 	assert.Equal(arty.Qualities[0].Name, `ephemeral_tag`)
 }
 
+func TestInsertWithSBOM(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	base := "ot/wackadoo"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), inMemoryDB("sbom"))
+	require.NoError(err)
+	sv := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	digest := "sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+	cn := base + "@" + digest
+
+	_, err = nc.GetSBOM(sv)
+	assert.Error(err, "no image has been built for sv")
+
+	packages := []sous.SBOMPackage{{Name: "openssl", Version: "1.1.0f-3", Type: "deb"}}
+	err = nc.InsertWithSBOM(sv, cn, digest, nil, sous.NewSBOM(sv, false, packages))
+	require.NoError(err)
+
+	sbom, err := nc.GetSBOM(sv)
+	assert.NoError(err)
+	require.NotNil(sbom)
+	assert.Equal(packages, sbom.Packages)
+}
+
+func TestInspectArtifact(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), inMemoryDB("inspect"))
+	require.NoError(err)
+	sv := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	digest := "sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+	cn := "ot/wackadoo@" + digest
+
+	dc.AddMetadata(`wackadoo`, docker_registry.Metadata{
+		Labels: map[string]string{
+			sous.BaseImageLabel: "docker.repo.io/base/alpine:3.7",
+			sous.CreatedLabel:   "2018-02-03T04:05:06Z",
+		},
+		Platforms: []string{"linux/amd64", "linux/arm64/v8"},
+	})
+
+	facts, err := nc.InspectArtifact(sv, &sous.BuildArtifact{Name: cn})
+	require.NoError(err)
+	assert.Equal("docker.repo.io/base/alpine:3.7", facts.BaseImage)
+	assert.Equal(2018, facts.Created.Year())
+	assert.Equal([]string{"linux/amd64", "linux/arm64/v8"}, facts.Platforms)
+
+	sbom := sous.NewSBOM(sv, false, nil)
+	require.NoError(nc.InsertWithSBOM(sv, cn, digest, nil, sbom))
+	facts, err = nc.InspectArtifact(sv, &sous.BuildArtifact{Name: cn})
+	require.NoError(err)
+	assert.True(sbom.Built.Equal(facts.Created), "the SBOM's build time should be preferred")
+}
+
+func TestPinArtifact(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), inMemoryDB("pin"))
+	require.NoError(err)
+	sv := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	tag := host + "/ot/wackadoo:1.2.3"
+	first := "ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+	require.NoError(nc.Insert(sv, tag, "", nil))
+
+	dc.AddMetadata(`wackadoo`, docker_registry.Metadata{Registry: host, CanonicalName: first})
+	art, err := nc.GetArtifact(sv)
+	require.NoError(err)
+	pinned, err := nc.PinArtifact(sv, art)
+	require.NoError(err)
+	assert.Equal(host+"/"+first, pinned.Name)
+	assert.Equal(tag, pinned.Tag)
+
+	again, err := nc.PinArtifact(sv, pinned)
+	require.NoError(err)
+	assert.Equal(pinned, again, "pinning a digest should change nothing")
+
+	sid, err := nc.GetSourceID(sous.NewBuildArtifact(pinned.Name, nil))
+	assert.NoError(err)
+	assert.Equal(sv, sid, "the pinned name should be known")
+
+	repushed := docker_registry.NewDummyClient()
+	repushed.AddMetadata(`wackadoo`, docker_registry.Metadata{
+		Registry:      host,
+		CanonicalName: "ot/wackadoo@sha256:FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210",
+	})
+	nc.RegistryClient = repushed
+	pinned, err = nc.PinArtifact(sv, art)
+	require.NoError(err)
+	assert.Equal(host+"/"+first, pinned.Name, "a repushed tag should stay pinned to its first digest")
+}
+
+func TestDeleteArtifact(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), inMemoryDB("delete"))
+	require.NoError(err)
+	keep := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.4")
+	sv := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	in := host + "/ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+	require.NoError(nc.Insert(keep, host+"/ot/wackadoo:1.2.4", "", nil))
+	require.NoError(nc.InsertWithSBOM(sv, in, "", []sous.Quality{{Name: "ephemeral_tag", Kind: "advisory"}},
+		sous.NewSBOM(sv, false, nil)))
+	require.NoError(nc.dbAddNames(in, []string{host + "/ot/wackadoo:1.2.3"}))
+	copied := sous.MustNewSourceID("https://github.com/opentable/wackadoo", "nested/there", "1.2.2")
+	require.NoError(nc.Insert(copied, host+"/ot/wackadoo-copy@sha256:012345678901234567890123456789AB012345678901234567890123456789AB", "", nil))
+
+	err = nc.DeleteArtifact(copied, []sous.SourceID{keep, sv})
+	require.IsType(&sous.SharedArtifactError{}, err, "an image sharing a needed digest should be kept")
+
+	require.NoError(nc.dbInsertPinnedDigest(host+"/ot/wackadoo:latest", in))
+	err = nc.DeleteArtifact(sv, []sous.SourceID{keep})
+	require.IsType(&sous.SharedArtifactError{}, err, "an image pinned under another name should be kept")
+	assert.Empty(dc.CallsTo("DeleteImage"))
+
+	_, err = nc.DB.Exec("delete from docker_pinned_digest")
+	require.NoError(err)
+	require.NoError(nc.dbInsertPinnedDigest(host+"/ot/wackadoo:1.2.3", in))
+	require.NoError(nc.DeleteArtifact(sv, []sous.SourceID{keep}))
+	calls := dc.CallsTo("DeleteImage")
+	require.Len(calls, 1)
+	assert.Equal(in, calls[0].PassedArgs().String(0))
+
+	ids, err := nc.ListSourceIDs()
+	require.NoError(err)
+	assert.Len(ids, 2)
+	assert.Contains(ids, keep)
+	assert.Contains(ids, copied)
+	_, err = nc.GetCanonicalName(host + "/ot/wackadoo:1.2.3")
+	assert.Error(err, "the deleted image's names should be forgotten")
+	var n int
+	require.NoError(nc.DB.QueryRow("select count(*) from docker_image_sbom").Scan(&n))
+	assert.Zero(n)
+	require.NoError(nc.DB.QueryRow("select count(*) from docker_pinned_digest").Scan(&n))
+	assert.Zero(n, "the deleted image's pins should be forgotten")
+
+	assert.Error(nc.DeleteArtifact(sv, nil), "the artifact should no longer be known")
+}
+
 func TestDump(t *testing.T) {
 	assert := assert.New(t)
 
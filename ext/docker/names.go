@@ -80,6 +80,12 @@ func tagName(v semv.Version) string {
 	return v.Format("M.m.p-?")
 }
 
+// platformTag returns platform in a form which can be part of an image tag,
+// e.g. "linux-arm64-v8".
+func platformTag(platform string) string {
+	return strings.Replace(platform, "/", "-", -1)
+}
+
 func versionName(sid sous.SourceID, kind string) string {
 	return strings.Join([]string{imageRepoName(sid.Location, kind), tagName(sid.Version)}, ":")
 }
@@ -1,7 +1,6 @@
 package docker
 
 import (
-	"errors"
 	"fmt"
 	"io"
 
@@ -11,31 +10,63 @@ import (
 )
 
 type selector struct {
-	regClient docker_registry.Client
-	log       logging.LogSink
+	regClient    docker_registry.Client
+	cache        *BuildCache
+	buildpackDir string
+	log          logging.LogSink
 }
 
-// NewBuildStrategySelector constructs a sous.Selector that uses docker build images as its strategies
-func NewBuildStrategySelector(ls logging.LogSink, rc docker_registry.Client) sous.Selector {
-	return &selector{regClient: rc, log: ls}
+const (
+	splitBuildpackPriority      = 20
+	dockerfileBuildpackPriority = 10
+)
+
+// NewBuildStrategySelector constructs a sous.Selector that uses docker build images as its strategies.
+// As well as the built in buildpacks, it selects among any ExternalBuildpacks in buildpackDir.
+// The built in buildpacks use bc, which may be nil.
+func NewBuildStrategySelector(ls logging.LogSink, rc docker_registry.Client, bc *BuildCache, buildpackDir string) sous.Selector {
+	return &selector{regClient: rc, cache: bc, buildpackDir: buildpackDir, log: ls}
+}
+
+// registry returns a BuildpackRegistry of the buildpacks available to s.
+func (s *selector) registry() (*sous.BuildpackRegistry, error) {
+	r := sous.NewBuildpackRegistry()
+	r.Register("split container", splitBuildpackPriority, func() sous.Buildpack {
+		sbp := NewSplitBuildpack(s.regClient)
+		sbp.Cache = s.cache
+		return sbp
+	})
+	r.Register("simple dockerfile", dockerfileBuildpackPriority, func() sous.Buildpack {
+		dfbp := NewDockerfileBuildpack()
+		dfbp.Cache = s.cache
+		return dfbp
+	})
+	if s.buildpackDir == "" {
+		return r, nil
+	}
+	ebps, err := ExternalBuildpacks(s.buildpackDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ebp := range ebps {
+		path := ebp.Path
+		r.Register(ebp.Name(), 0, func() sous.Buildpack { return NewExternalBuildpack(path) })
+	}
+	return r, nil
 }
 
 // SelectBuildpack tries to select a buildpack for this BuildContext.
 func (s *selector) SelectBuildpack(ctx *sous.BuildContext) (sous.Buildpack, error) {
-	sbp := NewSplitBuildpack(s.regClient)
-	dr, err := sbp.Detect(ctx)
-	if err == nil && dr.Compatible {
-		reportStrategyChoice("split container", s.log)
-		return sbp, nil
+	r, err := s.registry()
+	if err != nil {
+		return nil, err
 	}
-
-	dfbp := NewDockerfileBuildpack()
-	dr, err = dfbp.Detect(ctx)
-	if err == nil && dr.Compatible {
-		reportStrategyChoice("simple dockerfile", s.log)
-		return dfbp, nil
+	name, bp, err := r.Select(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("no Dockerfile present")
+	reportStrategyChoice(name, s.log)
+	return bp, nil
 }
 
 type strategyChoiceMessage struct {
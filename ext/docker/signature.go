@@ -0,0 +1,217 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Signer signs images as built from a SourceID, with a private key.
+	// Signatures are stored in the registry the way cosign stores them, so
+	// that `cosign verify --key` can verify them with the public key.
+	Signer struct {
+		Key    *ecdsa.PrivateKey
+		Client docker_registry.Client
+	}
+
+	// A Verifier verifies that images are signed as built from a SourceID
+	// with one of its keys.
+	Verifier struct {
+		Keys   []*ecdsa.PublicKey
+		Client docker_registry.Client
+	}
+
+	// signaturePayload is a cosign "simple signing" payload. Its optional
+	// claims are the Labels of the SourceID the image was built from.
+	signaturePayload struct {
+		Critical struct {
+			Identity struct {
+				DockerReference string `json:"docker-reference"`
+			} `json:"identity"`
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+		Optional map[string]string `json:"optional"`
+	}
+)
+
+const cosignSignatureType = "cosign container image signature"
+
+// NewSigner returns a Signer which signs with the ECDSA private key in the
+// unencrypted PEM file at keyPath.
+func NewSigner(keyPath string, cl docker_registry.Client) (*Signer, error) {
+	block, err := readPEM(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	default:
+		return nil, errors.Errorf("%s: unsupported key type %q: keys must be unencrypted", keyPath, block.Type)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", keyPath)
+	}
+	ek, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("%s: signing keys must be ECDSA keys, not %T", keyPath, key)
+	}
+	return &Signer{Key: ek, Client: cl}, nil
+}
+
+// NewVerifier returns a Verifier which trusts the ECDSA public keys in the
+// PEM files at keyPaths, e.g. cosign.pub files.
+func NewVerifier(keyPaths []string, cl docker_registry.Client) (*Verifier, error) {
+	v := &Verifier{Client: cl}
+	for _, kp := range keyPaths {
+		block, err := readPEM(kp)
+		if err != nil {
+			return nil, err
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", kp)
+		}
+		ek, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("%s: verification keys must be ECDSA keys, not %T", kp, key)
+		}
+		v.Keys = append(v.Keys, ek)
+	}
+	return v, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+// Sign signs the image imageName, which was built from sid, and adds the
+// signature to the image's signatures in its registry.
+func (s *Signer) Sign(sid sous.SourceID, imageName string) error {
+	repo, dgst, err := imageDigest(s.Client, imageName)
+	if err != nil {
+		return err
+	}
+	var p signaturePayload
+	p.Critical.Identity.DockerReference = repo
+	p.Critical.Image.DockerManifestDigest = dgst
+	p.Critical.Type = cosignSignatureType
+	p.Optional = Labels(sid)
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.Key, sum[:])
+	if err != nil {
+		return err
+	}
+	return s.Client.PutSignature(repo+"@"+dgst, docker_registry.Signature{
+		Payload:   payload,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+}
+
+// Verify returns an error unless the image imageName has a signature made
+// with one of v's keys, which claims that it was built from sid.
+func (v *Verifier) Verify(sid sous.SourceID, imageName string) error {
+	repo, dgst, err := imageDigest(v.Client, imageName)
+	if err != nil {
+		return err
+	}
+	sigs, err := v.Client.GetSignatures(repo + "@" + dgst)
+	if err != nil {
+		return errors.Wrapf(err, "getting signatures of %s", imageName)
+	}
+	if len(sigs) == 0 {
+		return errors.Errorf("%s is not signed", imageName)
+	}
+
+	var reasons []string
+	for _, sig := range sigs {
+		err := v.verifySignature(sig, dgst, sid)
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return errors.Errorf("no signature of %s is valid: %s", imageName, strings.Join(reasons, "; "))
+}
+
+func (v *Verifier) verifySignature(sig docker_registry.Signature, dgst string, sid sous.SourceID) error {
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return errors.Wrap(err, "decoding signature")
+	}
+	sum := sha256.Sum256(sig.Payload)
+	trusted := false
+	for _, k := range v.Keys {
+		if ecdsa.VerifyASN1(k, sum[:], raw) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return errors.New("not signed by a trusted key")
+	}
+
+	var p signaturePayload
+	if err := json.Unmarshal(sig.Payload, &p); err != nil {
+		return errors.Wrap(err, "parsing signed payload")
+	}
+	if p.Critical.Type != cosignSignatureType {
+		return errors.Errorf("unknown signature type %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != dgst {
+		return errors.Errorf("signs digest %s, not %s", p.Critical.Image.DockerManifestDigest, dgst)
+	}
+	signed, err := SourceIDFromLabels(p.Optional)
+	if err != nil {
+		return errors.Wrap(err, "signature doesn't claim a source")
+	}
+	if !signed.Equal(sid) {
+		return errors.Errorf("signed as built from %s, not %s", signed, sid)
+	}
+	return nil
+}
+
+// imageDigest returns the repository of imageName, including its registry
+// host, and the digest of the image it refers to.
+func imageDigest(cl docker_registry.Client, imageName string) (repo, dgst string, err error) {
+	md, err := cl.GetImageMetadata(imageName, "")
+	if err != nil {
+		return "", "", errors.Wrapf(err, "getting digest of %s", imageName)
+	}
+	parts := strings.SplitN(md.CanonicalName, "@", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("no digest for %s", imageName)
+	}
+	return md.Registry + "/" + parts[0], parts[1], nil
+}
@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/docker_registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes a new key pair to dir, returning the paths of the
+// private and public keys.
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	privPath := filepath.Join(dir, name+".key")
+	pubPath := filepath.Join(dir, name+".pub")
+	require.NoError(t, ioutil.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0600))
+	require.NoError(t, ioutil.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0644))
+	return privPath, pubPath
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-signing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	privPath, pubPath := writeKeyPair(t, dir, "trusted")
+	_, otherPub := writeKeyPair(t, dir, "other")
+
+	const dgst = "sha256:012345678901234567890123456789ab012345678901234567890123456789ab"
+	dc := docker_registry.NewDummyClient()
+	dc.FeedMetadata(docker_registry.Metadata{
+		Registry:      "docker.example.com",
+		CanonicalName: "test/project@" + dgst,
+	})
+
+	sid := sous.MustNewSourceID("https://github.com/opentable/test", "sub", "2.3.7+abcd")
+	signer, err := NewSigner(privPath, dc)
+	require.NoError(t, err)
+	require.NoError(t, signer.Sign(sid, "docker.example.com/test/project:2.3.7"))
+
+	puts := dc.CallsTo("PutSignature")
+	require.Len(t, puts, 1)
+	assert.Equal(t, "docker.example.com/test/project@"+dgst, puts[0].PassedArgs().String(0))
+	sig := puts[0].PassedArgs().Get(1).(docker_registry.Signature)
+
+	var payload signaturePayload
+	require.NoError(t, json.Unmarshal(sig.Payload, &payload))
+	assert.Equal(t, "docker.example.com/test/project", payload.Critical.Identity.DockerReference)
+	assert.Equal(t, dgst, payload.Critical.Image.DockerManifestDigest)
+	assert.Equal(t, cosignSignatureType, payload.Critical.Type)
+
+	verifier, err := NewVerifier([]string{otherPub, pubPath}, dc)
+	require.NoError(t, err)
+	err = verifier.Verify(sid, "docker.example.com/test/project:2.3.7")
+	assert.Contains(t, err.Error(), "is not signed")
+
+	dc.MatchMethod("GetSignatures", spies.AnyArgs, []docker_registry.Signature{sig}, nil)
+	assert.NoError(t, verifier.Verify(sid, "docker.example.com/test/project:2.3.7"))
+
+	other := sous.MustNewSourceID("https://github.com/opentable/test", "sub", "2.3.8+abcd")
+	err = verifier.Verify(other, "docker.example.com/test/project:2.3.7")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signed as built from")
+
+	untrusting, err := NewVerifier([]string{otherPub}, dc)
+	require.NoError(t, err)
+	err = untrusting.Verify(sid, "docker.example.com/test/project:2.3.7")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not signed by a trusted key")
+}
+
+func TestNameCacheVerifyArtifact(t *testing.T) {
+	nc := &NameCache{RegistryClient: docker_registry.NewDummyClient()}
+	sid := sous.MustNewSourceID("https://github.com/opentable/test", "", "1.0.0")
+	err := nc.VerifyArtifact(sid, &sous.BuildArtifact{Name: "docker.example.com/test:1.0.0"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no verification keys")
+}
@@ -17,6 +17,8 @@ import (
 type (
 	// A SplitBuildpack implements the pattern of using a build container and producing a separate deploy container
 	SplitBuildpack struct {
+		// Cache is the BuildCache used by builds, if any.
+		Cache    *BuildCache
 		registry docker_registry.Client
 		detected *sous.DetectResult
 	}
@@ -84,7 +86,14 @@ func (sbp *SplitBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, e
 // Build implements Buildpack on SplitBuildpack
 func (sbp *SplitBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
 	drez := sbp.detected
-	script := splitBuilder{context: ctx, detected: drez, subBuilders: []*runnableBuilder{}}
+	key, err := sbp.Cache.key(ctx, filepath.Join(ctx.Source.OffsetDir, "Dockerfile"))
+	if err != nil {
+		return nil, err
+	}
+	if br, hit := sbp.Cache.lookup(ctx, key); hit {
+		return br, nil
+	}
+	script := splitBuilder{context: ctx, detected: drez, subBuilders: []*runnableBuilder{}, cache: sbp.Cache, cacheKey: key}
 
 	/*
 			docker build <args> <offset> #-> Successfully build (image id)
@@ -94,8 +103,9 @@ func (sbp *SplitBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, err
 			runspec file <- files @
 			  docker cp <container id>:<file.sourcedir> $TMPDIR/<file.destdir>
 		  in $TMPDIR docker build - < {templated Dockerfile} #-> Successfully built (image id)
+			docker run --rm <test image id> #-> exit code (for images of kind "test")
 	*/
-	err := firsterr.Returned(
+	err = firsterr.Returned(
 		script.begin,
 		script.buildBuild,
 		script.setupTempdir,
@@ -108,7 +118,13 @@ func (sbp *SplitBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, err
 
 		script.templateDockerfiles,
 		script.buildRunnables,
+		script.runTests,
 	)
+	if err != nil {
+		return script.result(), err
+	}
 
-	return script.result(), err
+	br := script.result()
+	sbp.Cache.record(ctx, key, br)
+	return br, nil
 }
@@ -12,10 +12,15 @@ import (
 	"github.com/opentable/sous/util/logging/messages"
 )
 
+// testImageKind is the Kind of runspec image which is run as part of the
+// build, rather than deployed.
+const testImageKind = "test"
+
 type runnableBuilder struct {
 	RunSpec       SplitImageRunSpec
 	splitBuilder  *splitBuilder
 	deployImageID string
+	testResult    *sous.TestResult
 }
 
 func (rb *runnableBuilder) VersionConfig() string {
@@ -111,8 +116,28 @@ func (rb *runnableBuilder) build() error {
 	return nil
 }
 
+// test runs the built image if it is a test image, recording its exit code
+// and output. A failing test is not an error: it is reported as an advisory
+// on the products of the build.
+func (rb *runnableBuilder) test() error {
+	if rb.RunSpec.Kind != testImageKind {
+		return nil
+	}
+	sh := rb.splitBuilder.context.Sh.Clone()
+	sh.LongRunning(true)
+
+	res, err := sh.Cmd("docker", "run", "--rm", rb.deployImageID).Result()
+	if err != nil {
+		return err
+	}
+	rb.testResult = &sous.TestResult{ExitCode: res.ExitCode, Output: res.Combined.String()}
+
+	messages.ReportLogFieldsMessage("Ran test image", logging.InformationLevel, logging.Log, rb.deployImageID, rb.RunSpec.Offset, rb.testResult.String())
+	return nil
+}
+
 func (rb *runnableBuilder) product() *sous.BuildProduct {
-	advisories := rb.splitBuilder.context.Advisories
+	advisories := append([]string{}, rb.splitBuilder.context.Advisories...)
 	if rb.RunSpec.Kind != "" {
 		advisories = append(advisories, string(sous.NotService))
 	}
@@ -126,6 +151,7 @@ func (rb *runnableBuilder) product() *sous.BuildProduct {
 		Advisories:   advisories,
 		VersionName:  rb.versionName(),
 		RevisionName: rb.revisionName(),
+		TestResult:   rb.testResult,
 	}
 
 	return bp
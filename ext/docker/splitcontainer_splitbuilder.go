@@ -25,6 +25,8 @@ type splitBuilder struct {
 	buildDir         string
 	RunSpec          *MultiImageRunSpec
 	subBuilders      []*runnableBuilder
+	cache            *BuildCache
+	cacheKey         buildCacheKey
 }
 
 func (sb *splitBuilder) versionName() string {
@@ -57,6 +59,7 @@ func (sb *splitBuilder) buildBuild() error {
 	}
 
 	cmd := []interface{}{"build", "--pull"}
+	cmd = append(cmd, sb.cache.cacheFrom(sb.context, sb.cacheKey)...)
 	r := sb.detected.Data.(detectData)
 	if r.HasAppVersionArg {
 		cmd = append(cmd, "--build-arg", sb.versionConfig())
@@ -78,6 +81,7 @@ func (sb *splitBuilder) buildBuild() error {
 		return fmt.Errorf("Couldn't find container id in:\n%s", output)
 	}
 	sb.buildImageID = match[1]
+	sb.cache.store(sb.context, sb.cacheKey, sb.buildImageID)
 
 	return nil
 }
@@ -175,14 +179,34 @@ func (sb *splitBuilder) buildRunnables() error {
 	return sb.eachBuilder((*runnableBuilder).build)
 }
 
+func (sb *splitBuilder) runTests() error {
+	return sb.eachBuilder((*runnableBuilder).test)
+}
+
+// testsFailed returns true if any test image exited unsuccessfully.
+func (sb *splitBuilder) testsFailed() bool {
+	for _, rb := range sb.subBuilders {
+		if rb.testResult != nil && !rb.testResult.Passed() {
+			return true
+		}
+	}
+	return false
+}
+
 func (sb *splitBuilder) result() *sous.BuildResult {
-	return &sous.BuildResult{
+	br := &sous.BuildResult{
 		Elapsed: time.Since(sb.start),
 		Products: append(
 			sb.products(),
 			&sous.BuildProduct{ID: sb.buildImageID, Kind: "builder",
-				Advisories: append(sb.context.Advisories, string(sous.IsBuilder), string(sous.NotService))}),
+				Advisories: append(append([]string{}, sb.context.Advisories...), string(sous.IsBuilder), string(sous.NotService))}),
+	}
+	if sb.testsFailed() {
+		for _, p := range br.Products {
+			p.Advisories = append(p.Advisories, string(sous.TestsFailed))
+		}
 	}
+	return br
 }
 
 func (sb *splitBuilder) products() (ps []*sous.BuildProduct) {
@@ -7,6 +7,7 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/shell"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSplitBuilder_BuildBuild(t *testing.T) {
@@ -134,3 +135,43 @@ func TestSplitBuilder_Result(t *testing.T) {
 	res := builder.result()
 	assert.Len(t, res.Products, 2)
 }
+
+func TestSplitBuilder_RunTests(t *testing.T) {
+	sh, ctl := shell.NewTestShell()
+	_, cctl := ctl.CmdFor("docker", "run")
+	cctl.ResultFailure("1 test failed", "", 1)
+
+	builder := splitBuilder{
+		context: &sous.BuildContext{Sh: sh, Advisories: []string{"ok"}},
+	}
+	builder.subBuilders = []*runnableBuilder{
+		{splitBuilder: &builder, deployImageID: "service"},
+		{splitBuilder: &builder, deployImageID: "tests", RunSpec: SplitImageRunSpec{Kind: "test"}},
+	}
+
+	require.NoError(t, builder.runTests())
+	assert.Len(t, ctl.CmdsLike("docker", "run"), 1)
+
+	res := builder.result()
+	require.Len(t, res.Products, 3)
+	assert.Nil(t, res.Products[0].TestResult)
+	assert.Equal(t, &sous.TestResult{ExitCode: 1, Output: "1 test failed"}, res.Products[1].TestResult)
+	for _, p := range res.Products {
+		assert.Contains(t, p.Advisories, string(sous.TestsFailed))
+	}
+	assert.Equal(t, []string{"ok"}, builder.context.Advisories)
+}
+
+func TestSplitBuilder_Result_TestsPassed(t *testing.T) {
+	builder := splitBuilder{
+		context: &sous.BuildContext{},
+	}
+	builder.subBuilders = []*runnableBuilder{{
+		RunSpec:      SplitImageRunSpec{Kind: "test"},
+		splitBuilder: &builder,
+		testResult:   &sous.TestResult{},
+	}}
+	for _, p := range builder.result().Products {
+		assert.NotContains(t, p.Advisories, string(sous.TestsFailed))
+	}
+}
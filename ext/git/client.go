@@ -72,6 +72,12 @@ func (c *Client) CloneRepo(repo, localPath string) error {
 	return err
 }
 
+// Checkout checks out ref in the current directory.
+func (c *Client) Checkout(ref string) error {
+	_, err := c.stdout("checkout", "--quiet", ref)
+	return err
+}
+
 // OpenRepo opens a repo.
 func (c *Client) OpenRepo(dirpath string) (*Repo, error) {
 	sh := c.Sh.Clone()
@@ -0,0 +1,216 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// kubeClient abstracts the raw interactions with a Kubernetes API server.
+	kubeClient interface {
+		ListDeployments(namespace, selector string) (*DeploymentList, error)
+		GetDeployment(namespace, name string) (*Deployment, error)
+		CreateDeployment(namespace string, d *Deployment) error
+		UpdateDeployment(namespace string, d *Deployment) error
+		DeleteDeployment(namespace, name string) error
+
+		ListCronJobs(namespace, selector string) (*CronJobList, error)
+		GetCronJob(namespace, name string) (*CronJob, error)
+		CreateCronJob(namespace string, cj *CronJob) error
+		UpdateCronJob(namespace string, cj *CronJob) error
+		DeleteCronJob(namespace, name string) error
+
+		GetService(namespace, name string) (*Service, error)
+		CreateService(namespace string, s *Service) error
+		UpdateService(namespace string, s *Service) error
+		DeleteService(namespace, name string) error
+	}
+
+	// Client is a minimal JSON client for the Kubernetes API.
+	Client struct {
+		BaseURL     string
+		BearerToken string
+		http        *http.Client
+	}
+
+	// APIError is returned when the API server responds with a non-2xx status.
+	APIError struct {
+		Method, URL string
+		StatusCode  int
+		Status      Status
+	}
+)
+
+const (
+	deploymentsPath = "/apis/apps/v1/namespaces/%s/deployments"
+	cronJobsPath    = "/apis/batch/v1/namespaces/%s/cronjobs"
+	servicesPath    = "/api/v1/namespaces/%s/services"
+)
+
+// NewClient returns a Client for the API server at baseURL. If token is not
+// empty, it is sent as a bearer token with every request.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		BearerToken: token,
+		http:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (e *APIError) Error() string {
+	msg := e.Status.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, msg)
+}
+
+// IsNotFound returns true if err was caused by the API server responding 404.
+func IsNotFound(err error) bool {
+	apiErr, is := errors.Cause(err).(*APIError)
+	return is && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) do(method, path string, query url.Values, body, into interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody *bytes.Buffer
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrapf(err, "encoding %s %s", method, u)
+		}
+		reqBody = bytes.NewBuffer(b)
+	} else {
+		reqBody = &bytes.Buffer{}
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	rz, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer rz.Body.Close()
+	b, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading response to %s %s", method, u)
+	}
+	if rz.StatusCode < 200 || rz.StatusCode > 299 {
+		apiErr := &APIError{Method: method, URL: u, StatusCode: rz.StatusCode}
+		json.Unmarshal(b, &apiErr.Status) // best effort; message is optional
+		return apiErr
+	}
+	if into == nil || len(b) == 0 {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(b, into), "decoding response to %s %s", method, u)
+}
+
+func selectorQuery(selector string) url.Values {
+	if selector == "" {
+		return nil
+	}
+	return url.Values{"labelSelector": []string{selector}}
+}
+
+func collection(pathFmt, namespace string) string {
+	return fmt.Sprintf(pathFmt, url.PathEscape(namespace))
+}
+
+func member(pathFmt, namespace, name string) string {
+	return collection(pathFmt, namespace) + "/" + url.PathEscape(name)
+}
+
+// ListDeployments implements kubeClient on Client.
+func (c *Client) ListDeployments(namespace, selector string) (*DeploymentList, error) {
+	l := &DeploymentList{}
+	return l, c.do("GET", collection(deploymentsPath, namespace), selectorQuery(selector), nil, l)
+}
+
+// GetDeployment implements kubeClient on Client.
+func (c *Client) GetDeployment(namespace, name string) (*Deployment, error) {
+	d := &Deployment{}
+	return d, c.do("GET", member(deploymentsPath, namespace, name), nil, nil, d)
+}
+
+// CreateDeployment implements kubeClient on Client.
+func (c *Client) CreateDeployment(namespace string, d *Deployment) error {
+	return c.do("POST", collection(deploymentsPath, namespace), nil, d, nil)
+}
+
+// UpdateDeployment implements kubeClient on Client.
+func (c *Client) UpdateDeployment(namespace string, d *Deployment) error {
+	return c.do("PUT", member(deploymentsPath, namespace, d.Metadata.Name), nil, d, nil)
+}
+
+// DeleteDeployment implements kubeClient on Client.
+func (c *Client) DeleteDeployment(namespace, name string) error {
+	return c.do("DELETE", member(deploymentsPath, namespace, name), nil, nil, nil)
+}
+
+// ListCronJobs implements kubeClient on Client.
+func (c *Client) ListCronJobs(namespace, selector string) (*CronJobList, error) {
+	l := &CronJobList{}
+	return l, c.do("GET", collection(cronJobsPath, namespace), selectorQuery(selector), nil, l)
+}
+
+// GetCronJob implements kubeClient on Client.
+func (c *Client) GetCronJob(namespace, name string) (*CronJob, error) {
+	cj := &CronJob{}
+	return cj, c.do("GET", member(cronJobsPath, namespace, name), nil, nil, cj)
+}
+
+// CreateCronJob implements kubeClient on Client.
+func (c *Client) CreateCronJob(namespace string, cj *CronJob) error {
+	return c.do("POST", collection(cronJobsPath, namespace), nil, cj, nil)
+}
+
+// UpdateCronJob implements kubeClient on Client.
+func (c *Client) UpdateCronJob(namespace string, cj *CronJob) error {
+	return c.do("PUT", member(cronJobsPath, namespace, cj.Metadata.Name), nil, cj, nil)
+}
+
+// DeleteCronJob implements kubeClient on Client.
+func (c *Client) DeleteCronJob(namespace, name string) error {
+	return c.do("DELETE", member(cronJobsPath, namespace, name), nil, nil, nil)
+}
+
+// GetService implements kubeClient on Client.
+func (c *Client) GetService(namespace, name string) (*Service, error) {
+	s := &Service{}
+	return s, c.do("GET", member(servicesPath, namespace, name), nil, nil, s)
+}
+
+// CreateService implements kubeClient on Client.
+func (c *Client) CreateService(namespace string, s *Service) error {
+	return c.do("POST", collection(servicesPath, namespace), nil, s, nil)
+}
+
+// UpdateService implements kubeClient on Client.
+func (c *Client) UpdateService(namespace string, s *Service) error {
+	return c.do("PUT", member(servicesPath, namespace, s.Metadata.Name), nil, s, nil)
+}
+
+// DeleteService implements kubeClient on Client.
+func (c *Client) DeleteService(namespace, name string) error {
+	return c.do("DELETE", member(servicesPath, namespace, name), nil, nil, nil)
+}
@@ -0,0 +1,21 @@
+package kubernetes
+
+// DefaultNamespace is the namespace used when none is configured.
+const DefaultNamespace = "default"
+
+// Config describes how Sous talks to Kubernetes clusters.
+type Config struct {
+	// Namespace is the namespace Sous manages objects in.
+	Namespace string `env:"SOUS_KUBERNETES_NAMESPACE"`
+	// BearerToken authenticates Sous to the API servers of all Kubernetes
+	// clusters.
+	BearerToken string `env:"SOUS_KUBERNETES_TOKEN"`
+}
+
+// DefaultConfig builds a default configuration, which can be then overridden by
+// client code.
+func DefaultConfig() Config {
+	return Config{
+		Namespace: DefaultNamespace,
+	}
+}
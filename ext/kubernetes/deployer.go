@@ -0,0 +1,401 @@
+package kubernetes
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// ClusterKind is the value of sous.Cluster.Kind for Kubernetes clusters.
+const ClusterKind = "kubernetes"
+
+type deployer struct {
+	namespace   string
+	bearerToken string
+	dryrun      bool
+	clientFac   func(string) kubeClient
+	log         logging.LogSink
+}
+
+// NewDeployer creates a new Kubernetes-based sous.Deployer.
+func NewDeployer(ls logging.LogSink, options ...DeployerOption) sous.Deployer {
+	d := &deployer{namespace: DefaultNamespace, log: ls}
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
+}
+
+func (r *deployer) buildClient(url string) kubeClient {
+	var c kubeClient
+	if r.clientFac == nil {
+		c = NewClient(url, r.bearerToken)
+	} else {
+		c = r.clientFac(url)
+	}
+	if r.dryrun {
+		return dryrunClient{kubeClient: c, log: r.log}
+	}
+	return c
+}
+
+func rectifyRecover(d interface{}, f string, err *error) {
+	if r := recover(); r != nil {
+		stack := string(debug.Stack())
+		messages.ReportLogFieldsMessage("Panic", logging.WarningLevel, logging.Log, d, f, err, r, stack)
+		*err = errors.Errorf("Panicked: %s; stack trace:\n%s", r, stack)
+	}
+}
+
+// RunningDeployments collects data from the Kubernetes clusters and returns a
+// list of actual deployments.
+func (r *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters) (sous.DeployStates, error) {
+	deps := sous.NewDeployStates()
+	urls := map[string]struct{}{}
+	errs := make(chan error, len(clusters))
+	var wg sync.WaitGroup
+
+	for _, c := range clusters {
+		url := c.BaseURL
+		if _, seen := urls[url]; seen {
+			continue
+		}
+		urls[url] = struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			states, err := r.clusterStates(reg, clusters, url)
+			if err != nil {
+				errs <- errors.Wrapf(err, "reading %s", url)
+				return
+			}
+			for _, s := range states {
+				deps.Add(s)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		return deps, err
+	}
+	return deps, nil
+}
+
+func (r *deployer) clusterStates(reg sous.Registry, clusters sous.Clusters, url string) ([]*sous.DeployState, error) {
+	client := r.buildClient(url)
+	var states []*sous.DeployState
+
+	dl, err := client.ListDeployments(r.namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dl.Items {
+		d := &dl.Items[i]
+		svc, err := client.GetService(r.namespace, d.Metadata.Name)
+		if IsNotFound(err) {
+			svc, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s, err := deployStateFromDeployment(reg, clusters, url, d, svc)
+		if r.ignore(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+
+	cl, err := client.ListCronJobs(r.namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cl.Items {
+		s, err := deployStateFromCronJob(reg, clusters, url, &cl.Items[i])
+		if r.ignore(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+// ignore reports whether err means an object is not ours to manage, logging
+// it if so.
+func (r *deployer) ignore(err error) bool {
+	if !ignorableObject(err) {
+		return false
+	}
+	messages.ReportLogFieldsMessage("Ignoring Kubernetes object", logging.DebugLevel, r.log, err)
+	return true
+}
+
+// Status implements sous.Deployer on deployer.
+func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.DeployablePair) (*sous.DeployState, error) {
+	clusterName := pair.Post.Deployment.ClusterName
+	cluster, has := clusters[clusterName]
+	if !has {
+		return nil, errors.Errorf("No cluster found for %q. Known are: %q.", clusterName, clusters.Names())
+	}
+	name, err := MakeObjectName(pair.Post.ID())
+	if err != nil {
+		return nil, err
+	}
+	kind, err := workloadKind(pair.Post.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.buildClient(cluster.BaseURL)
+	var ds *sous.DeployState
+	switch kind {
+	case kindDeployment:
+		d, err := client.GetDeployment(r.namespace, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting deployment")
+		}
+		svc, err := client.GetService(r.namespace, name)
+		if IsNotFound(err) {
+			svc, err = nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting service")
+		}
+		ds, err = deployStateFromDeployment(reg, clusters, cluster.BaseURL, d, svc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting deployment state")
+		}
+		ds.SchedulerURL = cluster.BaseURL + member(deploymentsPath, r.namespace, name)
+	case kindCronJob:
+		cj, err := client.GetCronJob(r.namespace, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting cronjob")
+		}
+		ds, err = deployStateFromCronJob(reg, clusters, cluster.BaseURL, cj)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting cronjob state")
+		}
+		ds.SchedulerURL = cluster.BaseURL + member(cronJobsPath, r.namespace, name)
+	}
+	return ds, nil
+}
+
+// Rectify invokes actions to ensure that the real world matches pair.Post,
+// given that it currently matches pair.Prior.
+func (r *deployer) Rectify(pair *sous.DeployablePair) sous.DiffResolution {
+	postID := ""
+	version := ""
+	if pair.Post != nil {
+		postID = pair.Post.ID().String()
+		version = pair.Post.DeploySpec().Version.String()
+	}
+	if pair.UUID == uuid.Nil {
+		pair.UUID = uuid.NewV4()
+	}
+
+	switch k := pair.Kind(); k {
+	default:
+		panic(fmt.Sprintf("unrecognised kind %q", k))
+	case sous.SameKind:
+		resolution := pair.SameResolution()
+		if pair.Post.Status == sous.DeployStatusFailed {
+			resolution.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		}
+		messages.ReportLogFieldsMessage("SameKind", logging.InformationLevel, r.log, postID, version, resolution)
+		return resolution
+	case sous.AddedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleCreate(pair); err != nil {
+			result.Desc = "not created"
+			result.Error = sous.WrapResolveError(&sous.CreateError{Deployment: pair.Post.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.CreateDiff
+		}
+		messages.ReportLogFieldsMessage("Result of create", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.RemovedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleDelete(pair); err != nil {
+			result.Error = sous.WrapResolveError(&sous.DeleteError{Deployment: pair.Prior.Deployment.Clone(), Err: err})
+			result.Desc = "not deleted"
+		} else {
+			result.Desc = sous.DeleteDiff
+		}
+		messages.ReportLogFieldsMessage("Result of delete", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	case sous.ModifiedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.RectifySingleModification(pair); err != nil {
+			dp := &sous.DeploymentPair{
+				Prior: pair.Prior.Deployment.Clone(),
+				Post:  pair.Post.Deployment.Clone(),
+			}
+			result.Error = sous.WrapResolveError(&sous.ChangeError{Deployments: dp, Err: err})
+			result.Desc = "not updated"
+		} else if pair.Prior.Status == sous.DeployStatusFailed || pair.Post.Status == sous.DeployStatusFailed {
+			result.Desc = sous.ModifyDiff
+			result.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		} else {
+			result.Desc = sous.ModifyDiff
+		}
+		messages.ReportLogFieldsMessage("Result of modify", logging.InformationLevel, r.log, postID, version, result)
+		return result
+	}
+}
+
+// RectifySingleCreate creates the objects for pair.Post.
+func (r *deployer) RectifySingleCreate(pair *sous.DeployablePair) (err error) {
+	defer rectifyRecover(pair, "RectifySingleCreate", &err)
+	objs, err := buildObjects(*pair.Post, r.namespace, pair.UUID.String())
+	if err != nil {
+		return err
+	}
+	client := r.buildClient(pair.Post.Cluster.BaseURL)
+	if objs.deployment != nil {
+		if err := client.CreateDeployment(r.namespace, objs.deployment); err != nil {
+			return err
+		}
+	}
+	if objs.cronJob != nil {
+		if err := client.CreateCronJob(r.namespace, objs.cronJob); err != nil {
+			return err
+		}
+	}
+	return r.applyService(client, objs.service)
+}
+
+// RectifySingleDelete is called for deployments that no longer appear in the
+// GDM. Like the Singularity deployer, it does not yet delete anything.
+func (r *deployer) RectifySingleDelete(pair *sous.DeployablePair) (err error) {
+	defer rectifyRecover(pair, "RectifySingleDelete", &err)
+	data, ok := pair.ExecutorData.(*kubeTaskData)
+	if !ok {
+		return errors.Errorf("Delete record %#v doesn't contain Kubernetes compatible data: was %T\n\t%#v", pair.ID(), data, pair)
+	}
+	messages.ReportLogFieldsMessage("Rectify not deleting object", logging.WarningLevel, r.log, pair.ID(), data.kind, data.name)
+	return nil
+}
+
+// RectifySingleModification updates the objects for pair.Post.
+func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err error) {
+	defer rectifyRecover(pair, "RectifySingleModification", &err)
+	data, ok := pair.ExecutorData.(*kubeTaskData)
+	if !ok {
+		return errors.Errorf("Modification record %#v doesn't contain Kubernetes compatible data: was %T\n\t%#v", pair.ID(), data, pair)
+	}
+
+	client := r.buildClient(pair.Post.Cluster.BaseURL)
+
+	deployUUID := pair.UUID.String()
+	if !changesDep(pair) {
+		// Keep the pod template stable so that e.g. scaling doesn't trigger a
+		// rollout.
+		if prior, err := client.GetDeployment(r.namespace, data.name); err == nil {
+			if u, has := prior.Spec.Template.Metadata.Annotations[DeployUUIDAnnotation]; has {
+				deployUUID = u
+			}
+		}
+	}
+
+	objs, err := buildObjects(*pair.Post, r.namespace, deployUUID)
+	if err != nil {
+		return err
+	}
+
+	if objs.service == nil {
+		// The manifest's kind no longer needs a Service, e.g. it has changed
+		// from a service to a worker.
+		if err := r.deleteService(client, data.name); err != nil {
+			return err
+		}
+	}
+
+	newKind := kindDeployment
+	if objs.cronJob != nil {
+		newKind = kindCronJob
+	}
+	if newKind != data.kind {
+		messages.ReportLogFieldsMessage("Replacing Kubernetes workload", logging.InformationLevel, r.log, pair.ID(), data.kind, newKind)
+		if err := r.deleteWorkload(client, data); err != nil {
+			return err
+		}
+		return r.RectifySingleCreate(pair)
+	}
+
+	if objs.deployment != nil {
+		if err := client.UpdateDeployment(r.namespace, objs.deployment); err != nil {
+			return err
+		}
+	}
+	if objs.cronJob != nil {
+		if err := client.UpdateCronJob(r.namespace, objs.cronJob); err != nil {
+			return err
+		}
+	}
+	return r.applyService(client, objs.service)
+}
+
+func (r *deployer) deleteWorkload(client kubeClient, data *kubeTaskData) error {
+	switch data.kind {
+	default:
+		return errors.Errorf("unknown workload kind %q", data.kind)
+	case kindDeployment:
+		return client.DeleteDeployment(r.namespace, data.name)
+	case kindCronJob:
+		return client.DeleteCronJob(r.namespace, data.name)
+	}
+}
+
+// applyService creates or updates svc, preserving the cluster IP allocated
+// by Kubernetes.
+func (r *deployer) applyService(client kubeClient, svc *Service) error {
+	if svc == nil {
+		return nil
+	}
+	existing, err := client.GetService(r.namespace, svc.Metadata.Name)
+	if IsNotFound(err) {
+		return client.CreateService(r.namespace, svc)
+	}
+	if err != nil {
+		return err
+	}
+	svc.Metadata.ResourceVersion = existing.Metadata.ResourceVersion
+	svc.Spec.ClusterIP = existing.Spec.ClusterIP
+	return client.UpdateService(r.namespace, svc)
+}
+
+// deleteService deletes the Service called name, if there is one.
+func (r *deployer) deleteService(client kubeClient, name string) error {
+	_, err := client.GetService(r.namespace, name)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessage("Deleting Kubernetes service", logging.InformationLevel, r.log, name)
+	return client.DeleteService(r.namespace, name)
+}
+
+func changesDep(pair *sous.DeployablePair) bool {
+	return pair.Post.Status == sous.DeployStatusFailed ||
+		pair.Prior.Status == sous.DeployStatusFailed ||
+		!(pair.Prior.SourceID.Equal(pair.Post.SourceID) &&
+			pair.Prior.Resources.Equal(pair.Post.Resources) &&
+			pair.Prior.Env.Equal(pair.Post.Env) &&
+			pair.Prior.DeployConfig.Volumes.Equal(pair.Post.DeployConfig.Volumes) &&
+			pair.Prior.Startup.Equal(pair.Post.Startup) &&
+			pair.Prior.Rollout == pair.Post.Rollout)
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeployable(baseURL string, kind sous.ManifestKind) *sous.Deployable {
+	cluster := &sous.Cluster{Name: "kube-cluster", Kind: ClusterKind, BaseURL: baseURL}
+	d := &sous.Deployment{
+		SourceID:    sous.MustNewSourceID("github.com/opentable/example", "", "1.2.3"),
+		Flavor:      "tasty",
+		ClusterName: cluster.Name,
+		Cluster:     cluster,
+		Kind:        kind,
+		Owners:      sous.NewOwnerSet("owner@example.com"),
+		DeployConfig: sous.DeployConfig{
+			Resources:    sous.Resources{"cpus": "0.5", "memory": "256", "ports": "2"},
+			Env:          sous.Env{"GREETING": "hello"},
+			NumInstances: 3,
+			Startup: sous.Startup{
+				CheckReadyURIPath:    "/health",
+				CheckReadyProtocol:   "HTTP",
+				CheckReadyURITimeout: 5,
+			},
+			Volumes: sous.Volumes{{Host: "/srv/data", Container: "/data", Mode: sous.ReadOnly}},
+		},
+	}
+	if kind == sous.ManifestKindScheduled {
+		d.DeployConfig.Schedule = "*/5 * * * *"
+	}
+	return &sous.Deployable{
+		Status:        sous.DeployStatusActive,
+		Deployment:    d,
+		BuildArtifact: &sous.BuildArtifact{Name: "docker.example.com/example:1.2.3"},
+	}
+}
+
+func newTestDeployer() sous.Deployer {
+	return NewDeployer(logging.SilentLogSet(), OptNamespace("sous"))
+}
+
+func TestMakeObjectName(t *testing.T) {
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{
+			Source: sous.SourceLocation{Repo: "github.com/opentable/Example_Service", Dir: "some/dir"},
+			Flavor: "tasty",
+		},
+		Cluster: "kube-cluster",
+	}
+	name, err := MakeObjectName(did)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(name, "example-service-some-dir-tasty-kube-cluster-"), name)
+
+	did.ManifestID.Source.Dir = strings.Repeat("deep/", 30)
+	long, err := MakeObjectName(did)
+	require.NoError(t, err)
+	assert.True(t, len(long) <= maxObjectNameLen, long)
+
+	did.ManifestID.Source.Dir = strings.Repeat("deep/", 31)
+	other, err := MakeObjectName(did)
+	require.NoError(t, err)
+	assert.NotEqual(t, long, other)
+}
+
+func TestRectifyCreateRoundTrip(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := newTestDeployer()
+
+	post := testDeployable(f.URL, sous.ManifestKindService)
+	res := dep.Rectify(&sous.DeployablePair{Post: post})
+	require.Nil(t, res.Error)
+	assert.Equal(t, sous.CreateDiff, res.Desc)
+
+	name, err := MakeObjectName(post.ID())
+	require.NoError(t, err)
+	assert.True(t, f.has("/apis/apps/v1/namespaces/sous/deployments/"+name))
+	assert.True(t, f.has("/api/v1/namespaces/sous/services/"+name))
+
+	clusters := sous.Clusters{post.ClusterName: post.Cluster}
+	states, err := dep.RunningDeployments(sous.NewDummyRegistry(), clusters)
+	require.NoError(t, err)
+	ds, has := states.Get(post.ID())
+	require.True(t, has, "created deployment not found")
+
+	assert.Equal(t, sous.DeployStatusActive, ds.Status)
+	assert.Equal(t, &kubeTaskData{name: name, kind: kindDeployment}, ds.ExecutorData)
+	different, diffs := ds.Deployment.Diff(post.Deployment)
+	assert.False(t, different, "%v", diffs)
+
+	status, err := dep.Status(sous.NewDummyRegistry(), clusters, &sous.DeployablePair{Post: post})
+	require.NoError(t, err)
+	assert.Equal(t, sous.DeployStatusActive, status.Status)
+	assert.Contains(t, status.SchedulerURL, name)
+}
+
+func TestRectifyModify(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := newTestDeployer()
+
+	prior := testDeployable(f.URL, sous.ManifestKindService)
+	require.Nil(t, dep.Rectify(&sous.DeployablePair{Post: prior}).Error)
+
+	clusters := sous.Clusters{prior.ClusterName: prior.Cluster}
+	states, err := dep.RunningDeployments(sous.NewDummyRegistry(), clusters)
+	require.NoError(t, err)
+	ds, _ := states.Get(prior.ID())
+
+	post := testDeployable(f.URL, sous.ManifestKindService)
+	post.NumInstances = 5
+	pair := &sous.DeployablePair{
+		Prior:        &sous.Deployable{Status: ds.Status, Deployment: &ds.Deployment},
+		Post:         post,
+		ExecutorData: ds.ExecutorData,
+	}
+	res := dep.Rectify(pair)
+	require.Nil(t, res.Error)
+	assert.Equal(t, sous.ModifyDiff, res.Desc)
+
+	states, err = dep.RunningDeployments(sous.NewDummyRegistry(), clusters)
+	require.NoError(t, err)
+	ds, _ = states.Get(post.ID())
+	assert.Equal(t, 5, ds.NumInstances)
+}
+
+func TestRectifyChangeWorkloadKind(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := newTestDeployer()
+
+	prior := testDeployable(f.URL, sous.ManifestKindWorker)
+	require.Nil(t, dep.Rectify(&sous.DeployablePair{Post: prior}).Error)
+	name, err := MakeObjectName(prior.ID())
+	require.NoError(t, err)
+
+	post := testDeployable(f.URL, sous.ManifestKindScheduled)
+	res := dep.Rectify(&sous.DeployablePair{
+		Prior:        prior,
+		Post:         post,
+		ExecutorData: &kubeTaskData{name: name, kind: kindDeployment},
+	})
+	require.Nil(t, res.Error)
+	assert.False(t, f.has("/apis/apps/v1/namespaces/sous/deployments/"+name))
+	assert.True(t, f.has("/apis/batch/v1/namespaces/sous/cronjobs/"+name))
+}
+
+func TestRectifyServiceToWorkerDeletesService(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := newTestDeployer()
+
+	prior := testDeployable(f.URL, sous.ManifestKindService)
+	require.Nil(t, dep.Rectify(&sous.DeployablePair{Post: prior}).Error)
+	name, err := MakeObjectName(prior.ID())
+	require.NoError(t, err)
+	require.True(t, f.has("/api/v1/namespaces/sous/services/"+name))
+
+	post := testDeployable(f.URL, sous.ManifestKindWorker)
+	res := dep.Rectify(&sous.DeployablePair{
+		Prior:        prior,
+		Post:         post,
+		ExecutorData: &kubeTaskData{name: name, kind: kindDeployment},
+	})
+	require.Nil(t, res.Error)
+	assert.True(t, f.has("/apis/apps/v1/namespaces/sous/deployments/"+name))
+	assert.False(t, f.has("/api/v1/namespaces/sous/services/"+name))
+}
+
+func TestRunningDeploymentsIgnoresOtherClusters(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := newTestDeployer()
+
+	d := testDeployable(f.URL, sous.ManifestKindWorker)
+	require.Nil(t, dep.Rectify(&sous.DeployablePair{Post: d}).Error)
+
+	states, err := dep.RunningDeployments(sous.NewDummyRegistry(), sous.Clusters{
+		"elsewhere": {Name: "elsewhere", Kind: ClusterKind, BaseURL: f.URL},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, states.Len())
+}
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	f := newFakeAPIServer()
+	defer f.Close()
+	dep := NewDeployer(logging.SilentLogSet(), OptNamespace("sous"), OptDryRun())
+
+	res := dep.Rectify(&sous.DeployablePair{Post: testDeployable(f.URL, sous.ManifestKindService)})
+	require.Nil(t, res.Error)
+	for _, r := range f.requests {
+		assert.True(t, strings.HasPrefix(r, "GET "), r)
+	}
+}
@@ -0,0 +1,24 @@
+package kubernetes
+
+// DeployerOption is an option for configuring Kubernetes deployers.
+type DeployerOption func(*deployer)
+
+// OptNamespace sets the namespace the deployer manages objects in.
+func OptNamespace(ns string) DeployerOption {
+	return func(d *deployer) {
+		if ns != "" {
+			d.namespace = ns
+		}
+	}
+}
+
+// OptBearerToken sets the token used to authenticate to the API servers.
+func OptBearerToken(token string) DeployerOption {
+	return func(d *deployer) { d.bearerToken = token }
+}
+
+// OptDryRun makes the deployer read from the API servers as usual, but only
+// log the changes it would make.
+func OptDryRun() DeployerOption {
+	return func(d *deployer) { d.dryrun = true }
+}
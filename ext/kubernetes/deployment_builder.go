@@ -0,0 +1,219 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+type (
+	nonSousError struct {
+		name string
+	}
+
+	notThisClusterError struct {
+		foundClusterName        string
+		responsibleClusterNames []string
+	}
+)
+
+func (nse nonSousError) Error() string {
+	return fmt.Sprintf("%s is not a Sous managed object.", nse.name)
+}
+
+func (ntc notThisClusterError) Error() string {
+	return fmt.Sprintf("%s does not belong to this Sous server %#v.",
+		ntc.foundClusterName, ntc.responsibleClusterNames)
+}
+
+func ignorableObject(err error) bool {
+	switch errors.Cause(err).(type) {
+	case nonSousError, notThisClusterError:
+		return true
+	}
+	return false
+}
+
+// deployStateFromDeployment builds a DeployState from a Deployment and its
+// Service (which may be nil).
+func deployStateFromDeployment(reg sous.ImageLabeller, clusters sous.Clusters, baseURL string, d *Deployment, svc *Service) (*sous.DeployState, error) {
+	ds, err := baseDeployState(reg, clusters, baseURL, d.Metadata, d.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	ds.ExecutorData = &kubeTaskData{name: d.Metadata.Name, kind: kindDeployment}
+	ds.Kind = sous.ManifestKindWorker
+	if svc != nil {
+		ds.Kind = sous.ManifestKindService
+	}
+	if d.Spec.Replicas != nil {
+		ds.NumInstances = int(*d.Spec.Replicas)
+	}
+	ds.Status, ds.ExecutorMessage = deploymentStatus(d)
+	return ds, nil
+}
+
+// deployStateFromCronJob builds a DeployState from a CronJob.
+func deployStateFromCronJob(reg sous.ImageLabeller, clusters sous.Clusters, baseURL string, cj *CronJob) (*sous.DeployState, error) {
+	ds, err := baseDeployState(reg, clusters, baseURL, cj.Metadata, cj.Spec.JobTemplate.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	ds.ExecutorData = &kubeTaskData{name: cj.Metadata.Name, kind: kindCronJob}
+	ds.Kind = sous.ManifestKindScheduled
+	ds.Schedule = cj.Spec.Schedule
+	// Scheduled jobs have a single instance per run, and are live as soon as
+	// the API server accepts them.
+	ds.NumInstances = 1
+	ds.Status = sous.DeployStatusActive
+	return ds, nil
+}
+
+// deploymentStatus interprets the rollout status of a Deployment.
+func deploymentStatus(d *Deployment) (sous.DeployStatus, string) {
+	for _, c := range d.Status.Conditions {
+		if c.Type == "Progressing" && c.Status == "False" {
+			return sous.DeployStatusFailed, fmt.Sprintf("Deploy failure: %q %s", c.Reason, c.Message)
+		}
+	}
+	if d.Metadata.Generation > d.Status.ObservedGeneration {
+		return sous.DeployStatusPending, ""
+	}
+	var want int32
+	if d.Spec.Replicas != nil {
+		want = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas == want && d.Status.AvailableReplicas == want && d.Status.Replicas == want {
+		return sous.DeployStatusActive, ""
+	}
+	return sous.DeployStatusPending, ""
+}
+
+func baseDeployState(reg sous.ImageLabeller, clusters sous.Clusters, baseURL string, meta ObjectMeta, template PodTemplateSpec) (*sous.DeployState, error) {
+	ds := &sous.DeployState{}
+	an := meta.Annotations
+
+	clusterName, has := an[sous.ClusterNameLabel]
+	if !has {
+		return nil, nonSousError{meta.Name}
+	}
+	if _, has := clusters[clusterName]; !has {
+		return nil, notThisClusterError{clusterName, clusters.Names()}
+	}
+	ds.ClusterName = clusterName
+	ds.Cluster = &sous.Cluster{Name: clusterName, Kind: "kubernetes", BaseURL: baseURL}
+	ds.Flavor = an[sous.FlavorLabel]
+
+	if len(template.Spec.Containers) == 0 {
+		return nil, errors.Errorf("%s has no containers", meta.Name)
+	}
+	c := template.Spec.Containers[0]
+
+	sid, err := sourceID(reg, an, c.Image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", meta.Name)
+	}
+	ds.SourceID = sid
+
+	ds.Owners = sous.NewOwnerSet()
+	for _, o := range strings.Split(an[OwnersAnnotation], ",") {
+		if o != "" {
+			ds.Owners.Add(o)
+		}
+	}
+
+	if s, has := an[StartupAnnotation]; has {
+		if err := json.Unmarshal([]byte(s), &ds.Startup); err != nil {
+			return nil, errors.Wrapf(err, "%s: decoding %s", meta.Name, StartupAnnotation)
+		}
+	}
+	if md, has := an[MetadataAnnotation]; has {
+		if err := json.Unmarshal([]byte(md), &ds.Metadata); err != nil {
+			return nil, errors.Wrapf(err, "%s: decoding %s", meta.Name, MetadataAnnotation)
+		}
+	}
+	if ro, has := an[sous.RolloutLabel]; has {
+		if err := json.Unmarshal([]byte(ro), &ds.Rollout); err != nil {
+			return nil, errors.Wrapf(err, "%s: decoding %s", meta.Name, sous.RolloutLabel)
+		}
+	}
+
+	ds.Env = sous.Env{}
+	for _, e := range c.Env {
+		if portEnvName.MatchString(e.Name) {
+			continue
+		}
+		ds.Env[e.Name] = e.Value
+	}
+
+	ds.Resources = sous.Resources{
+		"cpus":   fmt.Sprintf("%f", parseCPU(c.Resources.Requests["cpu"])),
+		"memory": fmt.Sprintf("%f", parseMemoryMB(c.Resources.Requests["memory"])),
+		"ports":  fmt.Sprintf("%d", len(c.Ports)),
+	}
+
+	hostPaths := map[string]string{}
+	for _, v := range template.Spec.Volumes {
+		if v.HostPath != nil {
+			hostPaths[v.Name] = v.HostPath.Path
+		}
+	}
+	for _, m := range c.VolumeMounts {
+		mode := sous.ReadWrite
+		if m.ReadOnly {
+			mode = sous.ReadOnly
+		}
+		ds.Volumes = append(ds.Volumes, &sous.Volume{Host: hostPaths[m.Name], Container: m.MountPath, Mode: mode})
+	}
+	return ds, nil
+}
+
+// sourceID recovers the SourceID of a deployment from its annotations, falling
+// back to the labels on its image.
+func sourceID(reg sous.ImageLabeller, an map[string]string, image string) (sous.SourceID, error) {
+	if v, has := an[sous.VersionLabel]; has {
+		sid, err := sous.NewSourceID(an[sous.RepoLabel], an[sous.PathLabel], v)
+		sid.Version.Meta = an[sous.RevisionLabel]
+		return sid, err
+	}
+	labels, err := reg.ImageLabels(image)
+	if err != nil {
+		return sous.SourceID{}, err
+	}
+	return docker.SourceIDFromLabels(labels)
+}
+
+// parseCPU parses a Kubernetes CPU quantity, e.g. "0.5" or "500m".
+func parseCPU(q string) float64 {
+	if strings.HasSuffix(q, "m") {
+		m, _ := strconv.ParseFloat(strings.TrimSuffix(q, "m"), 64)
+		return m / 1000
+	}
+	cpus, _ := strconv.ParseFloat(q, 64)
+	return cpus
+}
+
+var memorySuffixes = []struct {
+	suffix string
+	mb     float64
+}{
+	{"Ki", 1.0 / 1024}, {"Mi", 1}, {"Gi", 1024},
+	{"K", 1000.0 / (1024 * 1024)}, {"M", 1000000.0 / (1024 * 1024)}, {"G", 1000000000.0 / (1024 * 1024)},
+}
+
+// parseMemoryMB parses a Kubernetes memory quantity into MiB.
+func parseMemoryMB(q string) float64 {
+	for _, s := range memorySuffixes {
+		if strings.HasSuffix(q, s.suffix) {
+			n, _ := strconv.ParseFloat(strings.TrimSuffix(q, s.suffix), 64)
+			return n * s.mb
+		}
+	}
+	bytes, _ := strconv.ParseFloat(q, 64)
+	return bytes / (1024 * 1024)
+}
@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+// dryrunClient reads from a real API server, but only logs writes.
+type dryrunClient struct {
+	kubeClient
+	log logging.LogSink
+}
+
+func (c dryrunClient) logWrite(op, name string) error {
+	messages.ReportLogFieldsMessage("Dry run: not calling Kubernetes", logging.InformationLevel, c.log, op, name)
+	return nil
+}
+
+func (c dryrunClient) CreateDeployment(_ string, d *Deployment) error {
+	return c.logWrite("CreateDeployment", d.Metadata.Name)
+}
+
+func (c dryrunClient) UpdateDeployment(_ string, d *Deployment) error {
+	return c.logWrite("UpdateDeployment", d.Metadata.Name)
+}
+
+func (c dryrunClient) DeleteDeployment(_, name string) error {
+	return c.logWrite("DeleteDeployment", name)
+}
+
+func (c dryrunClient) CreateCronJob(_ string, cj *CronJob) error {
+	return c.logWrite("CreateCronJob", cj.Metadata.Name)
+}
+
+func (c dryrunClient) UpdateCronJob(_ string, cj *CronJob) error {
+	return c.logWrite("UpdateCronJob", cj.Metadata.Name)
+}
+
+func (c dryrunClient) DeleteCronJob(_, name string) error {
+	return c.logWrite("DeleteCronJob", name)
+}
+
+func (c dryrunClient) CreateService(_ string, s *Service) error {
+	return c.logWrite("CreateService", s.Metadata.Name)
+}
+
+func (c dryrunClient) UpdateService(_ string, s *Service) error {
+	return c.logWrite("UpdateService", s.Metadata.Name)
+}
+
+func (c dryrunClient) DeleteService(_, name string) error {
+	return c.logWrite("DeleteService", name)
+}
@@ -0,0 +1,198 @@
+package kubernetes
+
+// The types in this file are a deliberately small subset of the Kubernetes
+// API objects: only the fields Sous reads or writes are represented. Unknown
+// fields returned by the API server are ignored when decoding.
+
+type (
+	// ObjectMeta is the metadata common to all Kubernetes objects.
+	ObjectMeta struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace,omitempty"`
+		Labels          map[string]string `json:"labels,omitempty"`
+		Annotations     map[string]string `json:"annotations,omitempty"`
+		ResourceVersion string            `json:"resourceVersion,omitempty"`
+		Generation      int64             `json:"generation,omitempty"`
+	}
+
+	// LabelSelector selects objects by their labels.
+	LabelSelector struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	}
+
+	// Deployment is an apps/v1 Deployment.
+	Deployment struct {
+		APIVersion string           `json:"apiVersion"`
+		Kind       string           `json:"kind"`
+		Metadata   ObjectMeta       `json:"metadata"`
+		Spec       DeploymentSpec   `json:"spec"`
+		Status     DeploymentStatus `json:"status,omitempty"`
+	}
+
+	// DeploymentSpec is the desired state of a Deployment.
+	DeploymentSpec struct {
+		Replicas                *int32          `json:"replicas,omitempty"`
+		Selector                *LabelSelector  `json:"selector,omitempty"`
+		Template                PodTemplateSpec `json:"template"`
+		ProgressDeadlineSeconds *int32          `json:"progressDeadlineSeconds,omitempty"`
+	}
+
+	// DeploymentStatus is the observed state of a Deployment.
+	DeploymentStatus struct {
+		ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+		Replicas           int32                 `json:"replicas,omitempty"`
+		UpdatedReplicas    int32                 `json:"updatedReplicas,omitempty"`
+		AvailableReplicas  int32                 `json:"availableReplicas,omitempty"`
+		Conditions         []DeploymentCondition `json:"conditions,omitempty"`
+	}
+
+	// DeploymentCondition describes the state of a Deployment at a point in
+	// time.
+	DeploymentCondition struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	// DeploymentList is a list of Deployments.
+	DeploymentList struct {
+		Items []Deployment `json:"items"`
+	}
+
+	// CronJob is a batch/v1 CronJob.
+	CronJob struct {
+		APIVersion string      `json:"apiVersion"`
+		Kind       string      `json:"kind"`
+		Metadata   ObjectMeta  `json:"metadata"`
+		Spec       CronJobSpec `json:"spec"`
+	}
+
+	// CronJobSpec is the desired state of a CronJob.
+	CronJobSpec struct {
+		Schedule    string          `json:"schedule"`
+		Suspend     *bool           `json:"suspend,omitempty"`
+		JobTemplate JobTemplateSpec `json:"jobTemplate"`
+	}
+
+	// JobTemplateSpec describes the Jobs created by a CronJob.
+	JobTemplateSpec struct {
+		Metadata ObjectMeta `json:"metadata,omitempty"`
+		Spec     JobSpec    `json:"spec"`
+	}
+
+	// JobSpec is the desired state of a Job.
+	JobSpec struct {
+		Template PodTemplateSpec `json:"template"`
+	}
+
+	// CronJobList is a list of CronJobs.
+	CronJobList struct {
+		Items []CronJob `json:"items"`
+	}
+
+	// Service is a v1 Service.
+	Service struct {
+		APIVersion string      `json:"apiVersion"`
+		Kind       string      `json:"kind"`
+		Metadata   ObjectMeta  `json:"metadata"`
+		Spec       ServiceSpec `json:"spec"`
+	}
+
+	// ServiceSpec is the desired state of a Service.
+	ServiceSpec struct {
+		Selector  map[string]string `json:"selector,omitempty"`
+		Ports     []ServicePort     `json:"ports,omitempty"`
+		ClusterIP string            `json:"clusterIP,omitempty"`
+	}
+
+	// ServicePort is a single port exposed by a Service.
+	ServicePort struct {
+		Name       string `json:"name"`
+		Port       int32  `json:"port"`
+		TargetPort int32  `json:"targetPort,omitempty"`
+	}
+
+	// PodTemplateSpec describes the pods created by a workload.
+	PodTemplateSpec struct {
+		Metadata ObjectMeta `json:"metadata,omitempty"`
+		Spec     PodSpec    `json:"spec"`
+	}
+
+	// PodSpec is the specification of a pod.
+	PodSpec struct {
+		Containers    []Container `json:"containers"`
+		Volumes       []Volume    `json:"volumes,omitempty"`
+		RestartPolicy string      `json:"restartPolicy,omitempty"`
+	}
+
+	// Container is a single container in a pod.
+	Container struct {
+		Name           string               `json:"name"`
+		Image          string               `json:"image"`
+		Env            []EnvVar             `json:"env,omitempty"`
+		Ports          []ContainerPort      `json:"ports,omitempty"`
+		Resources      ResourceRequirements `json:"resources,omitempty"`
+		VolumeMounts   []VolumeMount        `json:"volumeMounts,omitempty"`
+		ReadinessProbe *Probe               `json:"readinessProbe,omitempty"`
+	}
+
+	// EnvVar is an environment variable set in a container.
+	EnvVar struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// ContainerPort is a port exposed by a container.
+	ContainerPort struct {
+		Name          string `json:"name,omitempty"`
+		ContainerPort int32  `json:"containerPort"`
+	}
+
+	// ResourceRequirements are the compute resources of a container.
+	ResourceRequirements struct {
+		Limits   map[string]string `json:"limits,omitempty"`
+		Requests map[string]string `json:"requests,omitempty"`
+	}
+
+	// Volume is a volume available to the containers of a pod.
+	Volume struct {
+		Name     string                `json:"name"`
+		HostPath *HostPathVolumeSource `json:"hostPath,omitempty"`
+	}
+
+	// HostPathVolumeSource maps a path on the node into a pod.
+	HostPathVolumeSource struct {
+		Path string `json:"path"`
+	}
+
+	// VolumeMount mounts a Volume into a container.
+	VolumeMount struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+		ReadOnly  bool   `json:"readOnly,omitempty"`
+	}
+
+	// Probe is a container health check.
+	Probe struct {
+		HTTPGet             *HTTPGetAction `json:"httpGet,omitempty"`
+		InitialDelaySeconds int32          `json:"initialDelaySeconds,omitempty"`
+		TimeoutSeconds      int32          `json:"timeoutSeconds,omitempty"`
+		PeriodSeconds       int32          `json:"periodSeconds,omitempty"`
+		FailureThreshold    int32          `json:"failureThreshold,omitempty"`
+	}
+
+	// HTTPGetAction describes an HTTP health check.
+	HTTPGetAction struct {
+		Path   string `json:"path,omitempty"`
+		Port   int32  `json:"port"`
+		Scheme string `json:"scheme,omitempty"`
+	}
+
+	// Status is returned by the API server for failed requests.
+	Status struct {
+		Message string `json:"message,omitempty"`
+		Reason  string `json:"reason,omitempty"`
+		Code    int    `json:"code,omitempty"`
+	}
+)
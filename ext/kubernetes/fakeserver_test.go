@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+)
+
+// fakeAPIServer is an in-memory stand in for the subset of the Kubernetes API
+// used by the deployer. Objects are stored as raw JSON keyed by their path.
+// Deployments are reported as fully rolled out as soon as they are written.
+type fakeAPIServer struct {
+	*httptest.Server
+	sync.Mutex
+	objects  map[string]map[string]interface{}
+	requests []string
+}
+
+func newFakeAPIServer() *fakeAPIServer {
+	f := &fakeAPIServer{objects: map[string]map[string]interface{}{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeAPIServer) serve(rw http.ResponseWriter, req *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, req.Method+" "+req.URL.Path)
+
+	p := req.URL.Path
+	isCollection := strings.HasSuffix(p, "/deployments") ||
+		strings.HasSuffix(p, "/cronjobs") || strings.HasSuffix(p, "/services")
+
+	switch {
+	case req.Method == "GET" && isCollection:
+		items := []interface{}{}
+		for k, o := range f.objects {
+			if path.Dir(k) == p {
+				items = append(items, o)
+			}
+		}
+		f.respond(rw, http.StatusOK, map[string]interface{}{"items": items})
+	case req.Method == "GET":
+		o, has := f.objects[p]
+		if !has {
+			f.notFound(rw)
+			return
+		}
+		f.respond(rw, http.StatusOK, o)
+	case req.Method == "POST" && isCollection:
+		o := f.decode(req)
+		name := o["metadata"].(map[string]interface{})["name"].(string)
+		if _, has := f.objects[p+"/"+name]; has {
+			f.respond(rw, http.StatusConflict, map[string]interface{}{"message": "already exists", "code": 409})
+			return
+		}
+		f.store(p+"/"+name, o)
+		f.respond(rw, http.StatusCreated, o)
+	case req.Method == "PUT":
+		if _, has := f.objects[p]; !has {
+			f.notFound(rw)
+			return
+		}
+		o := f.decode(req)
+		f.store(p, o)
+		f.respond(rw, http.StatusOK, o)
+	case req.Method == "DELETE":
+		if _, has := f.objects[p]; !has {
+			f.notFound(rw)
+			return
+		}
+		delete(f.objects, p)
+		f.respond(rw, http.StatusOK, map[string]interface{}{})
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeAPIServer) store(p string, o map[string]interface{}) {
+	if strings.Contains(p, "/deployments/") {
+		replicas := o["spec"].(map[string]interface{})["replicas"]
+		o["status"] = map[string]interface{}{
+			"replicas":          replicas,
+			"updatedReplicas":   replicas,
+			"availableReplicas": replicas,
+		}
+	}
+	f.objects[p] = o
+}
+
+func (f *fakeAPIServer) decode(req *http.Request) map[string]interface{} {
+	b, _ := ioutil.ReadAll(req.Body)
+	o := map[string]interface{}{}
+	json.Unmarshal(b, &o)
+	return o
+}
+
+func (f *fakeAPIServer) notFound(rw http.ResponseWriter) {
+	f.respond(rw, http.StatusNotFound, map[string]interface{}{"message": "not found", "code": 404})
+}
+
+func (f *fakeAPIServer) respond(rw http.ResponseWriter, status int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(body)
+}
+
+// has reports whether an object exists at the given path.
+func (f *fakeAPIServer) has(p string) bool {
+	f.Lock()
+	defer f.Unlock()
+	_, has := f.objects[p]
+	return has
+}
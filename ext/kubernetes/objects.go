@@ -0,0 +1,308 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// Kubernetes object names must be valid DNS-1123 labels.
+const maxObjectNameLen = 63
+
+// basePort is the container port assigned to the first port a deployment
+// requests; subsequent ports are numbered consecutively.
+const basePort = 8080
+
+// defaultProgressDeadline is the number of seconds Kubernetes is given to roll
+// out a Deployment before it is considered failed. It matches the timeout we
+// give Singularity deploys.
+const defaultProgressDeadline = sous.SingularityDeployTimeout
+
+const (
+	// ManagedByLabel is the label used to select the objects Sous manages.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the value of ManagedByLabel on Sous managed objects.
+	ManagedByValue = "sous"
+	// InstanceLabel identifies the pods belonging to a single deployment.
+	InstanceLabel = "app.kubernetes.io/instance"
+
+	// StartupAnnotation records the sous.Startup of a deployment as JSON, since
+	// a readiness probe cannot represent all of its fields.
+	StartupAnnotation = "com.opentable.sous.startup"
+	// OwnersAnnotation records the comma separated owners of a deployment.
+	OwnersAnnotation = "com.opentable.sous.owners"
+	// MetadataAnnotation records the sous.Metadata of a deployment as JSON.
+	MetadataAnnotation = "com.opentable.sous.metadata"
+	// DeployUUIDAnnotation is set on the pod template; changing it forces a
+	// new rollout even when nothing else in the template has changed.
+	DeployUUIDAnnotation = "com.opentable.sous.deploy-uuid"
+
+	kindDeployment = "Deployment"
+	kindCronJob    = "CronJob"
+	kindService    = "Service"
+	containerName  = "app"
+)
+
+var (
+	selector               = ManagedByLabel + "=" + ManagedByValue
+	illegalObjectNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	portEnvName            = regexp.MustCompile(`^PORT[0-9]+$`)
+)
+
+type (
+	// objects is the set of Kubernetes objects used to run a single
+	// sous.Deployable.
+	objects struct {
+		deployment *Deployment
+		cronJob    *CronJob
+		service    *Service
+	}
+
+	// kubeTaskData is the ExecutorData attached to deployments read from
+	// Kubernetes.
+	kubeTaskData struct {
+		name, kind string
+	}
+
+	unsupportedKindError struct {
+		kind sous.ManifestKind
+	}
+)
+
+func (e unsupportedKindError) Error() string {
+	return fmt.Sprintf("manifest kind %q cannot be deployed to Kubernetes", e.kind)
+}
+
+// MakeObjectName creates a Kubernetes object name from a sous.DeploymentID.
+func MakeObjectName(did sous.DeploymentID) (string, error) {
+	sn, err := did.ManifestID.Source.ShortName()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{sn}
+	if did.ManifestID.Source.Dir != "" {
+		parts = append(parts, did.ManifestID.Source.Dir)
+	}
+	if did.ManifestID.Flavor != "" {
+		parts = append(parts, did.ManifestID.Flavor)
+	}
+	parts = append(parts, did.Cluster)
+	base := illegalObjectNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-")
+	suffix := fmt.Sprintf("-%x", did.Digest()[:4])
+	if len(base) > maxObjectNameLen-len(suffix) {
+		base = base[:maxObjectNameLen-len(suffix)]
+	}
+	return strings.Trim(base, "-") + suffix, nil
+}
+
+// workloadKind returns the Kubernetes kind used to run a manifest kind.
+func workloadKind(k sous.ManifestKind) (string, error) {
+	switch k {
+	default:
+		return "", unsupportedKindError{k}
+	case sous.ManifestKindService, sous.ManifestKindWorker:
+		return kindDeployment, nil
+	case sous.ManifestKindScheduled:
+		return kindCronJob, nil
+	}
+}
+
+func buildObjects(d sous.Deployable, namespace string, deployUUID string) (objects, error) {
+	var objs objects
+	if d.BuildArtifact == nil {
+		return objs, &sous.MissingImageNameError{Cause: fmt.Errorf("Missing BuildArtifact on Deployable")}
+	}
+	if refs := d.Env.SecretRefs(); len(refs) > 0 {
+		return objs, errors.Errorf("%s refers to secrets in its env, which is not yet supported on Kubernetes clusters", d.ID())
+	}
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return objs, err
+	}
+	kind, err := workloadKind(d.Kind)
+	if err != nil {
+		return objs, err
+	}
+	meta, err := objectMeta(d.Deployment, name, namespace)
+	if err != nil {
+		return objs, err
+	}
+	template := podTemplate(d, name, deployUUID)
+
+	switch kind {
+	case kindDeployment:
+		replicas := int32(d.NumInstances)
+		deadline := int32(defaultProgressDeadline)
+		objs.deployment = &Deployment{
+			APIVersion: "apps/v1",
+			Kind:       kindDeployment,
+			Metadata:   meta,
+			Spec: DeploymentSpec{
+				Replicas:                &replicas,
+				Selector:                &LabelSelector{MatchLabels: podLabels(name)},
+				Template:                template,
+				ProgressDeadlineSeconds: &deadline,
+			},
+		}
+	case kindCronJob:
+		template.Spec.RestartPolicy = "OnFailure"
+		objs.cronJob = &CronJob{
+			APIVersion: "batch/v1",
+			Kind:       kindCronJob,
+			Metadata:   meta,
+			Spec: CronJobSpec{
+				Schedule:    d.Schedule,
+				JobTemplate: JobTemplateSpec{Spec: JobSpec{Template: template}},
+			},
+		}
+	}
+
+	if d.Kind == sous.ManifestKindService {
+		objs.service = &Service{
+			APIVersion: "v1",
+			Kind:       kindService,
+			Metadata: ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    meta.Labels,
+			},
+			Spec: ServiceSpec{
+				Selector: podLabels(name),
+				Ports:    servicePorts(d.Resources.Ports()),
+			},
+		}
+	}
+	return objs, nil
+}
+
+func podLabels(name string) map[string]string {
+	return map[string]string{
+		ManagedByLabel: ManagedByValue,
+		InstanceLabel:  name,
+	}
+}
+
+func objectMeta(d *sous.Deployment, name, namespace string) (ObjectMeta, error) {
+	startup, err := json.Marshal(d.Startup)
+	if err != nil {
+		return ObjectMeta{}, errors.Wrapf(err, "encoding startup")
+	}
+	annotations := map[string]string{
+		sous.ClusterNameLabel: d.ClusterName,
+		sous.FlavorLabel:      d.Flavor,
+		sous.RepoLabel:        d.SourceID.Location.Repo,
+		sous.PathLabel:        d.SourceID.Location.Dir,
+		sous.VersionLabel:     d.SourceID.Version.Format("M.m.p-?"),
+		sous.RevisionLabel:    d.SourceID.RevID(),
+		StartupAnnotation:     string(startup),
+		OwnersAnnotation:      strings.Join(d.Owners.Slice(), ","),
+	}
+	if len(d.Metadata) > 0 {
+		md, err := json.Marshal(d.Metadata)
+		if err != nil {
+			return ObjectMeta{}, errors.Wrapf(err, "encoding metadata")
+		}
+		annotations[MetadataAnnotation] = string(md)
+	}
+	// Kubernetes replaces pods by its own rolling update; the Rollout is
+	// only recorded so that it survives the round trip.
+	if d.Rollout.Staged() {
+		ro, err := json.Marshal(d.Rollout)
+		if err != nil {
+			return ObjectMeta{}, errors.Wrapf(err, "encoding rollout")
+		}
+		annotations[sous.RolloutLabel] = string(ro)
+	}
+	return ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      podLabels(name),
+		Annotations: annotations,
+	}, nil
+}
+
+func podTemplate(d sous.Deployable, name, deployUUID string) PodTemplateSpec {
+	r := d.Resources
+	ports := r.Ports()
+
+	env := []EnvVar{}
+	for k, v := range d.Env {
+		env = append(env, EnvVar{Name: k, Value: v})
+	}
+	cports := []ContainerPort{}
+	for i := int32(0); i < ports; i++ {
+		env = append(env, EnvVar{Name: fmt.Sprintf("PORT%d", i), Value: strconv.Itoa(int(basePort + i))})
+		cports = append(cports, ContainerPort{Name: fmt.Sprintf("port%d", i), ContainerPort: basePort + i})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+
+	quantities := map[string]string{
+		"cpu":    strconv.FormatFloat(r.Cpus(), 'f', -1, 64),
+		"memory": strconv.FormatFloat(r.Memory(), 'f', -1, 64) + "Mi",
+	}
+
+	var volumes []Volume
+	var mounts []VolumeMount
+	for i, v := range d.Volumes {
+		if v == nil {
+			continue
+		}
+		vn := fmt.Sprintf("vol%d", i)
+		volumes = append(volumes, Volume{Name: vn, HostPath: &HostPathVolumeSource{Path: v.Host}})
+		mounts = append(mounts, VolumeMount{Name: vn, MountPath: v.Container, ReadOnly: v.Mode == sous.ReadOnly})
+	}
+
+	return PodTemplateSpec{
+		Metadata: ObjectMeta{
+			Labels:      podLabels(name),
+			Annotations: map[string]string{DeployUUIDAnnotation: deployUUID},
+		},
+		Spec: PodSpec{
+			Containers: []Container{{
+				Name:           containerName,
+				Image:          d.BuildArtifact.Name,
+				Env:            env,
+				Ports:          cports,
+				Resources:      ResourceRequirements{Limits: quantities, Requests: quantities},
+				VolumeMounts:   mounts,
+				ReadinessProbe: readinessProbe(d.Startup, d.Kind),
+			}},
+			Volumes: volumes,
+		},
+	}
+}
+
+func readinessProbe(s sous.Startup, kind sous.ManifestKind) *Probe {
+	if s.SkipCheck || kind != sous.ManifestKindService {
+		return nil
+	}
+	return &Probe{
+		HTTPGet: &HTTPGetAction{
+			Path:   s.CheckReadyURIPath,
+			Port:   basePort + int32(s.CheckReadyPortIndex),
+			Scheme: strings.ToUpper(s.CheckReadyProtocol),
+		},
+		InitialDelaySeconds: int32(s.ConnectDelay),
+		TimeoutSeconds:      int32(s.CheckReadyURITimeout),
+		PeriodSeconds:       int32(s.CheckReadyInterval),
+		FailureThreshold:    int32(s.CheckReadyRetries),
+	}
+}
+
+func servicePorts(n int32) []ServicePort {
+	ports := []ServicePort{}
+	for i := int32(0); i < n; i++ {
+		ports = append(ports, ServicePort{
+			Name:       fmt.Sprintf("port%d", i),
+			Port:       basePort + i,
+			TargetPort: basePort + i,
+		})
+	}
+	return ports
+}
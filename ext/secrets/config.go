@@ -0,0 +1,70 @@
+// Package secrets provides sous.SecretProviders, which look up the secrets
+// referred to by secret:// values in a DeployConfig.Env.
+package secrets
+
+import (
+	"net/http"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// vaultTimeout limits how long each read of a secret from Vault may take, so
+// that an unresponsive Vault cannot hold up rectification.
+const vaultTimeout = 10 * time.Second
+
+// Config describes where Sous looks up secrets.
+type Config struct {
+	// Provider is the kind of secret provider: "file", "vault", or empty for
+	// none, in which case deployments which refer to secrets fail.
+	Provider string `env:"SOUS_SECRETS_PROVIDER"`
+	// Dir is the directory read by the "file" provider, which holds a
+	// directory of secrets for each manifest.
+	Dir string `env:"SOUS_SECRETS_DIR"`
+	// VaultAddr is the base URL of the Vault-compatible HTTP API read by the
+	// "vault" provider.
+	VaultAddr string `env:"SOUS_VAULT_ADDR"`
+	// VaultMount is the path, below /v1/ on VaultAddr, under which the
+	// secrets of each manifest are kept. It defaults to "secret".
+	VaultMount string `env:"SOUS_VAULT_MOUNT"`
+	// VaultKVVersion is the version, 1 or 2, of the key/value secrets engine
+	// at VaultMount. It defaults to 1.
+	VaultKVVersion int `env:"SOUS_VAULT_KV_VERSION"`
+	// VaultToken authenticates Sous to VaultAddr.
+	VaultToken string `env:"SOUS_VAULT_TOKEN"`
+}
+
+// NewProvider returns the SecretProvider described by c, or nil if there is
+// none.
+func NewProvider(c Config) (sous.SecretProvider, error) {
+	switch c.Provider {
+	default:
+		return nil, errors.Errorf("unknown secret provider %q: expected file or vault", c.Provider)
+	case "":
+		return nil, nil
+	case "file":
+		if c.Dir == "" {
+			return nil, errors.New("the file secret provider needs a directory")
+		}
+		return NewFileProvider(c.Dir), nil
+	case "vault":
+		if c.VaultAddr == "" {
+			return nil, errors.New("the vault secret provider needs an address")
+		}
+		mount := c.VaultMount
+		if mount == "" {
+			mount = "secret"
+		}
+		version := c.VaultKVVersion
+		switch version {
+		default:
+			return nil, errors.Errorf("unknown vault key/value engine version %d: expected 1 or 2", version)
+		case 0:
+			version = 1
+		case 1, 2:
+		}
+		client := &http.Client{Timeout: vaultTimeout}
+		return NewHTTPProvider(c.VaultAddr, mount, version, c.VaultToken, client), nil
+	}
+}
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// A FileProvider implements sous.SecretProvider by reading files under a
+// directory. The path of a reference names a file below the directory of its
+// manifest, laid out like the prefixes read by HTTPProvider: the secret at
+// path for github.com/example/project,dir~flavor is the file
+// github.com/example/project/dir~flavor/path. If the reference has a key, the
+// file must hold a JSON object and the value of the key is the secret,
+// otherwise the whole file (less surrounding whitespace) is.
+type FileProvider struct {
+	dir string
+}
+
+// NewFileProvider returns a FileProvider reading secrets under dir.
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+// Secret implements sous.SecretProvider on FileProvider.
+func (p *FileProvider) Secret(ref sous.SecretRef) (string, error) {
+	scoped, err := manifestPath(ref)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(p.dir, filepath.FromSlash(scoped))
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref.Path)
+	}
+	if ref.Key == "" {
+		return strings.TrimSpace(string(b)), nil
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return "", errors.Wrapf(err, "parsing secret %s", ref.Path)
+	}
+	v, has := values[ref.Key]
+	if !has {
+		return "", errors.Errorf("secret %s has no key %q", ref.Path, ref.Key)
+	}
+	return v, nil
+}
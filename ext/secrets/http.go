@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// An HTTPProvider implements sous.SecretProvider by reading secrets from a
+// Vault-compatible HTTP API, with the token in the X-Vault-Token header. Each
+// manifest may only read the secrets under its own prefix: the secret at path
+// for the manifest github.com/example/project,dir~flavor is read from
+// /v1/<mount>/github.com/example/project/dir~flavor/path from version 1 of the
+// key/value secrets engine, and from
+// /v1/<mount>/data/github.com/example/project/dir~flavor/path from version 2.
+type HTTPProvider struct {
+	addr, mount, token string
+	kvVersion          int
+	client             *http.Client
+}
+
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// NewHTTPProvider returns an HTTPProvider for the API at addr, reading
+// secrets below mount, which is a version kvVersion key/value engine.
+func NewHTTPProvider(addr, mount string, kvVersion int, token string, client *http.Client) *HTTPProvider {
+	return &HTTPProvider{
+		addr:      strings.TrimSuffix(addr, "/"),
+		mount:     strings.Trim(mount, "/"),
+		kvVersion: kvVersion,
+		token:     token,
+		client:    client,
+	}
+}
+
+// manifestPrefix returns the path below which the secrets of mid are kept.
+// The flavor is always marked with a ~, so that no manifest's prefix is a
+// prefix of another's.
+func manifestPrefix(mid sous.ManifestID) string {
+	return path.Join(mid.Source.Repo, mid.Source.Dir) + "~" + mid.Flavor
+}
+
+// manifestPath returns the path of ref below the prefix of its manifest, or
+// an error if ref is not scoped to a manifest, or tries to escape the prefix.
+func manifestPath(ref sous.SecretRef) (string, error) {
+	for _, part := range strings.Split(ref.Path, "/") {
+		if part == ".." {
+			return "", errors.Errorf("secret path %s may not contain ..", ref.Path)
+		}
+	}
+	if ref.ManifestID.Source.Repo == "" {
+		return "", errors.Errorf("reading secret %s: no manifest to scope it to", ref.Path)
+	}
+	// Cleaning the path as if absolute keeps it within the manifest's prefix.
+	return path.Join(manifestPrefix(ref.ManifestID), path.Clean("/"+ref.Path)), nil
+}
+
+// Secret implements sous.SecretProvider on HTTPProvider.
+func (p *HTTPProvider) Secret(ref sous.SecretRef) (string, error) {
+	scoped, err := manifestPath(ref)
+	if err != nil {
+		return "", err
+	}
+	mount := p.mount
+	if p.kvVersion == 2 {
+		mount = path.Join(mount, "data")
+	}
+	url := p.addr + "/v1/" + path.Join(mount, scoped)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Vault-Token", p.token)
+	}
+	rz, err := p.client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref.Path)
+	}
+	defer rz.Body.Close()
+	if rz.StatusCode != http.StatusOK {
+		return "", errors.Errorf("reading secret %s: %s", ref.Path, rz.Status)
+	}
+
+	vr := vaultResponse{}
+	if err := json.NewDecoder(rz.Body).Decode(&vr); err != nil {
+		return "", errors.Wrapf(err, "parsing secret %s", ref.Path)
+	}
+	data := vr.Data
+	// Version 2 of the key/value engine nests the secret beside its metadata.
+	if p.kvVersion == 2 {
+		inner, ok := data["data"].(map[string]interface{})
+		if !ok {
+			return "", errors.Errorf("parsing secret %s: no data in the response", ref.Path)
+		}
+		data = inner
+	}
+
+	key := ref.Key
+	if key == "" {
+		if len(data) != 1 {
+			return "", errors.Errorf("secret %s has %d keys: the reference must name one", ref.Path, len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+	v, has := data[key]
+	if !has {
+		return "", errors.Errorf("secret %s has no key %q", ref.Path, key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider_Secret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	projectDir := filepath.Join(dir, "github.com", "example", "project~")
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "db"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(projectDir, "db", "creds"), []byte(`{"password":"hunter2"}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(projectDir, "token"), []byte("abc123\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("unscoped\n"), 0600))
+
+	project := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}
+	canary := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}, Flavor: "canary"}
+	p := NewFileProvider(dir)
+
+	v, err := p.Secret(sous.SecretRef{Path: "db/creds", Key: "password", ManifestID: project})
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	v, err = p.Secret(sous.SecretRef{Path: "token", ManifestID: project})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", v)
+
+	_, err = p.Secret(sous.SecretRef{Path: "db/creds", Key: "username", ManifestID: project})
+	assert.Error(t, err)
+
+	_, err = p.Secret(sous.SecretRef{Path: "token", ManifestID: canary})
+	assert.Error(t, err, "read another manifest's secret")
+
+	_, err = p.Secret(sous.SecretRef{Path: "token"})
+	assert.Error(t, err, "read a secret for no manifest")
+
+	_, err = p.Secret(sous.SecretRef{Path: "../../../../token", ManifestID: project})
+	assert.Error(t, err, "read outside the manifest's directory")
+}
+
+func TestHTTPProvider_Secret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "s.token" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		case "/v1/secret/github.com/example/project~/db":
+			rw.Write([]byte(`{"data":{"password":"hunter2","username":"app"}}`))
+		case "/v1/kv/data/github.com/example/project/util~canary/api":
+			rw.Write([]byte(`{"data":{"data":{"key":"abc123"},"metadata":{"version":3}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	project := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}
+	util := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project", Dir: "util"}, Flavor: "canary"}
+	p := NewHTTPProvider(srv.URL+"/", "secret", 1, "s.token", srv.Client())
+
+	v, err := p.Secret(sous.SecretRef{Path: "db", Key: "password", ManifestID: project})
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	v, err = NewHTTPProvider(srv.URL, "/kv/", 2, "s.token", srv.Client()).Secret(sous.SecretRef{Path: "api", ManifestID: util})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", v)
+
+	_, err = p.Secret(sous.SecretRef{Path: "db", ManifestID: project})
+	assert.Error(t, err, "no key, but many values")
+
+	_, err = p.Secret(sous.SecretRef{Path: "missing", Key: "x", ManifestID: project})
+	assert.Error(t, err)
+
+	_, err = p.Secret(sous.SecretRef{Path: "db", Key: "password", ManifestID: util})
+	assert.Error(t, err, "read another manifest's secret")
+
+	_, err = p.Secret(sous.SecretRef{Path: "db", Key: "password"})
+	assert.Error(t, err, "read a secret for no manifest")
+
+	_, err = p.Secret(sous.SecretRef{Path: "../project~/db", Key: "password", ManifestID: project})
+	assert.Error(t, err, "read outside the manifest's prefix")
+
+	_, err = NewHTTPProvider(srv.URL, "secret", 1, "wrong", srv.Client()).Secret(sous.SecretRef{Path: "db", Key: "password", ManifestID: project})
+	assert.Error(t, err)
+}
+
+func TestNewProvider(t *testing.T) {
+	p, err := NewProvider(Config{})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	p, err = NewProvider(Config{Provider: "file", Dir: "/etc/sous/secrets"})
+	require.NoError(t, err)
+	assert.IsType(t, &FileProvider{}, p)
+
+	_, err = NewProvider(Config{Provider: "vault"})
+	assert.Error(t, err)
+
+	p, err = NewProvider(Config{Provider: "vault", VaultAddr: "https://vault.example.com", VaultKVVersion: 2})
+	require.NoError(t, err)
+	require.IsType(t, &HTTPProvider{}, p)
+	assert.Equal(t, 2, p.(*HTTPProvider).kvVersion)
+	assert.NotZero(t, p.(*HTTPProvider).client.Timeout)
+
+	_, err = NewProvider(Config{Provider: "vault", VaultAddr: "https://vault.example.com", VaultKVVersion: 3})
+	assert.Error(t, err)
+
+	_, err = NewProvider(Config{Provider: "keepass"})
+	assert.Error(t, err)
+}
@@ -277,7 +277,8 @@ func changesDep(pair *sous.DeployablePair) bool {
 			pair.Prior.Resources.Equal(pair.Post.Resources) &&
 			pair.Prior.Env.Equal(pair.Post.Env) &&
 			pair.Prior.DeployConfig.Volumes.Equal(pair.Post.DeployConfig.Volumes) &&
-			pair.Prior.Startup.Equal(pair.Post.Startup))
+			pair.Prior.Startup.Equal(pair.Post.Startup) &&
+			pair.Prior.Rollout == pair.Post.Rollout)
 }
 
 func computeRequestID(d *sous.Deployable) (string, error) {
@@ -280,7 +280,7 @@ func matchedPair(t *testing.T, startDep *sous.Deployment) *sous.DeployablePair {
 	req := &dtos.SingularityRequest{}
 	jsonRoundtrip(t, aReq, req)
 
-	aDepReq, err := buildDeployRequest(deployable, reqID, depID, map[string]string{})
+	aDepReq, err := buildDeployRequest(deployable, reqID, depID, map[string]string{}, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, aDepReq)
 
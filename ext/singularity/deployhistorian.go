@@ -0,0 +1,90 @@
+package singularity
+
+import (
+	"github.com/opentable/go-singularity"
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+// deployHistoryDepth is the number of past deploys examined for each request.
+const deployHistoryDepth = 20
+
+// A DeployHistorian implements sous.DeployHistorian by reading the deploy
+// history of Singularity requests.
+type DeployHistorian struct {
+	clusters sous.Clusters
+	labeller sous.ImageLabeller
+	singFac  func(string) singClient
+	log      logging.LogSink
+}
+
+// NewDeployHistorian returns a DeployHistorian for clusters, using labeller
+// to recover the SourceIDs of deployed images.
+func NewDeployHistorian(clusters sous.Clusters, labeller sous.ImageLabeller, ls logging.LogSink) *DeployHistorian {
+	return &DeployHistorian{
+		clusters: clusters,
+		labeller: labeller,
+		singFac: func(url string) singClient {
+			return singularity.NewClient(url, ls)
+		},
+		log: ls,
+	}
+}
+
+// DeployedVersions implements sous.DeployHistorian on DeployHistorian.
+// Deploys whose images can no longer be found are skipped.
+func (h *DeployHistorian) DeployedVersions(did sous.DeploymentID) ([]sous.SourceID, error) {
+	cluster, ok := h.clusters[did.Cluster]
+	if !ok {
+		return nil, errors.Errorf("no cluster named %q", did.Cluster)
+	}
+	if cluster.Kind != "" && cluster.Kind != "singularity" {
+		return nil, errors.Errorf("cluster %q is a %s cluster; deploy history is only available from Singularity", did.Cluster, cluster.Kind)
+	}
+	reqID, err := MakeRequestID(did)
+	if err != nil {
+		return nil, err
+	}
+
+	client := h.singFac(cluster.BaseURL)
+	deploys, err := client.GetDeploys(reqID, deployHistoryDepth, 1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting deploy history of %s", reqID)
+	}
+
+	var sids []sous.SourceID
+	for _, partial := range deploys {
+		if partial.DeployMarker == nil {
+			continue
+		}
+		dh, err := client.GetDeploy(reqID, partial.DeployMarker.DeployId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting deploy %s of %s", partial.DeployMarker.DeployId, reqID)
+		}
+		if dh.DeployResult == nil || dh.DeployResult.DeployState != dtos.SingularityDeployResultDeployStateSUCCEEDED {
+			continue
+		}
+		sid, err := h.deployedSourceID(dh.Deploy)
+		if err != nil {
+			messages.ReportLogFieldsMessage("Skipping deploy in history", logging.WarningLevel, h.log, reqID, partial.DeployMarker.DeployId, err)
+			continue
+		}
+		sids = append(sids, sid)
+	}
+	return sids, nil
+}
+
+func (h *DeployHistorian) deployedSourceID(deploy *dtos.SingularityDeploy) (sous.SourceID, error) {
+	if deploy == nil || deploy.ContainerInfo == nil || deploy.ContainerInfo.Docker == nil {
+		return sous.SourceID{}, malformedResponse{"Singularity deploy didn't include a docker info"}
+	}
+	labels, err := h.labeller.ImageLabels(deploy.ContainerInfo.Docker.Image)
+	if err != nil {
+		return sous.SourceID{}, err
+	}
+	return docker.SourceIDFromLabels(labels)
+}
@@ -0,0 +1,101 @@
+package singularity
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type historyLabeller map[string]sous.SourceID
+
+func (hl historyLabeller) ImageLabels(imageName string) (map[string]string, error) {
+	sid, ok := hl[imageName]
+	if !ok {
+		return nil, fmt.Errorf("no image %q", imageName)
+	}
+	return docker.Labels(sid), nil
+}
+
+func historicDeploy(depID, image string, state dtos.SingularityDeployResultDeployState) *dtos.SingularityDeployHistory {
+	return &dtos.SingularityDeployHistory{
+		DeployResult: &dtos.SingularityDeployResult{DeployState: state},
+		Deploy: &dtos.SingularityDeploy{
+			Id: depID,
+			ContainerInfo: &dtos.SingularityContainerInfo{
+				Type:   dtos.SingularityContainerInfoSingularityContainerTypeDOCKER,
+				Docker: &dtos.SingularityDockerInfo{Image: image},
+			},
+		},
+	}
+}
+
+func TestDeployHistorian_DeployedVersions(t *testing.T) {
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}},
+		Cluster:    "left",
+	}
+	sid := func(v string) sous.SourceID { return sous.MustNewSourceID(did.ManifestID.Source.Repo, "", v) }
+	labeller := historyLabeller{
+		"image:3": sid("3.0.0"),
+		"image:2": sid("2.0.0"),
+		"image:1": sid("1.0.0"),
+	}
+
+	fakeSing, c := newSingClientSpy()
+	deploys := map[string]*dtos.SingularityDeployHistory{
+		"dep4": historicDeploy("dep4", "image:3", dtos.SingularityDeployResultDeployStateFAILED),
+		"dep3": historicDeploy("dep3", "image:2", dtos.SingularityDeployResultDeployStateSUCCEEDED),
+		"dep2": historicDeploy("dep2", "image:gone", dtos.SingularityDeployResultDeployStateSUCCEEDED),
+		"dep1": historicDeploy("dep1", "image:1", dtos.SingularityDeployResultDeployStateSUCCEEDED),
+	}
+	list := dtos.SingularityDeployHistoryList{}
+	for _, id := range []string{"dep4", "dep3", "dep2", "dep1"} {
+		list = append(list, &dtos.SingularityDeployHistory{DeployMarker: &dtos.SingularityDeployMarker{DeployId: id}})
+		dh := deploys[id]
+		c.MatchMethod("GetDeploy", func(args mock.Arguments) bool { return args.String(1) == dh.Deploy.Id }, dh, nil)
+	}
+	c.MatchMethod("GetDeploys", spies.AnyArgs, list, nil)
+
+	h := NewDeployHistorian(sous.Clusters{"left": &sous.Cluster{Name: "left", BaseURL: "http://left"}}, labeller, logging.SilentLogSet())
+	var urls []string
+	h.singFac = func(url string) singClient {
+		urls = append(urls, url)
+		return fakeSing
+	}
+
+	sids, err := h.DeployedVersions(did)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://left"}, urls)
+	require.Len(t, sids, 2)
+	assert.Equal(t, "2.0.0", sids[0].Version.String())
+	assert.Equal(t, "1.0.0", sids[1].Version.String())
+
+	reqID, err := MakeRequestID(did)
+	require.NoError(t, err)
+	calls := c.CallsTo("GetDeploys")
+	require.Len(t, calls, 1)
+	assert.Equal(t, reqID, calls[0].PassedArgs().String(0))
+}
+
+func TestDeployHistorian_DeployedVersions_errors(t *testing.T) {
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}},
+		Cluster:    "kube",
+	}
+	h := NewDeployHistorian(sous.Clusters{"kube": &sous.Cluster{Name: "kube", Kind: "kubernetes"}}, historyLabeller{}, logging.SilentLogSet())
+
+	_, err := h.DeployedVersions(did)
+	assert.Error(t, err)
+
+	did.Cluster = "missing"
+	_, err = h.DeployedVersions(did)
+	assert.Error(t, err)
+}
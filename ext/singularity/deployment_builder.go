@@ -345,6 +345,11 @@ func (db *deploymentBuilder) extractArtifactName() error {
 	}
 
 	db.imageName = dkr.Image
+	db.Target.BuildArtifact = &sous.BuildArtifact{
+		Name: dkr.Image,
+		Type: "docker",
+		Tag:  db.deploy.Metadata[sous.ImageTagLabel],
+	}
 	return nil
 }
 
@@ -393,11 +398,23 @@ func (db *deploymentBuilder) restoreFromMetadata() error {
 }
 
 func (db *deploymentBuilder) unpackDeployConfig() error {
-	db.Target.Env = db.deploy.Env
-	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.deploy.Env)
-	if db.Target.Env == nil {
-		db.Target.Env = make(map[string]string)
+	db.Target.Env = make(sous.Env, len(db.deploy.Env))
+	for name, v := range db.deploy.Env {
+		db.Target.Env[name] = v
+	}
+	if sr, has := db.deploy.Metadata[sous.SecretsLabel]; has {
+		refs := map[string]string{}
+		if err := json.Unmarshal([]byte(sr), &refs); err != nil {
+			return malformedResponse{fmt.Sprintf("Deploy Metadata %s could not be parsed: %s", sous.SecretsLabel, err)}
+		}
+		// Put back the references the deploy's secrets were resolved from.
+		for name, ref := range refs {
+			if _, set := db.Target.Env[name]; set {
+				db.Target.Env[name] = ref
+			}
+		}
 	}
+	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.Target.Env)
 
 	singRez := db.deploy.Resources
 	if singRez == nil {
@@ -446,6 +463,12 @@ func (db *deploymentBuilder) unpackDeployConfig() error {
 		db.Target.Startup.SkipCheck = true
 	}
 
+	if ro, has := db.deploy.Metadata[sous.RolloutLabel]; has {
+		if err := json.Unmarshal([]byte(ro), &db.Target.Rollout); err != nil {
+			return malformedResponse{fmt.Sprintf("Deploy Metadata %s could not be parsed: %s", sous.RolloutLabel, err)}
+		}
+	}
+
 	return nil
 }
 
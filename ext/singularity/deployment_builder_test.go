@@ -157,6 +157,7 @@ func TestBuildDeployment(t *testing.T) {
 			Metadata: map[string]string{
 				"com.opentable.sous.clustername": "left",
 				"com.opentable.sous.flavor":      "vanilla",
+				"com.opentable.sous.image_tag":   "image-name:1.2.3",
 			},
 
 			Healthcheck: &dtos.HealthcheckOptions{
@@ -167,7 +168,7 @@ func TestBuildDeployment(t *testing.T) {
 
 			ContainerInfo: &dtos.SingularityContainerInfo{
 				Type:   "DOCKER",
-				Docker: &dtos.SingularityDockerInfo{Image: "image-name"},
+				Docker: &dtos.SingularityDockerInfo{Image: "image-name@sha256:0123"},
 				Volumes: dtos.SingularityVolumeList{
 					&dtos.SingularityVolume{
 						HostPath:      "hostpath",
@@ -199,6 +200,11 @@ func TestBuildDeployment(t *testing.T) {
 
 	assert.NoError(t, err)
 
+	if assert.NotNil(t, actual.BuildArtifact) {
+		assert.Equal(t, "image-name@sha256:0123", actual.BuildArtifact.Name)
+		assert.Equal(t, "image-name:1.2.3", actual.BuildArtifact.Tag)
+	}
+
 	expected := sous.DeployState{Status: sous.DeployStatusActive}
 	expected.ClusterName = "left"
 	expected.Flavor = "vanilla"
@@ -1,6 +1,7 @@
 package singularity
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -39,6 +40,7 @@ type (
 		singClients map[string]*singularity.Client
 		sync.RWMutex
 		labeller sous.ImageLabeller
+		secrets  sous.SecretProvider
 	}
 
 	singularityTaskData struct {
@@ -46,11 +48,13 @@ type (
 	}
 )
 
-// NewRectiAgent returns a set-up RectiAgent
-func NewRectiAgent(l sous.ImageLabeller) *RectiAgent {
+// NewRectiAgent returns a set-up RectiAgent. Secret references in deployment
+// environments are resolved with sp, which may be nil if there are none.
+func NewRectiAgent(l sous.ImageLabeller, sp sous.SecretProvider) *RectiAgent {
 	return &RectiAgent{
 		singClients: make(map[string]*singularity.Client),
 		labeller:    l,
+		secrets:     sp,
 	}
 }
 
@@ -76,20 +80,25 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 		return err
 	}
 	messages.ReportLogFieldsMessage("Build deploying instance", logging.DebugLevel, Log, d, reqID)
-	depReq, err := buildDeployRequest(d, reqID, depID, labels)
+	depReq, err := buildDeployRequest(d, reqID, depID, labels, ra.secrets)
 	if err != nil {
 		return err
 	}
 
-	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, Log, depReq)
+	logged := redactedDeployRequest(depReq, d.Deployment.Env)
+	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, Log, logged)
 	_, err = ra.singularityClient(clusterURI).Deploy(depReq)
 	if err != nil {
-		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, Log, depReq, reqID, err)
+		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, Log, logged, reqID, err)
 	}
 	return err
 }
 
-func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string) (*dtos.SingularityDeployRequest, error) {
+// buildDeployRequest builds the request to deploy d. This is the only place
+// the secrets referred to in its Env are resolved; the references themselves
+// are recorded in the deploy metadata, so that the deployment read back from
+// Singularity matches the one in the GDM.
+func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string, secrets sous.SecretProvider) (*dtos.SingularityDeployRequest, error) {
 	var depReq swaggering.Fielder
 	dockerImage := d.BuildArtifact.Name
 	r := d.Deployment.DeployConfig.Resources
@@ -98,6 +107,29 @@ func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[str
 
 	metadata[sous.ClusterNameLabel] = d.Deployment.ClusterName
 	metadata[sous.FlavorLabel] = d.Deployment.Flavor
+	if d.BuildArtifact.Tag != "" {
+		metadata[sous.ImageTagLabel] = d.BuildArtifact.Tag
+	}
+
+	if refs := e.SecretRefs(); len(refs) > 0 {
+		sr, err := json.Marshal(refs)
+		if err != nil {
+			return nil, err
+		}
+		metadata[sous.SecretsLabel] = string(sr)
+		if e, err = e.ResolveSecrets(secrets, d.ManifestID()); err != nil {
+			return nil, err
+		}
+	}
+
+	rollout := d.Deployment.DeployConfig.Rollout
+	if rollout.Staged() {
+		ro, err := json.Marshal(rollout)
+		if err != nil {
+			return nil, err
+		}
+		metadata[sous.RolloutLabel] = string(ro)
+	}
 
 	dockerInfo, err := swaggering.LoadMap(&dtos.SingularityDockerInfo{}, dtoMap{
 		"Image":   dockerImage,
@@ -151,17 +183,37 @@ func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[str
 		return nil, err
 	}
 
+	if rollout.Staged() {
+		// Sous advances each step itself; c.f. deployer.AdvanceRollout.
+		depMap["DeployInstanceCountPerStep"] = int32(rollout.Stages(d.Deployment.NumInstances)[0])
+		depMap["AutoAdvanceDeploySteps"] = false
+	}
+
 	dep, err := swaggering.LoadMap(&dtos.SingularityDeploy{}, depMap)
 	if err != nil {
 		return nil, err
 	}
-	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, Log, dep, ci, dockerInfo)
 
 	depReq, err = swaggering.LoadMap(&dtos.SingularityDeployRequest{}, dtoMap{"Deploy": dep})
 	if err != nil {
 		return nil, err
 	}
-	return depReq.(*dtos.SingularityDeployRequest), nil
+	dr := depReq.(*dtos.SingularityDeployRequest)
+	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, Log, redactedDeployRequest(dr, d.Deployment.Env).Deploy, ci, dockerInfo)
+	return dr, nil
+}
+
+// redactedDeployRequest returns a copy of dr suitable for logging, in which the
+// values of the variables which are secret references in env are redacted.
+func redactedDeployRequest(dr *dtos.SingularityDeployRequest, env sous.Env) *dtos.SingularityDeployRequest {
+	if dr == nil || dr.Deploy == nil {
+		return dr
+	}
+	deploy := *dr.Deploy
+	deploy.Env = sous.Env(deploy.Env).Redacted(env)
+	redacted := *dr
+	redacted.Deploy = &deploy
+	return &redacted
 }
 
 // MapStartupIntoHealthcheckOptions updates the given dtoMap with fields for a
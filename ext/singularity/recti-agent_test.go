@@ -5,6 +5,7 @@ import (
 
 	"github.com/opentable/go-singularity/dtos"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -91,7 +92,7 @@ func TestDetermineRequestType(t *testing.T) {
 func TestFailOnNilBuildArtifact(t *testing.T) {
 	r := sous.NewDummyRegistry()
 	d := sous.Deployable{}
-	ra := NewRectiAgent(r)
+	ra := NewRectiAgent(r, nil)
 	err := ra.Deploy(d, "testReq", "testDep")
 	if err != nil {
 		t.Logf("Correctly returned an error upon encountering: %#v", err)
@@ -163,7 +164,7 @@ func TestContainerStartupOptions(t *testing.T) {
 	d.Startup.CheckReadyURIPath = checkReadyPath
 	d.Startup.Timeout = checkReadyTimeout
 
-	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{})
+	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -177,3 +178,81 @@ func TestContainerStartupOptions(t *testing.T) {
 	}
 
 }
+
+func TestRolloutDeployOptions(t *testing.T) {
+	d := *sous.DeployableFixture("")
+	d.NumInstances = 10
+	d.Rollout = sous.Rollout{CanaryPercent: 20, Steps: 2}
+
+	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dr.Deploy.DeployInstanceCountPerStep != 2 {
+		t.Errorf("expected 2 instances in first step, got %d", dr.Deploy.DeployInstanceCountPerStep)
+	}
+	if dr.Deploy.AutoAdvanceDeploySteps {
+		t.Errorf("expected deploy steps not to auto-advance")
+	}
+	if _, has := dr.Deploy.Metadata[sous.RolloutLabel]; !has {
+		t.Errorf("expected %s in deploy metadata", sous.RolloutLabel)
+	}
+
+	d.Rollout = sous.Rollout{}
+	dr, err = buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Deploy.DeployInstanceCountPerStep != 0 {
+		t.Errorf("expected no deploy steps, got %d instances per step", dr.Deploy.DeployInstanceCountPerStep)
+	}
+}
+
+type fakeSecretProvider map[string]string
+
+func (p fakeSecretProvider) Secret(ref sous.SecretRef) (string, error) {
+	return p[ref.String()], nil
+}
+
+func TestSecretDeployOptions(t *testing.T) {
+	d := *sous.DeployableFixture("")
+	d.Env = sous.Env{"DB_PASSWORD": "secret://db/creds#password", "GREETING": "hello"}
+	sp := fakeSecretProvider{"secret://db/creds#password": "hunter2"}
+
+	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hunter2", dr.Deploy.Env["DB_PASSWORD"])
+	assert.Equal(t, "hello", dr.Deploy.Env["GREETING"])
+	assert.Equal(t, "secret://db/creds#password", d.Env["DB_PASSWORD"], "resolving changed the deployable")
+	assert.Equal(t, "<redacted>", redactedDeployRequest(dr, d.Env).Deploy.Env["DB_PASSWORD"])
+	assert.Equal(t, "hunter2", dr.Deploy.Env["DB_PASSWORD"], "redacting changed the request")
+
+	db := &deploymentBuilder{
+		deploy:  dr.Deploy,
+		request: &dtos.SingularityRequest{},
+		log:     logging.SilentLogSet(),
+	}
+	if err := db.unpackDeployConfig(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, d.Env, db.Target.Env, "secret references not restored from metadata")
+
+	if _, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil); err == nil {
+		t.Errorf("expected an error deploying secrets with no provider")
+	}
+}
+
+func TestImageTagDeployMetadata(t *testing.T) {
+	d := *sous.DeployableFixture("")
+	d.BuildArtifact.Tag = "docker.example.com/ot/one:1.2.3"
+
+	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, d.BuildArtifact.Name, dr.Deploy.ContainerInfo.Docker.Image)
+	assert.Equal(t, d.BuildArtifact.Tag, dr.Deploy.Metadata[sous.ImageTagLabel])
+}
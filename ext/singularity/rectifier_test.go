@@ -39,7 +39,7 @@ func TestBuildDeployRequest(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, map[string]string{})
+	}, rID, dID, map[string]string{}, nil)
 	require.NoError(err)
 	assert.NotNil(dr)
 	assert.Equal(dr.Deploy.RequestId, rID)
@@ -76,7 +76,7 @@ func TestDockerMetadataSet(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, md)
+	}, rID, dID, md, nil)
 
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,73 @@
+package singularity
+
+import (
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+// rolloutTarget returns the Singularity client, request ID and deploy ID for
+// the rollout of pair.Post.
+func (r *deployer) rolloutTarget(pair *sous.DeployablePair) (singClient, string, string, error) {
+	if pair.Post == nil || pair.Post.Deployment == nil || pair.Post.Cluster == nil {
+		return nil, "", "", errors.Errorf("%q has no cluster to roll out to", pair.ID())
+	}
+	reqID, err := r.getRequestID(pair.Post)
+	if err != nil {
+		return nil, "", "", err
+	}
+	depID := computeDeployIDFromUUID(pair.Post, pair.UUID)
+	return r.buildSingClient(pair.Post.Cluster.BaseURL), reqID, depID, nil
+}
+
+// RolloutProgress implements sous.RolloutDeployer on deployer, reporting the
+// progress of the pending incremental deploy for pair.
+func (r *deployer) RolloutProgress(pair *sous.DeployablePair) (*sous.RolloutProgress, error) {
+	client, reqID, depID, err := r.rolloutTarget(pair)
+	if err != nil {
+		return nil, err
+	}
+	reqParent, err := client.GetRequest(reqID, false) //don't use the web cache
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting request")
+	}
+	pending := reqParent.PendingDeployState
+	if pending == nil || pending.DeployMarker == nil || pending.DeployMarker.DeployId != depID || pending.DeployProgress == nil {
+		return nil, nil
+	}
+	dp := pending.DeployProgress
+	return &sous.RolloutProgress{
+		TargetInstances: int(dp.TargetActiveInstances),
+		ActiveInstances: int(dp.CurrentActiveInstances),
+		FailedInstances: len(dp.FailedDeployTasks),
+		StepComplete:    dp.StepComplete,
+	}, nil
+}
+
+// AdvanceRollout implements sous.RolloutDeployer on deployer.
+func (r *deployer) AdvanceRollout(pair *sous.DeployablePair, target int) error {
+	client, reqID, depID, err := r.rolloutTarget(pair)
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessage("Advancing rollout", logging.InformationLevel, r.log, reqID, depID, target)
+	_, err = client.UpdatePendingDeploy(&dtos.SingularityUpdatePendingDeployRequest{
+		RequestId:             reqID,
+		DeployId:              depID,
+		TargetActiveInstances: int32(target),
+	})
+	return errors.Wrapf(err, "advancing deploy %s of %s", depID, reqID)
+}
+
+// AbortRollout implements sous.RolloutDeployer on deployer.
+func (r *deployer) AbortRollout(pair *sous.DeployablePair) error {
+	client, reqID, depID, err := r.rolloutTarget(pair)
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessage("Aborting rollout", logging.WarningLevel, r.log, reqID, depID)
+	_, err = client.CancelDeploy(reqID, depID)
+	return errors.Wrapf(err, "cancelling deploy %s of %s", depID, reqID)
+}
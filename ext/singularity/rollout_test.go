@@ -0,0 +1,65 @@
+package singularity
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/go-singularity/dtos"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployer_Rollout(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	dep := &deployer{log: ls}
+
+	sing, c := newSingClientSpy()
+	dep.SetSingularityFactory(func(string) singClient { return sing })
+
+	pair := &sous.DeployablePair{Post: sous.DeployableFixture("")}
+	pair.Post.Cluster = &sous.Cluster{Name: "cluster-1", BaseURL: "http://sing.example.com"}
+	reqID, err := computeRequestID(pair.Post)
+	require.NoError(t, err)
+	depID := computeDeployIDFromUUID(pair.Post, pair.UUID)
+
+	parent := &dtos.SingularityRequestParent{
+		PendingDeployState: &dtos.SingularityPendingDeploy{
+			DeployMarker: &dtos.SingularityDeployMarker{RequestId: reqID, DeployId: depID},
+			DeployProgress: &dtos.SingularityDeployProgress{
+				TargetActiveInstances:  2,
+				CurrentActiveInstances: 1,
+				FailedDeployTasks:      dtos.SingularityTaskIdList{&dtos.SingularityTaskId{}},
+			},
+		},
+	}
+	c.MatchMethod("GetRequest", spies.AnyArgs, parent, nil)
+	c.MatchMethod("UpdatePendingDeploy", spies.AnyArgs, parent, nil)
+	c.MatchMethod("CancelDeploy", spies.AnyArgs, parent, nil)
+
+	progress, err := dep.RolloutProgress(pair)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	assert.Equal(t, 2, progress.TargetInstances)
+	assert.Equal(t, 1, progress.ActiveInstances)
+	assert.Equal(t, 1, progress.FailedInstances)
+	assert.False(t, progress.StepComplete)
+
+	require.NoError(t, dep.AdvanceRollout(pair, 5))
+	calls := c.CallsTo("UpdatePendingDeploy")
+	require.Len(t, calls, 1)
+	update := calls[0].PassedArgs().Get(0).(*dtos.SingularityUpdatePendingDeployRequest)
+	assert.Equal(t, reqID, update.RequestId)
+	assert.Equal(t, depID, update.DeployId)
+	assert.Equal(t, int32(5), update.TargetActiveInstances)
+
+	require.NoError(t, dep.AbortRollout(pair))
+	assert.Len(t, c.CallsTo("CancelDeploy"), 1)
+
+	// Another deploy pending isn't this rollout.
+	parent.PendingDeployState.DeployMarker.DeployId = "someone-elses"
+	progress, err = dep.RolloutProgress(pair)
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+}
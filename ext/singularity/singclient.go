@@ -6,13 +6,16 @@ import (
 )
 
 type (
-	// singClient abstracts the queries we use to retrieve data from singularity.
+	// singClient abstracts the queries we use to retrieve data from singularity,
+	// and the calls we use to steer a deploy in progress.
 	singClient interface {
 		GetRequest(reqID string, useCache bool) (*dtos.SingularityRequestParent, error)
 		GetRequests(useCache bool) (dtos.SingularityRequestParentList, error)
 		GetDeploy(reqID, depID string) (*dtos.SingularityDeployHistory, error)
 		GetDeploys(reqID string, count int32, page int32) (dtos.SingularityDeployHistoryList, error)
 		GetPendingDeploys() (dtos.SingularityPendingDeployList, error)
+		UpdatePendingDeploy(body *dtos.SingularityUpdatePendingDeployRequest) (*dtos.SingularityRequestParent, error)
+		CancelDeploy(reqID, depID string) (*dtos.SingularityRequestParent, error)
 	}
 
 	singClientSpy struct {
@@ -54,6 +57,16 @@ func (spy singClientSpy) GetPendingDeploys() (dtos.SingularityPendingDeployList,
 	return res.Get(0).(dtos.SingularityPendingDeployList), res.Error(1)
 }
 
+func (spy singClientSpy) UpdatePendingDeploy(body *dtos.SingularityUpdatePendingDeployRequest) (*dtos.SingularityRequestParent, error) {
+	res := spy.spy.Called(body)
+	return res.Get(0).(*dtos.SingularityRequestParent), res.Error(1)
+}
+
+func (spy singClientSpy) CancelDeploy(reqID, depID string) (*dtos.SingularityRequestParent, error) {
+	res := spy.spy.Called(reqID, depID)
+	return res.Get(0).(*dtos.SingularityRequestParent), res.Error(1)
+}
+
 func (ctrl singClientSpyController) cannedRequest(answer *dtos.SingularityRequestParent) {
 	ctrl.MatchMethod("GetRequest", spies.AnyArgs, answer, nil)
 	ctrl.MatchMethod("GetRequests", spies.AnyArgs, dtos.SingularityRequestParentList{answer}, nil)
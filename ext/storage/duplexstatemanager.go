@@ -24,6 +24,16 @@ func NewDuplexStateManager(primary, secondary sous.StateManager, log logging.Log
 	}
 }
 
+// Primary returns the primary StateManager of dup.
+func (dup *DuplexStateManager) Primary() sous.StateManager {
+	return dup.primary
+}
+
+// Secondary returns the secondary StateManager of dup.
+func (dup *DuplexStateManager) Secondary() sous.StateManager {
+	return dup.secondary
+}
+
 // ReadState implements StateManager on DuplexStateManager
 func (dup *DuplexStateManager) ReadState() (*sous.State, error) {
 	user := sous.User{}
@@ -38,13 +48,26 @@ func (dup *DuplexStateManager) ReadState() (*sous.State, error) {
 	return state, err
 }
 
-// WriteState implements StateManager on DuplexStateManager
+// WriteState implements StateManager on DuplexStateManager. The state is
+// only written to the secondary once the primary has accepted it, so that
+// both are given the same state.
 func (dup *DuplexStateManager) WriteState(state *sous.State, user sous.User) error {
 	start := time.Now()
-	if err := dup.secondary.WriteState(state, user); err != nil {
-		logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
-	}
 	err := dup.primary.WriteState(state, user)
+	if err == nil {
+		if err := dup.secondary.WriteState(state, user); err != nil {
+			logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
+		}
+	}
 	reportWriting(dup.log, start, state, err)
 	return err
 }
+
+// ReadStateAt implements sous.StateVersions on DuplexStateManager, by reading
+// from the primary StateManager, if it can.
+func (dup *DuplexStateManager) ReadStateAt(etag string) (*sous.State, error) {
+	if versions, ok := dup.primary.(sous.StateVersions); ok {
+		return versions.ReadStateAt(etag)
+	}
+	return nil, &sous.StaleStateError{Etag: etag}
+}
@@ -1,7 +1,10 @@
 package storage
 
 import (
-	"fmt"
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -58,6 +61,18 @@ func (gsm *GitStateManager) gitOut(cmd ...string) (string, error) {
 	if !gsm.isRepo() {
 		return "", gsmError("not in a git repo")
 	}
+	git := gsm.gitCmd(cmd...)
+	out, err := git.CombinedOutput()
+	if err == nil {
+		messages.ReportLogFieldsMessage("success", logging.DebugLevel, logging.Log, git.Args)
+	} else {
+		messages.ReportLogFieldsMessage("error", logging.DebugLevel, logging.Log, err)
+	}
+	messages.ReportLogFieldsMessage("git", logging.ExtraDebug1Level, logging.Log, string(out))
+	return string(out), errors.Wrapf(err, strings.Join(git.Args, " ")+": "+string(out))
+}
+
+func (gsm *GitStateManager) gitCmd(cmd ...string) *exec.Cmd {
 	git := exec.Command(`git`, cmd...)
 	git.Dir = gsm.DiskStateManager.BaseDir
 
@@ -72,14 +87,7 @@ func (gsm *GitStateManager) gitOut(cmd ...string) (string, error) {
 	if gitssh != "" {
 		git.Env = append(git.Env, "GIT_SSH="+gitssh)
 	}
-	out, err := git.CombinedOutput()
-	if err == nil {
-		messages.ReportLogFieldsMessage("success", logging.DebugLevel, logging.Log, git.Args)
-	} else {
-		messages.ReportLogFieldsMessage("error", logging.DebugLevel, logging.Log, err)
-	}
-	messages.ReportLogFieldsMessage("git", logging.ExtraDebug1Level, logging.Log, string(out))
-	return string(out), errors.Wrapf(err, strings.Join(git.Args, " ")+": "+string(out))
+	return git
 }
 
 func (gsm *GitStateManager) reset(tn string) {
@@ -132,55 +140,71 @@ func (gsm *GitStateManager) needCommit() bool {
 	return false
 }
 
-func (gsm *GitStateManager) assertOneChange() error {
-	diffIndex, err := gsm.gitOut("diff-index", "--cached", "master@{upstream}")
+// stateAt reads the state as it was at rev.
+func (gsm *GitStateManager) stateAt(rev string) (*sous.State, error) {
+	out, err := gsm.gitCmd("archive", "--format=tar", rev).Output()
 	if err != nil {
-		return err
+		return nil, errors.Wrapf(err, "reading state at %s", rev)
 	}
-
-	firstNL := strings.IndexByte(diffIndex, '\n')
-	if firstNL == -1 {
-		// empty diff-index?
-		return nil
+	dir, err := ioutil.TempDir("", "sous-state")
+	if err != nil {
+		return nil, err
 	}
+	defer os.RemoveAll(dir)
 
-	secondNL := strings.IndexByte(diffIndex[firstNL+1:], '\n')
-	if secondNL != -1 {
-		return errors.Errorf("git update touches more than one file: %q", diffIndex)
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading state at %s", rev)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading state at %s", rev)
+		}
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			return nil, err
+		}
 	}
-
-	return nil
+	return NewDiskStateManager(dir).ReadState()
 }
 
-// WriteState writes sous state to disk, then attempts to push it to Remote.
-// If the push fails, the state is reset and an error is returned.
-func (gsm *GitStateManager) WriteState(s *sous.State, u sous.User) error {
+// ReadStateAt implements sous.StateVersions on GitStateManager, by reading
+// the state committed at the revision etag.
+func (gsm *GitStateManager) ReadStateAt(etag string) (*sous.State, error) {
 	gsm.Lock()
 	defer gsm.Unlock()
-
-	etag, err := gsm.headRev()
+	state, err := gsm.stateAt(etag)
 	if err != nil {
-		return err
-	}
-	if err := s.CheckEtag(etag); err != nil {
-		return err
-	}
-
-	tn := "sous-fallback-" + uuid.New()
-	if err := gsm.git("tag", tn); err != nil {
-		return err
+		messages.ReportLogFieldsMessage("Unable to read state at revision", logging.DebugLevel, logging.Log, etag, err)
+		return nil, &sous.StaleStateError{Etag: etag}
 	}
-	defer gsm.git("tag", "-d", tn)
+	state.SetEtag(etag)
+	return state, nil
+}
 
+// commit writes s to disk, and commits it on top of the fallback tag tn,
+// returning false if there was nothing to commit.
+func (gsm *GitStateManager) commit(s *sous.State, u sous.User, tn string) (bool, error) {
 	if err := gsm.DiskStateManager.WriteState(s, u); err != nil {
-		return err
+		return false, err
 	}
 	if err := gsm.git(`add`, `.`); err != nil {
 		gsm.reset(tn)
-		return err
+		return false, err
 	}
 	if !gsm.needCommit() {
-		return nil
+		return false, nil
 	}
 
 	// Commit the changes.
@@ -191,49 +215,58 @@ func (gsm *GitStateManager) WriteState(s *sous.State, u sous.User) error {
 	}
 	if err := gsm.git(commitCommand...); err != nil {
 		gsm.reset(tn)
-		return err
+		return false, err
 	}
+	return true, nil
+}
+
+// WriteState writes sous state to disk, then attempts to push it to Remote.
+//
+// If s was read at an earlier revision than HEAD, or the push fails because
+// the remote has moved on, nothing is written, and a *sous.StaleStateError
+// is returned, so that a sous.MergingStateManager can merge the changes made
+// to s with the ones made in the meantime, and try again.
+func (gsm *GitStateManager) WriteState(s *sous.State, u sous.User) error {
+	gsm.Lock()
+	defer gsm.Unlock()
 
-	// Tag this commit.
-	newTag := "sous-new-" + uuid.New()
-	if err := gsm.git("tag", newTag); err != nil {
+	head, err := gsm.headRev()
+	if err != nil {
 		return err
 	}
-	defer gsm.git("tag", "-d", newTag)
+	if err := s.CheckEtag(head); err != nil {
+		etag, _ := s.GetEtag()
+		return &sous.StaleStateError{Etag: etag, Current: head}
+	}
 
-	// If push fails:
-	//   - Reset to HEAD^
-	//   - Git pull (if this fails, give up)
-	//   - Cherry-pick sous-new-{UUID}"
-	//   - Try again.
+	tn := "sous-fallback-" + uuid.New()
+	if err := gsm.git("tag", tn); err != nil {
+		return err
+	}
+	defer gsm.git("tag", "-d", tn)
 
-	const gitRectifyAttempts = 5
-	for remainingAttempts := gitRectifyAttempts; remainingAttempts > 0; remainingAttempts-- {
-		err := gsm.assertOneChange()
-		if err != nil {
-			gsm.reset(tn)
-			return err
-		}
+	committed, err := gsm.commit(s, u, tn)
+	if err != nil || !committed {
+		return err
+	}
+	pushErr := gsm.git("push", "-u", "origin", "master")
+	if pushErr == nil {
+		return nil
+	}
 
-		err = gsm.git("push", "-u", "origin", "master")
-		if err == nil {
-			// Success.
-			return nil
-		}
-		messages.ReportLogFieldsMessage("git push failed; trying again with # attempts left", logging.DebugLevel, logging.Log, remainingAttempts, err)
-		gsm.reset(tn)
-		if err := gsm.git("pull"); err != nil {
-			return err
-		}
-		if err := gsm.git("cherry-pick", newTag); err != nil {
-			// If cherry-pick fails, then there's a real conflict.
-			messages.ReportLogFieldsMessage("attempt to rectify conflicts with git cherry-pick failed", logging.WarningLevel, logging.Log, err)
-			if err := gsm.git("cherry-pick", "--abort"); err != nil {
-				messages.ReportLogFieldsMessage("cherry-pick --abort failed", logging.WarningLevel, logging.Log, err)
-				return err
-			}
-			messages.ReportLogFieldsMessage("Successfully cherry-picked new changes, re-attempting push.", logging.DebugLevel, logging.Log)
-		}
+	// If the push failed because the remote has moved on, pulling moves
+	// HEAD, and s is now stale.
+	gsm.reset(tn)
+	if err := gsm.git("pull"); err != nil {
+		return err
+	}
+	current, err := gsm.headRev()
+	if err != nil {
+		return err
+	}
+	if current == head {
+		return pushErr
 	}
-	return fmt.Errorf("unable to merge changes")
+	messages.ReportLogFieldsMessage("git push failed, and the remote has moved on", logging.DebugLevel, logging.Log, head, current, pushErr)
+	return &sous.StaleStateError{Etag: head, Current: current}
 }
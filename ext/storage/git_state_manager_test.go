@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/yaml"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -65,14 +66,17 @@ func TestGitStateManager_WriteState_multiple_manifests(t *testing.T) {
 
 	s.Manifests.Set(m.ID(), m)
 
-	// 4. Attempt to write new manifest, expect error.
-	actualErr := gsm.WriteState(s, testUser)
-	if actualErr == nil {
-		t.Fatal("erroneously allowed writing state with modifications in multiple files")
-	}
-	expectedSubstr := "git update touches more than one file"
-	if !strings.Contains(actualErr.Error(), expectedSubstr) {
-		t.Fatalf("got error %q; want error containing %q", actualErr, expectedSubstr)
+	require.NoError(t, gsm.WriteState(s, testUser))
+
+	actual, err := gsm.ReadState()
+	require.NoError(t, err)
+	for repo, cluster := range map[string]string{
+		"github.com/opentable/sous": "cluster-1",
+		"github.com/user/project":   "other-cluster",
+	} {
+		m, ok := actual.Manifests.Any(func(m *sous.Manifest) bool { return m.Source.Repo == repo })
+		require.True(t, ok, "no manifest for %s", repo)
+		assert.Equal(t, "YOLO", m.Deployments[cluster].Env["NEWVAR"], "change to %s not written", repo)
 	}
 }
 
@@ -174,11 +178,69 @@ func TestStateEtags(t *testing.T) {
 		t.Errorf("Got unexpect error when writing state: %v", err)
 	}
 
+	// s is now stale, and is refused, but its changes since it was read can
+	// be merged.
 	s.Manifests.Add(&sous.Manifest{Source: sous.SourceLocation{Repo: "github.com/opentable/supernewextahotness"}})
-	if err := gsm.WriteState(s, testUser); err == nil {
-		t.Errorf("Got no error when re-writing state: %v (have to re-Read first)", err)
+	if _, stale := gsm.WriteState(s, testUser).(*sous.StaleStateError); !stale {
+		t.Errorf("Got no StaleStateError when re-writing stale state")
+	}
+	msm := sous.NewMergingStateManager(gsm, gsm, logging.SilentLogSet())
+	if err := msm.WriteState(s, testUser); err != nil {
+		t.Errorf("Got unexpected error when merging stale state: %v", err)
+	}
+
+	s.SetEtag("cannot match this")
+	if _, stale := errors.Cause(msm.WriteState(s, testUser)).(*sous.StaleStateError); !stale {
+		t.Errorf("Got no StaleStateError when writing state with an unknown etag")
+	}
+}
+
+func TestGitStateManager_WriteState_merges(t *testing.T) {
+	git, _ := setupManagers(t)
+	gsm := sous.NewMergingStateManager(git, git, logging.SilentLogSet())
+
+	ours, err := gsm.ReadState()
+	require.NoError(t, err)
+	theirs, err := gsm.ReadState()
+	require.NoError(t, err)
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}}
+
+	spec := func(s *sous.State, cluster string) (*sous.Manifest, sous.DeploySpec) {
+		m, ok := s.Manifests.Get(mid)
+		require.True(t, ok)
+		return m, m.Deployments[cluster]
 	}
 
+	m, d := spec(theirs, "cluster-1")
+	d.NumInstances = 7
+	m.Deployments["cluster-1"] = d
+	require.NoError(t, gsm.WriteState(theirs, testUser))
+
+	// A change to another cluster of the same manifest merges.
+	m, d = spec(ours, "other-cluster")
+	d.NumInstances = 9
+	m.Deployments["other-cluster"] = d
+	require.NoError(t, gsm.WriteState(ours, testUser))
+
+	actual, err := gsm.ReadState()
+	require.NoError(t, err)
+	_, d = spec(actual, "cluster-1")
+	assert.Equal(t, 7, d.NumInstances)
+	_, d = spec(actual, "other-cluster")
+	assert.Equal(t, 9, d.NumInstances)
+
+	// A different change to the same field conflicts.
+	m, d = spec(ours, "cluster-1")
+	d.NumInstances = 8
+	m.Deployments["cluster-1"] = d
+	err = gsm.WriteState(ours, testUser)
+	require.Error(t, err)
+	conflicts, ok := errors.Cause(err).(sous.MergeConflicts)
+	require.True(t, ok, "got %T: %v, want sous.MergeConflicts", err, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "Manifests[github.com/opentable/sous].Deployments[cluster-1].NumInstances", conflicts[0].Path)
+	assert.Equal(t, 7, conflicts[0].Theirs)
+	assert.Equal(t, 8, conflicts[0].Ours)
 }
 
 func TestGitPulls(t *testing.T) {
@@ -242,7 +304,9 @@ func TestGitConflicts(t *testing.T) {
 
 	actual.Manifests.Add(&sous.Manifest{Source: sous.SourceLocation{Repo: "github.com/opentable/newhotness"}})
 
-	actualErr := gsm.WriteState(actual, testUser)
+	// The push loses the race with the commit to origin, so the write is
+	// stale, and is merged with the commit pulled.
+	actualErr := sous.NewMergingStateManager(gsm, gsm, logging.SilentLogSet()).WriteState(actual, testUser)
 	assert.NoError(actualErr)
 
 	actual, err = gsm.ReadState()
@@ -289,7 +353,7 @@ func TestGitRemoteDelete(t *testing.T) {
 	require.NoError(err)
 
 	actual.Manifests.Add(&sous.Manifest{Source: sous.SourceLocation{Repo: "github.com/opentable/newhotness"}})
-	actualErr := gsm.WriteState(actual, testUser)
+	actualErr := sous.NewMergingStateManager(gsm, gsm, logging.SilentLogSet()).WriteState(actual, testUser)
 	assert.NoError(actualErr)
 
 	expected, err = remote.ReadState()
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// An ObjectStore stores objects by key, with conditional writes against
+	// their etags, like S3 and the stores compatible with it.
+	ObjectStore interface {
+		// GetObject returns the contents and etag of the object at key, or
+		// ErrObjectNotFound.
+		GetObject(key string) (data []byte, etag string, err error)
+		// PutObject stores data at key, and returns its new etag. If etag is
+		// empty, there must be no object at key yet, otherwise the object
+		// must still have that etag, or ErrPreconditionFailed is returned.
+		PutObject(key string, data []byte, etag string) (string, error)
+		// DeleteObject deletes the object at key, which must still have etag,
+		// or ErrPreconditionFailed is returned. Deleting a missing object is
+		// not an error.
+		DeleteObject(key string, etag string) error
+		// ListObjects describes every object whose key begins with prefix.
+		ListObjects(prefix string) ([]ObjectInfo, error)
+	}
+
+	// ObjectInfo describes an object in an ObjectStore.
+	ObjectInfo struct {
+		Key, ETag string
+	}
+
+	// MemoryObjectStore is an ObjectStore that lives only as long as the
+	// process, for testing.
+	MemoryObjectStore struct {
+		sync.Mutex
+		objects map[string][]byte
+	}
+)
+
+var (
+	// ErrObjectNotFound is returned by an ObjectStore for a missing object.
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrPreconditionFailed is returned by an ObjectStore when a conditional
+	// write loses to another writer.
+	ErrPreconditionFailed = errors.New("object changed since it was read")
+)
+
+// NewMemoryObjectStore returns an empty MemoryObjectStore.
+func NewMemoryObjectStore() *MemoryObjectStore {
+	return &MemoryObjectStore{objects: map[string][]byte{}}
+}
+
+// memoryETag returns the etag of data, which, like S3's for objects uploaded
+// in one part, is its quoted MD5 sum.
+func memoryETag(data []byte) string {
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", md5.Sum(data)))
+}
+
+// GetObject implements ObjectStore on MemoryObjectStore.
+func (ms *MemoryObjectStore) GetObject(key string) ([]byte, string, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	data, has := ms.objects[key]
+	if !has {
+		return nil, "", ErrObjectNotFound
+	}
+	return append([]byte{}, data...), memoryETag(data), nil
+}
+
+// PutObject implements ObjectStore on MemoryObjectStore.
+func (ms *MemoryObjectStore) PutObject(key string, data []byte, etag string) (string, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	if err := ms.check(key, etag); err != nil {
+		return "", err
+	}
+	ms.objects[key] = append([]byte{}, data...)
+	return memoryETag(data), nil
+}
+
+// DeleteObject implements ObjectStore on MemoryObjectStore.
+func (ms *MemoryObjectStore) DeleteObject(key, etag string) error {
+	ms.Lock()
+	defer ms.Unlock()
+	if _, has := ms.objects[key]; !has {
+		return nil
+	}
+	if err := ms.check(key, etag); err != nil {
+		return err
+	}
+	delete(ms.objects, key)
+	return nil
+}
+
+// check returns ErrPreconditionFailed unless the object at key has etag, or
+// etag is empty and there is no object at key.
+func (ms *MemoryObjectStore) check(key, etag string) error {
+	data, has := ms.objects[key]
+	if etag == "" && !has {
+		return nil
+	}
+	if has && etag == memoryETag(data) {
+		return nil
+	}
+	return ErrPreconditionFailed
+}
+
+// ListObjects implements ObjectStore on MemoryObjectStore.
+func (ms *MemoryObjectStore) ListObjects(prefix string) ([]ObjectInfo, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	infos := []ObjectInfo{}
+	for key, data := range ms.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, ObjectInfo{Key: key, ETag: memoryETag(data)})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
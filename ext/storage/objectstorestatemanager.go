@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// ObjectStoreStateManager implements StateManager by storing the state in
+	// an ObjectStore, with the definitions in one object and each manifest in
+	// another:
+	//
+	//     <prefix>defs.json
+	//     <prefix>manifests/<manifest id>.json
+	//
+	// Each object is only written if it has changed, and only if it has not
+	// changed since the state was read, so concurrent writers cannot lose one
+	// another's changes. A write is not atomic as a whole, though: a reader may
+	// see some of a write's manifests before the others.
+	ObjectStoreStateManager struct {
+		store  ObjectStore
+		prefix string
+		log    logging.LogSink
+
+		sync.Mutex
+		// reads are the latest states read, by etag, kept to merge the
+		// changes made to them with the ones written since.
+		reads     map[string]*objectStoreRead
+		readOrder []string
+	}
+
+	// objectStoreRead is the state read from an ObjectStore, and the objects
+	// it was read from.
+	objectStoreRead struct {
+		state   *sous.State
+		objects map[string]storedObject
+		etag    string
+	}
+
+	storedObject struct {
+		data []byte
+		etag string
+	}
+)
+
+const (
+	// objectStoreEtagPrefix marks the etags of states read from an
+	// ObjectStoreStateManager, to tell them from the etags of states read
+	// elsewhere, which are overwritten rather than merged.
+	objectStoreEtagPrefix = "objects:"
+	// objectStoreReads is the number of reads an ObjectStoreStateManager
+	// keeps to merge with.
+	objectStoreReads = 32
+	// objectStoreWriteAttempts is the number of times a write which loses a
+	// race with another writer is merged and tried again.
+	objectStoreWriteAttempts = 5
+)
+
+// NewObjectStoreStateManager returns an ObjectStoreStateManager storing the
+// state in store, under keys beginning with prefix.
+func NewObjectStoreStateManager(store ObjectStore, prefix string, log logging.LogSink) *ObjectStoreStateManager {
+	return &ObjectStoreStateManager{
+		store:  store,
+		prefix: prefix,
+		log:    log,
+		reads:  map[string]*objectStoreRead{},
+	}
+}
+
+func (m *ObjectStoreStateManager) defsKey() string {
+	return m.prefix + "defs.json"
+}
+
+func (m *ObjectStoreStateManager) manifestKey(mid sous.ManifestID) string {
+	return m.prefix + "manifests/" + mid.String() + ".json"
+}
+
+// objectsEtag returns the etag of a state stored in objects, which changes
+// whenever any of them does.
+func objectsEtag(objects map[string]string) string {
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s %s\n", k, objects[k])
+	}
+	return objectStoreEtagPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadState implements StateManager on ObjectStoreStateManager.
+func (m *ObjectStoreStateManager) ReadState() (*sous.State, error) {
+	start := time.Now()
+	read, err := m.read()
+	if err != nil {
+		reportReading(m.log, start, nil, err)
+		return nil, err
+	}
+	state := read.state.Clone()
+	state.SetEtag(read.etag)
+	reportReading(m.log, start, state, nil)
+	return state, nil
+}
+
+// read reads the state from the store, and keeps it to merge with later.
+func (m *ObjectStoreStateManager) read() (*objectStoreRead, error) {
+	infos, err := m.store.ListObjects(m.prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing state objects")
+	}
+	read := &objectStoreRead{state: sous.NewState(), objects: map[string]storedObject{}}
+	etags := map[string]string{}
+	for _, info := range infos {
+		isDefs := info.Key == m.defsKey()
+		if !isDefs && !strings.HasPrefix(info.Key, m.prefix+"manifests/") {
+			continue
+		}
+		data, etag, err := m.store.GetObject(info.Key)
+		if err == ErrObjectNotFound {
+			// Deleted since it was listed.
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", info.Key)
+		}
+		read.objects[info.Key] = storedObject{data: data, etag: etag}
+		etags[info.Key] = etag
+
+		if isDefs {
+			if err := json.Unmarshal(data, &read.state.Defs); err != nil {
+				return nil, errors.Wrapf(err, "decoding %s", info.Key)
+			}
+			continue
+		}
+		manifest := &sous.Manifest{}
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", info.Key)
+		}
+		read.state.Manifests.Add(manifest)
+	}
+	read.etag = objectsEtag(etags)
+	m.remember(read)
+	return read, nil
+}
+
+func (m *ObjectStoreStateManager) remember(read *objectStoreRead) {
+	m.Lock()
+	defer m.Unlock()
+	if _, has := m.reads[read.etag]; has {
+		return
+	}
+	m.reads[read.etag] = read
+	m.readOrder = append(m.readOrder, read.etag)
+	if len(m.readOrder) > objectStoreReads {
+		delete(m.reads, m.readOrder[0])
+		m.readOrder = m.readOrder[1:]
+	}
+}
+
+func (m *ObjectStoreStateManager) remembered(etag string) (*objectStoreRead, bool) {
+	m.Lock()
+	defer m.Unlock()
+	read, has := m.reads[etag]
+	return read, has
+}
+
+// WriteState implements StateManager on ObjectStoreStateManager.
+//
+// If s was read from this ObjectStoreStateManager, and the state has changed
+// since, or another writer changes an object while s is being written, a
+// *sous.StaleStateError is returned, so that a sous.MergingStateManager can
+// merge the changes made to s with the ones made in the meantime, and try
+// again. A state read from anywhere else is written as it is.
+func (m *ObjectStoreStateManager) WriteState(s *sous.State, u sous.User) error {
+	start := time.Now()
+	// Cloned, as states read are, so that the objects written are the same
+	// as the ones read.
+	err := m.writeState(s.Clone())
+	reportWriting(m.log, start, s, err)
+	return err
+}
+
+func (m *ObjectStoreStateManager) writeState(s *sous.State) error {
+	etag, _ := s.GetEtag()
+	ours := strings.HasPrefix(etag, objectStoreEtagPrefix)
+	for remainingAttempts := objectStoreWriteAttempts; remainingAttempts > 0; remainingAttempts-- {
+		current, err := m.read()
+		if err != nil {
+			return err
+		}
+		if ours && etag != current.etag {
+			return &sous.StaleStateError{Etag: etag, Current: current.etag}
+		}
+
+		err = m.write(s, current)
+		if errors.Cause(err) != ErrPreconditionFailed {
+			return err
+		}
+		if ours {
+			return &sous.StaleStateError{Etag: etag}
+		}
+		messages.ReportLogFieldsMessage("object changed while writing state; trying again with # attempts left", logging.DebugLevel, m.log, remainingAttempts, err)
+	}
+	return errors.New("objects kept changing while writing state")
+}
+
+// ReadStateAt implements sous.StateVersions on ObjectStoreStateManager, for
+// the recent states it has read.
+func (m *ObjectStoreStateManager) ReadStateAt(etag string) (*sous.State, error) {
+	read, has := m.remembered(etag)
+	if !has {
+		return nil, &sous.StaleStateError{Etag: etag}
+	}
+	state := read.state.Clone()
+	state.SetEtag(etag)
+	return state, nil
+}
+
+// write writes the objects of s which differ from current, and deletes the
+// ones s has no manifest for.
+func (m *ObjectStoreStateManager) write(s *sous.State, current *objectStoreRead) error {
+	objects := map[string]interface{}{m.defsKey(): s.Defs}
+	for mid, manifest := range s.Manifests.Snapshot() {
+		objects[m.manifestKey(mid)] = manifest
+	}
+
+	for key, object := range objects {
+		data, err := json.Marshal(object)
+		if err != nil {
+			return errors.Wrapf(err, "encoding %s", key)
+		}
+		prior, has := current.objects[key]
+		if has && bytes.Equal(data, prior.data) {
+			continue
+		}
+		if _, err := m.store.PutObject(key, data, prior.etag); err != nil {
+			return errors.Wrapf(err, "writing %s", key)
+		}
+	}
+	for key, prior := range current.objects {
+		if _, keep := objects[key]; keep {
+			continue
+		}
+		if err := m.store.DeleteObject(key, prior.etag); err != nil {
+			return errors.Wrapf(err, "deleting %s", key)
+		}
+	}
+	return nil
+}
+
+// Watch implements sous.StateWatcher on ObjectStoreStateManager, by polling
+// the etags of the objects in the state.
+func (m *ObjectStoreStateManager) Watch(done <-chan struct{}) (<-chan sous.StateChange, error) {
+	return sous.PollState(done, StatePollInterval, m.revision, m.log), nil
+}
+
+func (m *ObjectStoreStateManager) revision() (string, error) {
+	infos, err := m.store.ListObjects(m.prefix)
+	if err != nil {
+		return "", errors.Wrapf(err, "listing state objects")
+	}
+	etags := map[string]string{}
+	for _, info := range infos {
+		etags[info.Key] = info.ETag
+	}
+	return objectsEtag(etags), nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exampleMID = sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}}
+
+func setSpec(t *testing.T, s *sous.State, cluster string, fn func(*sous.DeploySpec)) {
+	t.Helper()
+	m, ok := s.Manifests.Get(exampleMID)
+	require.True(t, ok)
+	spec := m.Deployments[cluster]
+	fn(&spec)
+	m.Deployments[cluster] = spec
+}
+
+func TestObjectStoreStateManager_ReadWrite(t *testing.T) {
+	store := NewMemoryObjectStore()
+	osm := NewObjectStoreStateManager(store, "gdm/", logging.SilentLogSet())
+
+	empty, err := osm.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.Manifests.Len())
+
+	require.NoError(t, osm.WriteState(exampleState(), testUser))
+	objects, err := store.ListObjects("gdm/")
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	assert.Equal(t, "gdm/defs.json", objects[0].Key)
+	assert.Equal(t, "gdm/manifests/"+exampleMID.String()+".json", objects[1].Key)
+
+	s, err := osm.ReadState()
+	require.NoError(t, err)
+	assertStatesEqual(t, exampleState(), s)
+	assert.Empty(t, sous.DiffStates(exampleState(), s))
+	etag, err := s.GetEtag()
+	require.NoError(t, err)
+	rev, err := osm.revision()
+	require.NoError(t, err)
+	assert.Equal(t, etag, rev)
+
+	// Only the changed manifest is written.
+	setSpec(t, s, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	defsBefore, defsEtag, err := store.GetObject("gdm/defs.json")
+	require.NoError(t, err)
+	require.NoError(t, osm.WriteState(s, testUser))
+	_, defsAfter, err := store.GetObject("gdm/defs.json")
+	require.NoError(t, err)
+	assert.Equal(t, defsEtag, defsAfter, "defs.json was rewritten: %s", defsBefore)
+
+	s, err = osm.ReadState()
+	require.NoError(t, err)
+	m, _ := s.Manifests.Get(exampleMID)
+	assert.Equal(t, 9, m.Deployments["cluster-1"].NumInstances)
+
+	s.Manifests.Remove(exampleMID)
+	require.NoError(t, osm.WriteState(s, testUser))
+	_, _, err = store.GetObject("gdm/manifests/" + exampleMID.String() + ".json")
+	assert.Equal(t, ErrObjectNotFound, err)
+}
+
+func TestObjectStoreStateManager_Merge(t *testing.T) {
+	store := NewObjectStoreStateManager(NewMemoryObjectStore(), "", logging.SilentLogSet())
+	osm := sous.NewMergingStateManager(store, store, logging.SilentLogSet())
+	require.NoError(t, osm.WriteState(exampleState(), testUser))
+
+	first, err := osm.ReadState()
+	require.NoError(t, err)
+	second, err := osm.ReadState()
+	require.NoError(t, err)
+
+	setSpec(t, first, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	setSpec(t, second, "other-cluster", func(spec *sous.DeploySpec) { spec.NumInstances = 7 })
+	require.NoError(t, osm.WriteState(first, testUser))
+	_, stale := store.WriteState(second, testUser).(*sous.StaleStateError)
+	assert.True(t, stale, "a stale state should be refused without merging")
+	require.NoError(t, osm.WriteState(second, testUser))
+
+	s, err := osm.ReadState()
+	require.NoError(t, err)
+	m, _ := s.Manifests.Get(exampleMID)
+	assert.Equal(t, 9, m.Deployments["cluster-1"].NumInstances)
+	assert.Equal(t, 7, m.Deployments["other-cluster"].NumInstances)
+
+	third, err := osm.ReadState()
+	require.NoError(t, err)
+	setSpec(t, s, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 1 })
+	setSpec(t, third, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 2 })
+	require.NoError(t, osm.WriteState(s, testUser))
+	err = osm.WriteState(third, testUser)
+	require.Error(t, err)
+	assert.IsType(t, sous.MergeConflicts{}, errors.Cause(err))
+}
+
+// racingObjectStore writes a change to the state before the first PutObject
+// made through it.
+type racingObjectStore struct {
+	ObjectStore
+	race func()
+}
+
+func (r *racingObjectStore) PutObject(key string, data []byte, etag string) (string, error) {
+	if r.race != nil {
+		race := r.race
+		r.race = nil
+		race()
+	}
+	return r.ObjectStore.PutObject(key, data, etag)
+}
+
+func TestObjectStoreStateManager_WriteRace(t *testing.T) {
+	store := &racingObjectStore{ObjectStore: NewMemoryObjectStore()}
+	objects := NewObjectStoreStateManager(store, "", logging.SilentLogSet())
+	osm := sous.NewMergingStateManager(objects, objects, logging.SilentLogSet())
+	require.NoError(t, osm.WriteState(exampleState(), testUser))
+
+	other := NewObjectStoreStateManager(store.ObjectStore, "", logging.SilentLogSet())
+	store.race = func() {
+		s, err := other.ReadState()
+		require.NoError(t, err)
+		setSpec(t, s, "other-cluster", func(spec *sous.DeploySpec) { spec.NumInstances = 7 })
+		require.NoError(t, other.WriteState(s, testUser))
+	}
+
+	s, err := osm.ReadState()
+	require.NoError(t, err)
+	setSpec(t, s, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	require.NoError(t, osm.WriteState(s, testUser))
+
+	s, err = osm.ReadState()
+	require.NoError(t, err)
+	m, _ := s.Manifests.Get(exampleMID)
+	assert.Equal(t, 9, m.Deployments["cluster-1"].NumInstances)
+	assert.Equal(t, 7, m.Deployments["other-cluster"].NumInstances)
+}
+
+func TestObjectStoreStateManager_Secondary(t *testing.T) {
+	primary := sous.NewDummyStateManager()
+	primary.State = exampleState()
+	primary.State.SetEtag("some-git-revision")
+	osm := NewObjectStoreStateManager(NewMemoryObjectStore(), "", logging.SilentLogSet())
+	dup := NewDuplexStateManager(primary, osm, logging.SilentLogSet())
+
+	s, err := dup.ReadState()
+	require.NoError(t, err)
+	stored, err := osm.ReadState()
+	require.NoError(t, err)
+	assertStatesEqual(t, s, stored)
+
+	setSpec(t, s, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	require.NoError(t, dup.WriteState(s, testUser))
+	stored, err = osm.ReadState()
+	require.NoError(t, err)
+	m, _ := stored.Manifests.Get(exampleMID)
+	assert.Equal(t, 9, m.Deployments["cluster-1"].NumInstances)
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// The PostgresHistory provides the sous.History interface by reading/writing
+// the gdm_history and gdm_history_outcomes tables of a postgres database.
+type PostgresHistory struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresHistory creates a new PostgresHistory.
+func NewPostgresHistory(db *sql.DB, log logging.LogSink) *PostgresHistory {
+	return &PostgresHistory{db: db, log: log}
+}
+
+const (
+	insertHistorySQL = `insert into gdm_history
+	(history_id, recorded_at, user_name, user_email, repo, dir, flavor, kind, diffs, clusters)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	insertHistoryOutcomeSQL = `insert into gdm_history_outcomes
+	(history_id, cluster, recorded_at, resolution)
+	select history_id, $4, clock_timestamp(), $5 from gdm_history
+	where repo = $1 and dir = $2 and flavor = $3 and clusters::jsonb ? $4
+	order by recorded_at desc, history_id desc limit 1
+	on conflict (history_id, cluster) do nothing`
+
+	selectHistorySQL = `select history_id, recorded_at, user_name, user_email, repo, dir, flavor, kind, diffs, clusters
+	from gdm_history`
+
+	selectHistoryOutcomesSQL = `select history_id, cluster, recorded_at, resolution
+	from gdm_history_outcomes where history_id in (select history_id from (%s) matched)`
+)
+
+// RecordChanges implements sous.History on PostgresHistory.
+func (h *PostgresHistory) RecordChanges(entries []sous.HistoryEntry) error {
+	ctx := context.TODO()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		diffs, err := json.Marshal(e.Diffs)
+		if err != nil {
+			return errors.Wrapf(err, "encoding diffs for %s", e.ManifestID)
+		}
+		clusters, err := json.Marshal(e.Clusters)
+		if err != nil {
+			return errors.Wrapf(err, "encoding clusters for %s", e.ManifestID)
+		}
+		mid := e.ManifestID
+		if err := h.exec(ctx, tx, "gdm_history", insertHistorySQL,
+			e.ID, e.Time, e.User.Name, e.User.Email, mid.Source.Repo, mid.Source.Dir, mid.Flavor,
+			string(e.Kind), string(diffs), string(clusters)); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(tx.Commit(), "committing transaction")
+}
+
+// RecordOutcome implements sous.History on PostgresHistory.
+func (h *PostgresHistory) RecordOutcome(did sous.DeploymentID, dr sous.DiffResolution) error {
+	rez, err := json.Marshal(dr)
+	if err != nil {
+		return errors.Wrapf(err, "encoding resolution for %s", did)
+	}
+
+	ctx := context.TODO()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	mid := did.ManifestID
+	if err := h.exec(ctx, tx, "gdm_history_outcomes", insertHistoryOutcomeSQL,
+		mid.Source.Repo, mid.Source.Dir, mid.Flavor, did.Cluster, string(rez)); err != nil {
+		return err
+	}
+	return errors.Wrapf(tx.Commit(), "committing transaction")
+}
+
+func (h *PostgresHistory) exec(ctx context.Context, tx *sql.Tx, table, sql string, args ...interface{}) error {
+	start := time.Now()
+	res, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		reportSQLMessage(h.log, start, table, write, sql, 0, err)
+		return errors.Wrapf(err, "sql %q", sql)
+	}
+	n, _ := res.RowsAffected()
+	reportSQLMessage(h.log, start, table, write, sql, int(n), nil)
+	return nil
+}
+
+// historyQuery returns the SQL, and its arguments, selecting at most limit
+// entries matched by filter from gdm_history, newest first.
+func historyQuery(filter *sous.ResolveFilter, limit int) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	arg := func(cond string, val interface{}) {
+		args = append(args, val)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if filter != nil {
+		if !filter.Repo.All() {
+			arg("repo = $%d", *filter.Repo.Match)
+		}
+		if !filter.Offset.All() {
+			arg("dir = $%d", *filter.Offset.Match)
+		}
+		if !filter.Flavor.All() {
+			arg("flavor = $%d", *filter.Flavor.Match)
+		}
+		if !filter.Cluster.All() {
+			arg("clusters::jsonb ? $%d", *filter.Cluster.Match)
+		}
+	}
+	sql := selectHistorySQL
+	if len(where) > 0 {
+		sql += " where " + strings.Join(where, " and ")
+	}
+	sql += " order by recorded_at desc, history_id desc"
+	if limit > 0 {
+		args = append(args, limit)
+		sql += fmt.Sprintf(" limit $%d", len(args))
+	}
+	return sql, args
+}
+
+// Entries implements sous.History on PostgresHistory.
+func (h *PostgresHistory) Entries(filter *sous.ResolveFilter, limit int) ([]sous.HistoryEntry, error) {
+	ctx := context.TODO()
+	tx, err := h.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	query, args := historyQuery(filter, limit)
+
+	entries := []sous.HistoryEntry{}
+	err = loadTable(ctx, h.log, tx, "gdm_history", query, func(rows *sql.Rows) error {
+		var (
+			e               sous.HistoryEntry
+			kind            string
+			diffs, clusters string
+		)
+		if err := rows.Scan(&e.ID, &e.Time, &e.User.Name, &e.User.Email,
+			&e.ManifestID.Source.Repo, &e.ManifestID.Source.Dir, &e.ManifestID.Flavor,
+			&kind, &diffs, &clusters); err != nil {
+			return err
+		}
+		e.Kind = sous.HistoryKind(kind)
+		if err := json.Unmarshal([]byte(diffs), &e.Diffs); err != nil {
+			return errors.Wrapf(err, "decoding diffs for %s", e.ID)
+		}
+		if err := json.Unmarshal([]byte(clusters), &e.Clusters); err != nil {
+			return errors.Wrapf(err, "decoding clusters for %s", e.ID)
+		}
+		entries = append(entries, e)
+		return nil
+	}, args...)
+	if err != nil || len(entries) == 0 {
+		return entries, err
+	}
+
+	outcomes := map[string]map[string]sous.HistoryOutcome{}
+	outcomesSQL := fmt.Sprintf(selectHistoryOutcomesSQL, query)
+	err = loadTable(ctx, h.log, tx, "gdm_history_outcomes", outcomesSQL, func(rows *sql.Rows) error {
+		var (
+			id, cluster, resolution string
+			outcome                 sous.HistoryOutcome
+		)
+		if err := rows.Scan(&id, &cluster, &outcome.Time, &resolution); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(resolution), &outcome.Resolution); err != nil {
+			return errors.Wrapf(err, "decoding resolution for %s", id)
+		}
+		if outcomes[id] == nil {
+			outcomes[id] = map[string]sous.HistoryOutcome{}
+		}
+		outcomes[id][cluster] = outcome
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Outcomes = outcomes[entries[i].ID]
+	}
+	return entries, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresHistory(t *testing.T) {
+	h := NewPostgresHistory(setupDB(t), logging.SilentLogSet())
+
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}
+	user := sous.User{Name: "Test User", Email: "test@example.com"}
+	now := time.Now()
+	require.NoError(t, h.RecordChanges([]sous.HistoryEntry{{
+		ID: "first", Time: now.Add(-time.Minute), User: user, ManifestID: mid,
+		Kind: sous.HistoryAdded, Diffs: []string{"manifest added"}, Clusters: []string{"one"},
+	}}))
+	require.NoError(t, h.RecordChanges([]sous.HistoryEntry{{
+		ID: "second", Time: now, User: user, ManifestID: mid,
+		Kind: sous.HistoryModified, Diffs: []string{"one: version"}, Clusters: []string{"one"},
+	}}))
+
+	did := sous.DeploymentID{ManifestID: mid, Cluster: "one"}
+	require.NoError(t, h.RecordOutcome(did, sous.DiffResolution{Desc: sous.ModifyDiff}))
+	require.NoError(t, h.RecordOutcome(did, sous.DiffResolution{Desc: sous.StableDiff}))
+
+	entries, err := h.Entries(nil, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "second", entries[0].ID)
+	assert.Equal(t, user, entries[0].User)
+	assert.Equal(t, []string{"one: version"}, entries[0].Diffs)
+	assert.Equal(t, sous.ModifyDiff, entries[0].Outcomes["one"].Resolution.Desc)
+	assert.Empty(t, entries[1].Outcomes)
+
+	limited, err := h.Entries(&sous.ResolveFilter{Cluster: sous.NewResolveFieldMatcher("one")}, 1)
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, "second", limited[0].ID)
+	assert.Equal(t, sous.ModifyDiff, limited[0].Outcomes["one"].Resolution.Desc)
+
+	none, err := h.Entries(&sous.ResolveFilter{Cluster: sous.NewResolveFieldMatcher("two")}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	none, err = h.Entries(&sous.ResolveFilter{Flavor: sous.NewResolveFieldMatcher("vanilla")}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestHistoryQuery(t *testing.T) {
+	sql, args := historyQuery(nil, 0)
+	assert.Equal(t, selectHistorySQL+" order by recorded_at desc, history_id desc", sql)
+	assert.Empty(t, args)
+
+	sql, args = historyQuery(&sous.ResolveFilter{
+		Repo:    sous.NewResolveFieldMatcher("github.com/example/project"),
+		Cluster: sous.NewResolveFieldMatcher("one"),
+	}, 10)
+	assert.Equal(t, selectHistorySQL+" where repo = $1 and clusters::jsonb ? $2 order by recorded_at desc, history_id desc limit $3", sql)
+	assert.Equal(t, []interface{}{"github.com/example/project", "one", 10}, args)
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// The PostgresR11nStore provides the sous.R11nStore interface by
+// reading/writing the r11ns table of a postgres database.
+type PostgresR11nStore struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresR11nStore creates a new PostgresR11nStore.
+func NewPostgresR11nStore(db *sql.DB, log logging.LogSink) *PostgresR11nStore {
+	return &PostgresR11nStore{db: db, log: log}
+}
+
+const (
+	upsertR11nSQL = `insert into r11ns
+	(r11n_id, cluster, repo, dir, flavor, state, pair, resolution, queued_at, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, clock_timestamp(), clock_timestamp())
+	on conflict (r11n_id) do update set
+	state = excluded.state, resolution = excluded.resolution, updated_at = excluded.updated_at`
+
+	trimR11nsSQL = `delete from r11ns
+	where cluster = $1 and repo = $2 and dir = $3 and flavor = $4 and state = 'done'
+	and r11n_id not in (
+		select r11n_id from r11ns
+		where cluster = $1 and repo = $2 and dir = $3 and flavor = $4 and state = 'done'
+		order by updated_at desc limit $5
+	)`
+
+	selectR11nsSQL = `select r11n_id, cluster, repo, dir, flavor, state, pair, resolution
+	from r11ns order by queued_at, r11n_id`
+)
+
+// StoreR11n implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) StoreR11n(qr *sous.QueuedR11n, state sous.R11nState) error {
+	sr := sous.NewStoredR11n(qr, state)
+	did := sr.Pair.ID()
+	pair, err := json.Marshal(sr.Pair)
+	if err != nil {
+		return errors.Wrapf(err, "encoding pair for %s", sr.ID)
+	}
+	var resolution *string
+	if state == sous.R11nDone {
+		rez, err := json.Marshal(sr.Resolution)
+		if err != nil {
+			return errors.Wrapf(err, "encoding resolution for %s", sr.ID)
+		}
+		r := string(rez)
+		resolution = &r
+	}
+
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	repo, dir, flavor := did.ManifestID.Source.Repo, did.ManifestID.Source.Dir, did.ManifestID.Flavor
+	if err := s.exec(ctx, tx, upsertR11nSQL,
+		string(sr.ID), did.Cluster, repo, dir, flavor, string(state), string(pair), resolution); err != nil {
+		return err
+	}
+	if state == sous.R11nDone {
+		if err := s.exec(ctx, tx, trimR11nsSQL,
+			did.Cluster, repo, dir, flavor, sous.MaxRefsPerR11nQueue); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(tx.Commit(), "committing transaction")
+}
+
+func (s *PostgresR11nStore) exec(ctx context.Context, tx *sql.Tx, sql string, args ...interface{}) error {
+	start := time.Now()
+	res, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		reportSQLMessage(s.log, start, "r11ns", write, sql, 0, err)
+		return errors.Wrapf(err, "sql %q", sql)
+	}
+	n, _ := res.RowsAffected()
+	reportSQLMessage(s.log, start, "r11ns", write, sql, int(n), nil)
+	return nil
+}
+
+// LoadR11ns implements sous.R11nStore on PostgresR11nStore.
+func (s *PostgresR11nStore) LoadR11ns() ([]sous.StoredR11n, error) {
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	var stored []sous.StoredR11n
+	err = loadTable(ctx, s.log, tx, "r11ns", selectR11nsSQL, func(rows *sql.Rows) error {
+		var (
+			id, state, pair string
+			resolution      sql.NullString
+			did             sous.DeploymentID
+		)
+		if err := rows.Scan(&id, &did.Cluster, &did.ManifestID.Source.Repo, &did.ManifestID.Source.Dir,
+			&did.ManifestID.Flavor, &state, &pair, &resolution); err != nil {
+			return err
+		}
+		sr := sous.StoredR11n{ID: sous.R11nID(id), State: sous.R11nState(state)}
+		if err := json.Unmarshal([]byte(pair), &sr.Pair); err != nil {
+			return errors.Wrapf(err, "decoding pair for %s", id)
+		}
+		sr.Pair.SetID(did)
+		if resolution.Valid {
+			if err := json.Unmarshal([]byte(resolution.String), &sr.Resolution); err != nil {
+				return errors.Wrapf(err, "decoding resolution for %s", id)
+			}
+		}
+		stored = append(stored, sr)
+		return nil
+	})
+	return stored, err
+}
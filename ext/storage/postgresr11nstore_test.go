@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueuedR11n(repo string) *sous.QueuedR11n {
+	d := &sous.Deployment{
+		SourceID:    sous.MustNewSourceID(repo, "", "1.0.0"),
+		ClusterName: "cluster",
+	}
+	r := sous.NewRectification(sous.DeployablePair{Post: &sous.Deployable{Deployment: d}})
+	r.Pair.SetID(d.ID())
+	return &sous.QueuedR11n{ID: sous.NewR11nID(), Rectification: r}
+}
+
+func TestPostgresR11nStore(t *testing.T) {
+	store := NewPostgresR11nStore(setupDB(t), logging.SilentLogSet())
+
+	done := testQueuedR11n("github.com/example/done")
+	require.NoError(t, store.StoreR11n(done, sous.R11nQueued))
+	done.Rectification.Resolution = sous.DiffResolution{Desc: sous.CreateDiff}
+	require.NoError(t, store.StoreR11n(done, sous.R11nDone))
+
+	queued := testQueuedR11n("github.com/example/queued")
+	require.NoError(t, store.StoreR11n(queued, sous.R11nQueued))
+
+	stored, err := store.LoadR11ns()
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+
+	assert.Equal(t, done.ID, stored[0].ID)
+	assert.Equal(t, sous.R11nDone, stored[0].State)
+	assert.Equal(t, sous.CreateDiff, stored[0].Resolution.Desc)
+	assert.Equal(t, done.Rectification.Pair.ID(), stored[0].Pair.ID())
+
+	assert.Equal(t, queued.ID, stored[1].ID)
+	assert.Equal(t, sous.R11nQueued, stored[1].State)
+	assert.True(t, stored[1].Pair.Post.SourceID.Equal(queued.Rectification.Pair.Post.SourceID))
+}
+
+func TestPostgresR11nStore_Trim(t *testing.T) {
+	store := NewPostgresR11nStore(setupDB(t), logging.SilentLogSet())
+	for i := 0; i < sous.MaxRefsPerR11nQueue+3; i++ {
+		require.NoError(t, store.StoreR11n(testQueuedR11n("github.com/example/busy"), sous.R11nDone))
+	}
+	stored, err := store.LoadR11ns()
+	require.NoError(t, err)
+	assert.Len(t, stored, sous.MaxRefsPerR11nQueue)
+}
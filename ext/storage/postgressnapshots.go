@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// The PostgresSnapshotStore provides the sous.SnapshotStore interface by
+// reading/writing the gdm_snapshots table of a postgres database.
+type PostgresSnapshotStore struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// snapshotState is how the state in a snapshot is stored.
+type snapshotState struct {
+	Defs      sous.Defs
+	Manifests []*sous.Manifest
+}
+
+// NewPostgresSnapshotStore creates a new PostgresSnapshotStore.
+func NewPostgresSnapshotStore(db *sql.DB, log logging.LogSink) *PostgresSnapshotStore {
+	return &PostgresSnapshotStore{db: db, log: log}
+}
+
+const (
+	insertSnapshotSQL = `insert into gdm_snapshots
+	(snapshot_id, taken_at, user_name, user_email, reason, state)
+	values ($1, $2, $3, $4, $5, $6)`
+
+	selectSnapshotInfosSQL = `select snapshot_id, taken_at, user_name, user_email, reason
+	from gdm_snapshots order by taken_at desc, snapshot_id desc`
+
+	selectSnapshotSQL = `select snapshot_id, taken_at, user_name, user_email, reason, state
+	from gdm_snapshots where snapshot_id = $1`
+
+	selectSnapshotAtSQL = `select snapshot_id, taken_at, user_name, user_email, reason, state
+	from gdm_snapshots where taken_at <= $1
+	order by taken_at desc, snapshot_id desc limit 1`
+)
+
+// SaveSnapshot implements sous.SnapshotStore on PostgresSnapshotStore.
+func (s *PostgresSnapshotStore) SaveSnapshot(ss *sous.StateSnapshot) error {
+	state, err := json.Marshal(snapshotState{Defs: ss.Defs, Manifests: ss.Manifests})
+	if err != nil {
+		return errors.Wrapf(err, "encoding snapshot %s", ss.ID)
+	}
+
+	ctx := context.TODO()
+	start := time.Now()
+	res, err := s.db.ExecContext(ctx, insertSnapshotSQL,
+		ss.ID, ss.Time, ss.User.Name, ss.User.Email, string(ss.Reason), string(state))
+	if err != nil {
+		reportSQLMessage(s.log, start, "gdm_snapshots", write, insertSnapshotSQL, 0, err)
+		return errors.Wrapf(err, "sql %q", insertSnapshotSQL)
+	}
+	n, _ := res.RowsAffected()
+	reportSQLMessage(s.log, start, "gdm_snapshots", write, insertSnapshotSQL, int(n), nil)
+	return nil
+}
+
+// Snapshots implements sous.SnapshotStore on PostgresSnapshotStore.
+func (s *PostgresSnapshotStore) Snapshots(limit int) ([]sous.SnapshotInfo, error) {
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	q := selectSnapshotInfosSQL
+	if limit > 0 {
+		q += fmt.Sprintf(" limit %d", limit)
+	}
+	infos := []sous.SnapshotInfo{}
+	err = loadTable(ctx, s.log, tx, "gdm_snapshots", q, func(rows *sql.Rows) error {
+		var (
+			info   sous.SnapshotInfo
+			reason string
+		)
+		if err := rows.Scan(&info.ID, &info.Time, &info.User.Name, &info.User.Email, &reason); err != nil {
+			return err
+		}
+		info.Reason = sous.SnapshotReason(reason)
+		infos = append(infos, info)
+		return nil
+	})
+	return infos, err
+}
+
+// Snapshot implements sous.SnapshotStore on PostgresSnapshotStore.
+func (s *PostgresSnapshotStore) Snapshot(id string) (*sous.StateSnapshot, bool, error) {
+	return s.selectSnapshot(selectSnapshotSQL, id)
+}
+
+// SnapshotAt implements sous.SnapshotStore on PostgresSnapshotStore.
+func (s *PostgresSnapshotStore) SnapshotAt(t time.Time) (*sous.StateSnapshot, bool, error) {
+	return s.selectSnapshot(selectSnapshotAtSQL, t)
+}
+
+func (s *PostgresSnapshotStore) selectSnapshot(q string, arg interface{}) (*sous.StateSnapshot, bool, error) {
+	var (
+		ss            sous.StateSnapshot
+		reason, state string
+	)
+	start := time.Now()
+	err := s.db.QueryRowContext(context.TODO(), q, arg).Scan(
+		&ss.ID, &ss.Time, &ss.User.Name, &ss.User.Email, &reason, &state)
+	if err == sql.ErrNoRows {
+		reportSQLMessage(s.log, start, "gdm_snapshots", read, q, 0, nil)
+		return nil, false, nil
+	}
+	if err != nil {
+		reportSQLMessage(s.log, start, "gdm_snapshots", read, q, 0, err)
+		return nil, false, errors.Wrapf(err, "sql %q", q)
+	}
+	reportSQLMessage(s.log, start, "gdm_snapshots", read, q, 1, nil)
+
+	ss.Reason = sous.SnapshotReason(reason)
+	var stored snapshotState
+	if err := json.Unmarshal([]byte(state), &stored); err != nil {
+		return nil, false, errors.Wrapf(err, "decoding snapshot %s", ss.ID)
+	}
+	ss.Defs, ss.Manifests = stored.Defs, stored.Manifests
+	return &ss, true, nil
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresSnapshotStore(t *testing.T) {
+	store := NewPostgresSnapshotStore(setupDB(t), logging.SilentLogSet())
+
+	user := sous.User{Name: "Test User", Email: "test@example.com"}
+	now := time.Now()
+	first := sous.NewStateSnapshot(exampleState(), sous.SnapshotOnDemand, user, now.Add(-time.Hour))
+	second := sous.NewStateSnapshot(sous.NewState(), sous.SnapshotPeriodic, sous.User{}, now)
+	require.NoError(t, store.SaveSnapshot(first))
+	require.NoError(t, store.SaveSnapshot(second))
+
+	infos, err := store.Snapshots(0)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, second.ID, infos[0].ID)
+	assert.Equal(t, user, infos[1].User)
+	assert.Equal(t, sous.SnapshotOnDemand, infos[1].Reason)
+
+	limited, err := store.Snapshots(1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	got, found, err := store.Snapshot(first.ID)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Empty(t, sous.DiffStates(exampleState(), got.State()))
+
+	got, found, err = store.SnapshotAt(now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, first.ID, got.ID)
+
+	_, found, err = store.SnapshotAt(now.Add(-2 * time.Hour))
+	require.NoError(t, err)
+	assert.False(t, found)
+}
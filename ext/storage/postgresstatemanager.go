@@ -15,6 +15,8 @@ type (
 	PostgresStateManager struct {
 		db  *sql.DB
 		log logging.LogSink
+		// listenConnStr is used to open the connection Watch listens on.
+		listenConnStr string
 	}
 
 	// A PostgresConfig describes how to connect to a postgres database
@@ -33,6 +35,13 @@ func NewPostgresStateManager(db *sql.DB, log logging.LogSink) *PostgresStateMana
 	return &PostgresStateManager{db: db, log: log}
 }
 
+// ListenWith has Watch listen for notifications of writes on a connection
+// opened with c, which should be the config the manager's db was opened with.
+func (m *PostgresStateManager) ListenWith(c PostgresConfig) *PostgresStateManager {
+	m.listenConnStr = c.connStr()
+	return m
+}
+
 func (c PostgresConfig) connStr() string {
 	conn := []string{}
 	if c.Host != "" {
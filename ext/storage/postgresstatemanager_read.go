@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -61,6 +62,9 @@ func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.Stat
 	if err := loadManifests(ctx, log, tx, state); err != nil {
 		return nil, err
 	}
+	if err := loadWebhooks(ctx, log, tx, state); err != nil {
+		return nil, err
+	}
 
 	return state, nil
 }
@@ -112,6 +116,7 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 		"crdef_skip", "crdef_connect_delay", "crdef_timeout", "crdef_connect_interval",
 		"crdef_proto", "crdef_path", "crdef_port_index", "crdef_failure_statuses",
 		"crdef_uri_timeout", "crdef_interval", "crdef_retries",
+		"artifact_policy", "platform", "require_signatures",
 		qualities.name
 		from
 			clusters
@@ -123,17 +128,24 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 		func(rows *sql.Rows) error {
 			var cid int
 			c := &sous.Cluster{}
-			var qname sql.NullString
+			var qname, policy sql.NullString
 			failStates := make(pq.Int64Array, 10)
 			if err := rows.Scan(
 				&cid, &c.Name, &c.Kind, &c.BaseURL,
 				&c.Startup.SkipCheck, &c.Startup.ConnectDelay, &c.Startup.Timeout, &c.Startup.ConnectInterval,
 				&c.Startup.CheckReadyProtocol, &c.Startup.CheckReadyURIPath, &c.Startup.CheckReadyPortIndex, &failStates,
 				&c.Startup.CheckReadyURITimeout, &c.Startup.CheckReadyInterval, &c.Startup.CheckReadyRetries,
+				&policy, &c.Platform, &c.RequireSignatures,
 				&qname,
 			); err != nil {
 				return errors.Wrapf(err, "loadClusters")
 			}
+			if policy.Valid {
+				c.ArtifactPolicy = &sous.ArtifactPolicy{}
+				if err := json.Unmarshal([]byte(policy.String), c.ArtifactPolicy); err != nil {
+					return errors.Wrapf(err, "loadClusters: artifact policy of %q", c.Name)
+				}
+			}
 			if newC, has := clusters[cid]; has {
 				c = newC
 			} else {
@@ -169,6 +181,7 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 			"cr_skip", "cr_connect_delay", "cr_timeout", "cr_connect_interval",
 			"cr_proto", "cr_path", "cr_port_index", "cr_failure_statuses",
 			"cr_uri_timeout", "cr_interval", "cr_retries",
+			"rollout_canary_percent", "rollout_steps", "rollout_step_pause", "rollout_abort_on_health_failure",
 			clusters.name,
 			"host", "container", "mode",
 			envs.key, envs.value,
@@ -221,6 +234,7 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 				&ds.Startup.SkipCheck, &ds.Startup.ConnectDelay, &ds.Startup.Timeout, &ds.Startup.ConnectInterval,
 				&ds.Startup.CheckReadyProtocol, &ds.Startup.CheckReadyURIPath, &ds.Startup.CheckReadyPortIndex, &failStates,
 				&ds.Startup.CheckReadyURITimeout, &ds.Startup.CheckReadyInterval, &ds.Startup.CheckReadyRetries,
+				&ds.Rollout.CanaryPercent, &ds.Rollout.Steps, &ds.Rollout.StepPause, &ds.Rollout.AbortOnHealthFailure,
 				&clusterName,
 				&volHost, &volContainer, &volMode,
 				&envKey, &envValue,
@@ -286,10 +300,10 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 		})
 }
 
-func loadTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, pack func(*sql.Rows) error) error {
+func loadTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, pack func(*sql.Rows) error, args ...interface{}) error {
 	rowcount := 0
 	start := time.Now()
-	rows, err := tx.QueryContext(ctx, sql)
+	rows, err := tx.QueryContext(ctx, sql, args...)
 	if err != nil {
 		reportSQLMessage(log, start, mainTable, read, sql, rowcount, err)
 		return errors.Wrapf(err, "loadTable %q", sql)
@@ -64,7 +64,7 @@ func TestPostgresStateManagerWriteState_success(t *testing.T) {
 	}
 	suite.Equal(int64(4), suite.pluckSQL("select count(*) from deployments"))
 
-	assert.Len(t, suite.logs.CallsTo("Fields"), 13)
+	assert.Len(t, suite.logs.CallsTo("Fields"), 14)
 	message := suite.logs.CallsTo("Fields")[0].PassedArgs().Get(0).([]logging.EachFielder)
 	// XXX This message deserves its own test
 	logging.AssertMessageFieldlist(t, message, append(
@@ -115,6 +115,104 @@ func TestPostgresStateManagerWriteState_success(t *testing.T) {
 	}
 }
 
+func TestPostgresStateManagerWriteState_webhooks(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	s.Defs.Webhooks = sous.Webhooks{{URL: "https://hooks.example.com/all"}}
+	m, _ := s.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}})
+	m.Webhooks = sous.Webhooks{
+		{URL: "https://hooks.example.com/sous", Format: "slack", Events: []sous.NotificationEvent{sous.DeployFailed}},
+		{URL: "https://hooks.example.com/sous-1", Clusters: []string{"cluster-1"}},
+	}
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.True(s.Defs.Webhooks.Equal(ns.Defs.Webhooks), "Defs webhooks: %v", ns.Defs.Webhooks)
+	nm, _ := ns.Manifests.Get(m.ID())
+	suite.True(m.Webhooks.Equal(nm.Webhooks), "manifest webhooks: %v", nm.Webhooks)
+
+	m.Webhooks = m.Webhooks[1:]
+	s.Defs.Webhooks = nil
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	ns, err = suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Empty(ns.Defs.Webhooks)
+	nm, _ = ns.Manifests.Get(m.ID())
+	suite.True(m.Webhooks.Equal(nm.Webhooks), "manifest webhooks: %v", nm.Webhooks)
+}
+
+func TestPostgresStateManagerWriteState_artifactPolicy(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	policy := &sous.ArtifactPolicy{MaxAge: "720h", AllowedBaseImages: []string{"docker.example.com/base/"}, MaxSeverity: "high"}
+	s.Defs.Clusters["cluster-1"].ArtifactPolicy = policy
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Equal(policy, ns.Defs.Clusters["cluster-1"].ArtifactPolicy)
+	suite.Nil(ns.Defs.Clusters["other-cluster"].ArtifactPolicy)
+}
+
+func TestPostgresStateManagerWriteState_platform(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	s.Defs.Clusters["cluster-1"].Platform = "linux/arm64"
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Equal("linux/arm64", ns.Defs.Clusters["cluster-1"].Platform)
+	suite.Equal("", ns.Defs.Clusters["other-cluster"].Platform)
+}
+
+func TestPostgresStateManagerWriteState_requireSignatures(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	s.Defs.Clusters["cluster-1"].RequireSignatures = true
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.True(ns.Defs.Clusters["cluster-1"].RequireSignatures)
+	suite.False(ns.Defs.Clusters["other-cluster"].RequireSignatures)
+}
+
+func TestPostgresStateManagerWriteState_clusterOnly(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	s.Defs.Clusters["cluster-1"].Platform = "linux/arm64"
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Equal("linux/arm64", ns.Defs.Clusters["cluster-1"].Platform, "a changed cluster should be stored without changed deployments")
+}
+
+func TestPostgresStateManagerWriteState_removeDeployment(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	m, ok := s.Manifests.Get(exampleMID)
+	suite.require.True(ok)
+	delete(m.Deployments, "other-cluster")
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	ns, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	nm, ok := ns.Manifests.Get(exampleMID)
+	suite.require.True(ok)
+	suite.NotContains(nm.Deployments, "other-cluster")
+}
+
 func (suite *PostgresStateManagerSuite) pluckSQL(sql string) interface{} {
 	var v interface{}
 
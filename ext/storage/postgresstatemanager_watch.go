@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// postgresStateChannel is the channel notified when WriteState changes the
+// deployments in the database.
+const postgresStateChannel = "sous_state"
+
+// Watch implements sous.StateWatcher on PostgresStateManager, by listening
+// for the notifications WriteState sends, on a connection of its own. It
+// returns an error unless ListenWith has been called.
+func (m *PostgresStateManager) Watch(done <-chan struct{}) (<-chan sous.StateChange, error) {
+	if m.listenConnStr == "" {
+		return nil, errors.New("no database config to listen for state changes with")
+	}
+	l := pq.NewListener(m.listenConnStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logging.ReportError(m.log, errors.Wrapf(err, "listening for state changes"))
+		}
+	})
+	if err := l.Listen(postgresStateChannel); err != nil {
+		l.Close()
+		return nil, errors.Wrapf(err, "listening on %s", postgresStateChannel)
+	}
+
+	out := make(chan sous.StateChange, 1)
+	go func() {
+		defer close(out)
+		defer l.Close()
+		for {
+			select {
+			case <-done:
+				return
+			case <-l.Notify:
+				// Notify receives nil after the connection is re-established,
+				// when changes may have been missed, which is reported as a
+				// change too.
+			}
+			select {
+			case out <- sous.StateChange{Time: time.Now()}:
+			default:
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// Webhooks are kept in the webhooks table, in the order they were inserted.
+// Those of a manifest refer to its component; those of the Defs have no
+// component.
+const (
+	selectWebhooksSQL = `select repo, dir, flavor, url, "format", events, webhooks.clusters
+	from webhooks left join components using (component_id)
+	order by webhook_id`
+
+	deleteDefsWebhooksSQL = `delete from webhooks where component_id is null`
+
+	deleteManifestWebhooksSQL = `delete from webhooks where component_id in
+	(select component_id from components where repo = $1 and dir = $2 and flavor = $3)`
+
+	insertDefsWebhookSQL = `insert into webhooks (component_id, url, "format", events, clusters)
+	values (null, $1, $2, $3, $4)`
+
+	insertManifestWebhookSQL = `insert into webhooks (component_id, url, "format", events, clusters)
+	select component_id, $4, $5, $6, $7 from components
+	where repo = $1 and dir = $2 and flavor = $3 and kind = $8`
+)
+
+// storeWebhooks brings the webhooks table up to date with state, given that
+// it currently holds those in current.
+func storeWebhooks(ctx context.Context, log logging.LogSink, state, current *sous.State, tx *sql.Tx) (bool, error) {
+	changed := false
+	if !state.Defs.Webhooks.Equal(current.Defs.Webhooks) {
+		changed = true
+		if err := execWebhooks(ctx, log, tx, deleteDefsWebhooksSQL); err != nil {
+			return changed, err
+		}
+		for _, w := range state.Defs.Webhooks {
+			if err := execWebhooks(ctx, log, tx, insertDefsWebhookSQL, webhookValues(w)...); err != nil {
+				return changed, err
+			}
+		}
+	}
+
+	for mid, cm := range current.Manifests.Snapshot() {
+		if _, has := state.Manifests.Get(mid); has || len(cm.Webhooks) == 0 {
+			continue
+		}
+		changed = true
+		if err := execWebhooks(ctx, log, tx, deleteManifestWebhooksSQL, mid.Source.Repo, mid.Source.Dir, mid.Flavor); err != nil {
+			return changed, err
+		}
+	}
+
+	for mid, m := range state.Manifests.Snapshot() {
+		cm, has := current.Manifests.Get(mid)
+		if has && cm.Webhooks.Equal(m.Webhooks) || !has && len(m.Webhooks) == 0 {
+			continue
+		}
+		changed = true
+		if has {
+			if err := execWebhooks(ctx, log, tx, deleteManifestWebhooksSQL, mid.Source.Repo, mid.Source.Dir, mid.Flavor); err != nil {
+				return changed, err
+			}
+		}
+		for _, w := range m.Webhooks {
+			args := append([]interface{}{mid.Source.Repo, mid.Source.Dir, mid.Flavor}, webhookValues(w)...)
+			if err := execWebhooks(ctx, log, tx, insertManifestWebhookSQL, append(args, m.Kind)...); err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+func webhookValues(w sous.Webhook) []interface{} {
+	events := make([]string, len(w.Events))
+	for i, e := range w.Events {
+		events[i] = string(e)
+	}
+	clusters := append([]string{}, w.Clusters...)
+	return []interface{}{w.URL, w.Format, pq.Array(events), pq.Array(clusters)}
+}
+
+func execWebhooks(ctx context.Context, log logging.LogSink, tx *sql.Tx, sql string, args ...interface{}) error {
+	start := time.Now()
+	res, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		reportSQLMessage(log, start, "webhooks", write, sql, 0, err)
+		return errors.Wrapf(err, "sql %q", sql)
+	}
+	n, _ := res.RowsAffected()
+	reportSQLMessage(log, start, "webhooks", write, sql, int(n), nil)
+	return nil
+}
+
+// loadWebhooks adds the webhooks of the Defs and of each manifest already in
+// state.
+func loadWebhooks(ctx context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
+	return loadTable(ctx, log, tx, "webhooks", selectWebhooksSQL, func(rows *sql.Rows) error {
+		var (
+			repo, dir, flavor sql.NullString
+			w                 sous.Webhook
+			events            []string
+		)
+		if err := rows.Scan(&repo, &dir, &flavor, &w.URL, &w.Format, pq.Array(&events), pq.Array(&w.Clusters)); err != nil {
+			return errors.Wrapf(err, "loadWebhooks")
+		}
+		for _, e := range events {
+			w.Events = append(w.Events, sous.NotificationEvent(e))
+		}
+		if len(w.Clusters) == 0 {
+			w.Clusters = nil
+		}
+		if !repo.Valid {
+			state.Defs.Webhooks = append(state.Defs.Webhooks, w)
+			return nil
+		}
+		mid := sous.ManifestID{Source: sous.SourceLocation{Repo: repo.String, Dir: dir.String}, Flavor: flavor.String}
+		if m, has := state.Manifests.Get(mid); has {
+			m.Webhooks = append(m.Webhooks, w)
+		}
+		return nil
+	})
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
@@ -71,6 +73,25 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		}
 	}
 
+	// Clusters are stored along with their deployments, so a cluster whose
+	// definition has changed is stored again along with one of its
+	// deployments, even if none of them has changed.
+	clusterdeps := sous.NewDeployments().Merge(alldeps)
+	storedClusters := map[string]bool{}
+	for _, dep := range alldeps.Snapshot() {
+		storedClusters[dep.ClusterName] = true
+	}
+	for _, dep := range newDeps.Snapshot() {
+		if dep.Cluster == nil || storedClusters[dep.ClusterName] {
+			continue
+		}
+		if current, has := currentState.Defs.Clusters[dep.ClusterName]; has && len(clusterDiffs(dep.Cluster, current)) == 0 {
+			continue
+		}
+		storedClusters[dep.ClusterName] = true
+		clusterdeps.Add(dep)
+	}
+
 	/* XXX consider logging this
 	currentDeps.Len(),
 	newDeps.Len(),
@@ -90,13 +111,16 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		return nil
 	}
 
-	if err := execInsertDeployments(ctx, log, tx, alldeps, "clusters", `on conflict {{.Candidates}} do update set {{.NonCandidates}} = {{.NSNonCandidates "excluded"}}`, func(fields sqlgen.FieldSet, dep *sous.Deployment) {
+	if err := execInsertDeployments(ctx, log, tx, clusterdeps, "clusters", `on conflict {{.Candidates}} do update set {{.NonCandidates}} = {{.NSNonCandidates "excluded"}}`, func(fields sqlgen.FieldSet, dep *sous.Deployment) {
 		c := dep.Cluster
 		s := c.Startup
 		fields.Row(func(r sqlgen.RowDef) {
 			r.CF("?", "name", dep.ClusterName)
 			r.FD("?", "kind", c.Kind)
 			r.FD("?", "base_url", c.BaseURL)
+			r.FD("?", "artifact_policy", artifactPolicyJSON(c.ArtifactPolicy))
+			r.FD("?", "platform", c.Platform)
+			r.FD("?", "require_signatures", c.RequireSignatures)
 			startupFields(r, "crdef", s)
 		})
 	}); err != nil {
@@ -117,6 +141,7 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 			r.FD("?", "schedule_string", dep.Schedule)
 			r.FD("?", "lifecycle", "active")
 			startupFields(r, "cr", s)
+			rolloutFields(r, dep.Rollout)
 		})
 	}); err != nil {
 		return err
@@ -132,8 +157,9 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 			r.FD("?", "versionstring", dep.SourceID.Version.String())
 			r.FD("?", "num_instances", dep.NumInstances)
 			r.FD("?", "schedule_string", dep.Schedule)
-			r.FD("?", "lifecycle", "decommisioned")
+			r.FD("?", "lifecycle", "decommissioned")
 			startupFields(r, "cr", s)
+			rolloutFields(r, dep.Rollout)
 		})
 	}); err != nil {
 		return err
@@ -209,6 +235,17 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		return err
 	}
 
+	hooksChanged, err := storeWebhooks(ctx, log, state, currentState, tx)
+	if err != nil {
+		return err
+	}
+
+	// Listeners are only notified once the transaction commits.
+	if clusterdeps.Len() > 0 || hooksChanged {
+		if _, err := tx.ExecContext(ctx, "select pg_notify($1, '')", postgresStateChannel); err != nil {
+			return errors.Wrapf(err, "notifying %s", postgresStateChannel)
+		}
+	}
 	return nil
 }
 
@@ -240,6 +277,49 @@ func ownerID(row sqlgen.RowDef, ownername string) {
 	row.FD("(select owner_id from owners where email = ?)", "owner_id", ownername)
 }
 
+// clusterDiffs returns the differences between the fields of c and o which are
+// stored in the clusters table.
+func clusterDiffs(c, o *sous.Cluster) []string {
+	var diffs []string
+	diff := func(format string, a ...interface{}) { diffs = append(diffs, fmt.Sprintf(format, a...)) }
+	if c.Kind != o.Kind {
+		diff("kind; this: %q; other: %q", c.Kind, o.Kind)
+	}
+	if c.BaseURL != o.BaseURL {
+		diff("base URL; this: %q; other: %q", c.BaseURL, o.BaseURL)
+	}
+	if !c.Startup.Equal(o.Startup) {
+		diff("startup; this: %+v; other: %+v", c.Startup, o.Startup)
+	}
+	if cp, op := artifactPolicyJSON(c.ArtifactPolicy), artifactPolicyJSON(o.ArtifactPolicy); cp != op {
+		diff("artifact policy; this: %s; other: %s", cp.String, op.String)
+	}
+	if c.Platform != o.Platform {
+		diff("platform; this: %q; other: %q", c.Platform, o.Platform)
+	}
+	if c.RequireSignatures != o.RequireSignatures {
+		diff("require signatures; this: %t; other: %t", c.RequireSignatures, o.RequireSignatures)
+	}
+	return diffs
+}
+
+// artifactPolicyJSON returns p as JSON, or NULL if p is nil.
+func artifactPolicyJSON(p *sous.ArtifactPolicy) sql.NullString {
+	if p == nil {
+		return sql.NullString{}
+	}
+	// An ArtifactPolicy is only strings, so always encodes.
+	b, _ := json.Marshal(p)
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+func rolloutFields(r sqlgen.RowDef, ro sous.Rollout) {
+	r.FD("?", "rollout_canary_percent", ro.CanaryPercent)
+	r.FD("?", "rollout_steps", ro.Steps)
+	r.FD("?", "rollout_step_pause", ro.StepPause)
+	r.FD("?", "rollout_abort_on_health_failure", ro.AbortOnHealthFailure)
+}
+
 func startupFields(r sqlgen.RowDef, prefix string, s sous.Startup) {
 	statuses := []int64{}
 	for _, n := range s.CheckReadyFailureStatuses {
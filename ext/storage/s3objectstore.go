@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// An ObjectStoreConfig describes how to connect to an S3-compatible
+	// object store.
+	ObjectStoreConfig struct {
+		// Endpoint is the URL of the store's API, e.g.
+		// https://s3.us-west-2.amazonaws.com, or http://localhost:9000 for a
+		// local MinIO.
+		Endpoint string `env:"SOUS_OBJECT_STORE_ENDPOINT"`
+		// Bucket is the bucket the GDM is stored in. The object store is only
+		// used if it is set.
+		Bucket string `env:"SOUS_OBJECT_STORE_BUCKET"`
+		// Prefix is prepended to the key of each object in the GDM.
+		Prefix string `env:"SOUS_OBJECT_STORE_PREFIX"`
+		// Region is the region requests are signed for; us-east-1 if empty.
+		Region          string `env:"SOUS_OBJECT_STORE_REGION"`
+		AccessKeyID     string `env:"SOUS_OBJECT_STORE_ACCESS_KEY_ID"`
+		SecretAccessKey string `env:"SOUS_OBJECT_STORE_SECRET_ACCESS_KEY"`
+		// Primary makes the object store, rather than git, the primary storage
+		// of the GDM. Otherwise every write to the GDM is also made to it.
+		Primary bool `env:"SOUS_OBJECT_STORE_PRIMARY"`
+	}
+
+	// S3ObjectStore is an ObjectStore using the S3 API, over path-style URLs,
+	// so that it works with S3 and the stores compatible with it. Requests
+	// are signed with AWS Signature Version 4, unless AccessKeyID is empty.
+	S3ObjectStore struct {
+		Endpoint        *url.URL
+		Bucket          string
+		Region          string
+		AccessKeyID     string
+		SecretAccessKey string
+		Client          *http.Client
+	}
+
+	s3ListBucketResult struct {
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []struct {
+			Key  string
+			ETag string
+		}
+	}
+)
+
+// S3 returns an S3ObjectStore configured by c, or an error if c has no
+// bucket.
+func (c ObjectStoreConfig) S3() (*S3ObjectStore, error) {
+	if c.Bucket == "" {
+		return nil, errors.New("no object store bucket configured")
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "object store endpoint %q", c.Endpoint)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, errors.Errorf("object store endpoint %q must begin with http:// or https://", c.Endpoint)
+	}
+	region := c.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3ObjectStore{
+		Endpoint:        endpoint,
+		Bucket:          c.Bucket,
+		Region:          region,
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		Client:          &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// GetObject implements ObjectStore on S3ObjectStore.
+func (s *S3ObjectStore) GetObject(key string) ([]byte, string, error) {
+	rz, body, err := s.do("GET", key, nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	switch rz.StatusCode {
+	case http.StatusOK:
+		return body, rz.Header.Get("ETag"), nil
+	case http.StatusNotFound:
+		return nil, "", ErrObjectNotFound
+	}
+	return nil, "", s3Error("GET", key, rz, body)
+}
+
+// PutObject implements ObjectStore on S3ObjectStore.
+func (s *S3ObjectStore) PutObject(key string, data []byte, etag string) (string, error) {
+	headers := http.Header{"Content-Type": {"application/json"}}
+	if etag == "" {
+		headers.Set("If-None-Match", "*")
+	} else {
+		headers.Set("If-Match", etag)
+	}
+	rz, body, err := s.do("PUT", key, nil, headers, data)
+	if err != nil {
+		return "", err
+	}
+	switch rz.StatusCode {
+	case http.StatusOK:
+		return rz.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return "", ErrPreconditionFailed
+	}
+	return "", s3Error("PUT", key, rz, body)
+}
+
+// DeleteObject implements ObjectStore on S3ObjectStore.
+func (s *S3ObjectStore) DeleteObject(key, etag string) error {
+	rz, body, err := s.do("DELETE", key, nil, http.Header{"If-Match": {etag}}, nil)
+	if err != nil {
+		return err
+	}
+	switch rz.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrPreconditionFailed
+	}
+	return s3Error("DELETE", key, rz, body)
+}
+
+// ListObjects implements ObjectStore on S3ObjectStore.
+func (s *S3ObjectStore) ListObjects(prefix string) ([]ObjectInfo, error) {
+	infos := []ObjectInfo{}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		rz, body, err := s.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if rz.StatusCode != http.StatusOK {
+			return nil, s3Error("GET", "?prefix="+prefix, rz, body)
+		}
+		var result s3ListBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrapf(err, "listing objects in %s", s.Bucket)
+		}
+		for _, c := range result.Contents {
+			infos = append(infos, ObjectInfo{Key: c.Key, ETag: c.ETag})
+		}
+		if !result.IsTruncated {
+			return infos, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func s3Error(method, key string, rz *http.Response, body []byte) error {
+	return errors.Errorf("%s %s: %s: %s", method, key, rz.Status, bytes.TrimSpace(body))
+}
+
+// do makes a signed request for the object at key in the bucket, or for the
+// bucket itself if key is empty, and returns the response and its body.
+func (s *S3ObjectStore) do(method, key string, query url.Values, headers http.Header, data []byte) (*http.Response, []byte, error) {
+	path := "/" + s.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+	u := *s.Endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = s3Query(query)
+
+	rq, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range headers {
+		rq.Header[k] = vs
+	}
+	s.sign(rq, data, time.Now().UTC())
+
+	rz, err := s.Client.Do(rq)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "%s %s", method, u.String())
+	}
+	defer rz.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(rz.Body, 64<<20))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "reading response to %s %s", method, u.String())
+	}
+	return rz, body, nil
+}
+
+// sign adds the headers signing rq with AWS Signature Version 4.
+func (s *S3ObjectStore) sign(rq *http.Request, data []byte, now time.Time) {
+	payloadHash := sha256.Sum256(data)
+	amzDate := now.Format("20060102T150405Z")
+	rq.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	rq.Header.Set("X-Amz-Date", amzDate)
+	if s.AccessKeyID == "" {
+		return
+	}
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
+		rq.URL.Host, rq.Header.Get("X-Amz-Content-Sha256"), amzDate)
+	canonicalRequest := strings.Join([]string{
+		rq.Method,
+		rq.URL.EscapedPath(),
+		rq.URL.RawQuery,
+		canonicalHeaders,
+		strings.Join(signed, ";"),
+		rq.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), s.Region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	rq.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, strings.Join(signed, ";"), hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Query returns the canonical form of query, as AWS signatures need it:
+// sorted by name, and escaped.
+func s3Query(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, v := range values {
+			params = append(params, s3Escape(name, true)+"="+s3Escape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// s3Escape percent-encodes every byte of s except the unreserved characters,
+// and slashes unless escapeSlash is set, as AWS signatures need it.
+func s3Escape(s string, escapeSlash bool) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves the parts of the S3 API S3ObjectStore uses, for one bucket,
+// from a MemoryObjectStore.
+type fakeS3 struct {
+	bucket  string
+	store   *MemoryObjectStore
+	authzed []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.authzed = append(f.authzed, r.Header.Get("Authorization"))
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket)
+	if key == "" && r.Method == "GET" {
+		infos, _ := f.store.ListObjects(r.URL.Query().Get("prefix"))
+		result := s3ListBucketResult{}
+		for _, info := range infos {
+			result.Contents = append(result.Contents, struct{ Key, ETag string }{info.Key, info.ETag})
+		}
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"ListBucketResult"`
+			s3ListBucketResult
+		}{s3ListBucketResult: result})
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	var err error
+	switch r.Method {
+	case "GET":
+		var data []byte
+		var etag string
+		if data, etag, err = f.store.GetObject(key); err == nil {
+			w.Header().Set("ETag", etag)
+			w.Write(data)
+			return
+		}
+	case "PUT":
+		data, _ := ioutil.ReadAll(r.Body)
+		var etag string
+		if etag, err = f.store.PutObject(key, data, r.Header.Get("If-Match")); err == nil {
+			w.Header().Set("ETag", etag)
+			return
+		}
+	case "DELETE":
+		if err = f.store.DeleteObject(key, r.Header.Get("If-Match")); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	switch err {
+	case ErrObjectNotFound:
+		http.Error(w, "NoSuchKey", http.StatusNotFound)
+	case ErrPreconditionFailed:
+		http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+	default:
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}
+}
+
+func TestS3ObjectStore(t *testing.T) {
+	fake := &fakeS3{bucket: "sous", store: NewMemoryObjectStore()}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	_, err := ObjectStoreConfig{Endpoint: srv.URL}.S3()
+	assert.Error(t, err, "no bucket")
+	s3, err := ObjectStoreConfig{
+		Endpoint:        srv.URL,
+		Bucket:          "sous",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}.S3()
+	require.NoError(t, err)
+
+	_, _, err = s3.GetObject("gdm/defs.json")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	etag, err := s3.PutObject("gdm/manifests/github.com/opentable/sous,util~canary.json", []byte("{}"), "")
+	require.NoError(t, err)
+	_, err = s3.PutObject("gdm/manifests/github.com/opentable/sous,util~canary.json", []byte("{}"), "")
+	assert.Equal(t, ErrPreconditionFailed, err)
+	_, err = s3.PutObject("gdm/manifests/github.com/opentable/sous,util~canary.json", []byte(`{"a":1}`), etag)
+	require.NoError(t, err)
+	_, err = s3.PutObject("gdm/manifests/github.com/opentable/sous,util~canary.json", []byte(`{"a":2}`), etag)
+	assert.Equal(t, ErrPreconditionFailed, err)
+
+	data, got, err := s3.GetObject("gdm/manifests/github.com/opentable/sous,util~canary.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	infos, err := s3.ListObjects("gdm/")
+	require.NoError(t, err)
+	assert.Equal(t, []ObjectInfo{{Key: "gdm/manifests/github.com/opentable/sous,util~canary.json", ETag: got}}, infos)
+
+	assert.Equal(t, ErrPreconditionFailed, s3.DeleteObject("gdm/manifests/github.com/opentable/sous,util~canary.json", etag))
+	require.NoError(t, s3.DeleteObject("gdm/manifests/github.com/opentable/sous,util~canary.json", got))
+	require.NoError(t, s3.DeleteObject("gdm/manifests/github.com/opentable/sous,util~canary.json", got))
+
+	for _, authz := range fake.authzed {
+		assert.Regexp(t, `^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/\d{8}/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`, authz)
+	}
+
+	osm := NewObjectStoreStateManager(s3, "gdm/", logging.SilentLogSet())
+	require.NoError(t, osm.WriteState(exampleState(), testUser))
+	s, err := osm.ReadState()
+	require.NoError(t, err)
+	assertStatesEqual(t, exampleState(), s)
+}
+
+func TestS3Escape(t *testing.T) {
+	assert.Equal(t, "/sous/gdm/manifests/github.com/opentable/sous%2Cutil~canary.json",
+		s3Escape("/sous/gdm/manifests/github.com/opentable/sous,util~canary.json", false))
+	assert.Equal(t, "continuation-token=a%2Fb%3D&list-type=2&prefix=gdm%2F",
+		s3Query(map[string][]string{"prefix": {"gdm/"}, "list-type": {"2"}, "continuation-token": {"a/b="}}))
+}
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A StorageReport describes how the server's local Postgres storage
+	// differs from its primary storage.
+	StorageReport struct {
+		// Time is when the comparison was made.
+		Time time.Time
+		// Cluster is the local cluster, whose deployments were compared.
+		Cluster string
+		// Manifests is the number of manifests in the primary storage with a
+		// deployment to Cluster.
+		Manifests int
+		// Divergences are the manifests which differ, in order of their IDs.
+		Divergences []StorageDivergence
+		// Defs are the differences in the definitions, from the primary's
+		// point of view.
+		Defs []string
+		// Repaired is true if the secondary storage was then rewritten from
+		// the primary.
+		Repaired bool
+	}
+
+	// A StorageDivergence is a manifest which differs between the primary and
+	// secondary storage.
+	StorageDivergence struct {
+		ManifestID sous.ManifestID
+		Kind       DivergenceKind
+		// Diffs describe the differences in a modified manifest, as
+		// Manifest.Diff reports them, from the primary's point of view.
+		Diffs []string
+	}
+
+	// DivergenceKind describes how a manifest differs between the primary and
+	// secondary storage.
+	DivergenceKind string
+
+	// A StorageVerifier compares the server's local Postgres storage with its
+	// primary storage every Interval, and when asked to, reporting the
+	// divergences between them as metrics, and optionally repairing the
+	// secondary from the primary.
+	//
+	// The local storage only keeps the deployments to the local cluster up to
+	// date, since the other clusters' servers are responsible for theirs, so
+	// only those, and the definitions it stores, are compared.
+	StorageVerifier struct {
+		Primary   sous.StateReader
+		Secondary sous.StateManager
+		// Cluster is the name of the local cluster.
+		Cluster string
+		// Interval is the time between periodic checks. If it is zero, the
+		// storage is only checked when asked to.
+		Interval time.Duration
+		// Repair has periodic checks which find divergences repair them.
+		Repair bool
+		log    logging.LogSink
+	}
+)
+
+const (
+	// MissingFromSecondary is a manifest only in the primary storage.
+	MissingFromSecondary DivergenceKind = "missing"
+	// OnlyInSecondary is a manifest only in the secondary storage.
+	OnlyInSecondary DivergenceKind = "extra"
+	// ModifiedInSecondary is a manifest which differs between the two.
+	ModifiedInSecondary DivergenceKind = "modified"
+)
+
+// VerifierUser is the user periodic repairs of the secondary storage are
+// made as.
+var VerifierUser = sous.User{Name: "Sous Storage Verifier"}
+
+// Consistent returns true if no divergences were found.
+func (r *StorageReport) Consistent() bool {
+	return len(r.Divergences) == 0 && len(r.Defs) == 0
+}
+
+// compareStates compares the parts of primary and secondary which the local
+// Postgres storage keeps for cluster: the deployments to it, its definition
+// and the webhooks of the definitions. Owners are left out, since Postgres
+// only ever adds them.
+func compareStates(primary, secondary *sous.State, cluster string) *StorageReport {
+	pms, sms := localManifests(primary, cluster), localManifests(secondary, cluster)
+	report := &StorageReport{Time: time.Now(), Cluster: cluster, Manifests: pms.Len()}
+	for mid, pm := range pms.Snapshot() {
+		sm, has := sms.Get(mid)
+		if !has {
+			report.Divergences = append(report.Divergences, StorageDivergence{ManifestID: mid, Kind: MissingFromSecondary})
+			continue
+		}
+		if different, diffs := pm.Diff(sm); different {
+			report.Divergences = append(report.Divergences, StorageDivergence{ManifestID: mid, Kind: ModifiedInSecondary, Diffs: diffs})
+		}
+	}
+	for mid := range sms.Snapshot() {
+		if _, has := pms.Get(mid); !has {
+			report.Divergences = append(report.Divergences, StorageDivergence{ManifestID: mid, Kind: OnlyInSecondary})
+		}
+	}
+	sort.Slice(report.Divergences, func(i, j int) bool {
+		return report.Divergences[i].ManifestID.String() < report.Divergences[j].ManifestID.String()
+	})
+
+	// Clusters are only stored along with their deployments.
+	pc, sc := primary.Defs.Clusters[cluster], secondary.Defs.Clusters[cluster]
+	switch {
+	case report.Manifests == 0 || pc == nil:
+	case sc == nil:
+		report.Defs = append(report.Defs, fmt.Sprintf("missing cluster %q", cluster))
+	default:
+		for _, d := range clusterDiffs(pc, sc) {
+			report.Defs = append(report.Defs, fmt.Sprintf("cluster %q: %s", cluster, d))
+		}
+	}
+	if !primary.Defs.Webhooks.Equal(secondary.Defs.Webhooks) {
+		report.Defs = append(report.Defs, fmt.Sprintf("webhooks; this: %v; other: %v", primary.Defs.Webhooks, secondary.Defs.Webhooks))
+	}
+	return report
+}
+
+// localManifests returns copies of the manifests of state with a deployment
+// to cluster, with only that deployment, and without their owners.
+func localManifests(state *sous.State, cluster string) sous.Manifests {
+	ms := sous.NewManifests()
+	for _, m := range state.Manifests.Snapshot() {
+		spec, has := m.Deployments[cluster]
+		if !has {
+			continue
+		}
+		lm := m.Clone()
+		lm.Owners = nil
+		lm.Deployments = sous.DeploySpecs{cluster: spec}
+		ms.Add(lm)
+	}
+	return ms
+}
+
+// NewStorageVerifier returns a StorageVerifier which compares secondary, the
+// local Postgres storage of cluster, with primary.
+func NewStorageVerifier(primary sous.StateReader, secondary sous.StateManager, cluster string, interval time.Duration, repair bool, ls logging.LogSink) *StorageVerifier {
+	return &StorageVerifier{
+		Primary:   primary,
+		Secondary: secondary,
+		Cluster:   cluster,
+		Interval:  interval,
+		Repair:    repair,
+		log:       ls,
+	}
+}
+
+// Check compares the primary and secondary storage, and reports what it
+// finds. If repair is true and they have diverged, it then rewrites the
+// secondary storage with the state it read from the primary, as user.
+func (sv *StorageVerifier) Check(repair bool, user sous.User) (*StorageReport, error) {
+	start := time.Now()
+	report, err := sv.check(repair, user)
+	reportStorageVerify(sv.log, start, report, err)
+	return report, err
+}
+
+func (sv *StorageVerifier) check(repair bool, user sous.User) (*StorageReport, error) {
+	primary, err := sv.Primary.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading primary state")
+	}
+	secondary, err := sv.Secondary.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading secondary state")
+	}
+	report := compareStates(primary, secondary, sv.Cluster)
+	if !repair || report.Consistent() {
+		return report, nil
+	}
+	if err := sv.Secondary.WriteState(primary, user); err != nil {
+		return report, errors.Wrapf(err, "writing secondary state")
+	}
+	report.Repaired = true
+	return report, nil
+}
+
+// Start checks the storage every Interval, repairing it if Repair is set. It
+// does nothing if Interval is zero.
+func (sv *StorageVerifier) Start() {
+	if sv.Interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(sv.Interval) {
+			sv.Check(sv.Repair, VerifierUser)
+		}
+	}()
+}
+
+type storageVerifyMessage struct {
+	logging.CallerInfo
+	logging.MessageInterval
+	report *StorageReport
+	err    error
+}
+
+func reportStorageVerify(log logging.LogSink, started time.Time, report *StorageReport, err error) {
+	msg := &storageVerifyMessage{
+		CallerInfo:      logging.GetCallerInfo(logging.NotHere()),
+		MessageInterval: logging.NewInterval(started, time.Now()),
+		report:          report,
+		err:             err,
+	}
+	msg.CallerInfo.ExcludeMe()
+	logging.Deliver(log, msg)
+}
+
+// DefaultLevel implements LogMessage on storageVerifyMessage.
+func (msg *storageVerifyMessage) DefaultLevel() logging.Level {
+	if msg.err != nil || !msg.report.Consistent() {
+		return logging.WarningLevel
+	}
+	return logging.InformationLevel
+}
+
+// Message implements LogMessage on storageVerifyMessage.
+func (msg *storageVerifyMessage) Message() string {
+	switch {
+	case msg.err != nil:
+		return "Verifying secondary storage failed: " + msg.err.Error()
+	case msg.report.Repaired:
+		return fmt.Sprintf("Secondary storage diverged in %d manifests and %d definitions, and was repaired",
+			len(msg.report.Divergences), len(msg.report.Defs))
+	case !msg.report.Consistent():
+		return fmt.Sprintf("Secondary storage diverged in %d manifests and %d definitions",
+			len(msg.report.Divergences), len(msg.report.Defs))
+	}
+	return "Secondary storage is consistent"
+}
+
+// EachField implements LogMessage on storageVerifyMessage.
+func (msg *storageVerifyMessage) EachField(fn logging.FieldReportFn) {
+	fn("@loglov3-otl", logging.SousGenericV1)
+	msg.CallerInfo.EachField(fn)
+	msg.MessageInterval.EachField(fn)
+	if msg.err != nil {
+		fn("error", msg.err.Error())
+		return
+	}
+	var mids []string
+	for _, d := range msg.report.Divergences {
+		mids = append(mids, fmt.Sprintf("%s:%s", d.ManifestID, d.Kind))
+	}
+	fn(logging.SousDiffs, fmt.Sprint(append(mids, msg.report.Defs...)))
+}
+
+// MetricsTo implements MetricsMessage on storageVerifyMessage.
+func (msg *storageVerifyMessage) MetricsTo(sink logging.MetricsSink) {
+	msg.MessageInterval.TimeMetric("storage.verify.time", sink)
+	if msg.err != nil {
+		sink.IncCounter("storage.verify.errs", 1)
+		return
+	}
+	sink.IncCounter("storage.verify.count", 1)
+	sink.UpdateSample("storage.manifests", int64(msg.report.Manifests))
+	counts := map[DivergenceKind]int64{MissingFromSecondary: 0, OnlyInSecondary: 0, ModifiedInSecondary: 0}
+	for _, d := range msg.report.Divergences {
+		counts[d.Kind]++
+	}
+	for kind, n := range counts {
+		sink.UpdateSample("storage.divergences."+string(kind), n)
+	}
+	sink.UpdateSample("storage.divergences", int64(len(msg.report.Divergences)))
+	sink.UpdateSample("storage.divergences.defs", int64(len(msg.report.Defs)))
+	if msg.report.Repaired {
+		sink.IncCounter("storage.repairs", 1)
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageVerifier(t *testing.T) {
+	primary, secondary := sous.NewDummyStateManager(), sous.NewDummyStateManager()
+	primary.State, secondary.State = exampleState(), exampleState()
+	sv := NewStorageVerifier(primary, secondary, "cluster-1", 0, false, logging.SilentLogSet())
+
+	report, err := sv.Check(false, testUser)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 2, report.Manifests)
+
+	setSpec(t, secondary.State, "other-cluster", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	secondary.State.Defs.DockerRepo = "docker.example.com"
+	report, err = sv.Check(false, testUser)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent(), "only what the local cluster's storage keeps should be compared: %v", report)
+
+	setSpec(t, secondary.State, "cluster-1", func(spec *sous.DeploySpec) { spec.NumInstances = 9 })
+	other := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}}
+	secondary.State.Manifests.Remove(other)
+	extra := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/extra"}}
+	secondary.State.Manifests.Add(&sous.Manifest{Source: extra.Source, Kind: sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{"cluster-1": {Version: semv.MustParse("1.0.0")}}})
+	secondary.State.Defs.Clusters["cluster-1"].Platform = "linux/arm64"
+
+	report, err = sv.Check(false, testUser)
+	require.NoError(t, err)
+	require.Len(t, report.Divergences, 3)
+	assert.Equal(t, ModifiedInSecondary, report.Divergences[0].Kind)
+	assert.Equal(t, exampleMID, report.Divergences[0].ManifestID)
+	assert.NotEmpty(t, report.Divergences[0].Diffs)
+	assert.Equal(t, StorageDivergence{ManifestID: extra, Kind: OnlyInSecondary}, report.Divergences[1])
+	assert.Equal(t, StorageDivergence{ManifestID: other, Kind: MissingFromSecondary}, report.Divergences[2])
+	require.Len(t, report.Defs, 1)
+	assert.Contains(t, report.Defs[0], "platform")
+	assert.False(t, report.Repaired)
+
+	report, err = sv.Check(true, testUser)
+	require.NoError(t, err)
+	assert.Len(t, report.Divergences, 3)
+	assert.True(t, report.Repaired)
+
+	report, err = sv.Check(true, testUser)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.False(t, report.Repaired, "a consistent storage should not be rewritten")
+}
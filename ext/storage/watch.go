@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// StatePollInterval is how often DiskStateManager and GitStateManager check
+// for changes to the state they are watching.
+const StatePollInterval = 10 * time.Second
+
+// Watch implements sous.StateWatcher on DiskStateManager, by polling the
+// modification times of the files under BaseDir.
+func (dsm *DiskStateManager) Watch(done <-chan struct{}) (<-chan sous.StateChange, error) {
+	return sous.PollState(done, StatePollInterval, dsm.revision, logging.Log), nil
+}
+
+// revision summarises the files under BaseDir, so that it changes when any
+// of them do.
+func (dsm *DiskStateManager) revision() (string, error) {
+	var count int
+	var latest time.Time
+	err := filepath.Walk(dsm.BaseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		count++
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "checking %s for changes", dsm.BaseDir)
+	}
+	return fmt.Sprintf("%d@%d", count, latest.UnixNano()), nil
+}
+
+// Watch implements sous.StateWatcher on GitStateManager, by fetching from
+// the remote, and reporting a change whenever the local or the remote branch
+// moves. If BaseDir is not a git repo, its files are watched instead.
+func (gsm *GitStateManager) Watch(done <-chan struct{}) (<-chan sous.StateChange, error) {
+	return sous.PollState(done, StatePollInterval, gsm.revision, logging.Log), nil
+}
+
+func (gsm *GitStateManager) revision() (string, error) {
+	if !gsm.isRepo() {
+		gsm.Lock()
+		defer gsm.Unlock()
+		return gsm.DiskStateManager.revision()
+	}
+	// Fetching waits on the remote, so it is done without the lock, which
+	// would hold up reads and writes of the state meanwhile. It only moves
+	// the remote tracking branch, and git locks that ref itself.
+	// Without a remote, there is nothing to fetch, and only HEAD can move.
+	fetchErr := gsm.git("fetch", "--quiet")
+
+	gsm.Lock()
+	defer gsm.Unlock()
+	head, err := gsm.headRev()
+	if err != nil {
+		return "", err
+	}
+	if fetchErr != nil {
+		return head, nil
+	}
+	upstream, err := gsm.gitOut("rev-parse", "--verify", "--quiet", "@{upstream}")
+	if err != nil {
+		return head, nil
+	}
+	return head + " " + strings.TrimSpace(upstream), nil
+}
+
+// Watch implements sous.StateWatcher on DuplexStateManager, by watching both
+// its primary and secondary StateManagers, where they can be watched.
+func (dup *DuplexStateManager) Watch(done <-chan struct{}) (<-chan sous.StateChange, error) {
+	var ws []sous.StateWatcher
+	for _, sm := range []sous.StateManager{dup.primary, dup.secondary} {
+		if w, ok := sm.(sous.StateWatcher); ok {
+			ws = append(ws, w)
+		}
+	}
+	if len(ws) == 0 {
+		return nil, errors.New("neither primary nor secondary state can be watched")
+	}
+	return sous.MergeStateWatchers(dup.log, ws...).Watch(done)
+}
@@ -1,11 +1,14 @@
 package graph
 
 import (
+	"io"
 	"os"
 
 	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/storage"
 	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
 	"github.com/samsalisbury/semv"
 )
 
@@ -65,6 +68,47 @@ func (di *SousGraph) GetRectify(dryrun string, dff config.DeployFilterFlags) (ac
 	}, nil
 }
 
+// GetPlanUpdate produces an Action which plans the GDM changes that GetUpdate's
+// Action would make.
+func (di *SousGraph) GetPlanUpdate(dff config.DeployFilterFlags, otpl config.OTPLFlags) (*actions.PlanUpdate, error) {
+	di.guardedAdd("DeployFilterFlags", &dff)
+	di.guardedAdd("OTPLFlags", &otpl)
+	di.guardedAdd("Dryrun", DryrunNeither)
+
+	scoop := struct {
+		Manifest         TargetManifest
+		HTTPStateManager *sous.HTTPStateManager
+		ResolveFilter    *RefinedResolveFilter
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return &actions.PlanUpdate{
+		Manifest:      scoop.Manifest.Manifest,
+		StateReader:   scoop.HTTPStateManager,
+		ResolveFilter: (*sous.ResolveFilter)(scoop.ResolveFilter),
+	}, nil
+}
+
+// GetPlanRectify produces an Action which plans the changes that GetRectify's
+// Action would make.
+func (di *SousGraph) GetPlanRectify(dryrun string, dff config.DeployFilterFlags) (*actions.PlanRectify, error) {
+	di.guardedAdd("Dryrun", DryrunOption(dryrun))
+	di.guardedAdd("DeployFilterFlags", &dff)
+
+	scoop := struct {
+		Resolver *sous.Resolver
+		State    *sous.State
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return &actions.PlanRectify{
+		Resolver: scoop.Resolver,
+		State:    scoop.State,
+	}, nil
+}
+
 // GetPollStatus produces an Action to poll the status of a deployment.
 func (di *SousGraph) GetPollStatus(dryrun string, dff config.DeployFilterFlags) (actions.Action, error) {
 	di.guardedAdd("Dryrun", DryrunOption(dryrun))
@@ -83,6 +127,43 @@ func (di *SousGraph) GetPollStatus(dryrun string, dff config.DeployFilterFlags)
 	}, nil
 }
 
+// GetGDMWatcher returns a sous.StateWatcher which reports changes to the GDM
+// held by the Sous server.
+func (di *SousGraph) GetGDMWatcher() (sous.StateWatcher, error) {
+	scoop := struct {
+		HTTPStateManager *sous.HTTPStateManager
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return scoop.HTTPStateManager, nil
+}
+
+// GetRemoteBuilder returns a sous.RemoteBuilder which has the Sous server
+// build source, copying the output of each build to out.
+func (di *SousGraph) GetRemoteBuilder(out io.Writer) (*sous.RemoteBuilder, error) {
+	cfgScoop := struct{ Config LocalSousConfig }{}
+	if err := di.Inject(&cfgScoop); err != nil {
+		return nil, err
+	}
+	if cfgScoop.Config.Server == "" {
+		return nil, errors.New("remote builds need a Sous server: set SOUS_SERVER or Server in your config")
+	}
+
+	scoop := struct {
+		HTTPClient HTTPClient
+		User       sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return &sous.RemoteBuilder{
+		Client: scoop.HTTPClient.HTTPClient,
+		User:   scoop.User,
+		Out:    out,
+	}, nil
+}
+
 // GetServer returns the server action.
 func (di *SousGraph) GetServer(
 	dff config.DeployFilterFlags,
@@ -106,6 +187,9 @@ func (di *SousGraph) GetServer(
 		Config        *config.Config
 		ServerHandler ServerHandler
 		AutoResolver  *sous.AutoResolver
+		QueueSet      *sous.R11nQueueSet
+		Snapshotter   *sous.Snapshotter
+		Verifier      *storage.StorageVerifier
 	}{}
 
 	if err := di.Inject(&scoop); err != nil {
@@ -126,5 +210,8 @@ func (di *SousGraph) GetServer(
 		Config:            scoop.Config,
 		ServerHandler:     scoop.ServerHandler.Handler,
 		AutoResolver:      ar,
+		QueueSet:          scoop.QueueSet,
+		Snapshotter:       scoop.Snapshotter,
+		StorageVerifier:   scoop.Verifier,
 	}, nil
 }
@@ -13,6 +13,8 @@ import (
 	"github.com/opentable/sous/ext/docker"
 	"github.com/opentable/sous/ext/git"
 	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/secrets"
 	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
@@ -240,6 +242,7 @@ func AddDocker(graph adder) {
 		newNameCache,
 		newDockerBuilder,
 		newSelector,
+		newBuildCache,
 	)
 }
 
@@ -247,6 +250,7 @@ func AddDocker(graph adder) {
 func AddSingularity(graph adder) {
 	graph.Add(
 		newDeployer,
+		newDeployHistorian,
 	)
 }
 
@@ -292,6 +296,15 @@ func AddInternals(graph adder) {
 		newHTTPClientBundle,
 		newClusterSpecificHTTPClient,
 		NewR11nQueueSet,
+		newHistory,
+		newPromoter,
+		newNotifier,
+		newAuthenticator,
+		newAuthorizer,
+		newBuildQueue,
+		newStateChanges,
+		newSnapshotter,
+		newStorageVerifier,
 	)
 }
 
@@ -303,8 +316,12 @@ func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, l
 	return sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
 }
 
-func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, ls LogSink) *sous.AutoResolver {
-	return sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, sc *sous.StateChanges, p *sous.Promoter, n *sous.Notifier, ls LogSink) *sous.AutoResolver {
+	ar := sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+	ar.WatchState(sc)
+	ar.AfterResolve(p.HandleResolve)
+	ar.AfterResolve(n.HandleResolve)
+	return ar
 }
 
 func newSourceHostChooser() sous.SourceHostChooser {
@@ -430,7 +447,11 @@ func newBuildContext(wd LocalWorkDirShell, c *sous.SourceContext) *sous.BuildCon
 	return &sous.BuildContext{Sh: sh, Source: *c}
 }
 
-func newBuildConfig(f *config.DeployFilterFlags, p *config.PolicyFlags, bc *sous.BuildContext) *sous.BuildConfig {
+func newBuildConfig(f *config.DeployFilterFlags, p *config.PolicyFlags, bc *sous.BuildContext) (*sous.BuildConfig, error) {
+	platforms, err := sous.ParsePlatforms(p.Platforms)
+	if err != nil {
+		return nil, err
+	}
 	offset := f.Offset
 	if offset == "" {
 		offset = bc.Source.OffsetDir
@@ -442,11 +463,12 @@ func newBuildConfig(f *config.DeployFilterFlags, p *config.PolicyFlags, bc *sous
 		Revision:   f.Revision,
 		Strict:     p.Strict,
 		ForceClone: p.ForceClone,
+		Platforms:  platforms,
 		Context:    bc,
 	}
 	cfg.Resolve()
 
-	return &cfg
+	return &cfg, nil
 }
 
 func newBuildManager(bc *sous.BuildConfig, sl sous.Selector, lb sous.Labeller, rg sous.Registrar) *sous.BuildManager {
@@ -489,15 +511,24 @@ func newLocalWorkDirShell(verbosity *config.Verbosity, l LocalWorkDir) (v LocalW
 	return v, initErr(err, "getting current working directory")
 }
 
-func newSelector(regClient LocalDockerClient, log LogSink) sous.Selector {
-	return docker.NewBuildStrategySelector(log.Child("docker-build-strategy"), regClient)
+func newSelector(cfg LocalSousConfig, regClient LocalDockerClient, bc *docker.BuildCache, log LogSink) sous.Selector {
+	return docker.NewBuildStrategySelector(log.Child("docker-build-strategy"), regClient, bc, cfg.Docker.BuildpackDir)
+}
+
+func newBuildCache(cfg LocalSousConfig, nc *docker.NameCache, log LogSink) *docker.BuildCache {
+	return docker.NewBuildCache(cfg.Docker, cfg.BuildStateDir, nc, log.Child("build-cache"))
 }
 
-func newDockerBuilder(cfg LocalSousConfig, nc *docker.NameCache, ctx *sous.SourceContext, source LocalWorkDirShell, scratch ScratchDirShell) (*docker.Builder, error) {
+func newDockerBuilder(cfg LocalSousConfig, nc *docker.NameCache, cl LocalDockerClient, ctx *sous.SourceContext, source LocalWorkDirShell, scratch ScratchDirShell) (*docker.Builder, error) {
 	drh := cfg.Docker.RegistryHost
 	source.Sh = source.Sh.Clone().(*shell.Sh)
 	source.Sh.LongRunning(true)
-	return docker.NewBuilder(nc, drh, source.Sh, scratch.Sh)
+	b, err := docker.NewBuilder(nc, drh, source.Sh, scratch.Sh)
+	if err != nil || cfg.Docker.SigningKey == "" {
+		return b, err
+	}
+	b.Signer, err = docker.NewSigner(cfg.Docker.SigningKey, cl.Client)
+	return b, initErr(err, "loading signing key")
 }
 
 func newLabeller(db *docker.Builder) sous.Labeller {
@@ -515,27 +546,48 @@ func newRegistry(graph *SousGraph, nc lazyNameCache, dryrun DryrunOption) (sous.
 	return nc()
 }
 
+// newDeployHistorian returns a sous.DeployHistorian for the clusters in the
+// current state.
+func newDeployHistorian(state *sous.State, r sous.Registry, ls LogSink) sous.DeployHistorian {
+	return singularity.NewDeployHistorian(state.Defs.Clusters, r, ls.Child("deploy-history"))
+}
+
 func newDeployer(dryrun DryrunOption, nc lazyNameCache, ls LogSink, c LocalSousConfig) (sous.Deployer, error) {
-	// Eventually, based on configuration, we may make different decisions here.
+	var sing sous.Deployer
+	kubeOpts := []kubernetes.DeployerOption{
+		kubernetes.OptNamespace(c.Kubernetes.Namespace),
+		kubernetes.OptBearerToken(c.Kubernetes.BearerToken),
+	}
 	if dryrun == DryrunBoth || dryrun == DryrunScheduler {
 		drc := sous.NewDummyRectificationClient()
 		drc.SetLogger(ls.Child("rectify"))
-		return singularity.NewDeployer(
+		sing = singularity.NewDeployer(
 			drc,
 			ls.Child("singularity-deployer"),
 			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-		), nil
-	}
-	// We need the real name cache.
-	nameCache, err := nc()
-	if err != nil {
-		return nil, err
+		)
+		kubeOpts = append(kubeOpts, kubernetes.OptDryRun())
+	} else {
+		// We need the real name cache.
+		nameCache, err := nc()
+		if err != nil {
+			return nil, err
+		}
+		sp, err := secrets.NewProvider(c.Secrets)
+		if err != nil {
+			return nil, err
+		}
+		sing = singularity.NewDeployer(
+			singularity.NewRectiAgent(nameCache, sp),
+			ls,
+			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+		)
 	}
-	return singularity.NewDeployer(
-		singularity.NewRectiAgent(nameCache),
-		ls,
-		singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-	), nil
+	kube := kubernetes.NewDeployer(ls.Child("kubernetes-deployer"), kubeOpts...)
+	return sous.NewDispatchDeployer("singularity", map[string]sous.Deployer{
+		"singularity":          sing,
+		kubernetes.ClusterKind: kube,
+	}), nil
 }
 
 func newServerHandler(g *SousGraph, ComponentLocator server.ComponentLocator, metrics MetricsHandler, log LogSink) ServerHandler {
@@ -558,10 +610,25 @@ func newServerListData(c HTTPClient) (ServerListData, error) {
 	return serverList, err
 }
 
-func newHTTPClientBundle(serverList ServerListData, log LogSink) (ClientBundle, error) {
+// newRESTClient returns a client for the Sous server at serverURL, presenting
+// the credentials in c.ClientAuth.
+func newRESTClient(serverURL string, c LocalSousConfig, ls logging.LogSink) (*restful.LiveHTTPClient, error) {
+	cl, err := restful.NewClient(serverURL, ls)
+	if err != nil {
+		return nil, err
+	}
+	return cl, cl.SetCredentials(restful.Credentials{
+		BearerToken: c.ClientAuth.Token,
+		CertFile:    c.ClientAuth.CertFile,
+		KeyFile:     c.ClientAuth.KeyFile,
+		CAFile:      c.ClientAuth.CAFile,
+	})
+}
+
+func newHTTPClientBundle(serverList ServerListData, c LocalSousConfig, log LogSink) (ClientBundle, error) {
 	bundle := ClientBundle{}
 	for _, s := range serverList.Servers {
-		client, err := restful.NewClient(s.URL, log.Child(s.ClusterName+".http-client"))
+		client, err := newRESTClient(s.URL, c, log.Child(s.ClusterName+".http-client"))
 		if err != nil {
 			return nil, err
 		}
@@ -597,7 +664,7 @@ func newHTTPClient(c LocalSousConfig, user sous.User, srvr ServerHandler, log Lo
 		return HTTPClient{HTTPClient: cl}, err
 	}
 	messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("Using server %s", c.Server), logging.ExtraDebug1Level, log)
-	cl, err := restful.NewClient(c.Server, log.Child("http-client"))
+	cl, err := newRESTClient(c.Server, c, log.Child("http-client"))
 	return HTTPClient{HTTPClient: cl}, err
 }
 
@@ -615,8 +682,22 @@ func newServerStateManager(c LocalSousConfig, rf *sous.ResolveFilter, log LogSin
 	}
 
 	dm := storage.NewDiskStateManager(c.StateLocation)
-	gm := storage.NewGitStateManager(dm)
-	duplex := storage.NewDuplexStateManager(gm, secondary, log.Child("duplex-state"))
+	var primary sous.StateManager = storage.NewGitStateManager(dm)
+	if c.ObjectStore.Bucket != "" {
+		objects, err := c.ObjectStore.S3()
+		if err != nil {
+			logging.ReportError(log, errors.Wrapf(err, "connecting to object store bucket %q at %q", c.ObjectStore.Bucket, c.ObjectStore.Endpoint))
+		} else {
+			osm := storage.NewObjectStoreStateManager(objects, c.ObjectStore.Prefix, log.Child("object-store"))
+			if c.ObjectStore.Primary {
+				primary = osm
+			} else {
+				secondary = storage.NewDuplexStateManager(secondary, osm, log.Child("object-store-state"))
+			}
+		}
+	}
+
+	duplex := storage.NewDuplexStateManager(primary, secondary, log.Child("duplex-state"))
 	return &ServerStateManager{StateManager: duplex}
 }
 
@@ -626,11 +707,11 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, rf *sous.ResolveFilter
 		return nil, fmt.Errorf("cluster: %s", err) // errors.Wrapf && cli don't play nice
 	}
 
-	local := storage.NewPostgresStateManager(db, log.Child("database"))
+	local := storage.NewPostgresStateManager(db, log.Child("database")).ListenWith(c.Database)
 	list := ClientBundle{}
 	clusterNames := []string{}
 	for n, u := range c.SiblingURLs {
-		cl, err := restful.NewClient(u, log.Child(n+".http-client"))
+		cl, err := newRESTClient(u, c, log.Child(n+".http-client"))
 		if err != nil {
 			return nil, err
 		}
@@ -647,7 +728,8 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, rf *sous.ResolveFilter
 func newStateManager(cl HTTPClient, c LocalSousConfig, bundle ClientBundle, rf *sous.ResolveFilter, log LogSink) *StateManager {
 	if c.Server == "" {
 		messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("Using local state stored at %s", c.StateLocation), logging.WarningLevel, log, c.StateLocation)
-		return &StateManager{StateManager: newServerStateManager(c, rf, log).StateManager}
+		ssm := newServerStateManager(c, rf, log)
+		return &StateManager{StateManager: mergingStateManager(ssm.StateManager, ssm.StateManager, log)}
 	}
 	hsm := sous.NewHTTPStateManager(cl, bundle)
 	return &StateManager{StateManager: hsm}
@@ -696,11 +778,25 @@ func NewCurrentGDM(state *sous.State) (CurrentGDM, error) {
 // The funcs named makeXXX below are used to create specific implementations of
 // sous native types.
 
-func newInserter(cfg LocalSousConfig, nc lazyNameCache) (sous.Inserter, error) {
+func newInserter(cfg LocalSousConfig, nc lazyNameCache, log LogSink) (sous.Inserter, error) {
 	if cfg.Server == "" {
 		return nc()
 	}
-	return sous.NewHTTPNameInserter(cfg.Server)
+	hni, err := sous.NewHTTPNameInserter(cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	// The server authenticates inserts, so they present the same credentials
+	// as the rest of the client's requests.
+	cl, err := newRESTClient(cfg.Server, cfg, log.Child("name-inserter"))
+	if err != nil {
+		return nil, err
+	}
+	hni.Client = cl.Client
+	if cfg.ClientAuth.Token != "" {
+		hni.Header.Set("Authorization", "Bearer "+cfg.ClientAuth.Token)
+	}
+	return hni, nil
 }
 
 // initErr returns nil if error is nil, otherwise an initialisation error.
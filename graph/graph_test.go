@@ -151,6 +151,15 @@ func injectedStateManager(t *testing.T, cfg *config.Config) *StateManager {
 	g.Add(newHTTPClient)
 	g.Add(newHTTPClientBundle)
 	g.Add(NewR11nQueueSet)
+	g.Add(newHistory)
+	g.Add(newPromoter)
+	g.Add(newNotifier)
+	g.Add(newAuthenticator)
+	g.Add(newAuthorizer)
+	g.Add(newBuildQueue)
+	g.Add(newStateChanges)
+	g.Add(newSnapshotter)
+	g.Add(newStorageVerifier)
 	g.Add(rff)
 	g.Add(g)
 
@@ -184,15 +193,18 @@ func TestStateManagerSelectsServer(t *testing.T) {
 func TestStateManagerSelectsDuplex(t *testing.T) {
 	smgr := injectedStateManager(t, &config.Config{Server: "", StateLocation: "/tmp/sous"})
 
-	_, ok := smgr.StateManager.(*storage.DuplexStateManager)
+	msm, ok := smgr.StateManager.(*sous.MergingStateManager)
 	if !ok {
-		t.Errorf("Injected %#v which isn't a DuplexStateManager", smgr.StateManager)
+		t.Fatalf("Injected %#v which isn't a MergingStateManager", smgr.StateManager)
+	}
+	if _, ok := msm.StateManager.(*storage.DuplexStateManager); !ok {
+		t.Errorf("Injected %#v which doesn't wrap a DuplexStateManager", msm.StateManager)
 	}
 }
 
 func TestNewBuildConfig(t *testing.T) {
 	f := &config.DeployFilterFlags{}
-	p := &config.PolicyFlags{}
+	p := &config.PolicyFlags{Platforms: "linux/amd64,linux/arm64"}
 	bc := &sous.BuildContext{
 		Sh: &shell.Sh{},
 		Source: sous.SourceContext{
@@ -210,12 +222,22 @@ func TestNewBuildConfig(t *testing.T) {
 		},
 	}
 
-	cfg := newBuildConfig(f, p, bc)
+	cfg, err := newBuildConfig(f, p, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if cfg.Tag != `1.2.3` {
 		t.Errorf("Build config's tag wasn't 1.2.3: %#v", cfg.Tag)
 	}
 	if err := cfg.Validate(); err != nil {
 		t.Errorf("Not valid build config: %+v", err)
 	}
+	if len(cfg.Platforms) != 2 {
+		t.Errorf("Build config's platforms weren't parsed: %#v", cfg.Platforms)
+	}
 
+	p.Platforms = "amd64"
+	if _, err := newBuildConfig(f, p, bc); err == nil {
+		t.Errorf("Expected an error for an invalid platform")
+	}
 }
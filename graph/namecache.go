@@ -34,5 +34,15 @@ func generateNameCache(cfg LocalSousConfig, ls LogSink, cl LocalDockerClient) (*
 		return nil, errors.Wrap(err, "building name cache DB")
 	}
 	drh := cfg.Docker.RegistryHost
-	return docker.NewNameCache(drh, cl.Client, ls.Child("docker-images"), db)
+	nc, err := docker.NewNameCache(drh, cl.Client, ls.Child("docker-images"), db)
+	if err != nil {
+		return nil, err
+	}
+	if keys := cfg.Docker.VerificationKeyPaths(); len(keys) > 0 {
+		nc.Verifier, err = docker.NewVerifier(keys, cl.Client)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading verification keys")
+		}
+	}
+	return nc, nil
 }
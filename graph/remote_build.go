@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/git"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/shell"
+	"github.com/pkg/errors"
+)
+
+// remoteBuilder builds the source named by BuildRequests on the server, the
+// same way `sous build` does on a client.
+type remoteBuilder struct {
+	cfg    LocalSousConfig
+	nc     lazyNameCache
+	client LocalDockerClient
+	log    LogSink
+}
+
+// newBuildQueue returns the queue of builds requested by clients, or nil if
+// remote builds are turned off.
+func newBuildQueue(cfg LocalSousConfig, nc lazyNameCache, cl LocalDockerClient, ls LogSink) *sous.BuildQueue {
+	if cfg.BuildWorkers < 1 {
+		return nil
+	}
+	rb := &remoteBuilder{cfg: cfg, nc: nc, client: cl, log: ls}
+	return sous.NewBuildQueue(cfg.BuildWorkers, sous.BuildQueueCapDefault, rb.build)
+}
+
+// build clones the repo of req into a temporary directory, checks out its
+// revision, and builds and registers it.
+func (rb *remoteBuilder) build(req sous.BuildRequest, out io.Writer) (*sous.BuildResult, error) {
+	if err := req.Validate(rb.cfg.BuildRepoHosts); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "sous-remote-build")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	src, scratch := filepath.Join(dir, "src"), filepath.Join(dir, "scratch")
+	if err := os.Mkdir(scratch, 0700); err != nil {
+		return nil, err
+	}
+
+	sh, err := shell.DefaultInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sh.TeeEcho, sh.TeeOut, sh.TeeErr = out, out, out
+	gc, err := git.NewClient(sh)
+	if err != nil {
+		return nil, err
+	}
+	sid := req.SourceID
+	cloneURL := "https://" + sid.Location.Repo
+	fmt.Fprintf(out, "Cloning %s at %s\n", cloneURL, sid.RevID())
+	if err := gc.CloneRepo(cloneURL, src); err != nil {
+		return nil, errors.Wrapf(err, "cloning %s", cloneURL)
+	}
+	if err := sh.CD(src); err != nil {
+		return nil, err
+	}
+	if err := gc.Checkout(sid.RevID()); err != nil {
+		return nil, errors.Wrapf(err, "checking out %s", sid.RevID())
+	}
+	repo, err := git.NewRepo(gc)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := repo.SourceContext()
+	if err != nil {
+		return nil, err
+	}
+
+	source := sh.Clone().(*shell.Sh)
+	source.LongRunning(true)
+	scratchSh, err := shell.DefaultInDir(scratch)
+	if err != nil {
+		return nil, err
+	}
+	scratchSh.TeeEcho, scratchSh.TeeOut, scratchSh.TeeErr = out, out, out
+
+	nc, err := rb.nc()
+	if err != nil {
+		return nil, err
+	}
+	builder, err := docker.NewBuilder(nc, rb.cfg.Docker.RegistryHost, source, scratchSh)
+	if err != nil {
+		return nil, err
+	}
+	if rb.cfg.Docker.RemoteSigningKey != "" {
+		if builder.Signer, err = docker.NewSigner(rb.cfg.Docker.RemoteSigningKey, rb.client.Client); err != nil {
+			return nil, errors.Wrap(err, "loading signing key")
+		}
+	}
+	cache := docker.NewBuildCache(rb.cfg.Docker, rb.cfg.BuildStateDir, nc, rb.log.Child("build-cache"))
+	bm := &sous.BuildManager{
+		BuildConfig: &sous.BuildConfig{
+			Repo:      sid.Location.Repo,
+			Offset:    sid.Location.Dir,
+			Tag:       sid.Version.Format("M.m.p-?"),
+			Revision:  sid.RevID(),
+			Strict:    req.Strict,
+			Platforms: req.Platforms,
+			Context:   &sous.BuildContext{Sh: source, Source: *sc},
+		},
+		Selector:  docker.NewBuildStrategySelector(rb.log.Child("docker-build-strategy"), rb.client, cache, rb.cfg.Docker.BuildpackDir),
+		Labeller:  builder,
+		Registrar: builder,
+	}
+	return bm.Build()
+}
@@ -1,37 +1,187 @@
 package graph
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/opentable/sous/ext/storage"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
 	"github.com/samsalisbury/semv"
 )
 
-func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserter, sm *ServerStateManager, rf *sous.ResolveFilter, ar *sous.AutoResolver, v semv.Version, qs *sous.R11nQueueSet) server.ComponentLocator {
-	cm := sous.MakeClusterManager(sm.StateManager)
-	dm := sous.MakeDeploymentManager(sm.StateManager)
+func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserter, reg sous.Registry, ssm *ServerStateManager, rf *sous.ResolveFilter, ar *sous.AutoResolver, v semv.Version, qs *sous.R11nQueueSet, h sous.History, p *sous.Promoter, n *sous.Notifier, authn server.Authenticator, authz *server.Authorizer, bq *sous.BuildQueue, sc *sous.StateChanges, sn *sous.Snapshotter, sv *storage.StorageVerifier) server.ComponentLocator {
+	sm := newWritingStateManager(ssm, h, sc, ls)
+	cm := sous.MakeClusterManager(sm)
+	dm := sous.MakeDeploymentManager(sm)
 	return server.ComponentLocator{
 
 		LogSink:           ls.LogSink,
 		Config:            cfg.Config,
 		Inserter:          ins,
-		StateManager:      sm.StateManager,
+		Registry:          reg,
+		StateManager:      sm,
 		ClusterManager:    cm,
 		DeploymentManager: dm,
 		ResolveFilter:     rf,
 		AutoResolver:      ar,
 		Version:           v,
 		QueueSet:          qs,
+		History:           h,
+		Promoter:          p,
+		Notifier:          n,
+		Authenticator:     authn,
+		Authorizer:        authz,
+		BuildQueue:        bq,
+		StateChanges:      sc,
+		Snapshotter:       sn,
+		StorageVerifier:   sv,
 	}
 
 }
 
 // NewR11nQueueSet returns a new queue set configured to start processing r11ns
-// immediately.
-func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager) *sous.R11nQueueSet {
+// immediately. If the database is available, queued r11ns are stored there so
+// they can be resumed after a restart. The outcome of each r11n is recorded in
+// the GDM history, and sent to any webhooks by n, if it is not nil.
+func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager, c LocalSousConfig, h sous.History, n *sous.Notifier, ls LogSink) *sous.R11nQueueSet {
 	sr := sm.StateManager
-	return sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(
+	handler := sous.R11nQueueStartWithHandler(
 		func(qr *sous.QueuedR11n) sous.DiffResolution {
+			if n != nil {
+				n.RectificationStarted(&qr.Rectification.Pair)
+			}
 			qr.Rectification.Begin(d, r, rf, sr)
-			return qr.Rectification.Wait()
-		}))
+			dr := qr.Rectification.Wait()
+			if err := h.RecordOutcome(qr.Rectification.Pair.ID(), dr); err != nil {
+				messages.ReportLogFieldsMessage("Failed to record rectification outcome in history", logging.WarningLevel, ls, dr, err)
+			}
+			if n != nil {
+				n.RectificationFinished(&qr.Rectification.Pair, dr)
+			}
+			return dr
+		})
+	db, err := c.Database.DB()
+	if err != nil {
+		messages.ReportLogFieldsMessage("Database unavailable, deploy queues will not survive restarts", logging.WarningLevel, ls, err)
+		return sous.NewR11nQueueSet(handler)
+	}
+	return sous.NewPersistentR11nQueueSet(storage.NewPostgresR11nStore(db, ls.Child("r11n-store")), handler)
+}
+
+// newHistory returns a sous.History stored in the database if it is available,
+// or else in memory.
+func newHistory(c LocalSousConfig, ls LogSink) sous.History {
+	db, err := c.Database.DB()
+	if err != nil {
+		messages.ReportLogFieldsMessage("Database unavailable, GDM history will not survive restarts", logging.WarningLevel, ls, err)
+		return sous.NewMemoryHistory()
+	}
+	return storage.NewPostgresHistory(db, ls.Child("history"))
+}
+
+// newSnapshotter returns the sous.Snapshotter which takes snapshots of the
+// server's state, stored in the database if it is available, or else in
+// memory.
+func newSnapshotter(c LocalSousConfig, ssm *ServerStateManager, ls LogSink) *sous.Snapshotter {
+	interval := time.Duration(c.SnapshotInterval) * time.Minute
+	db, err := c.Database.DB()
+	if err != nil {
+		messages.ReportLogFieldsMessage("Database unavailable, GDM snapshots will not survive restarts", logging.WarningLevel, ls, err)
+		return sous.NewSnapshotter(sous.NewMemorySnapshotStore(), ssm.StateManager, interval, ls.Child("snapshots"))
+	}
+	store := storage.NewPostgresSnapshotStore(db, ls.Child("snapshots"))
+	return sous.NewSnapshotter(store, ssm.StateManager, interval, ls.Child("snapshots"))
+}
+
+// newStorageVerifier returns the storage.StorageVerifier which checks that
+// the server's local Postgres storage agrees with its primary storage, or nil
+// if its secondary storage is not Postgres.
+func newStorageVerifier(c LocalSousConfig, ssm *ServerStateManager, rf *sous.ResolveFilter, ls LogSink) *storage.StorageVerifier {
+	dup, ok := ssm.StateManager.(*storage.DuplexStateManager)
+	if !ok {
+		return nil
+	}
+	local := localPostgres(dup.Secondary())
+	if local == nil {
+		return nil
+	}
+	cluster, err := rf.Cluster.Value()
+	if err != nil {
+		return nil
+	}
+	interval := time.Duration(c.StorageVerifyInterval) * time.Minute
+	return storage.NewStorageVerifier(dup.Primary(), local, cluster, interval, c.StorageVerifyRepair, ls.Child("storage-verify"))
+}
+
+// localPostgres returns the local Postgres storage within sm, the secondary
+// storage built by newServerStateManager, or nil if it has none.
+func localPostgres(sm sous.StateManager) *storage.PostgresStateManager {
+	switch sm := sm.(type) {
+	case *storage.DuplexStateManager:
+		// An object store is the secondary of the secondary storage.
+		return localPostgres(sm.Primary())
+	case *sous.DispatchStateManager:
+		local, _ := sm.Local().(*storage.PostgresStateManager)
+		return local
+	}
+	return nil
+}
+
+// newPromoter returns a sous.Promoter which records its promotions in the GDM
+// history.
+func newPromoter(ssm *ServerStateManager, h sous.History, sc *sous.StateChanges, ls LogSink) *sous.Promoter {
+	return sous.NewPromoter(newWritingStateManager(ssm, h, sc, ls), ls.Child("promoter"))
+}
+
+// newWritingStateManager returns the StateManager which the server's writes
+// to the GDM go through. A stale state is merged with the changes made since
+// it was read before anything else sees it, so that the same state is
+// recorded in the GDM history, reported to watchers, and written to both
+// storages.
+func newWritingStateManager(ssm *ServerStateManager, h sous.History, sc *sous.StateChanges, ls LogSink) sous.StateManager {
+	rsm := sous.ReportingStateManager{StateManager: ssm.StateManager, Changes: sc}
+	sm := sous.NewHistoryStateManager(rsm, h, ls.Child("history"))
+	return mergingStateManager(sm, ssm.StateManager, ls)
+}
+
+// mergingStateManager wraps sm in a sous.MergingStateManager which reads the
+// states stale ones were read at from stored, if it can read them.
+func mergingStateManager(sm, stored sous.StateManager, ls LogSink) sous.StateManager {
+	versions, ok := stored.(sous.StateVersions)
+	if !ok {
+		return sm
+	}
+	return sous.NewMergingStateManager(sm, versions, ls.Child("state-merge"))
+}
+
+// newNotifier returns a sous.Notifier which sends notifications to the
+// webhooks defined in the GDM.
+func newNotifier(ssm *ServerStateManager, c LocalSousConfig, ls LogSink) *sous.Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return sous.NewNotifier(ssm.StateManager, client, c.WebhookHosts, ls.Child("notifier"))
+}
+
+// newAuthenticator returns the server.Authenticator used to identify the users
+// of the server.
+func newAuthenticator(c LocalSousConfig) (server.Authenticator, error) {
+	return server.NewAuthenticator(c.Auth)
+}
+
+// newAuthorizer returns the server.Authorizer used to check changes to
+// manifests, which is nil unless authorization is turned on.
+func newAuthorizer(c LocalSousConfig) *server.Authorizer {
+	return server.NewAuthorizer(c.Auth)
+}
+
+// newStateChanges returns the sous.StateChanges which reports changes to the
+// server's state, as seen by its StateManager, if that can be watched.
+func newStateChanges(ssm *ServerStateManager, ls LogSink) *sous.StateChanges {
+	var w sous.StateWatcher
+	if sw, ok := ssm.StateManager.(sous.StateWatcher); ok {
+		w = sw
+	}
+	return sous.NewStateChanges(w, ls.Child("state-changes"))
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/docker"
 	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/graph"
@@ -167,7 +168,7 @@ func (suite *integrationSuite) BeforeTest(suiteName, testName string) {
 
 	suite.T().Logf("New name cache for %q", testName)
 	suite.nameCache = suite.newNameCache(testName)
-	suite.client = singularity.NewRectiAgent(suite.nameCache)
+	suite.client = singularity.NewRectiAgent(suite.nameCache, nil)
 	suite.deployer = singularity.NewDeployer(suite.client, logset)
 }
 
@@ -417,7 +418,9 @@ func (suite *integrationSuite) TestMissingImage() {
 	rf := &sous.ResolveFilter{}
 	sr := sous.NewDummyStateManager()
 	sr.State = &stateOne
-	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+	ls := graph.LogSink{LogSink: logging.SilentLogSet()}
+	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr},
+		graph.LocalSousConfig{Config: &config.Config{}}, sous.NewMemoryHistory(), nil, ls)
 	r := sous.NewResolver(suite.deployer, suite.nameCache, rf, logging.SilentLogSet(), qs)
 
 	deploymentsOne, err := stateOne.Deployments()
@@ -477,7 +480,8 @@ func (suite *integrationSuite) TestResolve() {
 	rf := &sous.ResolveFilter{}
 	sr := sous.NewDummyStateManager()
 	sr.State = &stateOneTwo
-	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+	qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr},
+		graph.LocalSousConfig{Config: &config.Config{}}, sous.NewMemoryHistory(), nil, graph.LogSink{LogSink: logsink})
 	r := sous.NewResolver(suite.deployer, suite.nameCache, rf, logsink, qs)
 
 	suite.T().Log("Begining OneTwo")
@@ -543,13 +547,14 @@ func (suite *integrationSuite) TestResolve() {
 	// XXX Let's hope this is a temporary solution to a testing issue
 	// The problem is laid out in DCOPS-7625
 	for tries := 100; tries > 0; tries-- {
-		client := singularity.NewRectiAgent(suite.nameCache)
+		client := singularity.NewRectiAgent(suite.nameCache, nil)
 		deployer := singularity.NewDeployer(client, logging.SilentLogSet())
 
 		rf := &sous.ResolveFilter{}
 		sr := sous.NewDummyStateManager()
 		sr.State = &stateOneTwo
-		qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr})
+		qs := graph.NewR11nQueueSet(suite.deployer, suite.nameCache, rf, &graph.ServerStateManager{sr},
+			graph.LocalSousConfig{Config: &config.Config{}}, sous.NewMemoryHistory(), nil, graph.LogSink{LogSink: logging.SilentLogSet()})
 		r := sous.NewResolver(deployer, suite.nameCache, rf, logging.SilentLogSet(), qs)
 
 		err := r.Begin(deploymentsTwoThree, clusterDefs.Clusters).Wait()
@@ -0,0 +1,296 @@
+package sous
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// An ArtifactPolicy restricts which artifacts may be deployed to a cluster,
+	// beyond the advisories the cluster allows. Each field left empty imposes
+	// no restriction.
+	ArtifactPolicy struct {
+		// MaxAge is the oldest an artifact may be, as a Go duration, e.g.
+		// "720h".
+		MaxAge string `yaml:",omitempty"`
+		// AllowedBaseImages lists the base images artifacts may be built from.
+		// A base image is allowed if it starts with any of them, so that
+		// e.g. "docker.example.com/base/" allows all the images in a
+		// repository.
+		AllowedBaseImages []string `yaml:",omitempty"`
+		// RequiredLabels lists the labels every artifact must have.
+		RequiredLabels []string `yaml:",omitempty"`
+		// ScanReport is the path, on the server, of a JSON ScanReport from an
+		// external vulnerability scanner. Artifacts must appear in the report,
+		// with no vulnerability more severe than MaxSeverity.
+		ScanReport string `yaml:",omitempty"`
+		// MaxSeverity is the most severe vulnerability allowed by ScanReport,
+		// one of "negligible", "low", "medium", "high" or "critical". It
+		// defaults to "medium".
+		MaxSeverity string `yaml:",omitempty"`
+	}
+
+	// ArtifactFacts are what is known about an artifact when checking it
+	// against an ArtifactPolicy.
+	ArtifactFacts struct {
+		SourceID SourceID
+		Artifact *BuildArtifact
+		// Created is when the artifact was built, if known.
+		Created time.Time
+		// BaseImage is the image the artifact was built from, if known.
+		BaseImage string
+		// Labels are the artifact's labels.
+		Labels map[string]string
+		// Platforms lists the platforms the artifact has images for, if
+		// known.
+		Platforms []string
+	}
+
+	// An ArtifactInspector is a Registry which can find out the facts about
+	// its artifacts.
+	ArtifactInspector interface {
+		Registry
+		// InspectArtifact returns the facts about art, built from sid.
+		InspectArtifact(sid SourceID, art *BuildArtifact) (*ArtifactFacts, error)
+	}
+
+	// An ArtifactPolicyCheck checks one aspect of an ArtifactPolicy.
+	ArtifactPolicyCheck interface {
+		// CheckArtifact returns the reasons, if any, that facts violate p.
+		CheckArtifact(p *ArtifactPolicy, facts *ArtifactFacts) []string
+	}
+
+	// ArtifactPolicyCheckFunc is a function which is an ArtifactPolicyCheck.
+	ArtifactPolicyCheckFunc func(p *ArtifactPolicy, facts *ArtifactFacts) []string
+
+	// A ScanReport lists the vulnerabilities an external scanner found in
+	// images.
+	ScanReport struct {
+		Images []ScannedImage
+	}
+
+	// A ScannedImage is an image listed in a ScanReport.
+	ScannedImage struct {
+		// Image is the name of the image. It matches artifacts with the same
+		// name, or the same digest.
+		Image           string
+		Vulnerabilities []Vulnerability
+	}
+
+	// A Vulnerability is a vulnerability found in a ScannedImage.
+	Vulnerability struct {
+		ID       string
+		Package  string `json:",omitempty"`
+		Severity string
+	}
+)
+
+// Labels used to find out the facts about artifacts, as defined by the OCI
+// image spec.
+const (
+	CreatedLabel   = "org.opencontainers.image.created"
+	BaseImageLabel = "org.opencontainers.image.base.name"
+)
+
+// ArtifactPolicyChecks are the checks made of artifacts against the
+// ArtifactPolicy of the cluster they are to be deployed to. Programs which
+// embed Sous may add their own.
+var ArtifactPolicyChecks = []ArtifactPolicyCheck{
+	ArtifactPolicyCheckFunc(checkMaxAge),
+	ArtifactPolicyCheckFunc(checkBaseImage),
+	ArtifactPolicyCheckFunc(checkRequiredLabels),
+	ArtifactPolicyCheckFunc(checkScanReport),
+}
+
+var severities = []string{"negligible", "low", "medium", "high", "critical"}
+
+// CheckArtifact implements ArtifactPolicyCheck on ArtifactPolicyCheckFunc.
+func (f ArtifactPolicyCheckFunc) CheckArtifact(p *ArtifactPolicy, facts *ArtifactFacts) []string {
+	return f(p, facts)
+}
+
+// Clone returns a deep copy of this ArtifactPolicy.
+func (p *ArtifactPolicy) Clone() *ArtifactPolicy {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.AllowedBaseImages = append([]string(nil), p.AllowedBaseImages...)
+	c.RequiredLabels = append([]string(nil), p.RequiredLabels...)
+	return &c
+}
+
+// Check runs ArtifactPolicyChecks, returning an *ArtifactPolicyViolation
+// listing the reasons facts violate p for deployment to cluster, if any.
+func (p *ArtifactPolicy) Check(cluster string, facts *ArtifactFacts) error {
+	var reasons []string
+	for _, c := range ArtifactPolicyChecks {
+		reasons = append(reasons, c.CheckArtifact(p, facts)...)
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return &ArtifactPolicyViolation{SourceID: facts.SourceID, Cluster: cluster, Reasons: reasons}
+}
+
+// InspectArtifact returns the facts about art, built from sid, using r.
+// Registries which are not ArtifactInspectors can only supply its labels.
+func InspectArtifact(r Registry, sid SourceID, art *BuildArtifact) (*ArtifactFacts, error) {
+	if ai, ok := r.(ArtifactInspector); ok {
+		return ai.InspectArtifact(sid, art)
+	}
+	labels, err := r.ImageLabels(art.Name)
+	if err != nil {
+		return nil, err
+	}
+	facts := &ArtifactFacts{SourceID: sid, Artifact: art, Labels: labels}
+	facts.FromLabels()
+	return facts, nil
+}
+
+// FromLabels fills in Created and BaseImage from Labels, if they are not
+// already known.
+func (facts *ArtifactFacts) FromLabels() {
+	if facts.Created.IsZero() {
+		if t, err := time.Parse(time.RFC3339, facts.Labels[CreatedLabel]); err == nil {
+			facts.Created = t
+		}
+	}
+	if facts.BaseImage == "" {
+		facts.BaseImage = facts.Labels[BaseImageLabel]
+	}
+}
+
+// checkArtifactPolicy checks art against the ArtifactPolicy of d's cluster.
+func checkArtifactPolicy(r Registry, d *Deployment, art *BuildArtifact) error {
+	if d.Cluster == nil || d.Cluster.ArtifactPolicy == nil {
+		return nil
+	}
+	facts, err := InspectArtifact(r, d.SourceID, art)
+	if err != nil {
+		return errors.Wrapf(err, "inspecting artifact for %s", d.SourceID)
+	}
+	return d.Cluster.ArtifactPolicy.Check(d.ClusterName, facts)
+}
+
+func checkMaxAge(p *ArtifactPolicy, facts *ArtifactFacts) []string {
+	if p.MaxAge == "" {
+		return nil
+	}
+	max, err := time.ParseDuration(p.MaxAge)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid MaxAge %q: %s", p.MaxAge, err)}
+	}
+	if facts.Created.IsZero() {
+		return []string{"build time unknown, so its age cannot be checked"}
+	}
+	if age := time.Since(facts.Created); age > max {
+		return []string{fmt.Sprintf("built %s ago, longer than the maximum of %s", age.Round(time.Minute), max)}
+	}
+	return nil
+}
+
+func checkBaseImage(p *ArtifactPolicy, facts *ArtifactFacts) []string {
+	if len(p.AllowedBaseImages) == 0 {
+		return nil
+	}
+	if facts.BaseImage == "" {
+		return []string{"base image unknown"}
+	}
+	for _, allowed := range p.AllowedBaseImages {
+		if strings.HasPrefix(facts.BaseImage, allowed) {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("base image %s is not allowed", facts.BaseImage)}
+}
+
+func checkRequiredLabels(p *ArtifactPolicy, facts *ArtifactFacts) []string {
+	var reasons []string
+	for _, l := range p.RequiredLabels {
+		if _, has := facts.Labels[l]; !has {
+			reasons = append(reasons, fmt.Sprintf("missing required label %s", l))
+		}
+	}
+	return reasons
+}
+
+func checkScanReport(p *ArtifactPolicy, facts *ArtifactFacts) []string {
+	if p.ScanReport == "" {
+		return nil
+	}
+	report, err := ReadScanReport(p.ScanReport)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	maxSeverity := p.MaxSeverity
+	if maxSeverity == "" {
+		maxSeverity = "medium"
+	}
+	max := severityRank(maxSeverity)
+	if max < 0 {
+		return []string{fmt.Sprintf("invalid MaxSeverity %q", p.MaxSeverity)}
+	}
+	img := report.Image(facts.Artifact.Name)
+	if img == nil {
+		return []string{fmt.Sprintf("not found in scan report %s", p.ScanReport)}
+	}
+	var reasons []string
+	for _, v := range img.Vulnerabilities {
+		// Vulnerabilities of unknown severity are assumed to be the worst.
+		if r := severityRank(v.Severity); r < 0 || r > max {
+			reasons = append(reasons, fmt.Sprintf("%s vulnerability %s in %s", v.Severity, v.ID, v.Package))
+		}
+	}
+	return reasons
+}
+
+// severityRank returns the rank of severity s, or -1 if it is unknown.
+func severityRank(s string) int {
+	s = strings.ToLower(s)
+	for i, sev := range severities {
+		if s == sev {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReadScanReport reads the ScanReport at path.
+func ReadScanReport(path string) (*ScanReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading scan report")
+	}
+	defer f.Close()
+	report := &ScanReport{}
+	if err := json.NewDecoder(f).Decode(report); err != nil {
+		return nil, errors.Wrapf(err, "parsing scan report %s", path)
+	}
+	return report, nil
+}
+
+// Image returns the ScannedImage matching the image named name, or nil if
+// there isn't one.
+func (r *ScanReport) Image(name string) *ScannedImage {
+	digest := func(n string) string {
+		if i := strings.Index(n, "@"); i >= 0 {
+			return n[i+1:]
+		}
+		return ""
+	}
+	for i, img := range r.Images {
+		if img.Image == name {
+			return &r.Images[i]
+		}
+		if d := digest(name); d != "" && (digest(img.Image) == d || img.Image == d) {
+			return &r.Images[i]
+		}
+	}
+	return nil
+}
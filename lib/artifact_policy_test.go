@@ -0,0 +1,115 @@
+package sous
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactPolicyCheck(t *testing.T) {
+	sid := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	facts := func() *ArtifactFacts {
+		return &ArtifactFacts{
+			SourceID:  sid,
+			Artifact:  &BuildArtifact{Name: "docker.example.com/ot/one@sha256:abcd"},
+			Created:   time.Now().Add(-48 * time.Hour),
+			BaseImage: "docker.example.com/base/alpine:3.7",
+			Labels:    map[string]string{"team": "ops"},
+		}
+	}
+
+	testCases := []struct {
+		desc    string
+		policy  ArtifactPolicy
+		facts   func(*ArtifactFacts)
+		reasons int
+	}{
+		{desc: "empty policy", reasons: 0},
+		{desc: "young enough", policy: ArtifactPolicy{MaxAge: "72h"}, reasons: 0},
+		{desc: "too old", policy: ArtifactPolicy{MaxAge: "24h"}, reasons: 1},
+		{desc: "unknown age", policy: ArtifactPolicy{MaxAge: "24h"}, facts: func(f *ArtifactFacts) { f.Created = time.Time{} }, reasons: 1},
+		{desc: "bad MaxAge", policy: ArtifactPolicy{MaxAge: "a while"}, reasons: 1},
+		{desc: "allowed base", policy: ArtifactPolicy{AllowedBaseImages: []string{"docker.example.com/base/"}}, reasons: 0},
+		{desc: "disallowed base", policy: ArtifactPolicy{AllowedBaseImages: []string{"docker.example.com/approved/"}}, reasons: 1},
+		{desc: "unknown base", policy: ArtifactPolicy{AllowedBaseImages: []string{"docker.example.com/base/"}}, facts: func(f *ArtifactFacts) { f.BaseImage = "" }, reasons: 1},
+		{desc: "labels present", policy: ArtifactPolicy{RequiredLabels: []string{"team"}}, reasons: 0},
+		{desc: "labels missing", policy: ArtifactPolicy{RequiredLabels: []string{"team", "owner", "tier"}}, reasons: 2},
+		{desc: "everything wrong", policy: ArtifactPolicy{MaxAge: "1h", AllowedBaseImages: []string{"scratch"}, RequiredLabels: []string{"owner"}}, reasons: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := facts()
+			if tc.facts != nil {
+				tc.facts(f)
+			}
+			err := tc.policy.Check("x", f)
+			if tc.reasons == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.IsType(t, &ArtifactPolicyViolation{}, err)
+			v := err.(*ArtifactPolicyViolation)
+			assert.Len(t, v.Reasons, tc.reasons, "%v", v.Reasons)
+			assert.Equal(t, "x", v.Cluster)
+		})
+	}
+}
+
+func TestArtifactPolicyScanReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-scan-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"Images": [
+		{"Image": "docker.example.com/ot/one@sha256:abcd", "Vulnerabilities": [
+			{"ID": "CVE-2018-0001", "Package": "openssl", "Severity": "High"},
+			{"ID": "CVE-2018-0002", "Package": "zlib", "Severity": "low"}
+		]},
+		{"Image": "sha256:ef01", "Vulnerabilities": []}
+	]}`), 0644))
+
+	check := func(name, maxSeverity string) []string {
+		return checkScanReport(&ArtifactPolicy{ScanReport: path, MaxSeverity: maxSeverity},
+			&ArtifactFacts{Artifact: &BuildArtifact{Name: name}})
+	}
+
+	assert.Len(t, check("docker.example.com/ot/one@sha256:abcd", ""), 1)
+	assert.Len(t, check("docker.example.com/ot/one@sha256:abcd", "negligible"), 2)
+	assert.Len(t, check("docker.example.com/ot/one@sha256:abcd", "critical"), 0)
+	assert.Len(t, check("docker.example.com/ot/two@sha256:ef01", ""), 0, "should match by digest")
+	assert.Len(t, check("docker.example.com/ot/three:1.0", ""), 1, "unscanned images should be rejected")
+	assert.Len(t, check("docker.example.com/ot/one@sha256:abcd", "dire"), 1)
+
+	reasons := checkScanReport(&ArtifactPolicy{ScanReport: filepath.Join(dir, "missing.json")}, &ArtifactFacts{})
+	assert.Len(t, reasons, 1)
+}
+
+func TestGuardImagePolicyViolation(t *testing.T) {
+	sid := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	dr := NewDummyRegistry()
+	dr.FeedArtifact(&BuildArtifact{Name: "ot-docker/one", Type: "docker"}, nil)
+	cluster := &Cluster{Name: "x", ArtifactPolicy: &ArtifactPolicy{RequiredLabels: []string{"owner"}}}
+	d := &Deployment{ClusterName: "x", SourceID: sid, DeployConfig: DeployConfig{NumInstances: 1}, Cluster: cluster}
+
+	_, err := guardImage(dr, d)
+	require.IsType(t, &ArtifactPolicyViolation{}, err)
+	assert.Contains(t, err.Error(), "missing required label owner")
+	assert.False(t, IsTransientResolveError(err))
+
+	d.Cluster.ArtifactPolicy = nil
+	_, err = guardImage(dr, d)
+	assert.NoError(t, err)
+}
+
+func TestClusterCloneArtifactPolicy(t *testing.T) {
+	c := Cluster{ArtifactPolicy: &ArtifactPolicy{RequiredLabels: []string{"owner"}}}
+	clone := c.Clone()
+	clone.ArtifactPolicy.RequiredLabels[0] = "team"
+	assert.Equal(t, "owner", c.ArtifactPolicy.RequiredLabels[0])
+}
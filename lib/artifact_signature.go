@@ -0,0 +1,33 @@
+package sous
+
+// An ArtifactVerifier is a Registry which can verify that artifacts were
+// built by a trusted builder.
+type ArtifactVerifier interface {
+	Registry
+	// VerifyArtifact returns an error unless art carries a signature, made
+	// with a trusted key, which claims that it was built from sid.
+	VerifyArtifact(sid SourceID, art *BuildArtifact) error
+}
+
+// checkSignature checks that art is signed as built from d's SourceID, if
+// d's cluster requires signatures.
+func checkSignature(r Registry, d *Deployment, art *BuildArtifact) error {
+	if d == nil || d.Cluster == nil || !d.Cluster.RequireSignatures {
+		return nil
+	}
+	unverified := &UnverifiedArtifact{SourceID: d.SourceID, Cluster: d.ClusterName}
+	av, ok := r.(ArtifactVerifier)
+	if !ok {
+		unverified.Reason = "the registry in use cannot verify signatures"
+		return unverified
+	}
+	if art == nil {
+		unverified.Reason = "there is no artifact"
+		return unverified
+	}
+	if err := av.VerifyArtifact(d.SourceID, art); err != nil {
+		unverified.Reason = err.Error()
+		return unverified
+	}
+	return nil
+}
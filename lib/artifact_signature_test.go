@@ -0,0 +1,44 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type verifyingRegistry struct {
+	*DummyRegistry
+	err error
+}
+
+func (r verifyingRegistry) VerifyArtifact(sid SourceID, art *BuildArtifact) error {
+	return r.err
+}
+
+func TestGuardImageUnverifiedArtifact(t *testing.T) {
+	sid := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	dr := NewDummyRegistry()
+	dr.FeedArtifact(&BuildArtifact{Name: "ot-docker/one", Type: "docker"}, nil)
+	r := verifyingRegistry{DummyRegistry: dr}
+	d := &Deployment{ClusterName: "x", SourceID: sid, DeployConfig: DeployConfig{NumInstances: 1}, Cluster: &Cluster{Name: "x"}}
+
+	r.err = fmt.Errorf("no signatures")
+	_, err := guardImage(r, d)
+	assert.NoError(t, err, "clusters which don't require signatures should accept any image")
+
+	d.Cluster.RequireSignatures = true
+	_, err = guardImage(r, d)
+	require.IsType(t, &UnverifiedArtifact{}, err)
+	assert.Contains(t, err.Error(), "no signatures")
+	assert.False(t, IsTransientResolveError(err))
+
+	r.err = nil
+	_, err = guardImage(r, d)
+	assert.NoError(t, err)
+
+	_, err = guardImage(dr, d)
+	require.IsType(t, &UnverifiedArtifact{}, err)
+	assert.Contains(t, err.Error(), "cannot verify signatures")
+}
@@ -65,6 +65,71 @@ func (ar *AutoResolver) addListener(f autoResolveListener) {
 	ar.listeners = append(ar.listeners, f)
 }
 
+// AfterResolve adds f to be called with the status of each completed
+// resolution. It must be called before Kickoff.
+func (ar *AutoResolver) AfterResolve(f func(ResolveStatus)) {
+	ar.addListener(func(trigger, done TriggerChannel, ch announceChannel) {
+		select {
+		case <-done:
+			return
+		case <-ch:
+		}
+		if stable, _ := ar.Statuses(); stable != nil {
+			f(*stable)
+		}
+	})
+}
+
+// WatchState adds a listener which starts a resolution as soon as w reports
+// a change to the state, rather than waiting for UpdateTime to pass. It must
+// be called before Kickoff.
+func (ar *AutoResolver) WatchState(w StateWatcher) {
+	var changes <-chan StateChange
+	ar.addListener(func(trigger, done TriggerChannel, ch announceChannel) {
+		if changes == nil {
+			stop := make(chan struct{})
+			go func() {
+				<-done
+				close(stop)
+			}()
+			var err error
+			if changes, err = w.Watch(stop); err != nil {
+				messages.ReportLogFieldsMessage("Unable to watch state, resolving every UpdateTime instead", logging.WarningLevel, ar.LogSink, err)
+				changes = make(chan StateChange)
+			}
+		}
+		select {
+		case <-done:
+			return
+		case <-ch:
+			// Announcements must be received, or resolution stalls.
+			return
+		case _, ok := <-changes:
+			if !ok {
+				// The watch has ended: rely on UpdateTime from now on.
+				changes = make(chan StateChange)
+				return
+			}
+		}
+		logging.ReportMsg(ar.LogSink, logging.DebugLevel, "State changed, triggering resolve")
+		triggerResolve(trigger, done, ch)
+	})
+}
+
+// triggerResolve triggers a resolution, receiving announcements on ac while
+// it waits, so that the resolution underway isn't kept from finishing.
+func triggerResolve(tc, done TriggerChannel, ac announceChannel) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-ac:
+		case tc <- TriggerType{}:
+			return
+		}
+	}
+}
+
 // Kickoff starts the auto-resolve cycle.
 func (ar *AutoResolver) Kickoff() TriggerChannel {
 	trigger := make(TriggerChannel)
@@ -183,7 +248,9 @@ func (ar *AutoResolver) resolveOnce(ac announceChannel) {
 	defer ar.write(func() {
 		ar.currentRecorder = nil
 	})
-	ac <- ar.currentRecorder.Wait()
+	err = ar.currentRecorder.Wait()
+	// The stable status is recorded before announcing, so that listeners see
+	// the status of the resolution just completed.
 	ar.write(func() {
 		ss := ar.currentRecorder.CurrentStatus()
 
@@ -191,6 +258,7 @@ func (ar *AutoResolver) resolveOnce(ac announceChannel) {
 
 		ar.stableStatus = &ss
 	})
+	ac <- err
 	ar.Statuses() // XXX this is debugging
 }
 
@@ -200,12 +268,20 @@ func (ar *AutoResolver) afterDone(tc, done TriggerChannel, ac announceChannel) {
 		return
 	case <-ac:
 	}
-	select {
-	case <-done:
-		return
-	case <-time.After(ar.UpdateTime):
+	wait := time.After(ar.UpdateTime)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ac:
+			// Another resolution has finished, e.g. one started by a change
+			// to the state, so wait UpdateTime from now.
+			wait = time.After(ar.UpdateTime)
+		case <-wait:
+			triggerResolve(tc, done, ac)
+			return
+		}
 	}
-	tc.trigger()
 }
 
 func (ar *AutoResolver) errorLogging(tc, done TriggerChannel, errs announceChannel) {
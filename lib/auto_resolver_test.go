@@ -100,3 +100,26 @@ func TestResolveLoop(t *testing.T) {
 		t.Error("Should have announced a result")
 	}
 }
+
+func TestWatchState(t *testing.T) {
+	ar := setupAR()
+	ar.UpdateTime = time.Hour
+	resolved := make(chan struct{}, 10)
+	ar.AfterResolve(func(ResolveStatus) { resolved <- struct{}{} })
+	changes := NewStateChanges(nil, logging.SilentLogSet())
+	ar.WatchState(changes)
+
+	done := ar.Kickoff()
+	defer close(done)
+	select {
+	case <-resolved:
+	case <-time.After(time.Second):
+		t.Fatal("first resolve did not happen")
+	}
+	changes.Changed()
+	select {
+	case <-resolved:
+	case <-time.After(time.Second):
+		t.Fatal("change to state did not trigger a resolve")
+	}
+}
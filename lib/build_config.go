@@ -14,7 +14,9 @@ type (
 	BuildConfig struct {
 		Repo, Offset, Tag, Revision string
 		Strict, ForceClone          bool
-		Context                     *BuildContext
+		// Platforms lists the platforms to build images for.
+		Platforms []string
+		Context   *BuildContext
 	}
 
 	// An AdvisoryName is the type for advisory tokens.
@@ -62,6 +64,9 @@ const (
 	// untracked files present, or that one or more tracked files were modified
 	// since the last commit.
 	DirtyWS = AdvisoryName(`dirty workspace`)
+	// TestsFailed means that a test image produced by the build exited
+	// unsuccessfully.
+	TestsFailed = AdvisoryName(`tests failed`)
 )
 
 // AllAdvisories returns all advisories.
@@ -79,6 +84,7 @@ func AllAdvisories() []AdvisoryName {
 		UnpushedRev,
 		BogusRev,
 		DirtyWS,
+		TestsFailed,
 	}
 }
 
@@ -101,11 +107,12 @@ func (c *BuildConfig) NewContext() *BuildContext {
 	tag := c.chooseTag()
 	sh.CD(sc.RootDir)
 	bc := BuildContext{
-		Sh:      sh,
-		Scratch: ctx.Scratch,
-		Machine: ctx.Machine,
-		User:    ctx.User,
-		Changes: ctx.Changes,
+		Sh:        sh,
+		Scratch:   ctx.Scratch,
+		Machine:   ctx.Machine,
+		User:      ctx.User,
+		Changes:   ctx.Changes,
+		Platforms: c.Platforms,
 		Source: SourceContext{
 			OffsetDir:      c.chooseOffset(),
 			RemoteURL:      c.chooseRemoteURL(),
@@ -16,6 +16,10 @@ type (
 		User       user.User
 		Changes    Changes
 		Advisories []string
+		// Platforms lists the platforms, e.g. "linux/arm64", to build images
+		// for. If it is empty, images are built for the platform of the
+		// Docker daemon.
+		Platforms []string
 	}
 
 	// ScratchContext represents an isolated copy of a project's source code
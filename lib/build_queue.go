@@ -0,0 +1,241 @@
+package sous
+
+import (
+	"bytes"
+	"container/ring"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// MaxBuildJobs is the maximum number of build jobs whose output is kept in
+// memory.
+const MaxBuildJobs = 100
+
+// BuildQueueCapDefault is the default number of builds which may wait for a
+// worker.
+const BuildQueueCapDefault = 20
+
+type (
+	// A BuildRequest asks a Sous server to build a SourceID. The metadata of
+	// the SourceID's version is the revision to build.
+	BuildRequest struct {
+		SourceID SourceID
+		// Strict refuses to build if there are any advisories.
+		Strict bool
+		// Platforms lists the platforms to build images for.
+		Platforms []string `json:",omitempty"`
+	}
+
+	// BuildJobID identifies a BuildJob.
+	BuildJobID string
+
+	// BuildJobState is the state of a BuildJob.
+	BuildJobState string
+
+	// A BuildJob is the progress of a BuildRequest on a Sous server.
+	BuildJob struct {
+		ID      BuildJobID
+		Request BuildRequest
+		State   BuildJobState
+		// Since is the number of lines of output which precede Log.
+		Since int
+		// Log is the output of the build so far, one line per entry.
+		Log []string
+		// Result is the result of a successful build.
+		Result *BuildResult `json:",omitempty"`
+		// Error is why a build failed.
+		Error string `json:",omitempty"`
+	}
+
+	// A BuildFunc performs a BuildRequest, writing its output to out.
+	BuildFunc func(req BuildRequest, out io.Writer) (*BuildResult, error)
+
+	// A BuildQueue performs BuildRequests with a pool of workers, keeping the
+	// output and outcome of recent builds.
+	BuildQueue struct {
+		build   BuildFunc
+		queue   chan *BuildJob
+		jobs    map[BuildJobID]*BuildJob
+		partial map[BuildJobID]*bytes.Buffer
+		fifo    *ring.Ring
+		sync.Mutex
+	}
+
+	buildOutput struct {
+		bq *BuildQueue
+		id BuildJobID
+	}
+)
+
+// The states of a BuildJob.
+const (
+	BuildQueued    BuildJobState = "queued"
+	BuildRunning   BuildJobState = "running"
+	BuildSucceeded BuildJobState = "succeeded"
+	BuildFailed    BuildJobState = "failed"
+)
+
+var (
+	buildRepoPattern     = regexp.MustCompile(`^[A-Za-z0-9.-]+(/[A-Za-z0-9._-]+)+$`)
+	buildRevisionPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// Validate returns an error unless req names a repo on one of allowedHosts,
+// a hex revision ID, and an offset within the repo.
+func (req BuildRequest) Validate(allowedHosts []string) error {
+	loc := req.SourceID.Location
+	if !buildRepoPattern.MatchString(loc.Repo) {
+		return errors.Errorf("repo %q is not of the form host/path", loc.Repo)
+	}
+	host := strings.ToLower(strings.SplitN(loc.Repo, "/", 2)[0])
+	allowed := false
+	for _, h := range allowedHosts {
+		allowed = allowed || strings.ToLower(h) == host
+	}
+	if !allowed {
+		return errors.Errorf("repos on %q may not be built remotely", host)
+	}
+	for _, p := range append(strings.Split(loc.Repo, "/"), strings.Split(loc.Dir, "/")...) {
+		if p == ".." {
+			return errors.Errorf("%q may not contain ..", path.Join(loc.Repo, loc.Dir))
+		}
+	}
+	if rev := req.SourceID.RevID(); !buildRevisionPattern.MatchString(rev) {
+		return errors.Errorf("revision %q is not a hex commit ID", rev)
+	}
+	return nil
+}
+
+// Done returns true if the job has finished, successfully or not.
+func (s BuildJobState) Done() bool {
+	return s == BuildSucceeded || s == BuildFailed
+}
+
+// NewBuildQueue returns a BuildQueue which performs builds with build, in
+// workers goroutines, with up to cap builds waiting.
+func NewBuildQueue(workers, cap int, build BuildFunc) *BuildQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	bq := &BuildQueue{
+		build:   build,
+		queue:   make(chan *BuildJob, cap),
+		jobs:    map[BuildJobID]*BuildJob{},
+		partial: map[BuildJobID]*bytes.Buffer{},
+		fifo:    ring.New(MaxBuildJobs),
+	}
+	for i := 0; i < workers; i++ {
+		go bq.work()
+	}
+	return bq
+}
+
+// Push queues req to be built. It returns the job and true, or nil and false
+// if the queue is full.
+func (bq *BuildQueue) Push(req BuildRequest) (*BuildJob, bool) {
+	bq.Lock()
+	defer bq.Unlock()
+	if len(bq.queue) == cap(bq.queue) {
+		return nil, false
+	}
+	job := &BuildJob{ID: BuildJobID(uuid.New()), Request: req, State: BuildQueued}
+	bq.remember(job)
+	bq.queue <- job
+	copied := *job
+	return &copied, true
+}
+
+// Job returns a copy of the job with ID id, whose Log starts after the first
+// since lines of its output, and true. It returns false if there is no such
+// job.
+func (bq *BuildQueue) Job(id BuildJobID, since int) (BuildJob, bool) {
+	bq.Lock()
+	defer bq.Unlock()
+	job, ok := bq.jobs[id]
+	if !ok {
+		return BuildJob{}, false
+	}
+	copied := *job
+	if since < 0 || since > len(job.Log) {
+		since = len(job.Log)
+	}
+	copied.Since = since
+	copied.Log = append([]string(nil), job.Log[since:]...)
+	return copied, true
+}
+
+// remember adds job to the jobs of bq, forgetting the oldest if there are
+// too many. It assumes bq is already locked.
+func (bq *BuildQueue) remember(job *BuildJob) {
+	bq.jobs[job.ID] = job
+	bq.fifo = bq.fifo.Next()
+	if bq.fifo.Value != nil {
+		delete(bq.jobs, bq.fifo.Value.(BuildJobID))
+	}
+	bq.fifo.Value = job.ID
+}
+
+func (bq *BuildQueue) work() {
+	for job := range bq.queue {
+		bq.setState(job, BuildRunning)
+		messages.ReportLogFieldsMessage("Starting build", logging.InformationLevel, logging.Log, job.ID, job.Request.SourceID)
+
+		out := &buildOutput{bq: bq, id: job.ID}
+		result, err := bq.build(job.Request, out)
+
+		bq.Lock()
+		if p := bq.partial[job.ID]; p != nil && p.Len() > 0 {
+			job.Log = append(job.Log, p.String())
+		}
+		delete(bq.partial, job.ID)
+		if err != nil {
+			job.Error = err.Error()
+			job.State = BuildFailed
+		} else {
+			job.Result = result
+			job.State = BuildSucceeded
+		}
+		bq.Unlock()
+		messages.ReportLogFieldsMessage("Finished build", logging.InformationLevel, logging.Log, job.ID, job.Request.SourceID, job.State)
+	}
+}
+
+func (bq *BuildQueue) setState(job *BuildJob, state BuildJobState) {
+	bq.Lock()
+	defer bq.Unlock()
+	job.State = state
+}
+
+// Write implements io.Writer on buildOutput, adding each complete line
+// written to the Log of its job.
+func (o *buildOutput) Write(b []byte) (int, error) {
+	o.bq.Lock()
+	defer o.bq.Unlock()
+	job, ok := o.bq.jobs[o.id]
+	if !ok {
+		job = &BuildJob{}
+	}
+	p := o.bq.partial[o.id]
+	if p == nil {
+		p = &bytes.Buffer{}
+		o.bq.partial[o.id] = p
+	}
+	p.Write(b)
+	for {
+		i := bytes.IndexByte(p.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(p.Next(i + 1))
+		job.Log = append(job.Log, line[:len(line)-1])
+	}
+	return len(b), nil
+}
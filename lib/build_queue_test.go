@@ -0,0 +1,78 @@
+package sous
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForBuild waits for the job with ID id to finish, and returns it with
+// all of its output.
+func waitForBuild(t *testing.T, bq *BuildQueue, id BuildJobID) BuildJob {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		job, ok := bq.Job(id, 0)
+		require.True(t, ok, "job %s not found", id)
+		if job.State.Done() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return BuildJob{}
+}
+
+func TestBuildQueue(t *testing.T) {
+	release := make(chan struct{})
+	bq := NewBuildQueue(1, 1, func(req BuildRequest, out io.Writer) (*BuildResult, error) {
+		<-release
+		fmt.Fprintf(out, "building %s\nstep 1\npartial", req.SourceID.Location.Repo)
+		if req.Strict {
+			return nil, fmt.Errorf("advisories")
+		}
+		return &BuildResult{Products: []*BuildProduct{{Source: req.SourceID}}}, nil
+	})
+
+	good := BuildRequest{SourceID: MustNewSourceID("github.com/opentable/good", "", "1.0.0+abc")}
+	bad := BuildRequest{SourceID: MustNewSourceID("github.com/opentable/bad", "", "1.0.0+def"), Strict: true}
+
+	first, ok := bq.Push(good)
+	require.True(t, ok)
+	assert.Equal(t, BuildQueued, first.State)
+	// Wait for the worker to take the first job, so that the second waits.
+	for {
+		job, _ := bq.Job(first.ID, 0)
+		if job.State == BuildRunning {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	second, ok := bq.Push(bad)
+	require.True(t, ok)
+	_, ok = bq.Push(good)
+	assert.False(t, ok, "queue should be full")
+
+	close(release)
+	job := waitForBuild(t, bq, first.ID)
+	assert.Equal(t, BuildSucceeded, job.State)
+	assert.Equal(t, []string{"building github.com/opentable/good", "step 1", "partial"}, job.Log)
+	require.NotNil(t, job.Result)
+	assert.Equal(t, good.SourceID, job.Result.Products[0].Source)
+
+	later, ok := bq.Job(first.ID, 2)
+	require.True(t, ok)
+	assert.Equal(t, 2, later.Since)
+	assert.Equal(t, []string{"partial"}, later.Log)
+
+	job = waitForBuild(t, bq, second.ID)
+	assert.Equal(t, BuildFailed, job.State)
+	assert.Equal(t, "advisories", job.Error)
+	assert.Nil(t, job.Result)
+
+	_, ok = bq.Job("no-such-job", 0)
+	assert.False(t, ok)
+}
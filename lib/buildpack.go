@@ -35,6 +35,11 @@ type (
 	BuildArtifact struct {
 		Name, Type string
 		Qualities  Qualities
+		// SBOM is the software bill of materials of the artifact, if known.
+		SBOM *SBOM `json:",omitempty"`
+		// Tag is the mutable name the artifact was known by before Name was
+		// pinned to the digest of its image, if it was.
+		Tag string `json:",omitempty"`
 	}
 
 	// A Quality represents a characteristic of a BuildArtifact that needs to be recorded.
@@ -67,6 +72,9 @@ type (
 		// Data is an arbitrary value. It can be used to pass interesting
 		// detected information to the build step.
 		Data interface{}
+		// Priority, if non-zero, overrides the priority the Buildpack was
+		// registered with in a BuildpackRegistry.
+		Priority int
 	}
 	// BuildResult represents the result of a build made with a Buildpack.
 	BuildResult struct {
@@ -90,6 +98,25 @@ type (
 		// VersionName and RevisionName cache computations about how to refer to the image.
 		VersionName  string
 		RevisionName string
+
+		// TestResult is the outcome of running this product, if it is a test
+		// image.
+		TestResult *TestResult `json:",omitempty"`
+
+		// SBOM is the software bill of materials of this product.
+		SBOM *SBOM `json:",omitempty"`
+
+		// Platform is the platform the image was built for, if the build was
+		// for particular platforms. The images for each platform are
+		// combined into a single multi-platform image when registered.
+		Platform string `json:",omitempty"`
+	}
+
+	// A TestResult records the outcome of running a test image produced by a
+	// build.
+	TestResult struct {
+		ExitCode int
+		Output   string
 	}
 )
 
@@ -125,12 +152,27 @@ func (br *BuildResult) String() string {
 
 func (bp *BuildProduct) String() string {
 	str := fmt.Sprintf("Built: %q %q", bp.VersionName, bp.Kind)
+	if bp.TestResult != nil {
+		str = str + fmt.Sprintf("\nTests: %s", bp.TestResult)
+	}
 	if len(bp.Advisories) > 0 {
 		str = str + "\nAdvisories:\n  " + strings.Join(bp.Advisories, "  \n")
 	}
 	return str
 }
 
+// Passed returns true if the tests exited successfully.
+func (tr *TestResult) Passed() bool {
+	return tr.ExitCode == 0
+}
+
+func (tr *TestResult) String() string {
+	if tr.Passed() {
+		return "passed"
+	}
+	return fmt.Sprintf("failed (exit code %d)", tr.ExitCode)
+}
+
 // NewBuildArtifact creates a new BuildArtifact representing a Docker
 // image.
 func NewBuildArtifact(imageName string, qstrs Strpairs) *BuildArtifact {
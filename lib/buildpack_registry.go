@@ -0,0 +1,89 @@
+package sous
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A BuildpackRegistry selects among a set of registered Buildpacks the
+	// one to build a project with.
+	BuildpackRegistry struct {
+		entries []registeredBuildpack
+	}
+
+	registeredBuildpack struct {
+		name     string
+		priority int
+		newPack  func() Buildpack
+	}
+)
+
+// NewBuildpackRegistry returns an empty BuildpackRegistry.
+func NewBuildpackRegistry() *BuildpackRegistry {
+	return &BuildpackRegistry{}
+}
+
+// Register adds a Buildpack to r. newPack is called to make a fresh Buildpack
+// for each selection. Compatible Buildpacks with higher priorities are
+// selected over those with lower ones.
+func (r *BuildpackRegistry) Register(name string, priority int, newPack func() Buildpack) {
+	r.entries = append(r.entries, registeredBuildpack{name: name, priority: priority, newPack: newPack})
+}
+
+// Names returns the names of the registered Buildpacks, in the order they
+// were registered.
+func (r *BuildpackRegistry) Names() []string {
+	names := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// Select runs Detect on every registered Buildpack and returns the name of the
+// compatible one with the highest priority, along with the Buildpack itself.
+// The priority of a Buildpack is the one it was registered with, unless its
+// DetectResult has a non-zero Priority. Ties go to the Buildpack registered
+// first.
+func (r *BuildpackRegistry) Select(ctx *BuildContext) (string, Buildpack, error) {
+	type candidate struct {
+		name     string
+		priority int
+		pack     Buildpack
+	}
+	var candidates []candidate
+	var reasons []string
+	for _, e := range r.entries {
+		bp := e.newPack()
+		dr, err := bp.Detect(ctx)
+		if err != nil {
+			reasons = append(reasons, e.name+": "+err.Error())
+			continue
+		}
+		if dr == nil || !dr.Compatible {
+			reasons = append(reasons, e.name+": not compatible")
+			continue
+		}
+		priority := e.priority
+		if dr.Priority != 0 {
+			priority = dr.Priority
+		}
+		candidates = append(candidates, candidate{name: e.name, priority: priority, pack: bp})
+	}
+	if len(candidates) == 0 {
+		return "", nil, errors.Errorf("no buildpack is compatible with this project:\n  %s", strings.Join(reasons, "\n  "))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].priority > candidates[j].priority
+	})
+	return candidates[0].name, candidates[0].pack, nil
+}
+
+// SelectBuildpack implements Selector on BuildpackRegistry.
+func (r *BuildpackRegistry) SelectBuildpack(ctx *BuildContext) (Buildpack, error) {
+	_, bp, err := r.Select(ctx)
+	return bp, err
+}
@@ -0,0 +1,59 @@
+package sous
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type registryTestBuildpack struct {
+	result *DetectResult
+	err    error
+}
+
+func (bp *registryTestBuildpack) Detect(*BuildContext) (*DetectResult, error) {
+	return bp.result, bp.err
+}
+
+func (bp *registryTestBuildpack) Build(*BuildContext) (*BuildResult, error) {
+	return &BuildResult{}, nil
+}
+
+func registryTestPack(compatible bool, priority int, err error) func() Buildpack {
+	return func() Buildpack {
+		return &registryTestBuildpack{result: &DetectResult{Compatible: compatible, Priority: priority}, err: err}
+	}
+}
+
+func TestBuildpackRegistry_Select(t *testing.T) {
+	r := NewBuildpackRegistry()
+	r.Register("broken", 100, registryTestPack(true, 0, errors.New("broken")))
+	r.Register("incompatible", 50, registryTestPack(false, 0, nil))
+	r.Register("low", 10, registryTestPack(true, 0, nil))
+	r.Register("high", 20, registryTestPack(true, 0, nil))
+	r.Register("also high", 20, registryTestPack(true, 0, nil))
+
+	assert.Equal(t, []string{"broken", "incompatible", "low", "high", "also high"}, r.Names())
+	name, bp, err := r.Select(&BuildContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "high", name)
+	assert.NotNil(t, bp)
+
+	r.Register("plugin", 0, registryTestPack(true, 30, nil))
+	name, _, err = r.Select(&BuildContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "plugin", name, "a detected priority should override the registered one")
+}
+
+func TestBuildpackRegistry_SelectNone(t *testing.T) {
+	r := NewBuildpackRegistry()
+	r.Register("broken", 100, registryTestPack(true, 0, errors.New("no Dockerfile")))
+	r.Register("incompatible", 50, registryTestPack(false, 0, nil))
+
+	_, err := r.SelectBuildpack(&BuildContext{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken: no Dockerfile")
+	assert.Contains(t, err.Error(), "incompatible: not compatible")
+}
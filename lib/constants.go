@@ -10,6 +10,18 @@ const ClusterNameLabel = "com.opentable.sous.clustername"
 // SingularityDeployMetadataFlavor defines the namespace for storing a Sous Flavor in SingularityDeploy metadata.
 const FlavorLabel = "com.opentable.sous.flavor"
 
+// RolloutLabel is the metadata fieldname that records the sous.Rollout of a deploy, as JSON.
+const RolloutLabel = "com.opentable.sous.rollout"
+
+// SecretsLabel is the metadata fieldname that records the secret references
+// in the Env of a deploy, as JSON, so that they can be restored in place of
+// the secrets they were resolved to.
+const SecretsLabel = "com.opentable.sous.secrets"
+
+// ImageTagLabel is the metadata fieldname that records the tag the image of a
+// deploy was pinned from, when it is deployed by digest.
+const ImageTagLabel = "com.opentable.sous.image_tag"
+
 // RepoLabel is the metadata fieldname that records the version control repository URL of a Sous-controlled service.
 const RepoLabel = "com.opentable.sous.repo_url"
 
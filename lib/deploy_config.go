@@ -38,6 +38,8 @@ type (
 		Startup Startup `yaml:",omitempty"`
 		// Schedule is a cronjob-format schedule for jobs.
 		Schedule string
+		// Rollout controls how a new version replaces the running one.
+		Rollout Rollout `yaml:",omitempty"`
 	}
 
 	// A DeployConfigs is a map from cluster name to DeployConfig
@@ -79,6 +81,10 @@ func (dc *DeployConfig) Validate() []Flaw {
 
 	flaws = append(flaws, dc.Startup.Validate()...)
 
+	flaws = append(flaws, dc.Rollout.Validate()...)
+
+	flaws = append(flaws, dc.Env.ValidateSecrets()...)
+
 	for _, f := range flaws {
 		f.AddContext("deploy config", dc)
 	}
@@ -108,7 +114,7 @@ func (dc *DeployConfig) Repair(fs []Flaw) error {
 }
 
 func (dc *DeployConfig) String() string {
-	return fmt.Sprintf("#%d %s %+v : %+v %+v", dc.NumInstances, spew.Sprintf("%v", dc.Startup), dc.Resources, dc.Env, dc.Volumes)
+	return fmt.Sprintf("#%d %s %+v : %+v %+v", dc.NumInstances, spew.Sprintf("%v", dc.Startup), dc.Resources, dc.Env.Redacted(), dc.Volumes)
 }
 
 // Equal is used to compare DeployConfigs
@@ -128,7 +134,7 @@ func (dc *DeployConfig) Diff(o DeployConfig) (bool, []string) {
 	// This makes nil equal to zero-length map.
 	if len(dc.Env) != 0 || len(o.Env) != 0 {
 		if !dc.Env.Equal(o.Env) {
-			diffs = append(diffs, fmt.Sprintf("env; this: %v; other: %v", dc.Env, o.Env))
+			diffs = append(diffs, fmt.Sprintf("env; this: %v; other: %v", dc.Env.Redacted(o.Env), o.Env.Redacted(dc.Env)))
 		}
 	}
 	// Only compare contents if length of either > 0.
@@ -151,6 +157,7 @@ func (dc *DeployConfig) Diff(o DeployConfig) (bool, []string) {
 		}
 	}
 	diffs = append(diffs, dc.Startup.diff(o.Startup)...)
+	diffs = append(diffs, dc.Rollout.diff(o.Rollout)...)
 	// TODO: Compare Args
 	return len(diffs) == 0, diffs
 }
@@ -175,25 +182,28 @@ func (dc DeployConfig) Clone() (c DeployConfig) {
 	c.Volumes = dc.Volumes.Clone()
 	c.Startup = dc.Startup
 	c.Schedule = dc.Schedule
+	c.Rollout = dc.Rollout
 
 	return
 }
 
 // Equal compares Envs
 func (e Env) Equal(o Env) bool {
-	messages.ReportLogFieldsMessage("Envs", logging.ExtraDebug1Level, logging.Log, e, o)
+	// Either Env may hold resolved secrets, so only redacted copies are logged.
+	re, ro := e.Redacted(o), o.Redacted(e)
+	messages.ReportLogFieldsMessage("Envs", logging.ExtraDebug1Level, logging.Log, re, ro)
 	if len(e) != len(o) {
-		messages.ReportLogFieldsMessage("Envs !=", logging.ExtraDebug1Level, logging.Log, e, o, len(e), len(o))
+		messages.ReportLogFieldsMessage("Envs !=", logging.ExtraDebug1Level, logging.Log, re, ro, len(e), len(o))
 		return false
 	}
 
 	for name, value := range e {
 		if ov, ok := o[name]; !ok || ov != value {
-			messages.ReportLogFieldsMessage("Envs: !=", logging.ExtraDebug1Level, logging.Log, e, o, name, value, ov)
+			messages.ReportLogFieldsMessage("Envs: !=", logging.ExtraDebug1Level, logging.Log, re, ro, name, re[name], ro[name])
 			return false
 		}
 	}
-	messages.ReportLogFieldsMessage("Envs: == !", logging.ExtraDebug1Level, logging.Log, e, o)
+	messages.ReportLogFieldsMessage("Envs: == !", logging.ExtraDebug1Level, logging.Log, re, ro)
 	return true
 }
 
@@ -251,6 +261,12 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			break
 		}
 	}
+	for _, c := range dcs {
+		if c.Rollout.Staged() {
+			dc.Rollout = c.Rollout
+			break
+		}
+	}
 	for _, c := range dcs {
 		for n, v := range c.Resources {
 			if _, set := dc.Resources[n]; !set {
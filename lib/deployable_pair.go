@@ -63,7 +63,7 @@ func (dp *DeployablePair) Diffs() Differences {
 
 // SameResolution returns a DiffResolution indicating that there is no intended
 // change. The deployment may either be stable or in the process of being
-// deployed.
+// deployed. Its DeployState records the artifact deployed, if it is known.
 func (dp *DeployablePair) SameResolution() DiffResolution {
 	dep := dp.Prior
 	desc := StableDiff
@@ -74,9 +74,17 @@ func (dp *DeployablePair) SameResolution() DiffResolution {
 	if dep.Status == DeployStatusFailed {
 		err = WrapResolveError(&FailedStatusError{})
 	}
-	return DiffResolution{
+	dr := DiffResolution{
 		DeploymentID: dep.ID(),
 		Desc:         desc,
 		Error:        err,
 	}
+	if dp.Post != nil && dp.Post.BuildArtifact != nil {
+		dr.DeployState = &DeployState{
+			Deployment:    *dep.Deployment.Clone(),
+			Status:        dep.Status,
+			BuildArtifact: dp.Post.BuildArtifact,
+		}
+	}
+	return dr
 }
@@ -74,10 +74,8 @@ func (dm *deploymentManagerDecorator) WriteDeployment(dep *Deployment, user User
 		return err
 	}
 
-	deps, err := state.Deployments()
-	if err != nil {
+	if err := state.UpdateDeployments(dep); err != nil {
 		return err
 	}
-	deps.Set(dep.ID(), dep)
 	return dm.WriteState(state, user)
 }
@@ -19,6 +19,14 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.Cluster.BaseURL",
 		"Deployment.Cluster.Env",
 		"Deployment.Cluster.AllowedAdvisories",
+		"Deployment.Cluster.ArtifactPolicy",
+		"Deployment.Cluster.ArtifactPolicy.MaxAge",
+		"Deployment.Cluster.ArtifactPolicy.AllowedBaseImages",
+		"Deployment.Cluster.ArtifactPolicy.RequiredLabels",
+		"Deployment.Cluster.ArtifactPolicy.ScanReport",
+		"Deployment.Cluster.ArtifactPolicy.MaxSeverity",
+		"Deployment.Cluster.Platform",
+		"Deployment.Cluster.RequireSignatures",
 		"Deployment.Cluster.Startup",
 		"Deployment.Cluster.Startup.SkipCheck",
 		"Deployment.Cluster.Startup.CheckReadyURIPath",
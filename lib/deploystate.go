@@ -13,6 +13,9 @@ type DeployState struct {
 	ExecutorMessage string
 	ExecutorData    interface{}
 	SchedulerURL    string
+	// BuildArtifact is the artifact deployed, as reported by the executor, if
+	// known.
+	BuildArtifact *BuildArtifact `json:",omitempty"`
 }
 
 func (ds DeployState) String() string {
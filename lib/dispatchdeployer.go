@@ -0,0 +1,153 @@
+package sous
+
+import "github.com/pkg/errors"
+
+// A DispatchDeployer handles dispatching deployment operations to the
+// Deployer responsible for each kind of cluster.
+type DispatchDeployer struct {
+	defaultKind string
+	deployers   map[string]Deployer
+}
+
+// NewDispatchDeployer builds a DispatchDeployer. Clusters whose Kind is empty
+// are handled by the deployer registered for defaultKind.
+func NewDispatchDeployer(defaultKind string, deployers map[string]Deployer) *DispatchDeployer {
+	return &DispatchDeployer{
+		defaultKind: defaultKind,
+		deployers:   deployers,
+	}
+}
+
+func (dd *DispatchDeployer) kindOf(c *Cluster) string {
+	if c == nil || c.Kind == "" {
+		return dd.defaultKind
+	}
+	return c.Kind
+}
+
+// byKind partitions clusters according to their Kind.
+func (dd *DispatchDeployer) byKind(clusters Clusters) map[string]Clusters {
+	kinds := map[string]Clusters{}
+	for name, c := range clusters {
+		k := dd.kindOf(c)
+		if _, has := kinds[k]; !has {
+			kinds[k] = Clusters{}
+		}
+		kinds[k][name] = c
+	}
+	return kinds
+}
+
+func (dd *DispatchDeployer) deployerFor(kind string) (Deployer, error) {
+	d, ok := dd.deployers[kind]
+	if !ok {
+		return nil, errors.Errorf("No deployer for cluster kind %q", kind)
+	}
+	return d, nil
+}
+
+// pairCluster returns the most complete Cluster known for pair: Post is
+// preferred, since it is derived from the GDM.
+func pairCluster(pair *DeployablePair) *Cluster {
+	if pair.Post != nil && pair.Post.Deployment != nil && pair.Post.Cluster != nil {
+		return pair.Post.Cluster
+	}
+	if pair.Prior != nil && pair.Prior.Deployment != nil {
+		return pair.Prior.Cluster
+	}
+	return nil
+}
+
+// RunningDeployments implements Deployer on DispatchDeployer.
+//
+// Each returned DeployState has its Cluster replaced by the definition from
+// clusters, so that later calls to Rectify can be dispatched by Kind.
+func (dd *DispatchDeployer) RunningDeployments(reg Registry, from Clusters) (DeployStates, error) {
+	all := NewDeployStates()
+	for kind, clusters := range dd.byKind(from) {
+		d, err := dd.deployerFor(kind)
+		if err != nil {
+			return all, err
+		}
+		states, err := d.RunningDeployments(reg, clusters)
+		if err != nil {
+			return all, errors.Wrapf(err, "%s clusters", kind)
+		}
+		for _, s := range states.Snapshot() {
+			if c, has := clusters[s.ClusterName]; has {
+				s.Cluster = c
+			}
+			all.Add(s)
+		}
+	}
+	return all, nil
+}
+
+// Rectify implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Rectify(pair *DeployablePair) DiffResolution {
+	d, err := dd.deployerFor(dd.kindOf(pairCluster(pair)))
+	if err != nil {
+		return DiffResolution{DeploymentID: pair.ID(), Error: WrapResolveError(err)}
+	}
+	return d.Rectify(pair)
+}
+
+// Status implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Status(reg Registry, from Clusters, pair *DeployablePair) (*DeployState, error) {
+	kind := dd.kindOf(pairCluster(pair))
+	if pair.Post != nil && pair.Post.Deployment != nil {
+		if c, has := from[pair.Post.ClusterName]; has {
+			kind = dd.kindOf(c)
+		}
+	}
+	d, err := dd.deployerFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	return d.Status(reg, dd.byKind(from)[kind], pair)
+}
+
+// rolloutDeployerFor returns the RolloutDeployer responsible for pair, or nil
+// if that deployer cannot deploy in stages.
+func (dd *DispatchDeployer) rolloutDeployerFor(pair *DeployablePair) (RolloutDeployer, error) {
+	d, err := dd.deployerFor(dd.kindOf(pairCluster(pair)))
+	if err != nil {
+		return nil, err
+	}
+	rd, _ := d.(RolloutDeployer)
+	return rd, nil
+}
+
+// RolloutProgress implements RolloutDeployer on DispatchDeployer. Pairs whose
+// deployer cannot deploy in stages never have a rollout in progress.
+func (dd *DispatchDeployer) RolloutProgress(pair *DeployablePair) (*RolloutProgress, error) {
+	rd, err := dd.rolloutDeployerFor(pair)
+	if rd == nil {
+		return nil, err
+	}
+	return rd.RolloutProgress(pair)
+}
+
+// AdvanceRollout implements RolloutDeployer on DispatchDeployer.
+func (dd *DispatchDeployer) AdvanceRollout(pair *DeployablePair, target int) error {
+	rd, err := dd.rolloutDeployerFor(pair)
+	if err != nil {
+		return err
+	}
+	if rd == nil {
+		return errors.Errorf("%q cannot be deployed in stages", pair.ID())
+	}
+	return rd.AdvanceRollout(pair, target)
+}
+
+// AbortRollout implements RolloutDeployer on DispatchDeployer.
+func (dd *DispatchDeployer) AbortRollout(pair *DeployablePair) error {
+	rd, err := dd.rolloutDeployerFor(pair)
+	if err != nil {
+		return err
+	}
+	if rd == nil {
+		return errors.Errorf("%q cannot be deployed in stages", pair.ID())
+	}
+	return rd.AbortRollout(pair)
+}
@@ -0,0 +1,59 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchDeployer(t *testing.T) {
+	sing, sc := NewDeployerSpy()
+	kube, kc := NewDeployerSpy()
+
+	clusters := Clusters{
+		"old": {Name: "old"},
+		"new": {Name: "new", Kind: "kubernetes", BaseURL: "http://kube.example.com"},
+	}
+
+	singStates := NewDeployStates(&DeployState{Deployment: Deployment{ClusterName: "old"}})
+	kubeStates := NewDeployStates(&DeployState{Deployment: Deployment{ClusterName: "new", Cluster: &Cluster{Name: "new"}}})
+	sc.MatchMethod("RunningDeployments", spies.AnyArgs, singStates, nil)
+	kc.MatchMethod("RunningDeployments", spies.AnyArgs, kubeStates, nil)
+	sc.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: "singularity"})
+	kc.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: "kubernetes"})
+
+	dd := NewDispatchDeployer("singularity", map[string]Deployer{
+		"singularity": sing,
+		"kubernetes":  kube,
+	})
+
+	states, err := dd.RunningDeployments(NewDummyRegistry(), clusters)
+	require.NoError(t, err)
+	assert.Equal(t, 2, states.Len())
+	for _, s := range states.Snapshot() {
+		assert.Equal(t, clusters[s.ClusterName], s.Cluster)
+	}
+
+	require.Len(t, kc.CallsTo("RunningDeployments"), 1)
+	passed := kc.CallsTo("RunningDeployments")[0].PassedArgs().Get(1).(Clusters)
+	assert.Equal(t, []string{"new"}, passed.Names())
+
+	pair := func(cluster string) *DeployablePair {
+		return &DeployablePair{Post: &Deployable{Deployment: &Deployment{ClusterName: cluster, Cluster: clusters[cluster]}}}
+	}
+	assert.Equal(t, "kubernetes", string(dd.Rectify(pair("new")).Desc))
+	assert.Equal(t, "singularity", string(dd.Rectify(pair("old")).Desc))
+
+	// Neither spy can deploy in stages.
+	progress, err := dd.RolloutProgress(pair("new"))
+	assert.NoError(t, err)
+	assert.Nil(t, progress)
+	assert.Error(t, dd.AdvanceRollout(pair("new"), 2))
+
+	dd = NewDispatchDeployer("singularity", map[string]Deployer{"singularity": sing})
+	_, err = dd.RunningDeployments(NewDummyRegistry(), clusters)
+	assert.Error(t, err)
+	assert.NotNil(t, dd.Rectify(pair("new")).Error)
+}
@@ -26,6 +26,11 @@ func NewDispatchStateManager(localCluster string, clusters []string, local State
 	return dsm
 }
 
+// Local returns the StateManager of the local cluster.
+func (dsm *DispatchStateManager) Local() StateManager {
+	return dsm.local
+}
+
 // ReadState implements StateManager on DispatchStateManager.
 func (dsm *DispatchStateManager) ReadState() (*State, error) {
 	baseState, err := dsm.local.ReadState()
@@ -82,3 +87,13 @@ func (dsm *DispatchStateManager) WriteCluster(clusterName string, deps Deploymen
 	}
 	return cm.WriteCluster(clusterName, deps, user)
 }
+
+// Watch implements StateWatcher on DispatchStateManager, by watching its
+// local StateManager, if that can be watched.
+func (dsm *DispatchStateManager) Watch(done <-chan struct{}) (<-chan StateChange, error) {
+	w, ok := dsm.local.(StateWatcher)
+	if !ok {
+		return nil, errors.Errorf("local state manager %T cannot be watched", dsm.local)
+	}
+	return w.Watch(done)
+}
@@ -0,0 +1,269 @@
+package sous
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pborman/uuid"
+)
+
+type (
+	// A HistoryEntry records a single change to a manifest in the GDM.
+	HistoryEntry struct {
+		// ID uniquely identifies this entry.
+		ID string
+		// Time is when the change was written.
+		Time time.Time
+		// User is the user who made the change.
+		User User
+		// ManifestID identifies the changed manifest.
+		ManifestID ManifestID
+		// Kind is the kind of change.
+		Kind HistoryKind
+		// Diffs describes the change, as reported by Manifest.Diff.
+		Diffs []string
+		// Clusters lists the clusters whose deployments were affected.
+		Clusters []string
+		// Outcomes maps cluster names to the result of the first
+		// rectification of that cluster's deployment after the change.
+		Outcomes map[string]HistoryOutcome
+	}
+
+	// A HistoryOutcome is the result of rectifying a change.
+	HistoryOutcome struct {
+		Time       time.Time
+		Resolution DiffResolution
+	}
+
+	// HistoryKind describes a change to a manifest.
+	HistoryKind string
+
+	// History stores an audit trail of changes to the GDM.
+	History interface {
+		// RecordChanges stores entries.
+		RecordChanges(entries []HistoryEntry) error
+		// RecordOutcome attaches the result of a rectification to the most
+		// recent entry affecting the deployment, unless that entry already
+		// has an outcome for it.
+		RecordOutcome(did DeploymentID, dr DiffResolution) error
+		// Entries returns at most limit entries matched by filter, newest
+		// first. A limit of zero or less means no limit.
+		Entries(filter *ResolveFilter, limit int) ([]HistoryEntry, error)
+	}
+
+	// MemoryHistory is a History that lives only as long as the process.
+	MemoryHistory struct {
+		sync.Mutex
+		entries []HistoryEntry
+	}
+
+	// HistoryStateManager wraps a StateManager, recording a HistoryEntry
+	// for every manifest changed by WriteState.
+	HistoryStateManager struct {
+		StateManager
+		History History
+		log     logging.LogSink
+	}
+)
+
+const (
+	// HistoryAdded means a manifest was added to the GDM.
+	HistoryAdded HistoryKind = "added"
+	// HistoryModified means a manifest in the GDM was changed.
+	HistoryModified HistoryKind = "modified"
+	// HistoryRemoved means a manifest was removed from the GDM.
+	HistoryRemoved HistoryKind = "removed"
+)
+
+// HistoryChanges compares two states and returns a HistoryEntry for each
+// manifest that differs between them.
+func HistoryChanges(prior, post *State, user User, when time.Time) []HistoryEntry {
+	var entries []HistoryEntry
+	add := func(mid ManifestID, kind HistoryKind, diffs []string, clusters []string) {
+		sort.Strings(clusters)
+		entries = append(entries, HistoryEntry{
+			ID:         uuid.New(),
+			Time:       when,
+			User:       user,
+			ManifestID: mid,
+			Kind:       kind,
+			Diffs:      diffs,
+			Clusters:   clusters,
+		})
+	}
+
+	priors := prior.Manifests.Snapshot()
+	posts := post.Manifests.Snapshot()
+	for mid, pm := range posts {
+		before, existed := priors[mid]
+		if !existed {
+			add(mid, HistoryAdded, []string{"manifest added"}, clusterNames(pm))
+			continue
+		}
+		different, diffs := before.Diff(pm)
+		if !different {
+			continue
+		}
+		add(mid, HistoryModified, diffs, changedClusters(before, pm))
+	}
+	for mid, pm := range priors {
+		if _, remains := posts[mid]; !remains {
+			add(mid, HistoryRemoved, []string{"manifest removed"}, clusterNames(pm))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ManifestID.String() < entries[j].ManifestID.String()
+	})
+	return entries
+}
+
+func clusterNames(m *Manifest) []string {
+	names := []string{}
+	for name := range m.Deployments {
+		names = append(names, name)
+	}
+	return names
+}
+
+// changedClusters returns the clusters whose deployments differ between prior
+// and post. If only manifest-wide fields differ, that is every cluster.
+func changedClusters(prior, post *Manifest) []string {
+	all := map[string]struct{}{}
+	changed := []string{}
+	for name := range prior.Deployments {
+		all[name] = struct{}{}
+	}
+	for name := range post.Deployments {
+		all[name] = struct{}{}
+	}
+	for name := range all {
+		before, inPrior := prior.Deployments[name]
+		after, inPost := post.Deployments[name]
+		if !inPrior || !inPost {
+			changed = append(changed, name)
+			continue
+		}
+		if different, _ := before.Diff(after); different {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) > 0 {
+		return changed
+	}
+	for name := range all {
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// Matches returns true if e is matched by filter.
+func (e HistoryEntry) Matches(filter *ResolveFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if !filter.FilterManifestID(e.ManifestID) {
+		return false
+	}
+	if filter.Cluster.All() {
+		return true
+	}
+	for _, c := range e.Clusters {
+		if filter.FilterClusterName(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Affects returns true if e changed the deployment identified by did.
+func (e HistoryEntry) Affects(did DeploymentID) bool {
+	if e.ManifestID != did.ManifestID {
+		return false
+	}
+	for _, c := range e.Clusters {
+		if c == did.Cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMemoryHistory returns an empty MemoryHistory.
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+// RecordChanges implements History on MemoryHistory.
+func (h *MemoryHistory) RecordChanges(entries []HistoryEntry) error {
+	h.Lock()
+	defer h.Unlock()
+	h.entries = append(h.entries, entries...)
+	return nil
+}
+
+// RecordOutcome implements History on MemoryHistory.
+func (h *MemoryHistory) RecordOutcome(did DeploymentID, dr DiffResolution) error {
+	h.Lock()
+	defer h.Unlock()
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := &h.entries[i]
+		if !e.Affects(did) {
+			continue
+		}
+		if _, has := e.Outcomes[did.Cluster]; has {
+			return nil
+		}
+		if e.Outcomes == nil {
+			e.Outcomes = map[string]HistoryOutcome{}
+		}
+		e.Outcomes[did.Cluster] = HistoryOutcome{Time: time.Now(), Resolution: dr}
+		return nil
+	}
+	return nil
+}
+
+// Entries implements History on MemoryHistory.
+func (h *MemoryHistory) Entries(filter *ResolveFilter, limit int) ([]HistoryEntry, error) {
+	h.Lock()
+	defer h.Unlock()
+	entries := []HistoryEntry{}
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		if h.entries[i].Matches(filter) {
+			entries = append(entries, h.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// NewHistoryStateManager wraps sm so that changes written through it are
+// recorded in h.
+func NewHistoryStateManager(sm StateManager, h History, ls logging.LogSink) *HistoryStateManager {
+	return &HistoryStateManager{StateManager: sm, History: h, log: ls}
+}
+
+// WriteState implements StateWriter on HistoryStateManager.
+func (hsm *HistoryStateManager) WriteState(state *State, user User) error {
+	prior, err := hsm.StateManager.ReadState()
+	if err != nil {
+		// We may be writing the first state; there is nothing to compare.
+		prior = NewState()
+	}
+	entries := HistoryChanges(prior, state, user, time.Now())
+	if err := hsm.StateManager.WriteState(state, user); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := hsm.History.RecordChanges(entries); err != nil {
+		// The write has already happened, so we report rather than fail.
+		messages.ReportLogFieldsMessage("Failed to record GDM history", logging.WarningLevel, hsm.log, user, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTestManifest(repo string, versions map[string]string) *Manifest {
+	m := &Manifest{
+		Source:      SourceLocation{Repo: repo},
+		Kind:        ManifestKindService,
+		Deployments: DeploySpecs{},
+	}
+	for cluster, v := range versions {
+		m.Deployments[cluster] = DeploySpec{Version: semv.MustParse(v)}
+	}
+	return m
+}
+
+func historyTestState(ms ...*Manifest) *State {
+	s := NewState()
+	for _, m := range ms {
+		s.Manifests.Add(m)
+	}
+	return s
+}
+
+func TestHistoryChanges(t *testing.T) {
+	user := User{Name: "Test User", Email: "test@example.com"}
+	when := time.Now()
+
+	prior := historyTestState(
+		historyTestManifest("github.com/example/changed", map[string]string{"one": "1.0.0", "two": "1.0.0"}),
+		historyTestManifest("github.com/example/removed", map[string]string{"one": "1.0.0"}),
+		historyTestManifest("github.com/example/unchanged", map[string]string{"one": "1.0.0"}),
+	)
+	post := historyTestState(
+		historyTestManifest("github.com/example/added", map[string]string{"two": "1.0.0", "one": "1.0.0"}),
+		historyTestManifest("github.com/example/changed", map[string]string{"one": "1.0.0", "two": "2.0.0"}),
+		historyTestManifest("github.com/example/unchanged", map[string]string{"one": "1.0.0"}),
+	)
+
+	entries := HistoryChanges(prior, post, user, when)
+	require.Len(t, entries, 3)
+
+	added, changed, removed := entries[0], entries[1], entries[2]
+
+	assert.Equal(t, "github.com/example/added", added.ManifestID.Source.Repo)
+	assert.Equal(t, HistoryAdded, added.Kind)
+	assert.Equal(t, []string{"one", "two"}, added.Clusters)
+
+	assert.Equal(t, "github.com/example/changed", changed.ManifestID.Source.Repo)
+	assert.Equal(t, HistoryModified, changed.Kind)
+	assert.Equal(t, []string{"two"}, changed.Clusters)
+	require.Len(t, changed.Diffs, 1)
+	assert.Contains(t, changed.Diffs[0], "two: version")
+
+	assert.Equal(t, "github.com/example/removed", removed.ManifestID.Source.Repo)
+	assert.Equal(t, HistoryRemoved, removed.Kind)
+
+	for _, e := range entries {
+		assert.Equal(t, user, e.User)
+		assert.Equal(t, when, e.Time)
+		assert.NotEmpty(t, e.ID)
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	h := NewMemoryHistory()
+	mid := ManifestID{Source: SourceLocation{Repo: "github.com/example/project"}}
+	other := ManifestID{Source: SourceLocation{Repo: "github.com/example/other"}}
+
+	require.NoError(t, h.RecordChanges([]HistoryEntry{
+		{ID: "first", ManifestID: mid, Clusters: []string{"one"}},
+		{ID: "other", ManifestID: other, Clusters: []string{"one", "two"}},
+	}))
+	require.NoError(t, h.RecordChanges([]HistoryEntry{
+		{ID: "second", ManifestID: mid, Clusters: []string{"one", "two"}},
+	}))
+
+	did := DeploymentID{ManifestID: mid, Cluster: "one"}
+	require.NoError(t, h.RecordOutcome(did, DiffResolution{Desc: ModifyDiff}))
+	// Only the first rectification after a change is its outcome.
+	require.NoError(t, h.RecordOutcome(did, DiffResolution{Desc: StableDiff}))
+
+	all, err := h.Entries(nil, 0)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "second", all[0].ID)
+	assert.Equal(t, ModifyDiff, all[0].Outcomes["one"].Resolution.Desc)
+	assert.NotContains(t, all[0].Outcomes, "two")
+	assert.Empty(t, all[2].Outcomes)
+
+	limited, err := h.Entries(nil, 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	byRepo, err := h.Entries(&ResolveFilter{Repo: NewResolveFieldMatcher(mid.Source.Repo)}, 0)
+	require.NoError(t, err)
+	assert.Len(t, byRepo, 2)
+
+	byCluster, err := h.Entries(&ResolveFilter{Cluster: NewResolveFieldMatcher("two")}, 0)
+	require.NoError(t, err)
+	require.Len(t, byCluster, 2)
+	assert.Equal(t, "second", byCluster[0].ID)
+	assert.Equal(t, "other", byCluster[1].ID)
+}
+
+func TestHistoryStateManager_WriteState(t *testing.T) {
+	dsm := NewDummyStateManager()
+	dsm.State = historyTestState(historyTestManifest("github.com/example/project", map[string]string{"one": "1.0.0"}))
+	h := NewMemoryHistory()
+	hsm := NewHistoryStateManager(dsm, h, nil)
+
+	user := User{Name: "Test User"}
+	next := historyTestState(historyTestManifest("github.com/example/project", map[string]string{"one": "1.1.0"}))
+	require.NoError(t, hsm.WriteState(next, user))
+	assert.Equal(t, 1, dsm.WriteCount)
+
+	entries, err := h.Entries(nil, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, HistoryModified, entries[0].Kind)
+	assert.Equal(t, user, entries[0].User)
+	assert.Equal(t, []string{"one"}, entries[0].Clusters)
+
+	// A failed write records nothing.
+	dsm.WriteErr = assert.AnError
+	again := historyTestState(historyTestManifest("github.com/example/project", map[string]string{"one": "1.2.0"}))
+	assert.Error(t, hsm.WriteState(again, user))
+	entries, err = h.Entries(nil, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -13,6 +13,8 @@ import (
 type HTTPNameInserter struct {
 	serverURL *url.URL
 	http.Client
+	// Header is sent with each insert, e.g. to authenticate to the server.
+	Header http.Header
 }
 
 // NewHTTPNameInserter creates a new HTTPNameInserter
@@ -20,17 +22,23 @@ func NewHTTPNameInserter(server string) (*HTTPNameInserter, error) {
 	u, err := url.Parse(server)
 	return &HTTPNameInserter{
 		serverURL: u,
+		Header:    http.Header{},
 	}, errors.Wrapf(err, "new state manager")
 }
 
 // Insert implements Inserter for HTTPNameInserter
 func (hni *HTTPNameInserter) Insert(sid SourceID, in, etag string, qs []Quality) error {
+	return hni.InsertWithSBOM(sid, in, etag, qs, nil)
+}
+
+// InsertWithSBOM implements SBOMInserter for HTTPNameInserter
+func (hni *HTTPNameInserter) InsertWithSBOM(sid SourceID, in, etag string, qs []Quality, sbom *SBOM) error {
 	url, err := hni.serverURL.Parse("./artifact")
 	if err != nil {
 		return errors.Wrapf(err, "http insert name: %s for %v", in, sid)
 	}
 	url.RawQuery = sid.QueryValues().Encode()
-	art := &BuildArtifact{Name: in, Type: "docker", Qualities: qs}
+	art := &BuildArtifact{Name: in, Type: "docker", Qualities: qs, SBOM: sbom}
 	buf := &bytes.Buffer{}
 	enc := json.NewEncoder(buf)
 	err = enc.Encode(art)
@@ -42,6 +50,9 @@ func (hni *HTTPNameInserter) Insert(sid SourceID, in, etag string, qs []Quality)
 	if err != nil {
 		return errors.Wrapf(err, "http insert name %s, building request for %s/%v", in, url, art)
 	}
+	for k, vs := range hni.Header {
+		req.Header[k] = vs
+	}
 
 	rz, err := hni.Client.Do(req)
 	if err != nil {
@@ -20,6 +20,9 @@ func TestHTTPNameInserter(t *testing.T) {
 		if path := r.URL.Path; path != "/artifact" {
 			t.Errorf("Path should be '/artifact' but was: %s", path)
 		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer s3cret" {
+			t.Errorf("Authorization should be 'Bearer s3cret' but was: %q", auth)
+		}
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			t.Error(err)
@@ -37,6 +40,7 @@ func TestHTTPNameInserter(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	hni.Header.Set("Authorization", "Bearer s3cret")
 	err = hni.Insert(
 		SourceID{Location: SourceLocation{Repo: "a-repo", Dir: "offset"}, Version: semv.MustParse("5.5.5")},
 		"dockerthin.com/repo/latest",
@@ -2,6 +2,8 @@ package sous
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -186,3 +188,50 @@ func (g *gdmWrapper) manifests(defs Defs) (Manifests, error) {
 	}
 	return ds.RawManifests(defs)
 }
+
+// GDMWatchTimeout is how long a request to the server's /gdm/watch endpoint
+// waits for a change before returning without one.
+const GDMWatchTimeout = 30 * time.Second
+
+// Watch implements StateWatcher on HTTPStateManager, by long-polling the
+// server's /gdm/watch endpoint.
+func (hsm *HTTPStateManager) Watch(done <-chan struct{}) (<-chan StateChange, error) {
+	var latest StateChange
+	q := map[string]string{"timeout": "0s"}
+	if _, err := hsm.Retrieve("./gdm/watch", q, &latest, hsm.User.HTTPHeaders()); err != nil {
+		return nil, errors.Wrapf(err, "watching GDM")
+	}
+
+	out := make(chan StateChange, 1)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			var next StateChange
+			q := map[string]string{"seq": strconv.Itoa(latest.Seq), "timeout": GDMWatchTimeout.String()}
+			if _, err := hsm.Retrieve("./gdm/watch", q, &next, hsm.User.HTTPHeaders()); err != nil {
+				logging.ReportError(logging.Log, errors.Wrapf(err, "watching GDM"))
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+			if next.Seq == latest.Seq {
+				continue
+			}
+			latest = next
+			select {
+			case out <- next:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
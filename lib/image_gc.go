@@ -0,0 +1,159 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An ArtifactCollector is a Registry which can find every artifact built
+	// from a source location, and delete artifacts which are no longer needed.
+	ArtifactCollector interface {
+		Registry
+		// HarvestArtifacts finds the artifacts built from sl, so that
+		// ListSourceIDs lists them.
+		HarvestArtifacts(sl SourceLocation) error
+		// DeleteArtifact deletes the artifact built from sid, and forgets it.
+		// It returns a *SharedArtifactError, and deletes nothing, if the
+		// image of the artifact is also that of one of needed, or is pinned
+		// for another artifact.
+		DeleteArtifact(sid SourceID, needed []SourceID) error
+	}
+
+	// A SharedArtifactError is returned when an artifact cannot be deleted
+	// because its image is still needed by another.
+	SharedArtifactError struct {
+		SourceID SourceID
+		Image    string
+		// SharedWith describes what else needs the image.
+		SharedWith string
+	}
+)
+
+func (e *SharedArtifactError) Error() string {
+	return fmt.Sprintf("the image %s of %s is shared with %s", e.Image, e.SourceID, e.SharedWith)
+}
+
+// UnreferencedSourceIDs partitions known into those which are unneeded and
+// those which are needed by state. An artifact is needed if it is deployed
+// by state, or is among the keep most recent versions of the source of one
+// of its manifests. Artifacts built from sources which no manifest refers to
+// are only unneeded if orphans is true, and are otherwise needed. Both
+// results are ordered by source, then version.
+func UnreferencedSourceIDs(state *State, known []SourceID, keep int, orphans bool) (unneeded, needed []SourceID, err error) {
+	ds, err := state.Deployments()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var referenced []SourceID
+	for _, d := range ds.Snapshot() {
+		referenced = append(referenced, d.SourceID)
+	}
+
+	bySource := map[SourceLocation][]SourceID{}
+	for _, sid := range known {
+		bySource[sid.Location] = append(bySource[sid.Location], sid)
+	}
+	for _, m := range state.Manifests.Snapshot() {
+		sids := bySource[m.Source]
+		delete(bySource, m.Source)
+		sort.Slice(sids, func(i, j int) bool {
+			return sids[j].Version.Less(sids[i].Version)
+		})
+		for i := 0; i < keep && i < len(sids); i++ {
+			referenced = append(referenced, sids[i])
+		}
+	}
+	if !orphans {
+		for _, sids := range bySource {
+			referenced = append(referenced, sids...)
+		}
+	}
+
+	for _, sid := range known {
+		if KnownSourceID(sid, referenced) {
+			needed = append(needed, sid)
+		} else {
+			unneeded = append(unneeded, sid)
+		}
+	}
+	sortSourceIDs(unneeded)
+	sortSourceIDs(needed)
+	return unneeded, needed, nil
+}
+
+func sortSourceIDs(sids []SourceID) {
+	sort.Slice(sids, func(i, j int) bool {
+		a, b := sids[i], sids[j]
+		if a.Location != b.Location {
+			return a.Location.String() < b.Location.String()
+		}
+		return a.Version.Less(b.Version)
+	})
+}
+
+// ImageDigest returns the digest an image name refers to, or the name itself
+// if it is not named by digest.
+func ImageDigest(name string) string {
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// ServerImages returns the images which the Sous servers need, keyed by
+// digest, each with a description of what needs it. They are the images
+// deployed in each cluster, as reported by the /status of its server in
+// clusters, and the images of needed recorded by the server at main. Digests
+// are pinned by the servers as they deploy, so the registry of a client may
+// not know them. Needed artifacts which main cannot find are skipped.
+func ServerImages(main restful.HTTPClient, clusters map[string]restful.HTTPClient, needed []SourceID) (map[string]string, error) {
+	images := map[string]string{}
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data := &statusData{}
+		if _, err := clusters[name].Retrieve("./status", nil, data, nil); err != nil {
+			return nil, errors.Wrapf(err, "getting the deployments of cluster %s", name)
+		}
+		for _, rs := range []*ResolveStatus{data.Completed, data.InProgress} {
+			if rs == nil {
+				continue
+			}
+			for _, dr := range rs.Log {
+				if dr.DeployState == nil || dr.DeployState.BuildArtifact == nil || dr.DeployState.BuildArtifact.Name == "" {
+					continue
+				}
+				images[ImageDigest(dr.DeployState.BuildArtifact.Name)] = fmt.Sprintf("%s, deployed in %s", dr.DeploymentID, name)
+			}
+		}
+	}
+	for _, sid := range needed {
+		art := &BuildArtifact{}
+		if _, err := main.Retrieve("./artifact", sourceIDQuery(sid), art, nil); err != nil || art.Name == "" {
+			continue
+		}
+		if _, deployed := images[ImageDigest(art.Name)]; !deployed {
+			images[ImageDigest(art.Name)] = sid.String()
+		}
+	}
+	return images, nil
+}
+
+// sourceIDQuery returns the query parameters which identify sid to the
+// /artifact endpoint.
+func sourceIDQuery(sid SourceID) map[string]string {
+	q := map[string]string{}
+	for k, vs := range sid.QueryValues() {
+		q[k] = vs[0]
+	}
+	return q
+}
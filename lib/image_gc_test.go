@@ -0,0 +1,58 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnreferencedSourceIDs(t *testing.T) {
+	sl := MustParseSourceLocation("github.com/user/project")
+	gone := MustParseSourceLocation("github.com/user/removed")
+	state := &State{
+		Defs: Defs{Clusters: Clusters{"cluster-1": cluster1}},
+		Manifests: NewManifests(&Manifest{
+			Source: sl,
+			Kind:   ManifestKindService,
+			Deployments: DeploySpecs{
+				"cluster-1": {Version: semv.MustParse("1.0.0")},
+			},
+		}),
+	}
+	known := []SourceID{
+		sl.SourceID(semv.MustParse("0.9.0")),
+		sl.SourceID(semv.MustParse("1.2.0")),
+		gone.SourceID(semv.MustParse("3.0.0")),
+		sl.SourceID(semv.MustParse("1.0.0+abcdef")),
+		sl.SourceID(semv.MustParse("0.1.0")),
+		sl.SourceID(semv.MustParse("1.1.0")),
+	}
+
+	unneeded, needed, err := UnreferencedSourceIDs(state, known, 2, false)
+	require.NoError(t, err)
+	assert.Equal(t, []SourceID{
+		sl.SourceID(semv.MustParse("0.1.0")),
+		sl.SourceID(semv.MustParse("0.9.0")),
+	}, unneeded)
+	assert.Equal(t, []SourceID{
+		sl.SourceID(semv.MustParse("1.0.0+abcdef")),
+		sl.SourceID(semv.MustParse("1.1.0")),
+		sl.SourceID(semv.MustParse("1.2.0")),
+		gone.SourceID(semv.MustParse("3.0.0")),
+	}, needed, "sources without manifests should be kept without orphans")
+
+	unneeded, _, err = UnreferencedSourceIDs(state, known, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, []SourceID{
+		sl.SourceID(semv.MustParse("0.1.0")),
+		sl.SourceID(semv.MustParse("0.9.0")),
+		gone.SourceID(semv.MustParse("3.0.0")),
+	}, unneeded)
+
+	unneeded, needed, err = UnreferencedSourceIDs(state, known, 0, true)
+	require.NoError(t, err)
+	assert.Len(t, unneeded, 5, "only the deployed version should be kept")
+	assert.Len(t, needed, 1)
+}
@@ -31,6 +31,9 @@ type (
 		Kind ManifestKind `validate:"nonzero"`
 		// Deployments is a map of cluster names to DeploymentSpecs
 		Deployments DeploySpecs `validate:"keys=nonempty,values=nonzero"`
+		// Webhooks are notified of the rectification of this manifest's
+		// deployments.
+		Webhooks Webhooks `yaml:",omitempty"`
 	}
 )
 
@@ -59,6 +62,7 @@ func (m Manifest) Clone() (c *Manifest) {
 	}
 	c.Owners = owners
 	c.Deployments = deployments
+	c.Webhooks = m.Webhooks.Clone()
 	return
 }
 
@@ -111,6 +115,9 @@ func (m *Manifest) Diff(o *Manifest) (bool, []string) {
 			}
 		}
 	}
+	if !m.Webhooks.Equal(o.Webhooks) {
+		diff("webhooks; this: %v; other: %v", m.Webhooks, o.Webhooks)
+	}
 	return len(diffs) != 0, diffs
 }
 
@@ -131,6 +138,7 @@ func (m *Manifest) Validate() []Flaw {
 	} else {
 		flaws = append(flaws, m.Kind.Validate()...)
 	}
+	flaws = append(flaws, m.Webhooks.Validate()...)
 
 	/*
 		Cannot validate Deployments without defs...
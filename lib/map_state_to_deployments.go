@@ -62,6 +62,10 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests) (Manifests, er
 			m = &Manifest{Deployments: DeploySpecs{}}
 			m.Owners = d.Owners.Slice()
 			m.SetID(mid)
+			// Webhooks are not part of Deployments, so keep any already set.
+			if was {
+				m.Webhooks = old.Webhooks.Clone()
+			}
 		}
 		spec := DeploySpec{
 			Version:      d.SourceID.Version,
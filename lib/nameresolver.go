@@ -83,6 +83,12 @@ func guardImage(r Registry, d *Deployment) (*BuildArtifact, error) {
 	if err != nil {
 		return nil, &MissingImageNameError{err}
 	}
+	if dp, ok := r.(DigestPinner); ok {
+		art, err = dp.PinArtifact(d.SourceID, art)
+		if err != nil {
+			return nil, &MissingImageNameError{err}
+		}
+	}
 	for _, q := range art.Qualities {
 		if q.Kind != "advisory" || q.Name == "" {
 			continue
@@ -103,5 +109,14 @@ func guardImage(r Registry, d *Deployment) (*BuildArtifact, error) {
 			return nil, &UnacceptableAdvisory{q, &d.SourceID}
 		}
 	}
+	if err := checkArtifactPolicy(r, d, art); err != nil {
+		return nil, err
+	}
+	if err := checkPlatform(r, d, art); err != nil {
+		return nil, err
+	}
+	if err := checkSignature(r, d, art); err != nil {
+		return nil, err
+	}
 	return art, err
 }
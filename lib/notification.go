@@ -0,0 +1,395 @@
+package sous
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Webhooks is a list of Webhooks.
+	Webhooks []Webhook
+
+	// A Webhook is an HTTP endpoint which is sent Notifications about the
+	// rectification of deployments.
+	Webhook struct {
+		// URL is where Notifications are POSTed.
+		URL string
+		// Format is the format of the payload: "json" (the default) sends the
+		// Notification itself, "slack" sends a Slack-compatible message.
+		Format string `yaml:",omitempty"`
+		// Events restricts the Notifications sent to these events. If empty,
+		// every event is sent.
+		Events []NotificationEvent `yaml:",omitempty"`
+		// Clusters restricts the Notifications sent to those about
+		// deployments to these clusters. If empty, every cluster is included.
+		Clusters []string `yaml:",omitempty"`
+	}
+
+	// A NotificationEvent is something that happened to a deployment.
+	NotificationEvent string
+
+	// A Notification reports a NotificationEvent to Webhooks.
+	Notification struct {
+		Event        NotificationEvent
+		DeploymentID DeploymentID
+		// Version is the version being deployed.
+		Version string `json:",omitempty"`
+		// Error describes why a deploy failed.
+		Error string `json:",omitempty"`
+		Time  time.Time
+	}
+
+	// A NotificationDelivery records the delivery of a Notification to a
+	// Webhook.
+	NotificationDelivery struct {
+		Notification Notification
+		URL          string
+		// Attempts is the number of times delivery was tried.
+		Attempts int
+		// StatusCode is the HTTP status of the last attempt, or zero if no
+		// response was received.
+		StatusCode int
+		// Error describes why the last attempt failed, if it did.
+		Error string `json:",omitempty"`
+		Time  time.Time
+	}
+
+	// A Notifier sends Notifications about rectifications to the Webhooks
+	// defined for them in the GDM, either in Defs or in the manifest being
+	// deployed.
+	Notifier struct {
+		StateReader
+		// Retries is the number of times a failed delivery is retried.
+		Retries int
+		// RetryWait is how long to wait before the first retry. It doubles
+		// with each subsequent retry.
+		RetryWait time.Duration
+		// AllowedHosts are the hosts Notifications may be sent to, either
+		// exactly or, if they begin with a dot, any subdomain of them.
+		// Webhooks with any other host are refused, as are redirects to them.
+		AllowedHosts []string
+		client       *http.Client
+		log          logging.LogSink
+
+		sync.Mutex
+		deliveries []NotificationDelivery
+		// failed holds the last resolve error notified for each deployment
+		// by HandleResolve, so that repeated errors are notified only once.
+		failed map[DeploymentID]string
+	}
+)
+
+const (
+	// DeployStarted is sent when a rectification begins.
+	DeployStarted = NotificationEvent("deploy-started")
+	// DeploySucceeded is sent when a rectification completes successfully.
+	DeploySucceeded = NotificationEvent("deploy-succeeded")
+	// DeployFailed is sent when a rectification fails.
+	DeployFailed = NotificationEvent("deploy-failed")
+	// DeployFailedStatus is sent when a deploy was made but reported by its
+	// scheduler as having failed (see FailedStatusError).
+	DeployFailedStatus = NotificationEvent("deploy-failed-status")
+
+	// maxNotificationDeliveries is the number of deliveries kept by a Notifier.
+	maxNotificationDeliveries = 200
+)
+
+// Clone returns a deep copy of this Webhooks.
+func (ws Webhooks) Clone() Webhooks {
+	if ws == nil {
+		return nil
+	}
+	c := make(Webhooks, len(ws))
+	for i, w := range ws {
+		w.Events = append([]NotificationEvent(nil), w.Events...)
+		w.Clusters = append([]string(nil), w.Clusters...)
+		c[i] = w
+	}
+	return c
+}
+
+// Equal returns true if ws and o contain the same Webhooks in the same order.
+func (ws Webhooks) Equal(o Webhooks) bool {
+	if len(ws) != len(o) {
+		return false
+	}
+	for i := range ws {
+		if ws[i].URL != o[i].URL || ws[i].Format != o[i].Format ||
+			fmt.Sprint(ws[i].Events) != fmt.Sprint(o[i].Events) ||
+			fmt.Sprint(ws[i].Clusters) != fmt.Sprint(o[i].Clusters) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that each Webhook has an HTTP URL and a known format.
+func (ws Webhooks) Validate() []Flaw {
+	var flaws []Flaw
+	for _, w := range ws {
+		if w.URL == "" {
+			flaws = append(flaws, FatalFlaw("Webhook has no URL"))
+		} else if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			flaws = append(flaws, FatalFlaw("Webhook URL %q must be an http:// or https:// URL", w.URL))
+		}
+		if w.Format != "" && w.Format != "json" && w.Format != "slack" {
+			flaws = append(flaws, FatalFlaw("Webhook %q has unknown format %q: expected json or slack", w.URL, w.Format))
+		}
+	}
+	return flaws
+}
+
+// Wants returns true if w should be sent n.
+func (w Webhook) Wants(n Notification) bool {
+	return (len(w.Events) == 0 || containsEvent(w.Events, n.Event)) &&
+		(len(w.Clusters) == 0 || containsString(w.Clusters, n.DeploymentID.Cluster))
+}
+
+func containsEvent(es []NotificationEvent, e NotificationEvent) bool {
+	for _, x := range es {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// payload returns the body to POST to w for n.
+func (w Webhook) payload(n Notification) ([]byte, error) {
+	if w.Format != "slack" {
+		return json.Marshal(n)
+	}
+	text := fmt.Sprintf("*%s* %s", n.Event, n.DeploymentID)
+	if n.Version != "" {
+		text += fmt.Sprintf(" version %s", n.Version)
+	}
+	if n.Error != "" {
+		text += fmt.Sprintf(": %s", n.Error)
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// NewNotifier returns a Notifier which reads Webhooks from sr and delivers
+// Notifications using a copy of client, to the hosts in allowedHosts.
+func NewNotifier(sr StateReader, client *http.Client, allowedHosts []string, ls logging.LogSink) *Notifier {
+	n := &Notifier{
+		StateReader:  sr,
+		Retries:      3,
+		RetryWait:    time.Second,
+		AllowedHosts: allowedHosts,
+		log:          ls,
+		failed:       map[DeploymentID]string{},
+	}
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return n.checkURL(req.URL)
+	}
+	n.client = &c
+	return n
+}
+
+// checkURL returns an error unless u is an HTTP URL with one of the
+// AllowedHosts.
+func (n *Notifier) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("webhook URL %q is not http:// or https://", u)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range n.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return errors.Errorf("webhook host %q is not allowed", host)
+}
+
+// NotificationFor returns the Notification describing a finished
+// rectification of pair with result dr.
+func NotificationFor(pair *DeployablePair, dr DiffResolution) Notification {
+	n := Notification{
+		Event:        DeploySucceeded,
+		DeploymentID: pair.ID(),
+		Time:         time.Now(),
+	}
+	if pair.Post != nil {
+		n.Version = pair.Post.DeploySpec().Version.String()
+	}
+	if dr.Error != nil {
+		n.Event = DeployFailed
+		n.Error = dr.Error.Error()
+		if _, is := errors.Cause(dr.Error.error).(*FailedStatusError); is {
+			n.Event = DeployFailedStatus
+		}
+	}
+	return n
+}
+
+// RectificationStarted notifies that the rectification of pair has begun.
+func (n *Notifier) RectificationStarted(pair *DeployablePair) {
+	note := Notification{Event: DeployStarted, DeploymentID: pair.ID(), Time: time.Now()}
+	if pair.Post != nil {
+		note.Version = pair.Post.DeploySpec().Version.String()
+	}
+	n.Notify(note)
+}
+
+// RectificationFinished notifies the outcome of the rectification of pair.
+func (n *Notifier) RectificationFinished(pair *DeployablePair, dr DiffResolution) {
+	n.Notify(NotificationFor(pair, dr))
+}
+
+// HandleResolve notifies deploys which failed during a resolution before
+// they could be rectified, for instance because their artifact could not be
+// found. Each distinct error is notified only once per deployment. It is
+// intended to be passed to AutoResolver.AfterResolve.
+func (n *Notifier) HandleResolve(status ResolveStatus) {
+	for _, dr := range status.Log {
+		gated := false
+		if dr.Error != nil {
+			switch errors.Cause(dr.Error.error).(type) {
+			case *MissingImageNameError, *UnacceptableAdvisory, *ArtifactPolicyViolation, *UnsupportedPlatform, *UnverifiedArtifact:
+				gated = true
+			}
+		}
+		if !gated {
+			// The deployment got past the resolution, so the next error
+			// it meets there is notified again, even if it is the same.
+			n.Lock()
+			delete(n.failed, dr.DeploymentID)
+			n.Unlock()
+			continue
+		}
+		msg := dr.Error.Error()
+		n.Lock()
+		notified := n.failed[dr.DeploymentID] == msg
+		n.failed[dr.DeploymentID] = msg
+		n.Unlock()
+		if notified {
+			continue
+		}
+		n.Notify(Notification{
+			Event:        DeployFailed,
+			DeploymentID: dr.DeploymentID,
+			Error:        msg,
+			Time:         time.Now(),
+		})
+	}
+}
+
+// Notify sends note to the Webhooks that want it, in the background.
+func (n *Notifier) Notify(note Notification) {
+	hooks, err := n.webhooksFor(note.DeploymentID)
+	if err != nil {
+		messages.ReportLogFieldsMessage("Unable to read webhooks", logging.WarningLevel, n.log, note, err)
+		return
+	}
+	for _, w := range hooks {
+		if !w.Wants(note) {
+			continue
+		}
+		go n.deliver(w, note)
+	}
+}
+
+// webhooksFor returns the Webhooks defined for did in Defs and its manifest.
+func (n *Notifier) webhooksFor(did DeploymentID) (Webhooks, error) {
+	state, err := n.StateReader.ReadState()
+	if err != nil {
+		return nil, err
+	}
+	hooks := append(Webhooks{}, state.Defs.Webhooks...)
+	if m, ok := state.Manifests.Get(did.ManifestID); ok {
+		hooks = append(hooks, m.Webhooks...)
+	}
+	return hooks, nil
+}
+
+// deliver POSTs note to w, retrying failures, and records the delivery.
+func (n *Notifier) deliver(w Webhook, note Notification) {
+	d := NotificationDelivery{Notification: note, URL: w.URL}
+	defer func() {
+		d.Time = time.Now()
+		n.record(d)
+	}()
+
+	u, err := url.Parse(w.URL)
+	if err == nil {
+		err = n.checkURL(u)
+	}
+	if err != nil {
+		d.Error = err.Error()
+		messages.ReportLogFieldsMessage("Refused to deliver notification", logging.WarningLevel, n.log, d)
+		return
+	}
+	body, err := w.payload(note)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	wait := n.RetryWait
+	for d.Attempts = 1; ; d.Attempts++ {
+		d.StatusCode, err = n.post(w.URL, body)
+		if err == nil {
+			d.Error = ""
+			return
+		}
+		d.Error = err.Error()
+		if d.Attempts > n.Retries {
+			messages.ReportLogFieldsMessage("Failed to deliver notification", logging.WarningLevel, n.log, d)
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (n *Notifier) post(url string, body []byte) (int, error) {
+	rz, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer rz.Body.Close()
+	if rz.StatusCode < 200 || rz.StatusCode >= 300 {
+		return rz.StatusCode, errors.Errorf("webhook responded %s", rz.Status)
+	}
+	return rz.StatusCode, nil
+}
+
+func (n *Notifier) record(d NotificationDelivery) {
+	n.Lock()
+	defer n.Unlock()
+	n.deliveries = append(n.deliveries, d)
+	if over := len(n.deliveries) - maxNotificationDeliveries; over > 0 {
+		n.deliveries = n.deliveries[over:]
+	}
+}
+
+// Deliveries returns the most recent deliveries made by n, oldest first.
+func (n *Notifier) Deliveries() []NotificationDelivery {
+	n.Lock()
+	defer n.Unlock()
+	return append([]NotificationDelivery(nil), n.deliveries...)
+}
@@ -0,0 +1,198 @@
+package sous
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookRecorder is a test HTTP server which records the bodies POSTed to
+// it, failing the first failures requests.
+type webhookRecorder struct {
+	*httptest.Server
+	sync.Mutex
+	failures int
+	bodies   [][]byte
+}
+
+func newWebhookRecorder(failures int) *webhookRecorder {
+	wr := &webhookRecorder{failures: failures}
+	wr.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		wr.Lock()
+		defer wr.Unlock()
+		if wr.failures > 0 {
+			wr.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		wr.bodies = append(wr.bodies, body)
+	}))
+	return wr
+}
+
+func (wr *webhookRecorder) received() [][]byte {
+	wr.Lock()
+	defer wr.Unlock()
+	return append([][]byte(nil), wr.bodies...)
+}
+
+func notificationTestNotifier(s *State) *Notifier {
+	dsm := NewDummyStateManager()
+	dsm.State = s
+	n := NewNotifier(dsm, http.DefaultClient, []string{"127.0.0.1"}, logging.SilentLogSet())
+	n.RetryWait = time.Millisecond
+	return n
+}
+
+func awaitDeliveries(t *testing.T, n *Notifier, count int) []NotificationDelivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if ds := n.Deliveries(); len(ds) >= count {
+			return ds
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d deliveries", count)
+	return nil
+}
+
+func TestNotifier_ManifestAndDefsWebhooks(t *testing.T) {
+	defsHook := newWebhookRecorder(0)
+	defer defsHook.Close()
+	manifestHook := newWebhookRecorder(0)
+	defer manifestHook.Close()
+
+	m := historyTestManifest("github.com/example/project", map[string]string{"one": "1.0.0"})
+	m.Webhooks = Webhooks{{URL: manifestHook.URL, Format: "slack", Events: []NotificationEvent{DeployFailedStatus}}}
+	s := historyTestState(m)
+	s.Defs.Webhooks = Webhooks{{URL: defsHook.URL}}
+	n := notificationTestNotifier(s)
+
+	did := DeploymentID{ManifestID: m.ID(), Cluster: "one"}
+	n.Notify(Notification{Event: DeploySucceeded, DeploymentID: did})
+	awaitDeliveries(t, n, 1)
+	n.Notify(Notification{Event: DeployFailedStatus, DeploymentID: did, Error: "it broke"})
+	awaitDeliveries(t, n, 3)
+
+	require.Len(t, defsHook.received(), 2)
+	var got Notification
+	require.NoError(t, json.Unmarshal(defsHook.received()[0], &got))
+	assert.Equal(t, DeploySucceeded, got.Event)
+	assert.Equal(t, did, got.DeploymentID)
+
+	require.Len(t, manifestHook.received(), 1)
+	var slack map[string]string
+	require.NoError(t, json.Unmarshal(manifestHook.received()[0], &slack))
+	assert.Contains(t, slack["text"], "deploy-failed-status")
+	assert.Contains(t, slack["text"], "it broke")
+}
+
+func TestNotifier_Retries(t *testing.T) {
+	hook := newWebhookRecorder(2)
+	defer hook.Close()
+	s := historyTestState()
+	s.Defs.Webhooks = Webhooks{{URL: hook.URL}}
+	n := notificationTestNotifier(s)
+
+	n.Notify(Notification{Event: DeployStarted})
+	ds := awaitDeliveries(t, n, 1)
+	assert.Equal(t, 3, ds[0].Attempts)
+	assert.Equal(t, http.StatusOK, ds[0].StatusCode)
+	assert.Empty(t, ds[0].Error)
+	assert.Len(t, hook.received(), 1)
+}
+
+func TestNotifier_GivesUp(t *testing.T) {
+	hook := newWebhookRecorder(10)
+	defer hook.Close()
+	s := historyTestState()
+	s.Defs.Webhooks = Webhooks{{URL: hook.URL}}
+	n := notificationTestNotifier(s)
+	n.Retries = 1
+
+	n.Notify(Notification{Event: DeployStarted})
+	ds := awaitDeliveries(t, n, 1)
+	assert.Equal(t, 2, ds[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, ds[0].StatusCode)
+	assert.NotEmpty(t, ds[0].Error)
+}
+
+func TestNotificationFor(t *testing.T) {
+	pair := &DeployablePair{name: DeploymentID{Cluster: "one"}}
+	assert.Equal(t, DeploySucceeded, NotificationFor(pair, DiffResolution{}).Event)
+	assert.Equal(t, DeployFailedStatus, NotificationFor(pair, DiffResolution{Error: WrapResolveError(&FailedStatusError{})}).Event)
+	failed := NotificationFor(pair, DiffResolution{Error: WrapResolveError(&CreateError{Err: assert.AnError})})
+	assert.Equal(t, DeployFailed, failed.Event)
+	assert.NotEmpty(t, failed.Error)
+}
+
+func TestNotifier_HandleResolveNotifiesOnce(t *testing.T) {
+	hook := newWebhookRecorder(0)
+	defer hook.Close()
+	s := historyTestState()
+	s.Defs.Webhooks = Webhooks{{URL: hook.URL}}
+	n := notificationTestNotifier(s)
+
+	rs := ResolveStatus{Log: []DiffResolution{
+		{DeploymentID: DeploymentID{Cluster: "one"}, Error: WrapResolveError(&MissingImageNameError{Cause: assert.AnError})},
+		{DeploymentID: DeploymentID{Cluster: "two"}, Error: WrapResolveError(&CreateError{Err: assert.AnError})},
+		{DeploymentID: DeploymentID{Cluster: "three"}, Desc: StableDiff},
+	}}
+	n.HandleResolve(rs)
+	n.HandleResolve(rs)
+	ds := awaitDeliveries(t, n, 1)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, n.Deliveries(), 1)
+	assert.Equal(t, DeployFailed, ds[0].Notification.Event)
+	assert.Equal(t, "one", ds[0].Notification.DeploymentID.Cluster)
+
+	// Once the deployment resolves, the same error is notified again.
+	n.HandleResolve(ResolveStatus{Log: []DiffResolution{{DeploymentID: DeploymentID{Cluster: "one"}, Desc: StableDiff}}})
+	n.HandleResolve(rs)
+	awaitDeliveries(t, n, 2)
+}
+
+func TestNotifier_AllowedHosts(t *testing.T) {
+	hook := newWebhookRecorder(0)
+	defer hook.Close()
+	redirect := httptest.NewServer(http.RedirectHandler("http://internal.example.com/admin", http.StatusFound))
+	defer redirect.Close()
+	s := historyTestState()
+	s.Defs.Webhooks = Webhooks{{URL: hook.URL}, {URL: redirect.URL}}
+	n := notificationTestNotifier(s)
+	n.AllowedHosts = []string{"127.0.0.1", ".example.org"}
+
+	n.Notify(Notification{Event: DeployStarted})
+	ds := awaitDeliveries(t, n, 2)
+	assert.Len(t, hook.received(), 1)
+	for _, d := range ds {
+		if d.URL == redirect.URL {
+			assert.Contains(t, d.Error, `"internal.example.com" is not allowed`)
+		}
+	}
+
+	assert.NoError(t, n.checkURL(&url.URL{Scheme: "https", Host: "hooks.example.org"}))
+	assert.Error(t, n.checkURL(&url.URL{Scheme: "https", Host: "example.org"}))
+	assert.Error(t, n.checkURL(&url.URL{Scheme: "https", Host: "evil-example.org"}))
+	assert.Error(t, n.checkURL(&url.URL{Scheme: "file", Host: "127.0.0.1"}))
+
+	n.AllowedHosts = nil
+	assert.Error(t, n.checkURL(&url.URL{Scheme: "http", Host: "127.0.0.1:8080"}))
+}
+
+func TestWebhooks_Validate(t *testing.T) {
+	assert.Empty(t, Webhooks{{URL: "http://example.com", Format: "slack"}}.Validate())
+	assert.Len(t, Webhooks{{}}.Validate(), 1)
+	assert.Len(t, Webhooks{{URL: "http://example.com", Format: "xml"}}.Validate(), 1)
+	assert.Len(t, Webhooks{{URL: "file:///etc/passwd"}}.Validate(), 1)
+}
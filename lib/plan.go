@@ -0,0 +1,151 @@
+package sous
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type (
+	// A Plan describes the changes that rectification would make, without
+	// making them.
+	Plan struct {
+		Changes []PlannedChange
+	}
+
+	// A PlannedChange is a single change in a Plan.
+	PlannedChange struct {
+		DeploymentID DeploymentID
+		// Action is one of "create", "modify" or "delete", or "error" if the
+		// change cannot be made.
+		Action string
+		// PriorVersion is the version deployed now, if any.
+		PriorVersion string `json:",omitempty"`
+		// PostVersion is the version that would be deployed, if any.
+		PostVersion string `json:",omitempty"`
+		// Diffs lists the fields which would be changed by a "modify".
+		Diffs []string `json:",omitempty"`
+		// Error explains why an "error" change cannot be made.
+		Error string `json:",omitempty"`
+	}
+)
+
+// Plan computes the changes that resolving intended into clusters would make,
+// using the same pipeline as Begin but without rectifying anything.
+func (r *Resolver) Plan(intended Deployments, clusters Clusters) (*Plan, error) {
+	intended = intended.Filter(r.FilterDeployment)
+	clusters = r.FilteredClusters(clusters)
+
+	actual, err := r.Deployer.RunningDeployments(r.Registry, clusters)
+	if err != nil {
+		return nil, err
+	}
+	actual = actual.Filter(r.FilterDeployStates)
+
+	diffs := actual.Diff(intended)
+	return NewPlan(diffs.ResolveNames(context.Background(), r.Registry)), nil
+}
+
+// PlanChanges computes the changes that rectification would make if the GDM
+// were changed from prior to post.
+func PlanChanges(prior, post Deployments) *Plan {
+	return NewPlan(prior.Diff(post))
+}
+
+// NewPlan collects the pairs and errors from dcs into a Plan. Pairs which
+// would not change are left out.
+func NewPlan(dcs *DeployableChans) *Plan {
+	p := &Plan{Changes: []PlannedChange{}}
+	pairs, errs := dcs.Pairs, dcs.Errs
+	for pairs != nil || errs != nil {
+		select {
+		case dp, open := <-pairs:
+			if !open {
+				pairs = nil
+				continue
+			}
+			if c, changed := plannedChange(dp); changed {
+				p.Changes = append(p.Changes, c)
+			}
+		case dr, open := <-errs:
+			if !open {
+				errs = nil
+				continue
+			}
+			c := PlannedChange{DeploymentID: dr.DeploymentID, Action: "error"}
+			if dr.Error != nil {
+				c.Error = dr.Error.Error()
+			}
+			p.Changes = append(p.Changes, c)
+		}
+	}
+	sort.Slice(p.Changes, func(i, j int) bool {
+		return p.Changes[i].DeploymentID.String() < p.Changes[j].DeploymentID.String()
+	})
+	return p
+}
+
+func plannedChange(dp *DeployablePair) (PlannedChange, bool) {
+	c := PlannedChange{DeploymentID: dp.ID()}
+	if dp.Prior != nil && dp.Prior.Deployment != nil {
+		c.PriorVersion = dp.Prior.SourceID.Version.String()
+	}
+	if dp.Post != nil && dp.Post.Deployment != nil {
+		c.PostVersion = dp.Post.SourceID.Version.String()
+	}
+	switch dp.Kind() {
+	default:
+		return c, false
+	case AddedKind:
+		c.Action = "create"
+	case RemovedKind:
+		c.Action = "delete"
+	case ModifiedKind:
+		c.Action = "modify"
+		c.Diffs = dp.Diffs()
+	}
+	return c, true
+}
+
+// Empty returns true if p makes no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Render writes p to w in a human readable form: one line per change, marked
+// "+" for create, "~" for modify, "-" for delete and "!" for error, with the
+// differing fields of each modify listed beneath it, followed by a summary.
+func (p *Plan) Render(w io.Writer) error {
+	counts := map[string]int{}
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		var line string
+		switch c.Action {
+		case "create":
+			line = fmt.Sprintf("+ %s (version %s)", c.DeploymentID, c.PostVersion)
+		case "delete":
+			line = fmt.Sprintf("- %s (version %s)", c.DeploymentID, c.PriorVersion)
+		case "modify":
+			line = fmt.Sprintf("~ %s (version %s -> %s)", c.DeploymentID, c.PriorVersion, c.PostVersion)
+		default:
+			line = fmt.Sprintf("! %s: %s", c.DeploymentID, c.Error)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, d := range c.Diffs {
+			if _, err := fmt.Fprintf(w, "    %s\n", d); err != nil {
+				return err
+			}
+		}
+	}
+	if len(p.Changes) != 0 {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Plan: %d to create, %d to modify, %d to delete, %d errors.\n",
+		counts["create"], counts["modify"], counts["delete"], counts["error"])
+	return err
+}
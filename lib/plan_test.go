@@ -0,0 +1,65 @@
+package sous
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanChanges(t *testing.T) {
+	prior := NewDeployments(makeDepl("gone", 1), makeDepl("same", 1), makeDepl("changed", 1))
+	changed := makeDepl("changed", 2)
+	changed.SourceID.Version = semv.MustParse("2.0.0")
+	post := NewDeployments(makeDepl("same", 1), changed, makeDepl("new", 1))
+
+	p := PlanChanges(prior, post)
+	require.Len(t, p.Changes, 3)
+
+	actions := map[string]PlannedChange{}
+	for _, c := range p.Changes {
+		actions[c.DeploymentID.ManifestID.Source.Repo] = c
+	}
+	assert.Equal(t, "create", actions["new"].Action)
+	assert.Equal(t, "1.1.1-latest", actions["new"].PostVersion)
+	assert.Equal(t, "delete", actions["gone"].Action)
+	assert.Equal(t, "1.1.1-latest", actions["gone"].PriorVersion)
+	mod := actions["changed"]
+	assert.Equal(t, "modify", mod.Action)
+	assert.Equal(t, "2.0.0", mod.PostVersion)
+	assert.NotEmpty(t, mod.Diffs)
+	assert.False(t, p.Empty())
+}
+
+func TestPlanChanges_Empty(t *testing.T) {
+	ds := NewDeployments(makeDepl("same", 1))
+	p := PlanChanges(ds, ds.Clone())
+	assert.True(t, p.Empty())
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, p.Render(buf))
+	assert.Equal(t, "Plan: 0 to create, 0 to modify, 0 to delete, 0 errors.\n", buf.String())
+
+	js, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Changes":[]}`, string(js))
+}
+
+func TestPlan_Render(t *testing.T) {
+	p := &Plan{Changes: []PlannedChange{
+		{DeploymentID: DeploymentID{Cluster: "a"}, Action: "create", PostVersion: "1.0.0"},
+		{DeploymentID: DeploymentID{Cluster: "b"}, Action: "modify", PriorVersion: "1.0.0", PostVersion: "1.1.0",
+			Diffs: []string{"version differs"}},
+		{DeploymentID: DeploymentID{Cluster: "c"}, Action: "error", Error: "no image"},
+	}}
+	buf := &bytes.Buffer{}
+	require.NoError(t, p.Render(buf))
+	out := buf.String()
+	assert.Contains(t, out, "+ ")
+	assert.Contains(t, out, "(version 1.0.0 -> 1.1.0)\n    version differs\n")
+	assert.Contains(t, out, ": no image\n")
+	assert.Contains(t, out, "Plan: 1 to create, 1 to modify, 0 to delete, 1 errors.\n")
+}
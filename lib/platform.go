@@ -0,0 +1,57 @@
+package sous
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParsePlatforms parses a comma separated list of platforms, e.g.
+// "linux/amd64,linux/arm64/v8".
+func ParsePlatforms(s string) ([]string, error) {
+	var platforms []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if parts := strings.Split(p, "/"); len(parts) < 2 || len(parts) > 3 {
+			return nil, errors.Errorf("invalid platform %q: want os/arch or os/arch/variant", p)
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, nil
+}
+
+// PlatformSupported returns true if platforms, the platforms an artifact has
+// images for, include platform. A platform without a variant, e.g.
+// "linux/arm64", is supported by an image for any variant of it.
+func PlatformSupported(platforms []string, platform string) bool {
+	for _, p := range platforms {
+		if p == platform || strings.HasPrefix(p, platform+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlatform checks that art has an image for the Platform of d's
+// cluster.
+func checkPlatform(r Registry, d *Deployment, art *BuildArtifact) error {
+	if d.Cluster == nil || d.Cluster.Platform == "" {
+		return nil
+	}
+	facts, err := InspectArtifact(r, d.SourceID, art)
+	if err != nil {
+		return errors.Wrapf(err, "inspecting artifact for %s", d.SourceID)
+	}
+	if PlatformSupported(facts.Platforms, d.Cluster.Platform) {
+		return nil
+	}
+	return &UnsupportedPlatform{
+		SourceID:  d.SourceID,
+		Cluster:   d.ClusterName,
+		Platform:  d.Cluster.Platform,
+		Platforms: facts.Platforms,
+	}
+}
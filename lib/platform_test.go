@@ -0,0 +1,65 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type platformRegistry struct {
+	*DummyRegistry
+	platforms []string
+}
+
+func (r platformRegistry) InspectArtifact(sid SourceID, art *BuildArtifact) (*ArtifactFacts, error) {
+	return &ArtifactFacts{SourceID: sid, Artifact: art, Platforms: r.platforms}, nil
+}
+
+func TestParsePlatforms(t *testing.T) {
+	ps, err := ParsePlatforms("linux/amd64, linux/arm64/v8,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, ps)
+
+	ps, err = ParsePlatforms("")
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+
+	_, err = ParsePlatforms("arm64")
+	assert.Error(t, err)
+}
+
+func TestPlatformSupported(t *testing.T) {
+	platforms := []string{"linux/amd64", "linux/arm64/v8"}
+	assert.True(t, PlatformSupported(platforms, "linux/amd64"))
+	assert.True(t, PlatformSupported(platforms, "linux/arm64"))
+	assert.True(t, PlatformSupported(platforms, "linux/arm64/v8"))
+	assert.False(t, PlatformSupported(platforms, "linux/arm"))
+	assert.False(t, PlatformSupported(platforms, "linux/amd64/v2"))
+	assert.False(t, PlatformSupported(nil, "linux/amd64"))
+}
+
+func TestGuardImageUnsupportedPlatform(t *testing.T) {
+	sid := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	dr := NewDummyRegistry()
+	dr.FeedArtifact(&BuildArtifact{Name: "ot-docker/one", Type: "docker"}, nil)
+	r := platformRegistry{DummyRegistry: dr, platforms: []string{"linux/amd64"}}
+	d := &Deployment{ClusterName: "x", SourceID: sid, DeployConfig: DeployConfig{NumInstances: 1}, Cluster: &Cluster{Name: "x"}}
+
+	_, err := guardImage(r, d)
+	assert.NoError(t, err, "clusters without a platform should accept any image")
+
+	d.Cluster.Platform = "linux/amd64"
+	_, err = guardImage(r, d)
+	assert.NoError(t, err)
+
+	d.Cluster.Platform = "linux/arm64"
+	_, err = guardImage(r, d)
+	require.IsType(t, &UnsupportedPlatform{}, err)
+	assert.Contains(t, err.Error(), "it has images for linux/amd64")
+	assert.False(t, IsTransientResolveError(err))
+
+	_, err = guardImage(dr, d)
+	require.IsType(t, &UnsupportedPlatform{}, err)
+	assert.Contains(t, err.Error(), "its platforms are unknown")
+}
@@ -0,0 +1,388 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// Promotions is a collection of named promotion pipelines.
+	Promotions map[string]*Promotion
+
+	// A Promotion is a pipeline of clusters through which new versions are
+	// promoted automatically, e.g. ci, then pp, then prod.
+	Promotion struct {
+		// Stages lists the clusters of the pipeline, in order. Versions are
+		// never promoted into the first stage; that is where they are deployed
+		// by hand.
+		Stages []PromotionStage
+		// Repos restricts the pipeline to manifests for these repositories. If
+		// empty, every manifest with deployments in the stages is promoted.
+		Repos []string `yaml:",omitempty"`
+		// Paused stops all promotions through the pipeline.
+		Paused bool `yaml:",omitempty"`
+		// Pending are the promotions through the pipeline which are ready,
+		// but await approval, keyed by the DeploymentID they promote into.
+		// They are recorded here by the servers which resolve the clusters
+		// they are promoted from, so that every server sees them.
+		Pending map[string]PendingPromotion `yaml:",omitempty"`
+	}
+
+	// A PromotionStage is a single cluster in a Promotion, and the conditions
+	// for promoting a version into it from the previous stage.
+	PromotionStage struct {
+		// Cluster is the name of the cluster.
+		Cluster string
+		// StableMinutes is how long a version must have been active and healthy
+		// in the previous stage before it is promoted into this one.
+		StableMinutes int `yaml:",omitempty"`
+		// RequireApproval holds promotions into this stage until they are
+		// approved.
+		RequireApproval bool `yaml:",omitempty"`
+	}
+
+	// A PendingPromotion is a promotion which is ready, but awaiting approval.
+	PendingPromotion struct {
+		Pipeline     string
+		DeploymentID DeploymentID
+		From, To     semv.Version
+		// Approved is set once the promotion is approved. It is made, and
+		// removed from the pipeline, after the next auto-resolve.
+		Approved bool `yaml:",omitempty"`
+	}
+
+	// A NoPendingPromotionError is returned when approving a promotion which
+	// is not awaiting approval.
+	NoPendingPromotionError struct {
+		Pipeline     string
+		DeploymentID DeploymentID
+		Version      semv.Version
+	}
+
+	// PromotionStatus reports the state of a promotion pipeline.
+	PromotionStatus struct {
+		Name string
+		*Promotion
+		Pending []PendingPromotion
+	}
+
+	// A Promoter promotes versions through the Promotions defined in the GDM,
+	// using the results of each auto-resolve to decide how long a version has
+	// been stable in each cluster. It only sees the clusters resolved by this
+	// server, and only promotes from those; pauses and approvals are kept in
+	// the GDM, so that they are shared with the other servers.
+	Promoter struct {
+		StateManager
+		log logging.LogSink
+		now func() time.Time
+
+		sync.RWMutex
+		stable   map[DeploymentID]stableVersion
+		observed map[DeploymentID]bool
+	}
+
+	stableVersion struct {
+		version semv.Version
+		since   time.Time
+	}
+)
+
+// PromoterUser is the User recorded as the author of automatic promotions.
+var PromoterUser = User{Name: "Sous Promoter"}
+
+// Clone returns a deep copy of these Promotions.
+func (ps Promotions) Clone() Promotions {
+	if ps == nil {
+		return nil
+	}
+	c := make(Promotions, len(ps))
+	for name, p := range ps {
+		pc := *p
+		pc.Stages = append([]PromotionStage(nil), p.Stages...)
+		pc.Repos = append([]string(nil), p.Repos...)
+		if p.Pending != nil {
+			pc.Pending = make(map[string]PendingPromotion, len(p.Pending))
+			for k, pp := range p.Pending {
+				pc.Pending[k] = pp
+			}
+		}
+		c[name] = &pc
+	}
+	return c
+}
+
+// Validate checks that each Promotion refers only to known clusters.
+func (ps Promotions) Validate(clusters Clusters) []Flaw {
+	var flaws []Flaw
+	for name, p := range ps {
+		if len(p.Stages) < 2 {
+			flaws = append(flaws, FatalFlaw("Promotion %q has fewer than two stages", name))
+		}
+		seen := map[string]bool{}
+		for _, s := range p.Stages {
+			if _, has := clusters[s.Cluster]; !has {
+				flaws = append(flaws, FatalFlaw("Promotion %q refers to unknown cluster %q", name, s.Cluster))
+			}
+			if seen[s.Cluster] {
+				flaws = append(flaws, FatalFlaw("Promotion %q includes cluster %q more than once", name, s.Cluster))
+			}
+			seen[s.Cluster] = true
+			if s.StableMinutes < 0 {
+				flaws = append(flaws, FatalFlaw("Promotion %q stage %q StableMinutes less than zero: %d!", name, s.Cluster, s.StableMinutes))
+			}
+		}
+	}
+	return flaws
+}
+
+func (e *NoPendingPromotionError) Error() string {
+	return fmt.Sprintf("no promotion of %s to %s is awaiting approval in %q", e.DeploymentID, e.Version, e.Pipeline)
+}
+
+// Includes returns true if m is promoted through p.
+func (p *Promotion) Includes(m *Manifest) bool {
+	if len(p.Repos) == 0 {
+		return true
+	}
+	for _, r := range p.Repos {
+		if r == m.Source.Repo {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPromoter returns a Promoter which reads and writes sm.
+func NewPromoter(sm StateManager, ls logging.LogSink) *Promoter {
+	return &Promoter{
+		StateManager: sm,
+		log:          ls,
+		now:          time.Now,
+		stable:       map[DeploymentID]stableVersion{},
+		observed:     map[DeploymentID]bool{},
+	}
+}
+
+// HandleResolve observes the results of an auto-resolve, and then makes any
+// promotions which have become due. It is suitable for
+// AutoResolver.AfterResolve.
+func (p *Promoter) HandleResolve(rs ResolveStatus) {
+	p.Observe(rs)
+	if err := p.Promote(); err != nil {
+		messages.ReportLogFieldsMessage("Promotion failed", logging.WarningLevel, p.log, err)
+	}
+}
+
+// Observe records which deployments were active and healthy in rs, and since
+// when.
+func (p *Promoter) Observe(rs ResolveStatus) {
+	versions := map[DeploymentID]semv.Version{}
+	for _, d := range rs.Intended {
+		versions[d.ID()] = d.SourceID.Version
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	now := p.now()
+	for _, dr := range rs.Log {
+		did := dr.DeploymentID
+		p.observed[did] = true
+		v, known := versions[did]
+		if !known || dr.Desc != StableDiff || dr.Error != nil {
+			delete(p.stable, did)
+			continue
+		}
+		if s, has := p.stable[did]; has && s.version.Equals(v) {
+			continue
+		}
+		p.stable[did] = stableVersion{version: v, since: now}
+	}
+}
+
+// Promote writes the version of each deployment which has satisfied the
+// conditions of the next stage of its pipeline into that stage. Promotions
+// which need approval are recorded as pending in the GDM instead, and made
+// once they are approved there.
+func (p *Promoter) Promote() error {
+	state, err := p.ReadState()
+	if err != nil {
+		return err
+	}
+	state = state.Clone()
+
+	names := make([]string, 0, len(state.Defs.Promotions))
+	for name := range state.Defs.Promotions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changed := false
+	for _, name := range names {
+		promo := state.Defs.Promotions[name]
+		if promo.Paused {
+			continue
+		}
+		for i := 1; i < len(promo.Stages); i++ {
+			if p.promoteStage(state, name, promo, promo.Stages[i-1], promo.Stages[i]) {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return errors.Wrap(p.WriteState(state, PromoterUser), "recording promotions")
+}
+
+// promoteStage promotes into the stage to of promo from the stage from in
+// state, and records the promotions awaiting approval in promo. It returns
+// true if it changed state.
+func (p *Promoter) promoteStage(state *State, name string, promo *Promotion, from, to PromotionStage) bool {
+	changed := false
+	for _, m := range state.Manifests.Snapshot() {
+		if !promo.Includes(m) {
+			continue
+		}
+		fromID := DeploymentID{ManifestID: m.ID(), Cluster: from.Cluster}
+		toID := DeploymentID{ManifestID: m.ID(), Cluster: to.Cluster}
+		if !p.isObserved(fromID) {
+			// Another server resolves the cluster, and promotes from it.
+			continue
+		}
+		key := toID.String()
+		prior, hasPrior := promo.Pending[key]
+
+		fromSpec, hasFrom := m.Deployments[from.Cluster]
+		toSpec, hasTo := m.Deployments[to.Cluster]
+		stable, since := p.stableSince(fromID, fromSpec.Version)
+		if !hasFrom || !hasTo || !toSpec.Version.Less(fromSpec.Version) || !stable {
+			if hasPrior {
+				delete(promo.Pending, key)
+				changed = true
+			}
+			continue
+		}
+		// Until the version has been stable for long enough, what is pending
+		// is kept as it is, since this server may only just have started.
+		if p.now().Sub(since) < time.Duration(to.StableMinutes)*time.Minute {
+			continue
+		}
+
+		pp := PendingPromotion{
+			Pipeline:     name,
+			DeploymentID: toID,
+			From:         toSpec.Version,
+			To:           fromSpec.Version,
+		}
+		if to.RequireApproval {
+			if !hasPrior || !prior.From.Equals(pp.From) || !prior.To.Equals(pp.To) {
+				if promo.Pending == nil {
+					promo.Pending = map[string]PendingPromotion{}
+				}
+				promo.Pending[key] = pp
+				changed = true
+				continue
+			}
+			if !prior.Approved {
+				continue
+			}
+		}
+
+		messages.ReportLogFieldsMessage("Promoting", logging.InformationLevel, p.log, pp.DeploymentID, pp.From, pp.To)
+		toSpec.Version = pp.To
+		m.Deployments[to.Cluster] = toSpec
+		state.Manifests.Set(m.ID(), m)
+		delete(promo.Pending, key)
+		changed = true
+	}
+	return changed
+}
+
+func (p *Promoter) isObserved(did DeploymentID) bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.observed[did]
+}
+
+// stableSince returns true, and since when, if did has been observed active
+// and healthy at v.
+func (p *Promoter) stableSince(did DeploymentID, v semv.Version) (bool, time.Time) {
+	p.RLock()
+	defer p.RUnlock()
+	s, has := p.stable[did]
+	if !has || !s.version.Equals(v) {
+		return false, time.Time{}
+	}
+	return true, s.since
+}
+
+// Pause stops promotions through the named pipeline, or restarts them, by
+// setting its Paused in the GDM on behalf of user.
+func (p *Promoter) Pause(pipeline string, paused bool, user User) error {
+	state, err := p.ReadState()
+	if err != nil {
+		return err
+	}
+	state = state.Clone()
+	promo, has := state.Defs.Promotions[pipeline]
+	if !has || promo == nil {
+		return errors.Errorf("no promotion pipeline %q", pipeline)
+	}
+	if promo.Paused == paused {
+		return nil
+	}
+	promo.Paused = paused
+	return p.WriteState(state, user)
+}
+
+// Approve approves the pending promotion of did to version through pipeline
+// in the GDM, on behalf of user. The promotion is made after the next
+// auto-resolve of the cluster it is promoted from.
+func (p *Promoter) Approve(pipeline string, did DeploymentID, version semv.Version, user User) error {
+	state, err := p.ReadState()
+	if err != nil {
+		return err
+	}
+	state = state.Clone()
+	if promo, has := state.Defs.Promotions[pipeline]; has && promo != nil {
+		key := did.String()
+		if pp, pending := promo.Pending[key]; pending && pp.To.Equals(version) {
+			if pp.Approved {
+				return nil
+			}
+			pp.Approved = true
+			promo.Pending[key] = pp
+			return p.WriteState(state, user)
+		}
+	}
+	return &NoPendingPromotionError{Pipeline: pipeline, DeploymentID: did, Version: version}
+}
+
+// Statuses reports the state of each of these Promotions.
+func (ps Promotions) Statuses() []PromotionStatus {
+	var statuses []PromotionStatus
+	for name, promo := range ps {
+		pc := *promo
+		var pending []PendingPromotion
+		for _, pp := range promo.Pending {
+			pending = append(pending, pp)
+		}
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].DeploymentID.String() < pending[j].DeploymentID.String()
+		})
+		statuses = append(statuses, PromotionStatus{
+			Name:      name,
+			Promotion: &pc,
+			Pending:   pending,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,201 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func promotionTestState(approval bool) *State {
+	s := historyTestState(historyTestManifest("github.com/example/project", map[string]string{
+		"ci": "2.0.0", "pp": "1.0.0", "prod": "1.0.0",
+	}))
+	s.Defs.Clusters = Clusters{
+		"ci":   {Name: "ci"},
+		"pp":   {Name: "pp"},
+		"prod": {Name: "prod"},
+	}
+	s.Defs.Promotions = Promotions{
+		"main": {Stages: []PromotionStage{
+			{Cluster: "ci"},
+			{Cluster: "pp", StableMinutes: 10},
+			{Cluster: "prod", StableMinutes: 10, RequireApproval: approval},
+		}},
+	}
+	return s
+}
+
+func promotionTestPromoter(s *State) (*Promoter, *DummyStateManager, *time.Time) {
+	dsm := NewDummyStateManager()
+	dsm.State = s
+	p := NewPromoter(dsm, logging.SilentLogSet())
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	return p, dsm, &now
+}
+
+// observeStable reports every intended deployment as stable.
+func observeStable(t *testing.T, p *Promoter, sm StateReader) {
+	state, err := sm.ReadState()
+	require.NoError(t, err)
+	deps, err := state.Deployments()
+	require.NoError(t, err)
+	rs := ResolveStatus{}
+	for _, d := range deps.Snapshot() {
+		rs.Intended = append(rs.Intended, d)
+		rs.Log = append(rs.Log, DiffResolution{DeploymentID: d.ID(), Desc: StableDiff})
+	}
+	p.Observe(rs)
+}
+
+func promotionTestVersion(t *testing.T, sm StateReader, cluster string) string {
+	state, err := sm.ReadState()
+	require.NoError(t, err)
+	m, ok := state.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/example/project"}})
+	require.True(t, ok)
+	return m.Deployments[cluster].Version.String()
+}
+
+func TestPromoter_Promote(t *testing.T) {
+	p, dsm, now := promotionTestPromoter(promotionTestState(false))
+
+	observeStable(t, p, dsm)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "pp"), "promoted before stable for long enough")
+
+	*now = now.Add(11 * time.Minute)
+	observeStable(t, p, dsm)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "2.0.0", promotionTestVersion(t, dsm, "pp"))
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "prod"), "promoted through two stages at once")
+
+	// pp has only just become stable at 2.0.0.
+	observeStable(t, p, dsm)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "prod"))
+
+	*now = now.Add(11 * time.Minute)
+	observeStable(t, p, dsm)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "2.0.0", promotionTestVersion(t, dsm, "prod"))
+}
+
+func TestPromoter_Observe_unhealthy(t *testing.T) {
+	p, dsm, now := promotionTestPromoter(promotionTestState(false))
+
+	observeStable(t, p, dsm)
+	*now = now.Add(11 * time.Minute)
+	state, err := dsm.ReadState()
+	require.NoError(t, err)
+	deps, err := state.Deployments()
+	require.NoError(t, err)
+	rs := ResolveStatus{}
+	for _, d := range deps.Snapshot() {
+		rs.Intended = append(rs.Intended, d)
+		rs.Log = append(rs.Log, DiffResolution{DeploymentID: d.ID(), Desc: StableDiff, Error: WrapResolveError(&FailedStatusError{})})
+	}
+	p.Observe(rs)
+
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "pp"))
+}
+
+func TestPromoter_Approve(t *testing.T) {
+	s := promotionTestState(true)
+	s.Manifests.Snapshot()[ManifestID{Source: SourceLocation{Repo: "github.com/example/project"}}].Deployments["pp"] = DeploySpec{Version: semv.MustParse("2.0.0")}
+	p, dsm, now := promotionTestPromoter(s)
+
+	observeStable(t, p, dsm)
+	*now = now.Add(11 * time.Minute)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "prod"))
+
+	statuses := dsm.State.Defs.Promotions.Statuses()
+	require.Len(t, statuses, 1)
+	require.Len(t, statuses[0].Pending, 1)
+	pending := statuses[0].Pending[0]
+	assert.Equal(t, "prod", pending.DeploymentID.Cluster)
+	assert.Equal(t, "2.0.0", pending.To.String())
+	assert.False(t, pending.Approved)
+
+	_, isPending := p.Approve("main", pending.DeploymentID, semv.MustParse("3.0.0"), User{Name: "sam"}).(*NoPendingPromotionError)
+	assert.True(t, isPending)
+	require.NoError(t, p.Approve("main", pending.DeploymentID, pending.To, User{Name: "sam"}))
+	assert.True(t, dsm.State.Defs.Promotions.Statuses()[0].Pending[0].Approved)
+
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "2.0.0", promotionTestVersion(t, dsm, "prod"))
+	assert.Empty(t, dsm.State.Defs.Promotions.Statuses()[0].Pending)
+}
+
+func TestPromoter_Approve_restarted(t *testing.T) {
+	s := promotionTestState(true)
+	s.Manifests.Snapshot()[ManifestID{Source: SourceLocation{Repo: "github.com/example/project"}}].Deployments["pp"] = DeploySpec{Version: semv.MustParse("2.0.0")}
+	p, dsm, now := promotionTestPromoter(s)
+
+	observeStable(t, p, dsm)
+	*now = now.Add(11 * time.Minute)
+	require.NoError(t, p.Promote())
+	pending := dsm.State.Defs.Promotions.Statuses()[0].Pending
+	require.Len(t, pending, 1)
+
+	// Approved through another server, and promoted by a restarted one.
+	other := NewPromoter(dsm, logging.SilentLogSet())
+	require.NoError(t, other.Approve("main", pending[0].DeploymentID, pending[0].To, User{Name: "sam"}))
+	restarted, _, later := promotionTestPromoter(dsm.State)
+	observeStable(t, restarted, dsm)
+	require.NoError(t, restarted.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "prod"), "promoted before stable for long enough")
+	assert.True(t, dsm.State.Defs.Promotions.Statuses()[0].Pending[0].Approved, "approval lost on restart")
+
+	*later = later.Add(11 * time.Minute)
+	observeStable(t, restarted, dsm)
+	require.NoError(t, restarted.Promote())
+	assert.Equal(t, "2.0.0", promotionTestVersion(t, dsm, "prod"))
+}
+
+func TestPromoter_Promote_unobserved(t *testing.T) {
+	s := promotionTestState(true)
+	s.Manifests.Snapshot()[ManifestID{Source: SourceLocation{Repo: "github.com/example/project"}}].Deployments["pp"] = DeploySpec{Version: semv.MustParse("2.0.0")}
+	p, dsm, now := promotionTestPromoter(s)
+	observeStable(t, p, dsm)
+	*now = now.Add(11 * time.Minute)
+	require.NoError(t, p.Promote())
+	require.Len(t, dsm.State.Defs.Promotions.Statuses()[0].Pending, 1)
+
+	// A server which does not resolve pp leaves what it records alone.
+	other, _, _ := promotionTestPromoter(dsm.State)
+	require.NoError(t, other.Promote())
+	assert.Len(t, dsm.State.Defs.Promotions.Statuses()[0].Pending, 1)
+}
+
+func TestPromoter_Pause(t *testing.T) {
+	p, dsm, now := promotionTestPromoter(promotionTestState(false))
+	require.NoError(t, p.Pause("main", true, User{Name: "sam"}))
+	assert.True(t, dsm.State.Defs.Promotions["main"].Paused)
+	assert.Error(t, p.Pause("other", true, User{Name: "sam"}))
+
+	observeStable(t, p, dsm)
+	*now = now.Add(11 * time.Minute)
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "1.0.0", promotionTestVersion(t, dsm, "pp"))
+	assert.True(t, dsm.State.Defs.Promotions.Statuses()[0].Paused)
+
+	// Resumed through another server.
+	other := NewPromoter(dsm, logging.SilentLogSet())
+	require.NoError(t, other.Pause("main", false, User{Name: "sam"}))
+	require.NoError(t, p.Promote())
+	assert.Equal(t, "2.0.0", promotionTestVersion(t, dsm, "pp"))
+}
+
+func TestPromotions_Validate(t *testing.T) {
+	s := promotionTestState(false)
+	assert.Empty(t, s.Defs.Promotions.Validate(s.Defs.Clusters))
+
+	s.Defs.Promotions["broken"] = &Promotion{Stages: []PromotionStage{{Cluster: "nowhere"}}}
+	assert.Len(t, s.Defs.Promotions.Validate(s.Defs.Clusters), 2)
+}
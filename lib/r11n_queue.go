@@ -5,6 +5,8 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
 	"github.com/pborman/uuid"
 )
 
@@ -20,6 +22,7 @@ type (
 		fifoRefs      *ring.Ring
 		handler       func(*QueuedR11n) DiffResolution
 		start         bool
+		store         R11nStore
 		sync.Mutex
 	}
 	// QueuedR11n is a queue item wrapping a Rectification with an ID and position.
@@ -128,7 +131,9 @@ func (rq *R11nQueue) Start(handler func(*QueuedR11n) DiffResolution) {
 	go func() {
 		for {
 			qr := rq.next()
+			rq.storeState(qr, R11nStarted)
 			handler(qr)
+			rq.storeState(qr, R11nDone)
 			rq.Lock()
 			close(qr.done)
 			delete(rq.refs, qr.ID)
@@ -160,28 +165,61 @@ func (rq *R11nQueue) Push(r *Rectification) (*QueuedR11n, bool) {
 	if len(rq.queue) == rq.cap {
 		return nil, false
 	}
-	return rq.internalPush(r), true
+	qr := rq.internalPush(r)
+	return qr, qr != nil
 }
 
-// internalPush assumes rq is already locked.
+// internalPush assumes rq is already locked. It returns nil if r could not be
+// stored.
 func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
-	id := NewR11nID()
-	qr := &QueuedR11n{
+	qr := rq.newQueuedR11n(NewR11nID(), r)
+	if rq.store != nil {
+		if err := rq.store.StoreR11n(qr, R11nQueued); err != nil {
+			messages.ReportLogFieldsMessage("Failed to store queued rectification", logging.WarningLevel, logging.Log, qr.ID, err)
+			return nil
+		}
+	}
+	rq.enqueue(qr)
+	return qr
+}
+
+// newQueuedR11n assumes rq is already locked.
+func (rq *R11nQueue) newQueuedR11n(id R11nID, r *Rectification) *QueuedR11n {
+	return &QueuedR11n{
 		ID:            id,
 		Pos:           len(rq.queue),
 		Rectification: r,
 		done:          make(chan struct{}),
 	}
-	rq.refs[id] = qr
-	rq.allRefs[id] = qr
+}
+
+// enqueue assumes rq is already locked.
+func (rq *R11nQueue) enqueue(qr *QueuedR11n) {
+	rq.refs[qr.ID] = qr
+	rq.remember(qr)
+	rq.queue <- qr
+}
+
+// remember adds qr to the history of rq, forgetting the oldest entry if the
+// history is full. It assumes rq is already locked.
+func (rq *R11nQueue) remember(qr *QueuedR11n) {
+	rq.allRefs[qr.ID] = qr
 	rq.fifoRefs = rq.fifoRefs.Next()
 	if rq.fifoRefs.Value != nil {
 		idToDelete := rq.fifoRefs.Value.(R11nID)
 		delete(rq.allRefs, idToDelete)
 	}
-	rq.fifoRefs.Value = id
-	rq.queue <- qr
-	return qr
+	rq.fifoRefs.Value = qr.ID
+}
+
+// storeState records the state of qr if rq has a store.
+func (rq *R11nQueue) storeState(qr *QueuedR11n, state R11nState) {
+	if rq.store == nil {
+		return
+	}
+	if err := rq.store.StoreR11n(qr, state); err != nil {
+		messages.ReportLogFieldsMessage("Failed to store rectification state", logging.WarningLevel, logging.Log, qr.ID, state, err)
+	}
 }
 
 // PushIfEmpty adds an item to the queue if it is empty, and returns the wrapper
@@ -195,7 +233,8 @@ func (rq *R11nQueue) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
 	if len(rq.refs) != 0 {
 		return nil, false
 	}
-	return rq.internalPush(r), true
+	qr := rq.internalPush(r)
+	return qr, qr != nil
 }
 
 // Len returns the current number of items in the queue.
@@ -17,9 +17,10 @@ type (
 
 	// R11nQueueSet is a concurrency-safe mapping of DeploymentID to R11nQueue.
 	R11nQueueSet struct {
-		set  map[DeploymentID]*R11nQueue
-		opts []R11nQueueOpt
-		reg  Registry
+		set   map[DeploymentID]*R11nQueue
+		opts  []R11nQueueOpt
+		reg   Registry
+		store R11nStore
 		sync.RWMutex
 	}
 
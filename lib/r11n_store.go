@@ -0,0 +1,202 @@
+package sous
+
+import (
+	"fmt"
+	"sync"
+)
+
+type (
+	// An R11nStore persists the rectifications passing through R11nQueues,
+	// so that queued work and recent results survive restarts.
+	//
+	// Implementations should retain at most MaxRefsPerR11nQueue completed
+	// rectifications per DeploymentID, discarding the oldest first.
+	R11nStore interface {
+		// StoreR11n records qr as being in state. It is called once when qr is
+		// queued, and again each time its state changes.
+		StoreR11n(qr *QueuedR11n, state R11nState) error
+		// LoadR11ns returns all stored rectifications, in the order they were
+		// queued.
+		LoadR11ns() ([]StoredR11n, error)
+	}
+
+	// R11nState is the processing state of a stored rectification.
+	R11nState string
+
+	// A StoredR11n is a rectification as recorded by an R11nStore.
+	StoredR11n struct {
+		ID    R11nID
+		State R11nState
+		// Pair is the pair being rectified, with its ID set. Its ExecutorData
+		// is not stored.
+		Pair       DeployablePair
+		Resolution DiffResolution
+	}
+
+	// MemoryR11nStore is an in-memory R11nStore, useful for testing.
+	MemoryR11nStore struct {
+		sync.Mutex
+		order  []R11nID
+		stored map[R11nID]StoredR11n
+	}
+)
+
+const (
+	// R11nQueued means a rectification is waiting to be processed.
+	R11nQueued R11nState = "queued"
+	// R11nStarted means a rectification is being processed.
+	R11nStarted R11nState = "started"
+	// R11nDone means a rectification has been processed and has a Resolution.
+	R11nDone R11nState = "done"
+)
+
+// R11nQueueStore makes an R11nQueue record each rectification in store.
+func R11nQueueStore(store R11nStore) R11nQueueOpt {
+	return func(rq *R11nQueue) {
+		rq.store = store
+	}
+}
+
+// NewStoredR11n captures the current state of qr for storage.
+func NewStoredR11n(qr *QueuedR11n, state R11nState) StoredR11n {
+	qr.Rectification.RLock()
+	defer qr.Rectification.RUnlock()
+	pair := qr.Rectification.Pair
+	pair.ExecutorData = nil
+	return StoredR11n{
+		ID:         qr.ID,
+		State:      state,
+		Pair:       pair,
+		Resolution: qr.Rectification.Resolution,
+	}
+}
+
+// resumablePair returns the pair to rectify when resuming sr after a restart.
+// ExecutorData is not stored, so only the intended state (Post) is kept; this
+// is the same as a rectification requested via the single deployment
+// resource. Deletions cannot be resumed, and false is returned for them.
+func (sr StoredR11n) resumablePair() (DeployablePair, bool) {
+	if sr.Pair.Post == nil {
+		return DeployablePair{}, false
+	}
+	pair := DeployablePair{Post: sr.Pair.Post, UUID: sr.Pair.UUID}
+	pair.SetID(sr.Pair.ID())
+	return pair, true
+}
+
+// NewMemoryR11nStore returns an empty MemoryR11nStore.
+func NewMemoryR11nStore() *MemoryR11nStore {
+	return &MemoryR11nStore{stored: map[R11nID]StoredR11n{}}
+}
+
+// StoreR11n implements R11nStore on MemoryR11nStore.
+func (s *MemoryR11nStore) StoreR11n(qr *QueuedR11n, state R11nState) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, has := s.stored[qr.ID]; !has {
+		s.order = append(s.order, qr.ID)
+	}
+	s.stored[qr.ID] = NewStoredR11n(qr, state)
+	if state == R11nDone {
+		s.trim(qr.Rectification.Pair.ID())
+	}
+	return nil
+}
+
+// trim assumes s is locked.
+func (s *MemoryR11nStore) trim(did DeploymentID) {
+	done := 0
+	for i := len(s.order) - 1; i >= 0; i-- {
+		id := s.order[i]
+		sr := s.stored[id]
+		if sr.State != R11nDone || sr.Pair.ID() != did {
+			continue
+		}
+		done++
+		if done > MaxRefsPerR11nQueue {
+			delete(s.stored, id)
+			s.order = append(s.order[:i], s.order[i+1:]...)
+		}
+	}
+}
+
+// LoadR11ns implements R11nStore on MemoryR11nStore.
+func (s *MemoryR11nStore) LoadR11ns() ([]StoredR11n, error) {
+	s.Lock()
+	defer s.Unlock()
+	var all []StoredR11n
+	for _, id := range s.order {
+		all = append(all, s.stored[id])
+	}
+	return all, nil
+}
+
+// NewPersistentR11nQueueSet returns an R11nQueueSet which records each
+// rectification in store. Call Resume to restore the stored rectifications.
+func NewPersistentR11nQueueSet(store R11nStore, opts ...R11nQueueOpt) *R11nQueueSet {
+	rqs := NewR11nQueueSet(append(opts, R11nQueueStore(store))...)
+	rqs.store = store
+	return rqs
+}
+
+// Resume restores the rectifications recorded in the store of rqs. Completed
+// rectifications can be waited on and queried as before; unfinished ones are
+// queued again with their original IDs. It does nothing if rqs has no store.
+func (rqs *R11nQueueSet) Resume() error {
+	if rqs.store == nil {
+		return nil
+	}
+	stored, err := rqs.store.LoadR11ns()
+	if err != nil {
+		return err
+	}
+	rqs.Lock()
+	defer rqs.Unlock()
+	for _, sr := range stored {
+		id := sr.Pair.ID()
+		queue, ok := rqs.set[id]
+		if !ok {
+			queue = NewR11nQueue(rqs.opts...)
+			rqs.set[id] = queue
+		}
+		queue.restore(sr)
+	}
+	return nil
+}
+
+// restore adds sr to rq, either as history or as pending work.
+func (rq *R11nQueue) restore(sr StoredR11n) {
+	rq.Lock()
+	defer rq.Unlock()
+
+	if sr.State == R11nDone {
+		rq.remember(finishedR11n(sr.ID, sr.Pair, sr.Resolution))
+		return
+	}
+
+	pair, ok := sr.resumablePair()
+	if ok && len(rq.queue) < rq.cap {
+		rq.enqueue(rq.newQueuedR11n(sr.ID, NewRectification(pair)))
+		return
+	}
+	why := "queue full"
+	if !ok {
+		why = "deletions are not resumed"
+	}
+	qr := finishedR11n(sr.ID, sr.Pair, DiffResolution{
+		DeploymentID: sr.Pair.ID(),
+		Desc:         "not resumed",
+		Error:        WrapResolveError(fmt.Errorf("rectification %s dropped on restart: %s", sr.ID, why)),
+	})
+	rq.remember(qr)
+	rq.storeState(qr, R11nDone)
+}
+
+// finishedR11n returns a QueuedR11n which has already been processed.
+func finishedR11n(id R11nID, pair DeployablePair, dr DiffResolution) *QueuedR11n {
+	r := NewRectification(pair)
+	r.Resolution = dr
+	qr := &QueuedR11n{ID: id, Pos: -1, Rectification: r, done: make(chan struct{})}
+	close(qr.done)
+	return qr
+}
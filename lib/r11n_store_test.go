@@ -0,0 +1,99 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitWithTimeout(t *testing.T, rqs *R11nQueueSet, did DeploymentID, id R11nID) (DiffResolution, bool) {
+	t.Helper()
+	type result struct {
+		dr DiffResolution
+		ok bool
+	}
+	c := make(chan result)
+	go func() {
+		dr, ok := rqs.Wait(did, id)
+		c <- result{dr, ok}
+	}()
+	select {
+	case r := <-c:
+		return r.dr, r.ok
+	case <-time.After(time.Second):
+		t.Fatalf("Wait for %s did not return", id)
+	}
+	return DiffResolution{}, false
+}
+
+func TestPersistentR11nQueueSet_Resume(t *testing.T) {
+	store := NewMemoryR11nStore()
+
+	// Before the restart, one r11n completes and another is left in progress.
+	block := make(chan struct{})
+	defer close(block)
+	before := NewPersistentR11nQueueSet(store, R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		if qr.Rectification.Pair.ID().ManifestID.Source.Repo == "blocked" {
+			<-block
+		}
+		return DiffResolution{Desc: "before"}
+	}))
+	done, ok := before.Push(makeTestR11nWithRepo("done"))
+	require.True(t, ok)
+	doneID := done.Rectification.Pair.ID()
+	_, ok = waitWithTimeout(t, before, doneID, done.ID)
+	require.True(t, ok)
+
+	pending, ok := before.Push(makeTestR11nWithRepo("blocked"))
+	require.True(t, ok)
+	pendingID := pending.Rectification.Pair.ID()
+
+	removal := makeTestR11nWithRepo("removed")
+	removal.Pair.Prior, removal.Pair.Post = removal.Pair.Post, nil
+	removed, ok := before.Push(removal)
+	require.True(t, ok)
+
+	// After the restart, the completed r11n is remembered and the pending one
+	// is processed again, keeping its ID.
+	after := NewPersistentR11nQueueSet(store, R11nQueueStartWithHandler(func(*QueuedR11n) DiffResolution {
+		return DiffResolution{Desc: "after"}
+	}))
+	require.NoError(t, after.Resume())
+
+	dr, ok := waitWithTimeout(t, after, doneID, done.ID)
+	assert.True(t, ok)
+	assert.Equal(t, ResolutionType("before"), dr.Desc)
+
+	dr, ok = waitWithTimeout(t, after, pendingID, pending.ID)
+	assert.True(t, ok)
+	assert.Equal(t, ResolutionType("after"), dr.Desc)
+
+	qr, ok := after.Queues()[pendingID].ByID(pending.ID)
+	require.True(t, ok)
+	assert.Nil(t, qr.Rectification.Pair.Prior)
+
+	dr, ok = waitWithTimeout(t, after, removal.Pair.ID(), removed.ID)
+	assert.True(t, ok)
+	assert.NotNil(t, dr.Error)
+}
+
+func TestPersistentR11nQueueSet_NoStore(t *testing.T) {
+	assert.NoError(t, NewR11nQueueSet().Resume())
+}
+
+func TestMemoryR11nStore_Trim(t *testing.T) {
+	store := NewMemoryR11nStore()
+	for i := 0; i < MaxRefsPerR11nQueue+5; i++ {
+		qr := &QueuedR11n{ID: NewR11nID(), Rectification: makeTestR11nWithRepo("one")}
+		require.NoError(t, store.StoreR11n(qr, R11nDone))
+	}
+	qr := &QueuedR11n{ID: NewR11nID(), Rectification: makeTestR11nWithRepo("one")}
+	require.NoError(t, store.StoreR11n(qr, R11nQueued))
+
+	stored, err := store.LoadR11ns()
+	require.NoError(t, err)
+	assert.Len(t, stored, MaxRefsPerR11nQueue+1)
+	assert.Equal(t, qr.ID, stored[len(stored)-1].ID)
+}
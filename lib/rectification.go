@@ -69,6 +69,13 @@ func (r *Rectification) rectify(d Deployer, reg Registry) {
 			r.Unlock()
 			return
 		}
+	} else if err := checkSignature(reg, r.Pair.Post.Deployment, r.Pair.Post.BuildArtifact); err != nil {
+		// Artifacts resolved elsewhere are verified again here, so that an
+		// unsigned artifact can't bypass a cluster which requires signatures.
+		r.Lock()
+		r.Resolution.Error = WrapResolveError(err)
+		r.Unlock()
+		return
 	}
 	r.Lock()
 	r.Resolution = d.Rectify(&r.Pair)
@@ -91,10 +98,24 @@ func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, s
 	tick := time.NewTicker(250 * time.Millisecond)
 	defer tick.Stop()
 
-	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
+	timeout := 20 * time.Minute
+	ro := r.newRolloutDriver(d)
+	if ro != nil {
+		timeout += time.Duration(len(ro.stages)) * ro.rollout.Pause()
+	}
+
+	end, ec := context.WithTimeout(r.ctx, timeout)
 	defer ec()
 
 	for {
+		if ro != nil {
+			if err := r.driveRollout(ro); err != nil {
+				r.Lock()
+				r.Resolution.Error = WrapResolveError(err)
+				r.Unlock()
+				return
+			}
+		}
 		s, err := r.pollOnce(d, reg, clusters)
 		if err != nil {
 			r.Lock()
@@ -133,6 +154,84 @@ func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, s
 
 }
 
+// rolloutDriver tracks a staged rollout being advanced by a Rectification.
+type rolloutDriver struct {
+	deployer RolloutDeployer
+	rollout  Rollout
+	stages   []int
+	// completed is when the current stage was first seen to be complete.
+	completed time.Time
+}
+
+// newRolloutDriver returns a rolloutDriver if r.Pair should be deployed in
+// stages by d, or nil otherwise.
+func (r *Rectification) newRolloutDriver(d Deployer) *rolloutDriver {
+	rd, ok := d.(RolloutDeployer)
+	if !ok {
+		return nil
+	}
+	r.RLock()
+	defer r.RUnlock()
+	if r.Resolution.Error != nil || r.Pair.Post == nil || r.Pair.Post.Deployment == nil {
+		return nil
+	}
+	if k := r.Pair.Kind(); k != AddedKind && k != ModifiedKind {
+		return nil
+	}
+	post := r.Pair.Post.Deployment
+	if !post.Rollout.Staged() {
+		return nil
+	}
+	return &rolloutDriver{
+		deployer: rd,
+		rollout:  post.Rollout,
+		stages:   post.Rollout.Stages(post.NumInstances),
+	}
+}
+
+// driveRollout records the progress of a staged rollout in r.Resolution, and
+// advances it once the current stage has been healthy for the rollout's
+// pause, or aborts it if new instances have failed and the rollout calls for
+// that.
+func (r *Rectification) driveRollout(ro *rolloutDriver) error {
+	p, err := ro.deployer.RolloutProgress(&r.Pair)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return nil
+	}
+	p.Stages = len(ro.stages)
+	p.Stage = stageOf(ro.stages, p.TargetInstances)
+
+	defer func() {
+		r.Lock()
+		r.Resolution.Rollout = p
+		r.Unlock()
+	}()
+
+	if p.FailedInstances > 0 && ro.rollout.AbortOnHealthFailure {
+		if err := ro.deployer.AbortRollout(&r.Pair); err != nil {
+			return err
+		}
+		p.Aborted = true
+		return &RolloutAbortedError{Progress: *p}
+	}
+
+	if !p.StepComplete || p.Stage >= len(ro.stages) {
+		ro.completed = time.Time{}
+		return nil
+	}
+	if ro.completed.IsZero() {
+		ro.completed = time.Now()
+	}
+	if time.Since(ro.completed) < ro.rollout.Pause() {
+		return nil
+	}
+	ro.completed = time.Time{}
+	return ro.deployer.AdvanceRollout(&r.Pair, ro.stages[p.Stage])
+}
+
 func (r *Rectification) pollOnce(d Deployer, reg Registry, clusters Clusters) (*DeployState, error) {
 	// XXX thread the context from Begin into Deployer.Status
 	depState, err := d.Status(reg, clusters, &r.Pair)
@@ -30,6 +30,15 @@ type (
 		Warmup(string) error
 	}
 
+	// A DigestPinner is a Registry which can pin artifacts to the immutable
+	// digests of their images.
+	DigestPinner interface {
+		Registry
+		// PinArtifact returns a copy of art, built from sid, whose Name refers
+		// to its image by digest, and whose Tag is art's Name.
+		PinArtifact(sid SourceID, art *BuildArtifact) (*BuildArtifact, error)
+	}
+
 	// An Inserter puts data into a registry.
 	Inserter interface {
 		// Insert pairs a SourceID with an imagename, and tags the pairing with Qualities
@@ -7,6 +7,7 @@ import (
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
 	"github.com/samsalisbury/semv"
 )
 
@@ -45,6 +46,42 @@ func (rd *RegistryDumper) AsTable(to io.Writer) error {
 	return nil
 }
 
+// SBOMsAsTable writes a tabular dump of the packages listed in the SBOM of
+// each artifact in the registry to a Writer.
+func (rd *RegistryDumper) SBOMsAsTable(to io.Writer) error {
+	sr, ok := rd.Registry.(SBOMRegistry)
+	if !ok {
+		return errors.New("this registry does not record SBOMs")
+	}
+	ss, err := rd.Registry.ListSourceIDs()
+	if err != nil {
+		return err
+	}
+
+	w := &tabwriter.Writer{}
+	w.Init(to, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Repo\tOffset\tVersion\tRevision\tPackage\tPackageVersion\tType")
+	for _, s := range ss {
+		sbom, err := sr.GetSBOM(s)
+		if err != nil {
+			return err
+		}
+		prefix := fmt.Sprintf("%s\t%s\t%s", s.Location.Repo, s.Location.Dir, s.Version.Format(semv.MajorMinorPatch))
+		if sbom == nil {
+			fmt.Fprintf(w, "%s\t\t(no SBOM)\t\t\n", prefix)
+			continue
+		}
+		if len(sbom.Packages) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t(no packages)\t\t\n", prefix, sbom.Revision)
+			continue
+		}
+		for _, p := range sbom.Packages {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", prefix, sbom.Revision, p.Name, p.Version, p.Type)
+		}
+	}
+	return w.Flush()
+}
+
 // TabbedHeaders outputs the headers for the dump
 func (rd *RegistryDumper) TabbedHeaders() string {
 	return "Repo\tOffset\tVersion\tName\tType"
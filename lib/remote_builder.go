@@ -0,0 +1,87 @@
+package sous
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// RemoteBuildPollInterval is the default pause between requests for the
+// progress of a remote build.
+const RemoteBuildPollInterval = time.Second
+
+// A RemoteBuilder has a Sous server build BuildRequests, and copies the
+// output of each build to Out as it goes.
+type RemoteBuilder struct {
+	Client       restful.HTTPClient
+	User         User
+	Out          io.Writer
+	PollInterval time.Duration
+}
+
+// NewBuildRequest returns a BuildRequest for a Sous server to build the
+// source c would build locally. The server clones the source, so changes
+// which haven't been pushed are not built.
+func (c *BuildConfig) NewBuildRequest() (BuildRequest, error) {
+	bc := c.NewContext()
+	if bc.Source.RemoteURL == "" {
+		return BuildRequest{}, errors.New("remote builds need a repository to clone, and none was found")
+	}
+	if bc.Source.RevisionUnpushed && c.Revision == "" {
+		return BuildRequest{}, errors.Errorf("revision %s has not been pushed, so cannot be built remotely", bc.Source.Revision)
+	}
+	sid := bc.Source.Version()
+	if c.Revision != "" {
+		sid.Version.Meta = c.Revision
+	}
+	return BuildRequest{
+		SourceID:  sid,
+		Strict:    c.Strict,
+		Platforms: c.Platforms,
+	}, nil
+}
+
+// Build submits req to the server, and waits for it to be built.
+func (rb *RemoteBuilder) Build(req BuildRequest) (*BuildResult, error) {
+	up, err := rb.Client.Create("./build", nil, req, rb.User.HTTPHeaders())
+	if err != nil {
+		return nil, errors.Wrapf(err, "submitting build of %s", req.SourceID)
+	}
+	loc, err := url.Parse(up.Location())
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing build location %q", up.Location())
+	}
+	id := loc.Query().Get("id")
+	if id == "" {
+		return nil, errors.Errorf("server did not say where to follow the build of %s", req.SourceID)
+	}
+
+	interval := rb.PollInterval
+	if interval == 0 {
+		interval = RemoteBuildPollInterval
+	}
+	since := 0
+	for {
+		var job BuildJob
+		q := map[string]string{"id": id, "since": strconv.Itoa(since)}
+		if _, err := rb.Client.Retrieve("./build", q, &job, rb.User.HTTPHeaders()); err != nil {
+			return nil, errors.Wrapf(err, "getting progress of build %s", id)
+		}
+		for _, line := range job.Log {
+			fmt.Fprintln(rb.Out, line)
+		}
+		since = job.Since + len(job.Log)
+		switch job.State {
+		case BuildSucceeded:
+			return job.Result, nil
+		case BuildFailed:
+			return nil, errors.Errorf("remote build failed: %s", job.Error)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -46,6 +46,34 @@ type (
 		*SourceID
 	}
 
+	// An ArtifactPolicyViolation reports that an artifact violates the
+	// ArtifactPolicy of the cluster it is to be deployed to.
+	ArtifactPolicyViolation struct {
+		SourceID SourceID
+		Cluster  string
+		// Reasons lists each way the artifact violates the policy.
+		Reasons []string
+	}
+
+	// An UnsupportedPlatform reports that an artifact has no image for the
+	// Platform of the cluster it is to be deployed to.
+	UnsupportedPlatform struct {
+		SourceID SourceID
+		Cluster  string
+		Platform string
+		// Platforms lists the platforms the artifact has images for.
+		Platforms []string
+	}
+
+	// An UnverifiedArtifact reports that an artifact is not signed by a
+	// trusted key as built from its SourceID, and the cluster it is to be
+	// deployed to requires signatures.
+	UnverifiedArtifact struct {
+		SourceID SourceID
+		Cluster  string
+		Reason   string
+	}
+
 	// CreateError is returned when there's an error trying to create a deployment
 	CreateError struct {
 		Deployment *Deployment
@@ -172,6 +200,18 @@ func IsTransientResolveError(err error) bool {
 		// intervention: either the image needs to be rebuilt clean, or the cluster
 		// reconfigured to accept the advisory.
 		return false
+	case *ArtifactPolicyViolation:
+		// ArtifactPolicyViolation requires either a new artifact which
+		// conforms to the policy, or the policy to be changed.
+		return false
+	case *UnsupportedPlatform:
+		// UnsupportedPlatform requires the artifact to be rebuilt for the
+		// cluster's platform.
+		return false
+	case *UnverifiedArtifact:
+		// UnverifiedArtifact requires the artifact to be signed, or rebuilt
+		// by a signing builder.
+		return false
 	case *MissingImageNameError:
 		// MissingImageNameError isn't transient: it requires that an appropriate
 		// image be built with the desired name and the server needs to be able to
@@ -203,6 +243,25 @@ func (e *UnacceptableAdvisory) Error() string {
 	return fmt.Sprintf("Advisory unacceptable on image: %s for %v", e.Quality.Name, e.SourceID)
 }
 
+func (e *ArtifactPolicyViolation) Error() string {
+	return fmt.Sprintf("Artifact for %v violates the artifact policy of cluster %s:\n  %s",
+		e.SourceID, e.Cluster, strings.Join(e.Reasons, "\n  "))
+}
+
+func (e *UnsupportedPlatform) Error() string {
+	has := "its platforms are unknown"
+	if len(e.Platforms) > 0 {
+		has = "it has images for " + strings.Join(e.Platforms, ", ")
+	}
+	return fmt.Sprintf("Artifact for %v has no image for platform %s of cluster %s: %s",
+		e.SourceID, e.Platform, e.Cluster, has)
+}
+
+func (e *UnverifiedArtifact) Error() string {
+	return fmt.Sprintf("Artifact for %v cannot be deployed to cluster %s, which requires signed artifacts: %s",
+		e.SourceID, e.Cluster, e.Reason)
+}
+
 func (e *FailedStatusError) Error() string {
 	return "Deploy failed on Singularity."
 }
@@ -30,7 +30,7 @@ func TestGuardImageRejected(t *testing.T) {
 	clusterX := &Cluster{Name: "x"}
 	rejected := Deployment{ClusterName: `x`, SourceID: svTwo, DeployConfig: config, Cluster: clusterX}
 
-	dr.FeedArtifact(&BuildArtifact{"ot-docker/one", "docker", []Quality{{"ephemeral_tag", "advisory"}}}, nil)
+	dr.FeedArtifact(&BuildArtifact{Name: "ot-docker/one", Type: "docker", Qualities: []Quality{{"ephemeral_tag", "advisory"}}}, nil)
 
 	_, err := guardImage(dr, &rejected)
 	assert.Error(err)
@@ -59,7 +59,7 @@ func TestAllowsWhitelistedAdvisories(t *testing.T) {
 	config := DeployConfig{NumInstances: 1}
 	intoCI := Deployment{ClusterName: `ci`, Cluster: &Cluster{AllowedAdvisories: []string{"ephemeral_tag"}}, SourceID: svOne, DeployConfig: config}
 
-	dr.FeedArtifact(&BuildArtifact{"ot-docker/one", "docker", []Quality{{"ephemeral_tag", "advisory"}}}, nil)
+	dr.FeedArtifact(&BuildArtifact{Name: "ot-docker/one", Type: "docker", Qualities: []Quality{{"ephemeral_tag", "advisory"}}}, nil)
 
 	art, err := guardImage(dr, &intoCI)
 	assert.NoError(err)
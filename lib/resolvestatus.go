@@ -53,6 +53,9 @@ type (
 		// DeployState is the state of this deployment as running.
 		DeployState *DeployState
 
+		// Rollout is the progress of a staged rollout, if there is one.
+		Rollout *RolloutProgress
+
 		// SchedulerURL is a URL where this deployment can be seen.
 		SchedulerURL string
 	}
@@ -0,0 +1,42 @@
+package sous
+
+import (
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// A DeployHistorian reports the versions of a deployment that were
+// successfully deployed in the past.
+type DeployHistorian interface {
+	// DeployedVersions returns the SourceIDs successfully deployed for did,
+	// newest first.
+	DeployedVersions(did DeploymentID) ([]SourceID, error)
+}
+
+// RollbackVersion chooses the version to roll did back to: the most recently
+// deployed version, other than current, which is also among the known
+// SourceIDs of the registry.
+func RollbackVersion(did DeploymentID, current semv.Version, deployed, known []SourceID) (semv.Version, error) {
+	for _, sid := range deployed {
+		if sid.Version.Equals(current) {
+			continue
+		}
+		if sid.Location != did.ManifestID.Source {
+			continue
+		}
+		if KnownSourceID(sid, known) {
+			return sid.Version, nil
+		}
+	}
+	return semv.Version{}, errors.Errorf("no previous version of %s other than %s found in deploy history and registry", did, current)
+}
+
+// KnownSourceID returns true if sid is among known.
+func KnownSourceID(sid SourceID, known []SourceID) bool {
+	for _, k := range known {
+		if k.Equal(sid) {
+			return true
+		}
+	}
+	return false
+}
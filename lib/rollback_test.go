@@ -0,0 +1,40 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackVersion(t *testing.T) {
+	repo := "github.com/example/project"
+	did := DeploymentID{ManifestID: ManifestID{Source: SourceLocation{Repo: repo}}, Cluster: "one"}
+	sid := func(v string) SourceID { return MustNewSourceID(repo, "", v) }
+
+	deployed := []SourceID{sid("3.0.0"), sid("2.0.0"), sid("1.0.0")}
+
+	testCases := []struct {
+		desc    string
+		current string
+		known   []SourceID
+		want    string
+		wantErr bool
+	}{
+		{"previous", "3.0.0", deployed, "2.0.0", false},
+		{"current failed to deploy", "4.0.0", deployed, "3.0.0", false},
+		{"previous missing from registry", "3.0.0", []SourceID{sid("3.0.0"), sid("1.0.0")}, "1.0.0", false},
+		{"nothing else known", "3.0.0", []SourceID{sid("3.0.0")}, "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := RollbackVersion(did, semv.MustParse(tc.current), deployed, tc.known)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+}
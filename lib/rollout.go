@@ -0,0 +1,149 @@
+package sous
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// Rollout describes how a new version of a deployment replaces the
+	// instances of the old one. The zero Rollout replaces them all at once.
+	Rollout struct {
+		// CanaryPercent is the percentage of instances (rounded up) deployed in
+		// the first stage of the rollout.
+		CanaryPercent int `yaml:",omitempty"`
+		// Steps is the number of stages over which the remaining instances are
+		// deployed.
+		Steps int `yaml:",omitempty"`
+		// StepPause is the number of seconds to wait after a stage is healthy
+		// before starting the next one.
+		StepPause int `yaml:",omitempty"`
+		// AbortOnHealthFailure cancels the rollout, leaving the old version
+		// running, as soon as any new instance fails its health checks.
+		AbortOnHealthFailure bool `yaml:",omitempty"`
+	}
+
+	// RolloutProgress reports how far a staged rollout has got.
+	RolloutProgress struct {
+		// Stage is the stage now being deployed, counting from 1, of Stages.
+		Stage, Stages int
+		// TargetInstances is the number of new instances wanted in this stage.
+		TargetInstances int
+		// ActiveInstances is the number of new instances running.
+		ActiveInstances int
+		// FailedInstances is the number of new instances which have failed.
+		FailedInstances int
+		// StepComplete is true once the current stage is running and healthy.
+		StepComplete bool
+		// Aborted is true if the rollout was cancelled.
+		Aborted bool
+	}
+
+	// A RolloutDeployer is a Deployer which is able to deploy in stages. Its
+	// Rectify begins a staged rollout with only the first stage; the
+	// Rectification then advances it.
+	RolloutDeployer interface {
+		Deployer
+		// RolloutProgress returns the progress of the staged rollout of
+		// pair.Post, or nil if there is none in progress.
+		RolloutProgress(pair *DeployablePair) (*RolloutProgress, error)
+		// AdvanceRollout moves the rollout of pair.Post on to target instances.
+		AdvanceRollout(pair *DeployablePair, target int) error
+		// AbortRollout cancels the rollout of pair.Post.
+		AbortRollout(pair *DeployablePair) error
+	}
+
+	// RolloutAbortedError reports that a staged rollout was cancelled because
+	// new instances failed.
+	RolloutAbortedError struct {
+		Progress RolloutProgress
+	}
+)
+
+func (e *RolloutAbortedError) Error() string {
+	return fmt.Sprintf("rollout aborted at stage %d of %d: %d new instances failed",
+		e.Progress.Stage, e.Progress.Stages, e.Progress.FailedInstances)
+}
+
+// Staged returns true if r deploys in more than one stage.
+func (r Rollout) Staged() bool {
+	return r.CanaryPercent > 0 || r.Steps > 1
+}
+
+// Pause returns the time to wait between stages.
+func (r Rollout) Pause() time.Duration {
+	return time.Duration(r.StepPause) * time.Second
+}
+
+// Stages returns the cumulative number of new instances to be running at the
+// end of each stage of rolling out to instances.
+func (r Rollout) Stages(instances int) []int {
+	if !r.Staged() || instances <= 1 {
+		return []int{instances}
+	}
+	var stages []int
+	canary := 0
+	if r.CanaryPercent > 0 {
+		canary = (instances*r.CanaryPercent + 99) / 100
+		stages = append(stages, canary)
+	}
+	steps := r.Steps
+	if steps < 1 {
+		steps = 1
+	}
+	rest := instances - canary
+	for i := 1; i <= steps; i++ {
+		target := canary + (rest*i+steps-1)/steps
+		if len(stages) > 0 && target <= stages[len(stages)-1] {
+			continue
+		}
+		stages = append(stages, target)
+	}
+	return stages
+}
+
+// Validate implements Flawed on Rollout.
+func (r *Rollout) Validate() []Flaw {
+	flaws := []Flaw{}
+	if r.CanaryPercent < 0 || r.CanaryPercent > 100 {
+		flaws = append(flaws, FatalFlaw("CanaryPercent not between 0 and 100: %d!", r.CanaryPercent))
+	}
+	if r.Steps < 0 {
+		flaws = append(flaws, FatalFlaw("Steps less than zero: %d!", r.Steps))
+	}
+	if r.StepPause < 0 {
+		flaws = append(flaws, FatalFlaw("StepPause less than zero: %d!", r.StepPause))
+	}
+	return flaws
+}
+
+func (r Rollout) diff(o Rollout) []string {
+	diffs := []string{}
+	diff := func(format string, a ...interface{}) {
+		diffs = append(diffs, fmt.Sprintf(format, a...))
+	}
+	if r.CanaryPercent != o.CanaryPercent {
+		diff("rollout CanaryPercent; this %d, other %d", r.CanaryPercent, o.CanaryPercent)
+	}
+	if r.Steps != o.Steps {
+		diff("rollout Steps; this %d, other %d", r.Steps, o.Steps)
+	}
+	if r.StepPause != o.StepPause {
+		diff("rollout StepPause; this %d, other %d", r.StepPause, o.StepPause)
+	}
+	if r.AbortOnHealthFailure != o.AbortOnHealthFailure {
+		diff("rollout AbortOnHealthFailure; this %v, other %v", r.AbortOnHealthFailure, o.AbortOnHealthFailure)
+	}
+	return diffs
+}
+
+// stageOf returns the 1-based stage of stages reached by target instances.
+func stageOf(stages []int, target int) int {
+	stage := 0
+	for i, s := range stages {
+		if target >= s {
+			stage = i + 1
+		}
+	}
+	return stage
+}
@@ -0,0 +1,147 @@
+package sous
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nyarly/spies"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollout_Stages(t *testing.T) {
+	cases := []struct {
+		rollout   Rollout
+		instances int
+		want      []int
+	}{
+		{Rollout{}, 10, []int{10}},
+		{Rollout{Steps: 1}, 10, []int{10}},
+		{Rollout{CanaryPercent: 10}, 10, []int{1, 10}},
+		{Rollout{CanaryPercent: 10, Steps: 3}, 10, []int{1, 4, 7, 10}},
+		{Rollout{CanaryPercent: 25}, 3, []int{1, 3}},
+		{Rollout{CanaryPercent: 100, Steps: 2}, 4, []int{4}},
+		{Rollout{Steps: 4}, 2, []int{1, 2}},
+		{Rollout{Steps: 3}, 1, []int{1}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.rollout.Stages(c.instances), "%+v of %d", c.rollout, c.instances)
+	}
+}
+
+func TestRollout_Validate(t *testing.T) {
+	assert.Empty(t, (&Rollout{CanaryPercent: 10, Steps: 2, StepPause: 30}).Validate())
+	assert.Len(t, (&Rollout{CanaryPercent: 101, Steps: -1, StepPause: -1}).Validate(), 3)
+}
+
+func TestDeployConfig_Diff_Rollout(t *testing.T) {
+	dc := DeployConfig{Rollout: Rollout{CanaryPercent: 10}}
+	_, diffs := dc.Diff(DeployConfig{})
+	require.Len(t, diffs, 1)
+	assert.Contains(t, diffs[0], "rollout")
+	assert.Equal(t, dc.Rollout, dc.Clone().Rollout)
+}
+
+// fakeRolloutDeployer simulates a deployer whose staged rollouts become
+// healthy as soon as they are advanced.
+type fakeRolloutDeployer struct {
+	Deployer
+	sync.Mutex
+	instances, target, failed int
+	advanced                  []int
+	aborted                   bool
+}
+
+func (f *fakeRolloutDeployer) RolloutProgress(*DeployablePair) (*RolloutProgress, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.aborted || f.target >= f.instances {
+		return nil, nil
+	}
+	return &RolloutProgress{
+		TargetInstances: f.target,
+		ActiveInstances: f.target,
+		FailedInstances: f.failed,
+		StepComplete:    f.failed == 0,
+	}, nil
+}
+
+func (f *fakeRolloutDeployer) AdvanceRollout(pair *DeployablePair, target int) error {
+	f.Lock()
+	defer f.Unlock()
+	f.advanced = append(f.advanced, target)
+	f.target = target
+	return nil
+}
+
+func (f *fakeRolloutDeployer) AbortRollout(*DeployablePair) error {
+	f.Lock()
+	defer f.Unlock()
+	f.aborted = true
+	return nil
+}
+
+func (f *fakeRolloutDeployer) Status(Registry, Clusters, *DeployablePair) (*DeployState, error) {
+	f.Lock()
+	defer f.Unlock()
+	switch {
+	case f.aborted:
+		return &DeployState{Status: DeployStatusFailed}, nil
+	case f.target >= f.instances:
+		return &DeployState{Status: DeployStatusActive}, nil
+	}
+	return &DeployState{Status: DeployStatusPending}, nil
+}
+
+func stagedRectification(t *testing.T, failed int) (*Rectification, *fakeRolloutDeployer) {
+	rollout := Rollout{CanaryPercent: 10, Steps: 3, AbortOnHealthFailure: true}
+	sr := NewRectification(DeployablePair{
+		Prior: &Deployable{Deployment: &Deployment{DeployConfig: DeployConfig{NumInstances: 10}}},
+		Post: &Deployable{
+			Deployment:    &Deployment{DeployConfig: DeployConfig{NumInstances: 10, Rollout: rollout}},
+			BuildArtifact: &BuildArtifact{},
+		},
+	})
+
+	spy, c := NewDeployerSpy()
+	c.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+	d := &fakeRolloutDeployer{Deployer: spy, instances: 10, target: 1, failed: failed}
+
+	sr.Begin(d, &DummyRegistry{}, &ResolveFilter{}, NewDummyStateManager())
+
+	done := make(chan struct{})
+	go func() {
+		sr.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("staged rectification took more than 5 seconds")
+	}
+	return sr, d
+}
+
+func TestRectification_StagedRollout(t *testing.T) {
+	sr, d := stagedRectification(t, 0)
+
+	require.Nil(t, sr.Resolution.Error)
+	assert.Equal(t, []int{4, 7, 10}, d.advanced)
+	require.NotNil(t, sr.Resolution.DeployState)
+	assert.Equal(t, DeployStatusActive, sr.Resolution.DeployState.Status)
+	require.NotNil(t, sr.Resolution.Rollout)
+	assert.Equal(t, 4, sr.Resolution.Rollout.Stages)
+}
+
+func TestRectification_StagedRollout_aborts(t *testing.T) {
+	sr, d := stagedRectification(t, 1)
+
+	require.NotNil(t, sr.Resolution.Error)
+	assert.IsType(t, &RolloutAbortedError{}, sr.Resolution.Error.error)
+	assert.True(t, d.aborted)
+	assert.Empty(t, d.advanced)
+	require.NotNil(t, sr.Resolution.Rollout)
+	assert.True(t, sr.Resolution.Rollout.Aborted)
+	assert.Equal(t, 1, sr.Resolution.Rollout.Stage)
+}
@@ -0,0 +1,126 @@
+package sous
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// An SBOM (software bill of materials) records what went into a build
+	// artifact, so that what is deployed can be audited.
+	SBOM struct {
+		// Repo, Offset, Version and Revision identify the source built.
+		Repo     string
+		Offset   string `json:",omitempty"`
+		Version  string
+		Revision string
+		// DirtyWorkingTree is true if the source had uncommitted changes.
+		DirtyWorkingTree bool `json:",omitempty"`
+		// Built is when the artifact was built.
+		Built time.Time
+		// Packages are the operating system packages installed in the
+		// artifact.
+		Packages []SBOMPackage
+	}
+
+	// An SBOMPackage is a package installed in a build artifact.
+	SBOMPackage struct {
+		Name    string
+		Version string
+		// Type is the packaging system the package was installed with, e.g.
+		// "deb", "rpm" or "apk".
+		Type string
+	}
+
+	// An SBOMInserter is an Inserter which can also store the SBOMs of the
+	// artifacts it inserts.
+	SBOMInserter interface {
+		Inserter
+		// InsertWithSBOM is like Insert, but also stores sbom.
+		InsertWithSBOM(sid SourceID, in, etag string, qs []Quality, sbom *SBOM) error
+	}
+
+	// An SBOMRegistry is a Registry which can retrieve the SBOMs of its
+	// artifacts.
+	SBOMRegistry interface {
+		Registry
+		// GetSBOM returns the SBOM of the artifact for sid, or nil if it has
+		// none.
+		GetSBOM(sid SourceID) (*SBOM, error)
+	}
+)
+
+// NewSBOM returns an SBOM for the source sid, listing packages.
+func NewSBOM(sid SourceID, dirty bool, packages []SBOMPackage) *SBOM {
+	v := sid.Version
+	v.Meta = ""
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Type != packages[j].Type {
+			return packages[i].Type < packages[j].Type
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	return &SBOM{
+		Repo:             sid.Location.Repo,
+		Offset:           sid.Location.Dir,
+		Version:          v.String(),
+		Revision:         sid.RevID(),
+		DirtyWorkingTree: dirty,
+		Built:            time.Now().UTC(),
+		Packages:         packages,
+	}
+}
+
+// ParseSBOMPackages parses a package listing, each line of which is the
+// tab separated name, version and type of a package. Lines which do not
+// have three fields are ignored.
+func ParseSBOMPackages(listing string) []SBOMPackage {
+	packages := []SBOMPackage{}
+	scanner := bufio.NewScanner(strings.NewReader(listing))
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) != 3 || fields[0] == "" {
+			continue
+		}
+		packages = append(packages, SBOMPackage{Name: fields[0], Version: fields[1], Type: fields[2]})
+	}
+	return packages
+}
+
+// EncodeLabel encodes s compactly for use as the value of an image label.
+func (s *SBOM) EncodeLabel() (string, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeSBOMLabel decodes an SBOM encoded by EncodeLabel.
+func DecodeSBOMLabel(label string) (*SBOM, error) {
+	b, err := base64.StdEncoding.DecodeString(label)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	js, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SBOM{}
+	return s, json.Unmarshal(js, s)
+}
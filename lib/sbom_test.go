@@ -0,0 +1,48 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSBOMPackages(t *testing.T) {
+	listing := "openssl\t1.1.0f-3\tdeb\n\nbroken line\nzlib\t1.2.11\tapk\n"
+	assert.Equal(t, []SBOMPackage{
+		{Name: "openssl", Version: "1.1.0f-3", Type: "deb"},
+		{Name: "zlib", Version: "1.2.11", Type: "apk"},
+	}, ParseSBOMPackages(listing))
+}
+
+func TestNewSBOM(t *testing.T) {
+	sid := MustNewSourceID("github.com/opentable/test", "sub", "1.2.3+abcdef")
+	sbom := NewSBOM(sid, true, []SBOMPackage{
+		{Name: "zlib", Version: "1.2.11", Type: "deb"},
+		{Name: "musl", Version: "1.1.18", Type: "apk"},
+		{Name: "openssl", Version: "1.1.0f-3", Type: "deb"},
+	})
+	assert.Equal(t, "github.com/opentable/test", sbom.Repo)
+	assert.Equal(t, "sub", sbom.Offset)
+	assert.Equal(t, "1.2.3", sbom.Version)
+	assert.Equal(t, "abcdef", sbom.Revision)
+	assert.True(t, sbom.DirtyWorkingTree)
+	assert.Equal(t, []string{"musl", "openssl", "zlib"},
+		[]string{sbom.Packages[0].Name, sbom.Packages[1].Name, sbom.Packages[2].Name})
+}
+
+func TestSBOMLabelRoundTrip(t *testing.T) {
+	sid := MustNewSourceID("github.com/opentable/test", "", "1.2.3+abcdef")
+	sbom := NewSBOM(sid, false, []SBOMPackage{{Name: "openssl", Version: "1.1.0f-3", Type: "deb"}})
+	label, err := sbom.EncodeLabel()
+	require.NoError(t, err)
+
+	decoded, err := DecodeSBOMLabel(label)
+	require.NoError(t, err)
+	assert.Equal(t, sbom.Repo, decoded.Repo)
+	assert.Equal(t, sbom.Packages, decoded.Packages)
+	assert.True(t, sbom.Built.Equal(decoded.Built))
+
+	_, err = DecodeSBOMLabel("not base64!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package sous
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A SecretRef refers to a secret value held by a SecretProvider. In an Env
+	// it is written secret://path#key; the key may be omitted if the secret at
+	// path is a single value.
+	SecretRef struct {
+		Path, Key string
+		// ManifestID is the manifest whose deployment refers to the secret.
+		// Providers may use it to scope which secrets it can read.
+		ManifestID ManifestID
+	}
+
+	// A SecretProvider looks up the values of SecretRefs.
+	SecretProvider interface {
+		Secret(ref SecretRef) (string, error)
+	}
+)
+
+const (
+	// SecretRefScheme is the prefix of Env values which are SecretRefs.
+	SecretRefScheme = "secret://"
+
+	// RedactedValue is shown in place of the value of a secret.
+	RedactedValue = "<redacted>"
+)
+
+// IsSecretRef returns true if v is meant to be a SecretRef.
+func IsSecretRef(v string) bool {
+	return strings.HasPrefix(v, SecretRefScheme)
+}
+
+// ParseSecretRef parses a secret://path#key reference.
+func ParseSecretRef(v string) (SecretRef, error) {
+	if !IsSecretRef(v) {
+		return SecretRef{}, errors.Errorf("%q is not a secret reference: it must begin %s", v, SecretRefScheme)
+	}
+	ref := SecretRef{Path: strings.TrimPrefix(v, SecretRefScheme)}
+	if i := strings.Index(ref.Path, "#"); i >= 0 {
+		ref.Path, ref.Key = ref.Path[:i], ref.Path[i+1:]
+	}
+	if strings.Trim(ref.Path, "/") == "" {
+		return SecretRef{}, errors.Errorf("secret reference %q has no path", v)
+	}
+	return ref, nil
+}
+
+func (r SecretRef) String() string {
+	if r.Key == "" {
+		return SecretRefScheme + r.Path
+	}
+	return SecretRefScheme + r.Path + "#" + r.Key
+}
+
+// SecretRefs returns the variables of e whose values are SecretRefs, and those
+// values.
+func (e Env) SecretRefs() map[string]string {
+	refs := map[string]string{}
+	for name, v := range e {
+		if IsSecretRef(v) {
+			refs[name] = v
+		}
+	}
+	return refs
+}
+
+// ResolveSecrets returns a copy of e in which the values of each SecretRef
+// have been looked up in sp on behalf of the manifest mid. Values which are
+// not SecretRefs are unchanged.
+func (e Env) ResolveSecrets(sp SecretProvider, mid ManifestID) (Env, error) {
+	resolved := make(Env, len(e))
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := e[name]
+		if !IsSecretRef(v) {
+			resolved[name] = v
+			continue
+		}
+		if sp == nil {
+			return nil, errors.Errorf("env %s refers to a secret, but no secret provider is configured", name)
+		}
+		ref, err := ParseSecretRef(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "env %s", name)
+		}
+		ref.ManifestID = mid
+		if resolved[name], err = sp.Secret(ref); err != nil {
+			return nil, errors.Wrapf(err, "env %s: resolving %s", name, ref)
+		}
+	}
+	return resolved, nil
+}
+
+// Redacted returns a copy of e suitable for logging and display. SecretRefs
+// are shown as they are, since they do not reveal their secrets; but any other
+// value of a variable which is a SecretRef in one of others is replaced by
+// RedactedValue, since it may be a resolved secret.
+func (e Env) Redacted(others ...Env) Env {
+	if e == nil {
+		return nil
+	}
+	r := make(Env, len(e))
+	for name, v := range e {
+		r[name] = v
+		if IsSecretRef(v) {
+			continue
+		}
+		for _, o := range others {
+			if IsSecretRef(o[name]) {
+				r[name] = RedactedValue
+				break
+			}
+		}
+	}
+	return r
+}
+
+// ValidateSecrets returns a Flaw for each value of e which begins like a
+// SecretRef, but cannot be parsed as one.
+func (e Env) ValidateSecrets() []Flaw {
+	var flaws []Flaw
+	for name, v := range e {
+		if !IsSecretRef(v) {
+			continue
+		}
+		if _, err := ParseSecretRef(v); err != nil {
+			flaws = append(flaws, FatalFlaw("Env %s: %s", name, err))
+		}
+	}
+	return flaws
+}
@@ -0,0 +1,82 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapSecretProvider map[SecretRef]string
+
+func (p mapSecretProvider) Secret(ref SecretRef) (string, error) {
+	v, has := p[SecretRef{Path: ref.Path, Key: ref.Key}]
+	if !has {
+		return "", errors.Errorf("no secret %s", ref)
+	}
+	return v, nil
+}
+
+func TestParseSecretRef(t *testing.T) {
+	ref, err := ParseSecretRef("secret://db/creds#password")
+	require.NoError(t, err)
+	assert.Equal(t, SecretRef{Path: "db/creds", Key: "password"}, ref)
+	assert.Equal(t, "secret://db/creds#password", ref.String())
+
+	ref, err = ParseSecretRef("secret://token")
+	require.NoError(t, err)
+	assert.Equal(t, SecretRef{Path: "token"}, ref)
+	assert.Equal(t, "secret://token", ref.String())
+
+	_, err = ParseSecretRef("secret://#password")
+	assert.Error(t, err)
+	_, err = ParseSecretRef("hunter2")
+	assert.Error(t, err)
+}
+
+func TestEnv_ResolveSecrets(t *testing.T) {
+	env := Env{"DB_PASSWORD": "secret://db/creds#password", "GREETING": "hello"}
+	sp := mapSecretProvider{{Path: "db/creds", Key: "password"}: "hunter2"}
+
+	resolved, err := env.ResolveSecrets(sp, ManifestID{})
+	require.NoError(t, err)
+	assert.Equal(t, Env{"DB_PASSWORD": "hunter2", "GREETING": "hello"}, resolved)
+	assert.Equal(t, "secret://db/creds#password", env["DB_PASSWORD"], "resolving changed the original")
+
+	_, err = env.ResolveSecrets(nil, ManifestID{})
+	assert.Error(t, err)
+	_, err = env.ResolveSecrets(mapSecretProvider{}, ManifestID{})
+	assert.Error(t, err)
+
+	plain, err := Env{"GREETING": "hello"}.ResolveSecrets(nil, ManifestID{})
+	require.NoError(t, err)
+	assert.Equal(t, Env{"GREETING": "hello"}, plain)
+}
+
+func TestEnv_Redacted(t *testing.T) {
+	refs := Env{"DB_PASSWORD": "secret://db/creds#password", "GREETING": "hello"}
+	resolved := Env{"DB_PASSWORD": "hunter2", "GREETING": "hello"}
+
+	assert.Equal(t, Env{"DB_PASSWORD": "<redacted>", "GREETING": "hello"}, resolved.Redacted(refs))
+	assert.Equal(t, refs, refs.Redacted(resolved))
+}
+
+func TestDeployConfig_Diff_redactsSecrets(t *testing.T) {
+	intended := DeployConfig{Env: Env{"DB_PASSWORD": "secret://db/creds#password"}}
+	actual := DeployConfig{Env: Env{"DB_PASSWORD": "hunter2"}}
+
+	same, diffs := intended.Diff(actual)
+	assert.False(t, same)
+	require.Len(t, diffs, 1)
+	assert.NotContains(t, diffs[0], "hunter2")
+	assert.Contains(t, diffs[0], "secret://db/creds#password")
+}
+
+func TestEnv_ValidateSecrets(t *testing.T) {
+	env := Env{"BAD": "secret://", "GOOD": "secret://db#password", "PLAIN": "hello"}
+	flaws := env.ValidateSecrets()
+	require.Len(t, flaws, 1)
+	assert.Contains(t, fmt.Sprint(flaws[0]), "BAD")
+}
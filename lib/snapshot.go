@@ -0,0 +1,268 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pborman/uuid"
+)
+
+type (
+	// A SnapshotInfo describes a snapshot of the state.
+	SnapshotInfo struct {
+		// ID uniquely identifies the snapshot.
+		ID string
+		// Time is when the snapshot was taken.
+		Time time.Time
+		// User is the user who asked for the snapshot. It is empty for
+		// periodic snapshots.
+		User User
+		// Reason is why the snapshot was taken.
+		Reason SnapshotReason
+	}
+
+	// SnapshotReason describes why a snapshot was taken.
+	SnapshotReason string
+
+	// A StateSnapshot is a copy of the state as it was at a point in time.
+	StateSnapshot struct {
+		SnapshotInfo
+		Defs      Defs
+		Manifests []*Manifest
+	}
+
+	// SnapshotStore stores snapshots of the state.
+	SnapshotStore interface {
+		// SaveSnapshot stores ss.
+		SaveSnapshot(ss *StateSnapshot) error
+		// Snapshots describes at most limit snapshots, newest first. A limit
+		// of zero or less means no limit.
+		Snapshots(limit int) ([]SnapshotInfo, error)
+		// Snapshot returns the snapshot with ID id, and false if there is
+		// none.
+		Snapshot(id string) (*StateSnapshot, bool, error)
+		// SnapshotAt returns the latest snapshot taken at or before t, and
+		// false if there is none.
+		SnapshotAt(t time.Time) (*StateSnapshot, bool, error)
+	}
+
+	// MemorySnapshotStore is a SnapshotStore that lives only as long as the
+	// process.
+	MemorySnapshotStore struct {
+		sync.Mutex
+		snapshots []*StateSnapshot
+	}
+
+	// A Snapshotter takes snapshots of the state, every Interval and when
+	// asked to, and restores them.
+	Snapshotter struct {
+		Store SnapshotStore
+		State StateReader
+		// Interval is the time between periodic snapshots. If it is zero,
+		// snapshots are only taken when asked for.
+		Interval time.Duration
+		log      logging.LogSink
+	}
+)
+
+const (
+	// SnapshotPeriodic is the reason for snapshots taken every
+	// Snapshotter.Interval.
+	SnapshotPeriodic SnapshotReason = "periodic"
+	// SnapshotOnDemand is the reason for snapshots taken when a user asked for
+	// one.
+	SnapshotOnDemand SnapshotReason = "on-demand"
+	// SnapshotBeforeRestore is the reason for snapshots of the state
+	// replaced by a restore, so that the restore can be undone.
+	SnapshotBeforeRestore SnapshotReason = "before-restore"
+)
+
+// NewStateSnapshot returns a snapshot of s.
+func NewStateSnapshot(s *State, reason SnapshotReason, user User, when time.Time) *StateSnapshot {
+	s = s.Clone()
+	ms := s.Manifests.Snapshot()
+	ids := make([]ManifestID, 0, len(ms))
+	for id := range ms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	manifests := make([]*Manifest, 0, len(ids))
+	for _, id := range ids {
+		manifests = append(manifests, ms[id])
+	}
+	return &StateSnapshot{
+		SnapshotInfo: SnapshotInfo{
+			ID:     uuid.New(),
+			Time:   when,
+			User:   user,
+			Reason: reason,
+		},
+		Defs:      s.Defs,
+		Manifests: manifests,
+	}
+}
+
+// State returns a copy of the state in ss.
+func (ss *StateSnapshot) State() *State {
+	s := &State{Defs: ss.Defs, Manifests: NewManifests(ss.Manifests...)}
+	return s.Clone()
+}
+
+// DiffStates describes the differences between prior and post, one line for
+// the definitions if they differ, and one for each changed manifest.
+func DiffStates(prior, post *State) []string {
+	var diffs []string
+	if !prior.Defs.Equal(post.Defs) {
+		diffs = append(diffs, "defs: changed")
+	}
+	for _, e := range HistoryChanges(prior, post, User{}, time.Time{}) {
+		diffs = append(diffs, fmt.Sprintf("%s: %s", e.ManifestID, strings.Join(e.Diffs, "; ")))
+	}
+	return diffs
+}
+
+// NewMemorySnapshotStore returns an empty MemorySnapshotStore.
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{}
+}
+
+// SaveSnapshot implements SnapshotStore on MemorySnapshotStore.
+func (ms *MemorySnapshotStore) SaveSnapshot(ss *StateSnapshot) error {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.snapshots = append(ms.snapshots, ss)
+	sort.SliceStable(ms.snapshots, func(i, j int) bool {
+		return ms.snapshots[i].Time.Before(ms.snapshots[j].Time)
+	})
+	return nil
+}
+
+// Snapshots implements SnapshotStore on MemorySnapshotStore.
+func (ms *MemorySnapshotStore) Snapshots(limit int) ([]SnapshotInfo, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	infos := []SnapshotInfo{}
+	for i := len(ms.snapshots) - 1; i >= 0; i-- {
+		if limit > 0 && len(infos) == limit {
+			break
+		}
+		infos = append(infos, ms.snapshots[i].SnapshotInfo)
+	}
+	return infos, nil
+}
+
+// Snapshot implements SnapshotStore on MemorySnapshotStore.
+func (ms *MemorySnapshotStore) Snapshot(id string) (*StateSnapshot, bool, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	for _, ss := range ms.snapshots {
+		if ss.ID == id {
+			return ss, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// SnapshotAt implements SnapshotStore on MemorySnapshotStore.
+func (ms *MemorySnapshotStore) SnapshotAt(t time.Time) (*StateSnapshot, bool, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	for i := len(ms.snapshots) - 1; i >= 0; i-- {
+		if !ms.snapshots[i].Time.After(t) {
+			return ms.snapshots[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// NewSnapshotter returns a Snapshotter which stores snapshots of the state
+// read from sr in store.
+func NewSnapshotter(store SnapshotStore, sr StateReader, interval time.Duration, ls logging.LogSink) *Snapshotter {
+	return &Snapshotter{Store: store, State: sr, Interval: interval, log: ls}
+}
+
+// Take takes a snapshot of the state now.
+func (sn *Snapshotter) Take(reason SnapshotReason, user User) (*StateSnapshot, error) {
+	s, err := sn.State.ReadState()
+	if err != nil {
+		return nil, err
+	}
+	return sn.save(s, reason, user)
+}
+
+func (sn *Snapshotter) save(s *State, reason SnapshotReason, user User) (*StateSnapshot, error) {
+	ss := NewStateSnapshot(s, reason, user, time.Now())
+	if err := sn.Store.SaveSnapshot(ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// Start takes a snapshot every Interval, unless the state has not changed
+// since the latest snapshot. It does nothing if Interval is zero.
+func (sn *Snapshotter) Start() {
+	if sn.Interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(sn.Interval) {
+			if err := sn.takePeriodic(); err != nil {
+				messages.ReportLogFieldsMessage("Failed to take periodic snapshot of GDM", logging.WarningLevel, sn.log, err)
+			}
+		}
+	}()
+}
+
+func (sn *Snapshotter) takePeriodic() error {
+	s, err := sn.State.ReadState()
+	if err != nil {
+		return err
+	}
+	latest, found, err := sn.Store.SnapshotAt(time.Now())
+	if err != nil {
+		return err
+	}
+	if found && len(DiffStates(latest.State(), s)) == 0 {
+		return nil
+	}
+	_, err = sn.save(s, SnapshotPeriodic, User{})
+	return err
+}
+
+// Find returns the snapshot named by ref, which is either the ID of a
+// snapshot, or an RFC3339 time, naming the latest snapshot taken at or before
+// that time. It returns false if there is no such snapshot.
+func (sn *Snapshotter) Find(ref string) (*StateSnapshot, bool, error) {
+	if t, err := time.Parse(time.RFC3339, ref); err == nil {
+		return sn.Store.SnapshotAt(t)
+	}
+	return sn.Store.Snapshot(ref)
+}
+
+// Restore writes the state in ss back to sm, as user, having first taken a
+// snapshot of the state it replaces, which it returns. The state is written
+// with the etag of the one it replaces, so that it conflicts with any write
+// made in between, rather than silently undoing it.
+func (sn *Snapshotter) Restore(ss *StateSnapshot, sm StateManager, user User) (*StateSnapshot, error) {
+	prior, err := sm.ReadState()
+	if err != nil {
+		return nil, err
+	}
+	backup, err := sn.save(prior, SnapshotBeforeRestore, user)
+	if err != nil {
+		return nil, err
+	}
+	restored := ss.State()
+	if etag, err := prior.GetEtag(); err == nil {
+		restored.SetEtag(etag)
+	}
+	if err := sm.WriteState(restored, user); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
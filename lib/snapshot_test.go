@@ -0,0 +1,68 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotter(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	sn := NewSnapshotter(NewMemorySnapshotStore(), sm, 0, logging.SilentLogSet())
+	user := User{Name: "Test User", Email: "test@example.com"}
+
+	first, err := sn.Take(SnapshotOnDemand, user)
+	require.NoError(t, err)
+	assert.Equal(t, user, first.User)
+	assert.Len(t, first.Manifests, 3)
+
+	// Nothing has changed, so there is no periodic snapshot.
+	require.NoError(t, sn.takePeriodic())
+	infos, err := sn.Store.Snapshots(0)
+	require.NoError(t, err)
+	assert.Len(t, infos, 1)
+
+	changeSpec(t, sm.State, "cluster0", func(s *DeploySpec) { s.NumInstances = 9 })
+	require.NoError(t, sn.takePeriodic())
+	infos, err = sn.Store.Snapshots(0)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, SnapshotPeriodic, infos[0].Reason)
+	assert.Equal(t, first.ID, infos[1].ID)
+
+	found, ok, err := sn.Find(first.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, first.ID, found.ID)
+
+	found, ok, err = sn.Find(first.Time.Add(time.Second).Format(time.RFC3339))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, infos[0].ID, found.ID, "should find the latest snapshot before the time")
+
+	_, ok, err = sn.Find(first.Time.Add(-time.Hour).Format(time.RFC3339))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = sn.Find("no-such-snapshot")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	diffs := DiffStates(first.State(), sm.State)
+	require.Len(t, diffs, 1)
+	assert.Contains(t, diffs[0], mergedMID.String())
+
+	sm.State.SetEtag("before-restore")
+	backup, err := sn.Restore(first, sm, user)
+	require.NoError(t, err)
+	etag, err := sm.State.GetEtag()
+	require.NoError(t, err)
+	assert.Equal(t, "before-restore", etag, "the restored state should be written over the one read")
+	assert.Equal(t, SnapshotBeforeRestore, backup.Reason)
+	assert.Equal(t, 3, mergedSpec(t, sm.State, "cluster0").NumInstances)
+	assert.Len(t, DiffStates(backup.State(), sm.State), 1)
+	assert.Empty(t, DiffStates(first.State(), sm.State))
+}
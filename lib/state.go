@@ -1,6 +1,7 @@
 package sous
 
 import (
+	"reflect"
 	"sort"
 	"strings"
 
@@ -34,6 +35,11 @@ type (
 		Resources FieldDefinitions
 		// Metadata contains the definitions for metadata fields
 		Metadata FieldDefinitions
+		// Promotions defines pipelines of clusters through which new versions
+		// are promoted automatically.
+		Promotions Promotions `yaml:",omitempty"`
+		// Webhooks are notified of the rectification of every deployment.
+		Webhooks Webhooks `yaml:",omitempty"`
 	}
 
 	// EnvDefs is a collection of EnvDef
@@ -70,8 +76,8 @@ type (
 	Cluster struct {
 		// Name is the unique name of this cluster.
 		Name string
-		// Kind is the kind of cluster. Currently the only legal value is
-		// "singularity"
+		// Kind is the kind of cluster. Legal values are "singularity" (the
+		// default, if Kind is empty) and "kubernetes".
 		Kind string
 		// BaseURL is the main entrypoint URL for interacting with this cluster.
 		BaseURL string
@@ -82,6 +88,17 @@ type (
 		// AllowedAdvisories lists the artifact advisories which are permissible in
 		// this cluster
 		AllowedAdvisories []string
+		// ArtifactPolicy, if set, restricts which artifacts may be deployed to
+		// this cluster.
+		ArtifactPolicy *ArtifactPolicy `yaml:",omitempty"`
+		// Platform is the platform of this cluster's hosts, e.g.
+		// "linux/arm64". If set, only artifacts with an image for this
+		// platform are deployed to this cluster.
+		Platform string `yaml:",omitempty"`
+		// RequireSignatures, if true, means that only artifacts whose images
+		// are signed by a trusted key as built from their SourceID are
+		// deployed to this cluster.
+		RequireSignatures bool `yaml:",omitempty"`
 	}
 
 	// EnvDefaults is a list of named environment variables along with their values.
@@ -131,12 +148,20 @@ func (s State) Clone() *State {
 	return &s
 }
 
+// Equal returns true if d and other define the same things, treating nil and
+// empty collections as the same.
+func (d Defs) Equal(other Defs) bool {
+	return sameMerged(reflect.ValueOf(d), reflect.ValueOf(other))
+}
+
 // Clone returns a deep copy of this Defs.
 func (d Defs) Clone() Defs {
 	d.Clusters = d.Clusters.Clone()
 	d.EnvVars = d.EnvVars.Clone()
 	d.Resources = d.Resources.Clone()
 	d.Metadata = d.Metadata.Clone()
+	d.Promotions = d.Promotions.Clone()
+	d.Webhooks = d.Webhooks.Clone()
 	return d
 }
 
@@ -166,6 +191,7 @@ func (c Cluster) Clone() *Cluster {
 	allowedAdvisories := make([]string, len(c.AllowedAdvisories))
 	copy(allowedAdvisories, c.AllowedAdvisories)
 	c.AllowedAdvisories = allowedAdvisories
+	c.ArtifactPolicy = c.ArtifactPolicy.Clone()
 	return &c
 }
 
@@ -219,6 +245,9 @@ func (s *State) Validate() []Flaw {
 		flaws = append(flaws, depl.Validate()...)
 	}
 
+	flaws = append(flaws, s.Defs.Promotions.Validate(s.Defs.Clusters)...)
+	flaws = append(flaws, s.Defs.Webhooks.Validate()...)
+
 	for _, f := range flaws {
 		f.AddContext("state", s)
 	}
@@ -0,0 +1,302 @@
+package sous
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A MergeConflict is a field of the state which was changed in different
+	// ways by both sides of a merge.
+	MergeConflict struct {
+		// Path names the field, e.g.
+		// `Manifests[github.com/opentable/sous].Deployments[ci-sf].NumInstances`.
+		Path string
+		// Base, Ours and Theirs are the values of the field in each state, or
+		// nil where the field is absent.
+		Base, Ours, Theirs interface{}
+	}
+
+	// MergeConflicts is the error returned by MergeStates when the states
+	// cannot be merged.
+	MergeConflicts []MergeConflict
+
+	stateMerger struct {
+		conflicts MergeConflicts
+	}
+
+	// A StaleStateError is returned by writing a state read at an etag which
+	// is no longer the current one.
+	StaleStateError struct {
+		// Etag is the etag the state was read at.
+		Etag string
+		// Current is the current etag, if it is known.
+		Current string
+	}
+
+	// StateVersions is implemented by StateManagers which can read the
+	// states they gave out etags for, so that the changes made to them since
+	// can be merged.
+	StateVersions interface {
+		// ReadStateAt returns the state as it was when it had etag, or a
+		// *StaleStateError if it is no longer known.
+		ReadStateAt(etag string) (*State, error)
+	}
+
+	// A MergingStateManager wraps a StateManager whose writes fail with a
+	// *StaleStateError if the state written was not read at the current
+	// etag. It merges the changes made to the state since it was read with
+	// those made in the meantime, and writes the merged state instead, so
+	// that everything it wraps sees the same state.
+	MergingStateManager struct {
+		StateManager
+		Versions StateVersions
+		log      logging.LogSink
+	}
+)
+
+// mergeAttempts is the number of times a MergingStateManager merges a state
+// before giving up, if other writers keep changing it meanwhile.
+const mergeAttempts = 5
+
+var sousPkgPath = reflect.TypeOf(State{}).PkgPath()
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s: was %s, ours %s, theirs %s", c.Path, describeMerged(c.Base), describeMerged(c.Ours), describeMerged(c.Theirs))
+}
+
+func describeMerged(v interface{}) string {
+	if v == nil {
+		return "absent"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (mc MergeConflicts) Error() string {
+	lines := make([]string, 0, len(mc))
+	for _, c := range mc {
+		lines = append(lines, "  "+c.String())
+	}
+	return fmt.Sprintf("%d conflicting changes to the state:\n%s", len(mc), strings.Join(lines, "\n"))
+}
+
+func (e *StaleStateError) Error() string {
+	if e.Current == "" {
+		return fmt.Sprintf("state was read at %q, which is too old to merge with", e.Etag)
+	}
+	return fmt.Sprintf("state was read at %q, but is now at %q", e.Etag, e.Current)
+}
+
+// NewMergingStateManager returns a MergingStateManager writing through sm,
+// which reads the states stale ones were read at from versions.
+func NewMergingStateManager(sm StateManager, versions StateVersions, ls logging.LogSink) *MergingStateManager {
+	return &MergingStateManager{StateManager: sm, Versions: versions, log: ls}
+}
+
+// WriteState implements StateWriter on MergingStateManager. If s was read
+// at an etag which is no longer current, the changes made to it are merged
+// with the ones made since, and the merged state written. If both made
+// conflicting changes, a MergeConflicts is returned. s is not modified.
+func (msm *MergingStateManager) WriteState(s *State, u User) error {
+	// A state without an etag is written over whatever is current.
+	etag, err := s.GetEtag()
+	conditional := err == nil
+	var base *State
+	for remainingAttempts := mergeAttempts; remainingAttempts > 0; remainingAttempts-- {
+		merged := s.Clone()
+		if conditional {
+			current, err := msm.StateManager.ReadState()
+			if err != nil {
+				return err
+			}
+			if currentEtag, err := current.GetEtag(); err == nil && currentEtag != etag {
+				if base == nil {
+					if base, err = msm.Versions.ReadStateAt(etag); err != nil {
+						return err
+					}
+				}
+				if merged, err = MergeStates(base, s, current); err != nil {
+					return err
+				}
+				merged.SetEtag(currentEtag)
+				messages.ReportLogFieldsMessage("Merged state with changes written since it was read", logging.DebugLevel, msm.log, etag, currentEtag)
+			}
+		}
+		err := msm.StateManager.WriteState(merged, u)
+		if _, stale := errors.Cause(err).(*StaleStateError); !stale {
+			return err
+		}
+		messages.ReportLogFieldsMessage("State changed while writing; merging again with # attempts left", logging.DebugLevel, msm.log, remainingAttempts, err)
+	}
+	return errors.New("unable to merge changes")
+}
+
+// MergeStates merges the changes made to base by ours and by theirs, and
+// returns the result, without modifying any of them. Changes are merged field
+// by field: a field changed on only one side takes that side's value, and a
+// field changed on both sides must have been changed the same way, or it is
+// reported in the MergeConflicts returned as the error.
+//
+// Manifests, clusters and the other maps in the state are merged key by key,
+// and the structs in them field by field, but other values, like versions and
+// lists of owners or volumes, are only compared as a whole.
+func MergeStates(base, ours, theirs *State) (*State, error) {
+	m := &stateMerger{}
+	merged := &State{}
+	defs := m.merge("Defs", reflect.ValueOf(base.Defs), reflect.ValueOf(ours.Defs), reflect.ValueOf(theirs.Defs))
+	merged.Defs = defs.Interface().(Defs)
+
+	ms := m.merge("Manifests",
+		reflect.ValueOf(base.Manifests.Snapshot()),
+		reflect.ValueOf(ours.Manifests.Snapshot()),
+		reflect.ValueOf(theirs.Manifests.Snapshot()))
+	merged.Manifests = NewManifestsFromMap(ms.Interface().(map[ManifestID]*Manifest))
+
+	if len(m.conflicts) > 0 {
+		sort.Slice(m.conflicts, func(i, j int) bool { return m.conflicts[i].Path < m.conflicts[j].Path })
+		return nil, m.conflicts
+	}
+	return merged.Clone(), nil
+}
+
+// merge returns the merge of the values at path, which are invalid where
+// they are absent.
+func (m *stateMerger) merge(path string, base, ours, theirs reflect.Value) reflect.Value {
+	switch {
+	case sameMerged(ours, theirs), sameMerged(base, theirs):
+		return ours
+	case sameMerged(base, ours):
+		return theirs
+	}
+	if base.IsValid() && ours.IsValid() && theirs.IsValid() {
+		switch t := base.Type(); {
+		case t.Kind() == reflect.Map:
+			return m.mergeMap(path, base, ours, theirs)
+		case t.Kind() == reflect.Ptr && mergesByField(t.Elem()):
+			if !base.IsNil() && !ours.IsNil() && !theirs.IsNil() {
+				p := reflect.New(t.Elem())
+				p.Elem().Set(m.mergeStruct(path, base.Elem(), ours.Elem(), theirs.Elem()))
+				return p
+			}
+		case mergesByField(t):
+			return m.mergeStruct(path, base, ours, theirs)
+		}
+	}
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   path,
+		Base:   mergedInterface(base),
+		Ours:   mergedInterface(ours),
+		Theirs: mergedInterface(theirs),
+	})
+	return theirs
+}
+
+func (m *stateMerger) mergeMap(path string, base, ours, theirs reflect.Value) reflect.Value {
+	merged := reflect.MakeMap(base.Type())
+	keys := map[interface{}]reflect.Value{}
+	for _, v := range []reflect.Value{base, ours, theirs} {
+		for _, k := range v.MapKeys() {
+			keys[k.Interface()] = k
+		}
+	}
+	for _, k := range keys {
+		v := m.merge(fmt.Sprintf("%s[%v]", path, k.Interface()), base.MapIndex(k), ours.MapIndex(k), theirs.MapIndex(k))
+		if v.IsValid() {
+			merged.SetMapIndex(k, v)
+		}
+	}
+	return merged
+}
+
+// mergeStruct merges the exported fields of a struct. Unexported fields are
+// taken from ours.
+func (m *stateMerger) mergeStruct(path string, base, ours, theirs reflect.Value) reflect.Value {
+	merged := reflect.New(base.Type()).Elem()
+	merged.Set(ours)
+	t := base.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fieldPath := path
+		if !f.Anonymous {
+			fieldPath += "." + f.Name
+		}
+		merged.Field(i).Set(m.merge(fieldPath, base.Field(i), ours.Field(i), theirs.Field(i)))
+	}
+	return merged
+}
+
+// mergesByField is true of the structs defined in this package, whose fields
+// are merged separately. Structs from elsewhere, e.g. semv.Version, are
+// merged as a whole.
+func mergesByField(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == sousPkgPath
+}
+
+// sameMerged compares values for merging, treating nil and empty maps and
+// slices as the same, and ignoring the unexported fields of the structs
+// merged field by field.
+func sameMerged(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameMerged(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if !mergesByField(a.Type()) {
+			break
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if !sameMerged(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			if !sameMerged(a.MapIndex(k), b.MapIndex(k)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameMerged(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func mergedInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
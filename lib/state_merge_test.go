@@ -0,0 +1,170 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mergedMID  = MustParseManifestID("github.com/user0/repo0,dir0~flavor0")
+	removedMID = MustParseManifestID("github.com/user1/repo1,dir1~flavor1")
+)
+
+// changeSpec changes the spec for cluster in the manifest mergedMID of s.
+func changeSpec(t *testing.T, s *State, cluster string, f func(*DeploySpec)) {
+	t.Helper()
+	m, ok := s.Manifests.Get(mergedMID)
+	require.True(t, ok)
+	spec, ok := m.Deployments[cluster]
+	require.True(t, ok, "no spec for %s", cluster)
+	f(&spec)
+	m.Deployments[cluster] = spec
+}
+
+func mergedSpec(t *testing.T, s *State, cluster string) DeploySpec {
+	t.Helper()
+	m, ok := s.Manifests.Get(mergedMID)
+	require.True(t, ok)
+	return m.Deployments[cluster]
+}
+
+func TestMergeStates_nonOverlapping(t *testing.T) {
+	base := DefaultStateFixture()
+	ours, theirs := base.Clone(), base.Clone()
+
+	changeSpec(t, ours, "cluster0", func(s *DeploySpec) { s.NumInstances = 5 })
+	changeSpec(t, theirs, "cluster1", func(s *DeploySpec) { s.Version = semv.MustParse("2.0.0") })
+	// The same field of the same spec, changed the same way.
+	changeSpec(t, ours, "cluster2", func(s *DeploySpec) { s.Schedule = "@daily" })
+	changeSpec(t, theirs, "cluster2", func(s *DeploySpec) { s.Schedule = "@daily" })
+	// Different fields of the same spec.
+	changeSpec(t, ours, "cluster2", func(s *DeploySpec) { s.Env = Env{"A": "1"} })
+	changeSpec(t, theirs, "cluster2", func(s *DeploySpec) { s.NumInstances = 7 })
+
+	theirs.Defs.Clusters["cluster3"] = &Cluster{Name: "cluster3", Kind: "singularity"}
+	ours.Manifests.Remove(removedMID)
+
+	merged, err := MergeStates(base, ours, theirs)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, mergedSpec(t, merged, "cluster0").NumInstances)
+	assert.Equal(t, "2.0.0", mergedSpec(t, merged, "cluster1").Version.String())
+	spec := mergedSpec(t, merged, "cluster2")
+	assert.Equal(t, "@daily", spec.Schedule)
+	assert.Equal(t, Env{"A": "1"}, spec.Env)
+	assert.Equal(t, 7, spec.NumInstances)
+	assert.Contains(t, merged.Defs.Clusters, "cluster3")
+	_, present := merged.Manifests.Get(removedMID)
+	assert.False(t, present, "manifest removed by ours should stay removed")
+	assert.Equal(t, 2, merged.Manifests.Len())
+
+	// The inputs are unchanged.
+	assert.Equal(t, 3, mergedSpec(t, base, "cluster0").NumInstances)
+	assert.Equal(t, 3, base.Manifests.Len())
+}
+
+func TestMergeStates_conflicts(t *testing.T) {
+	base := DefaultStateFixture()
+	ours, theirs := base.Clone(), base.Clone()
+
+	changeSpec(t, ours, "cluster0", func(s *DeploySpec) { s.NumInstances = 5 })
+	changeSpec(t, theirs, "cluster0", func(s *DeploySpec) { s.NumInstances = 6 })
+	// Not a conflict, despite being in the same spec.
+	changeSpec(t, theirs, "cluster0", func(s *DeploySpec) { s.Schedule = "@daily" })
+
+	ours.Defs.Clusters["cluster1"].BaseURL = "http://ours"
+	delete(theirs.Defs.Clusters, "cluster1")
+
+	_, err := MergeStates(base, ours, theirs)
+	require.Error(t, err)
+	conflicts, ok := err.(MergeConflicts)
+	require.True(t, ok, "got %T, want MergeConflicts", err)
+	require.Len(t, conflicts, 2)
+
+	assert.Equal(t, "Defs.Clusters[cluster1]", conflicts[0].Path)
+	assert.Nil(t, conflicts[0].Theirs)
+
+	assert.Equal(t, "Manifests["+mergedMID.String()+"].Deployments[cluster0].NumInstances", conflicts[1].Path)
+	assert.Equal(t, 3, conflicts[1].Base)
+	assert.Equal(t, 5, conflicts[1].Ours)
+	assert.Equal(t, 6, conflicts[1].Theirs)
+	assert.Contains(t, err.Error(), "NumInstances: was 3, ours 5, theirs 6")
+}
+
+// versionedStateManager refuses writes of stale states, like the storage
+// a MergingStateManager wraps, and records the states written to it.
+type versionedStateManager struct {
+	states  []*State
+	written []*State
+}
+
+func (vsm *versionedStateManager) etag() string {
+	return fmt.Sprintf("rev-%d", len(vsm.states)-1)
+}
+
+func (vsm *versionedStateManager) ReadState() (*State, error) {
+	s := vsm.states[len(vsm.states)-1].Clone()
+	s.SetEtag(vsm.etag())
+	return s, nil
+}
+
+func (vsm *versionedStateManager) WriteState(s *State, u User) error {
+	if err := s.CheckEtag(vsm.etag()); err != nil {
+		etag, _ := s.GetEtag()
+		return &StaleStateError{Etag: etag, Current: vsm.etag()}
+	}
+	vsm.states = append(vsm.states, s.Clone())
+	vsm.written = append(vsm.written, s)
+	return nil
+}
+
+func (vsm *versionedStateManager) ReadStateAt(etag string) (*State, error) {
+	for i, s := range vsm.states {
+		if fmt.Sprintf("rev-%d", i) == etag {
+			return s.Clone(), nil
+		}
+	}
+	return nil, &StaleStateError{Etag: etag}
+}
+
+func TestMergingStateManager(t *testing.T) {
+	vsm := &versionedStateManager{states: []*State{DefaultStateFixture()}}
+	history := NewMemoryHistory()
+	msm := NewMergingStateManager(NewHistoryStateManager(vsm, history, logging.SilentLogSet()), vsm, logging.SilentLogSet())
+
+	ours, err := msm.ReadState()
+	require.NoError(t, err)
+	theirs, err := msm.ReadState()
+	require.NoError(t, err)
+
+	changeSpec(t, theirs, "cluster1", func(s *DeploySpec) { s.NumInstances = 7 })
+	require.NoError(t, msm.WriteState(theirs, User{Name: "them"}))
+	changeSpec(t, ours, "cluster0", func(s *DeploySpec) { s.NumInstances = 5 })
+	require.NoError(t, msm.WriteState(ours, User{Name: "us"}))
+
+	// The storage and the history both see the merged state.
+	require.Len(t, vsm.written, 2)
+	merged := vsm.written[1]
+	assert.Equal(t, 5, mergedSpec(t, merged, "cluster0").NumInstances)
+	assert.Equal(t, 7, mergedSpec(t, merged, "cluster1").NumInstances)
+	entries, err := history.Entries(nil, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "us", entries[0].User.Name)
+	assert.Equal(t, []string{"cluster0"}, entries[0].Clusters, "the merge should not record a revert of their change")
+
+	etag, err := ours.GetEtag()
+	require.NoError(t, err)
+	assert.Equal(t, "rev-0", etag, "the state written should not be changed")
+
+	// A state read too long ago to merge with is stale.
+	ours.SetEtag("rev-99")
+	_, stale := errors.Cause(msm.WriteState(ours, User{})).(*StaleStateError)
+	assert.True(t, stale)
+}
@@ -0,0 +1,246 @@
+package sous
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// A StateChange reports that the state was written.
+	StateChange struct {
+		// Seq numbers the changes seen by one StateChanges, starting from 1.
+		// It is 0 from a StateWatcher which doesn't number its changes.
+		Seq int
+		// Time is when the change was seen.
+		Time time.Time
+	}
+
+	// A StateWatcher reports changes to the state it manages, including
+	// those made by other processes which share its storage.
+	StateWatcher interface {
+		// Watch returns a channel which receives a StateChange each time the
+		// state changes, until done is closed. Changes in quick succession
+		// may be reported once.
+		Watch(done <-chan struct{}) (<-chan StateChange, error)
+	}
+
+	// StateChanges numbers the changes reported by a StateWatcher, and
+	// shares them with any number of watchers, so that the state is only
+	// watched once. It starts watching when it is first asked for a change.
+	StateChanges struct {
+		watcher StateWatcher
+		log     logging.LogSink
+		start   sync.Once
+		sync.Mutex
+		latest StateChange
+		// wait is closed, and replaced, when there is a change.
+		wait chan struct{}
+	}
+
+	// ReportingStateManager is a StateManager which records each of its
+	// successful writes in Changes, so that its watchers hear of them at
+	// once.
+	ReportingStateManager struct {
+		StateManager
+		Changes *StateChanges
+	}
+)
+
+// NewStateChanges returns a StateChanges which reports the changes seen by w,
+// which may be nil if only the changes recorded by Changed are wanted.
+func NewStateChanges(w StateWatcher, ls logging.LogSink) *StateChanges {
+	return &StateChanges{
+		watcher: w,
+		log:     ls,
+		wait:    make(chan struct{}),
+	}
+}
+
+func (sc *StateChanges) follow() {
+	if sc.watcher == nil {
+		return
+	}
+	changes, err := sc.watcher.Watch(nil)
+	if err != nil {
+		messages.ReportLogFieldsMessage("Unable to watch state, changes will only be seen when they are made here", logging.WarningLevel, sc.log, err)
+		return
+	}
+	go func() {
+		for range changes {
+			sc.Changed()
+		}
+	}()
+}
+
+// Changed records a change to the state.
+func (sc *StateChanges) Changed() {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.latest = StateChange{Seq: sc.latest.Seq + 1, Time: time.Now()}
+	close(sc.wait)
+	sc.wait = make(chan struct{})
+}
+
+// Latest returns the latest change, which has Seq 0 if there have been none.
+func (sc *StateChanges) Latest() StateChange {
+	sc.start.Do(sc.follow)
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.latest
+}
+
+func (sc *StateChanges) current() (StateChange, chan struct{}) {
+	sc.start.Do(sc.follow)
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.latest, sc.wait
+}
+
+// Next waits up to timeout for a change other than the one numbered seq,
+// and returns it and true. If there is none, it returns the latest change and
+// false.
+func (sc *StateChanges) Next(seq int, timeout time.Duration) (StateChange, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		latest, wait := sc.current()
+		if latest.Seq != seq {
+			return latest, true
+		}
+		select {
+		case <-wait:
+		case <-timer.C:
+			return latest, false
+		}
+	}
+}
+
+// Watch implements StateWatcher on StateChanges.
+func (sc *StateChanges) Watch(done <-chan struct{}) (<-chan StateChange, error) {
+	out := make(chan StateChange, 1)
+	seen, _ := sc.current()
+	go func() {
+		defer close(out)
+		for {
+			latest, wait := sc.current()
+			if latest.Seq != seen.Seq {
+				seen = latest
+				select {
+				case out <- latest:
+				case <-done:
+					return
+				}
+				continue
+			}
+			select {
+			case <-wait:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// WriteState implements StateWriter on ReportingStateManager.
+func (rsm ReportingStateManager) WriteState(s *State, u User) error {
+	if err := rsm.StateManager.WriteState(s, u); err != nil {
+		return err
+	}
+	rsm.Changes.Changed()
+	return nil
+}
+
+// PollState returns a channel which receives a StateChange whenever the
+// revision returned by rev differs from the one before, checking every
+// interval until done is closed. Errors from rev are logged, and the check
+// tried again after the next interval.
+func PollState(done <-chan struct{}, interval time.Duration, rev func() (string, error), ls logging.LogSink) <-chan StateChange {
+	out := make(chan StateChange, 1)
+	last, err := rev()
+	if err != nil {
+		logging.ReportError(ls, err)
+	}
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+			}
+			r, err := rev()
+			if err != nil {
+				logging.ReportError(ls, err)
+				continue
+			}
+			if r == last {
+				continue
+			}
+			last = r
+			notifyStateChange(out)
+		}
+	}()
+	return out
+}
+
+// notifyStateChange sends a StateChange on out unless one is already waiting
+// to be received, in which case that one reports this change too.
+func notifyStateChange(out chan StateChange) {
+	select {
+	case out <- StateChange{Time: time.Now()}:
+	default:
+	}
+}
+
+// MergeStateWatchers returns a StateWatcher which reports the changes seen by
+// each of ws which can be watched.
+func MergeStateWatchers(ls logging.LogSink, ws ...StateWatcher) StateWatcher {
+	return mergedWatchers{watchers: ws, log: ls}
+}
+
+type mergedWatchers struct {
+	watchers []StateWatcher
+	log      logging.LogSink
+}
+
+// Watch implements StateWatcher on mergedWatchers. It only returns an error
+// if none of its watchers can be watched.
+func (mw mergedWatchers) Watch(done <-chan struct{}) (<-chan StateChange, error) {
+	var chans []<-chan StateChange
+	var firstErr error
+	for _, w := range mw.watchers {
+		ch, err := w.Watch(done)
+		if err != nil {
+			messages.ReportLogFieldsMessage("Unable to watch state", logging.WarningLevel, mw.log, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		chans = append(chans, ch)
+	}
+	if len(chans) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	out := make(chan StateChange, 1)
+	var wg sync.WaitGroup
+	for _, ch := range chans {
+		wg.Add(1)
+		go func(ch <-chan StateChange) {
+			defer wg.Done()
+			for range ch {
+				notifyStateChange(out)
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out, nil
+}
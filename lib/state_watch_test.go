@@ -0,0 +1,113 @@
+package sous
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateChanges_Next(t *testing.T) {
+	sc := NewStateChanges(nil, logging.SilentLogSet())
+	assert.Equal(t, 0, sc.Latest().Seq)
+
+	change, ok := sc.Next(0, time.Millisecond)
+	assert.False(t, ok)
+	assert.Equal(t, 0, change.Seq)
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		sc.Changed()
+	}()
+	change, ok = sc.Next(0, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 1, change.Seq)
+
+	// A client which missed changes gets the latest at once.
+	sc.Changed()
+	change, ok = sc.Next(0, time.Millisecond)
+	assert.True(t, ok)
+	assert.Equal(t, 2, change.Seq)
+}
+
+func TestStateChanges_Watch(t *testing.T) {
+	sm := NewDummyStateManager()
+	sc := NewStateChanges(nil, logging.SilentLogSet())
+	rsm := ReportingStateManager{StateManager: sm, Changes: sc}
+
+	done := make(chan struct{})
+	changes, err := sc.Watch(done)
+	require.NoError(t, err)
+
+	require.NoError(t, rsm.WriteState(NewState(), User{}))
+	select {
+	case change := <-changes:
+		assert.Equal(t, 1, change.Seq)
+	case <-time.After(time.Second):
+		t.Fatal("write was not reported")
+	}
+
+	sm.WriteErr = errors.New("no")
+	assert.Error(t, rsm.WriteState(NewState(), User{}))
+	assert.Equal(t, 1, sc.Latest().Seq, "failed writes are not changes")
+
+	close(done)
+	for range changes {
+	}
+}
+
+func TestPollState(t *testing.T) {
+	revs := make(chan string, 3)
+	revs <- "a"
+	revs <- "a"
+	revs <- "b"
+	rev := func() (string, error) {
+		select {
+		case r := <-revs:
+			return r, nil
+		default:
+			return "b", nil
+		}
+	}
+	done := make(chan struct{})
+	defer close(done)
+
+	changes := PollState(done, time.Millisecond, rev, logging.SilentLogSet())
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change of revision was not reported")
+	}
+	select {
+	case <-changes:
+		t.Error("unchanged revision was reported")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+type unwatchable struct{}
+
+func (unwatchable) Watch(<-chan struct{}) (<-chan StateChange, error) {
+	return nil, errors.New("unwatchable")
+}
+
+func TestMergeStateWatchers(t *testing.T) {
+	ls := logging.SilentLogSet()
+	_, err := MergeStateWatchers(ls, unwatchable{}).Watch(nil)
+	assert.Error(t, err)
+
+	sc := NewStateChanges(nil, ls)
+	done := make(chan struct{})
+	changes, err := MergeStateWatchers(ls, unwatchable{}, sc).Watch(done)
+	require.NoError(t, err)
+	sc.Changed()
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("change was not reported")
+	}
+	close(done)
+}
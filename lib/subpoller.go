@@ -215,7 +215,14 @@ func (sub *subPoller) computeState(srvIntent *Deployment, current *DiffResolutio
 			}
 		}
 
-		reportSubPollerMessage(fmt.Sprintf("Deployment of %s to %s failed: %s", subject, sub.ClusterName, current.Error.String), sub.logs)
+		switch current.Error.Type {
+		case "*sous.ArtifactPolicyViolation":
+			reportSubPollerMessage(fmt.Sprintf("Deployment of %s to %s blocked by artifact policy: %s", subject, sub.ClusterName, current.Error.String), sub.logs)
+		case "*sous.UnsupportedPlatform", "*sous.UnverifiedArtifact":
+			reportSubPollerMessage(fmt.Sprintf("Deployment of %s to %s blocked: %s", subject, sub.ClusterName, current.Error.String), sub.logs)
+		default:
+			reportSubPollerMessage(fmt.Sprintf("Deployment of %s to %s failed: %s", subject, sub.ClusterName, current.Error.String), sub.logs)
+		}
 		return ResolveFailed, current.Error
 	}
 
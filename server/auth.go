@@ -0,0 +1,374 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An Authenticator identifies the user making a request.
+	Authenticator interface {
+		// Authenticate returns the user making req, or an error if they cannot
+		// be identified.
+		Authenticate(req *http.Request) (ClientUser, error)
+	}
+
+	// proxyAuthenticator trusts headers set by an authenticating proxy.
+	proxyAuthenticator struct {
+		nameHeader, emailHeader string
+	}
+
+	// tokenAuthenticator looks up bearer tokens in a fixed table.
+	tokenAuthenticator struct {
+		users map[string]ClientUser
+	}
+
+	// tlsAuthenticator identifies users by their verified client certificate.
+	tlsAuthenticator struct{}
+
+	// An Authorizer decides whether users may change manifests. A nil
+	// *Authorizer allows every change.
+	Authorizer struct {
+		admins    []string
+		deployers map[string][]string
+	}
+
+	// unauthenticatedExchanger responds to requests whose user could not be
+	// identified.
+	unauthenticatedExchanger struct {
+		err error
+	}
+)
+
+// NewAuthenticator returns the Authenticator described by cfg.
+func NewAuthenticator(cfg config.AuthConfig) (Authenticator, error) {
+	switch cfg.Mode {
+	default:
+		return nil, errors.Errorf("unknown auth mode %q", cfg.Mode)
+	case "", "header":
+		return userExtractor{}, nil
+	case "proxy":
+		return proxyAuthenticator{nameHeader: cfg.ProxyNameHeader, emailHeader: cfg.ProxyEmailHeader}, nil
+	case "token":
+		b, err := ioutil.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading token file")
+		}
+		users := map[string]ClientUser{}
+		if err := yaml.Unmarshal(b, &users); err != nil {
+			return nil, errors.Wrapf(err, "parsing token file %q", cfg.TokenFile)
+		}
+		return tokenAuthenticator{users: users}, nil
+	case "tls":
+		return tlsAuthenticator{}, nil
+	}
+}
+
+// Authenticate implements Authenticator on userExtractor, trusting the headers
+// sent by the client.
+func (ue userExtractor) Authenticate(req *http.Request) (ClientUser, error) {
+	return ue.GetUser(req), nil
+}
+
+func (pa proxyAuthenticator) Authenticate(req *http.Request) (ClientUser, error) {
+	user := ClientUser{}
+	if pa.nameHeader != "" {
+		user.Name = req.Header.Get(pa.nameHeader)
+	}
+	if pa.emailHeader != "" {
+		user.Email = req.Header.Get(pa.emailHeader)
+	}
+	if user.Name == "" && user.Email == "" {
+		return user, errors.New("request did not pass through the authenticating proxy")
+	}
+	return user, nil
+}
+
+func (ta tokenAuthenticator) Authenticate(req *http.Request) (ClientUser, error) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ClientUser{}, errors.New("no bearer token in Authorization header")
+	}
+	user, ok := ta.users[strings.TrimPrefix(auth, "Bearer ")]
+	if !ok {
+		return ClientUser{}, errors.New("unknown bearer token")
+	}
+	return user, nil
+}
+
+func (tlsAuthenticator) Authenticate(req *http.Request) (ClientUser, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return ClientUser{}, errors.New("no verified client certificate")
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	user := ClientUser{Name: cert.Subject.CommonName}
+	if len(cert.EmailAddresses) > 0 {
+		user.Email = cert.EmailAddresses[0]
+	}
+	return user, nil
+}
+
+// Exchange implements restful.Exchanger.
+func (ue unauthenticatedExchanger) Exchange() (interface{}, int) {
+	return fmt.Sprintf("Unauthenticated: %s.", ue.err), http.StatusUnauthorized
+}
+
+// NewAuthorizer returns the Authorizer described by cfg, or nil if cfg does
+// not turn on authorization.
+func NewAuthorizer(cfg config.AuthConfig) *Authorizer {
+	if !cfg.Authorize {
+		return nil
+	}
+	return &Authorizer{admins: cfg.Admins, deployers: cfg.ClusterDeployers}
+}
+
+// AuthorizeManifest returns an error if user may not replace prior with next.
+// prior is nil when next is a new manifest, and next is nil when prior is
+// being deleted. Changes to an unowned manifest need the user to be a
+// deployer of each of its clusters, and only admins may change its owners or
+// kind. Changes to the owners or kind of a manifest change its deployments
+// in every cluster.
+func (a *Authorizer) AuthorizeManifest(user ClientUser, prior, next *sous.Manifest) error {
+	if a == nil || userIn(user, a.admins) {
+		return nil
+	}
+	if user.Name == "" && user.Email == "" {
+		return errors.New("anonymous users may not change manifests")
+	}
+	clusters := changedClusters(prior, next)
+	if prior != nil {
+		if len(prior.Owners) > 0 && !userIn(user, prior.Owners) {
+			return errors.Errorf("%s is not an owner of %q (owners: %s)",
+				sous.User(user), prior.ID(), strings.Join(prior.Owners, ", "))
+		}
+		fields := manifestChanges(prior, next)
+		if len(prior.Owners) == 0 {
+			if len(fields) > 0 {
+				return errors.Errorf("%s may not change the %s of %q, which has no owners: only admins may",
+					sous.User(user), strings.Join(fields, " and "), prior.ID())
+			}
+			if err := a.authorizeUnowned(user, prior, next); err != nil {
+				return err
+			}
+		}
+		if len(fields) > 0 {
+			clusters = allClusters(prior, next)
+		}
+	}
+	for _, cluster := range clusters {
+		deployers, restricted := a.deployers[cluster]
+		if restricted && !userIn(user, deployers) {
+			return errors.Errorf("%s may not change deployments in cluster %q", sous.User(user), cluster)
+		}
+	}
+	return nil
+}
+
+// authorizeUnowned returns an error unless user is one of the deployers
+// listed for every cluster of prior and next, which would otherwise be open
+// to changes by any user, since prior has no owners.
+func (a *Authorizer) authorizeUnowned(user ClientUser, prior, next *sous.Manifest) error {
+	clusters := allClusters(prior, next)
+	if len(clusters) == 0 {
+		return errors.Errorf("%q has no owners or deployments: only admins may change it", prior.ID())
+	}
+	for _, cluster := range clusters {
+		if !userIn(user, a.deployers[cluster]) {
+			return errors.Errorf("%q has no owners: only admins, and deployers of cluster %q, may change it",
+				prior.ID(), cluster)
+		}
+	}
+	return nil
+}
+
+// AuthorizeManifests returns an error if user may not make every change
+// needed to replace prior with next.
+func (a *Authorizer) AuthorizeManifests(user ClientUser, prior, next sous.Manifests) error {
+	if a == nil {
+		return nil
+	}
+	ids := map[sous.ManifestID]struct{}{}
+	for _, id := range prior.Keys() {
+		ids[id] = struct{}{}
+	}
+	for _, id := range next.Keys() {
+		ids[id] = struct{}{}
+	}
+	for id := range ids {
+		p, hadPrior := prior.Get(id)
+		n, hasNext := next.Get(id)
+		if hadPrior && hasNext {
+			if different, _ := p.Diff(n); !different {
+				continue
+			}
+		}
+		if err := a.AuthorizeManifest(user, p, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthorizeState returns an error if user may not replace prior with next.
+// Only admins may change the definitions in the GDM.
+func (a *Authorizer) AuthorizeState(user ClientUser, prior, next *sous.State) error {
+	if a == nil {
+		return nil
+	}
+	if !prior.Defs.Equal(next.Defs) && !userIn(user, a.admins) {
+		return errors.Errorf("%s may not change the definitions in the GDM", sous.User(user))
+	}
+	return a.AuthorizeManifests(user, prior.Manifests, next.Manifests)
+}
+
+// AuthorizeClusters returns an error if user may not change the deployments
+// in clusters, regardless of their manifests.
+func (a *Authorizer) AuthorizeClusters(user ClientUser, clusters ...string) error {
+	if a == nil || userIn(user, a.admins) {
+		return nil
+	}
+	if user.Name == "" && user.Email == "" {
+		return errors.New("anonymous users may not change deployments")
+	}
+	for _, cluster := range clusters {
+		deployers, restricted := a.deployers[cluster]
+		if restricted && !userIn(user, deployers) {
+			return errors.Errorf("%s may not change deployments in cluster %q", sous.User(user), cluster)
+		}
+	}
+	return nil
+}
+
+// AuthorizeAdmin returns an error if user is not an admin, and so may not do
+// what, which affects the server as a whole rather than any one manifest.
+func (a *Authorizer) AuthorizeAdmin(user ClientUser, what string) error {
+	if a == nil || userIn(user, a.admins) {
+		return nil
+	}
+	return errors.Errorf("%s may not %s: only admins may", sous.User(user), what)
+}
+
+// AuthorizeBuild returns an error if user may not build the source at loc
+// on the server: only admins, and those who may change every deployment of
+// its manifests in state, may.
+func (a *Authorizer) AuthorizeBuild(user ClientUser, state *sous.State, loc sous.SourceLocation) error {
+	if a == nil || userIn(user, a.admins) {
+		return nil
+	}
+	found := false
+	for _, m := range state.Manifests.Snapshot() {
+		if m.Source != loc {
+			continue
+		}
+		found = true
+		if len(m.Owners) == 0 {
+			if err := a.authorizeUnowned(user, m, nil); err != nil {
+				return err
+			}
+		} else if !userIn(user, m.Owners) {
+			return errors.Errorf("%s is not an owner of %q (owners: %s)",
+				sous.User(user), m.ID(), strings.Join(m.Owners, ", "))
+		}
+		clusters := []string{}
+		for cluster := range m.Deployments {
+			clusters = append(clusters, cluster)
+		}
+		sort.Strings(clusters)
+		if err := a.AuthorizeClusters(user, clusters...); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return errors.Errorf("%s has no manifest, so only admins may build it", loc)
+	}
+	return nil
+}
+
+// changedClusters returns the names of the clusters whose deployments differ
+// between prior and next, in alphabetical order.
+func changedClusters(prior, next *sous.Manifest) []string {
+	specs := func(m *sous.Manifest) sous.DeploySpecs {
+		if m == nil {
+			return sous.DeploySpecs{}
+		}
+		return m.Deployments
+	}
+	before, after := specs(prior), specs(next)
+	changed := map[string]struct{}{}
+	for cluster, spec := range before {
+		other, ok := after[cluster]
+		if !ok {
+			changed[cluster] = struct{}{}
+			continue
+		}
+		if different, _ := spec.Diff(other); different {
+			changed[cluster] = struct{}{}
+		}
+	}
+	for cluster := range after {
+		if _, ok := before[cluster]; !ok {
+			changed[cluster] = struct{}{}
+		}
+	}
+	clusters := make([]string, 0, len(changed))
+	for cluster := range changed {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// manifestChanges returns the names of the fields other than Deployments
+// which differ between prior and next, and which apply to every cluster.
+func manifestChanges(prior, next *sous.Manifest) []string {
+	if prior == nil || next == nil {
+		return nil
+	}
+	fields := []string{}
+	if different, _ := sous.NewOwnerSet(prior.Owners...).Diff(sous.NewOwnerSet(next.Owners...)); different {
+		fields = append(fields, "owners")
+	}
+	if prior.Kind != next.Kind {
+		fields = append(fields, "kind")
+	}
+	return fields
+}
+
+// allClusters returns the names of the clusters deployed to by either of
+// prior and next, in alphabetical order.
+func allClusters(prior, next *sous.Manifest) []string {
+	names := map[string]struct{}{}
+	for _, m := range []*sous.Manifest{prior, next} {
+		if m == nil {
+			continue
+		}
+		for cluster := range m.Deployments {
+			names[cluster] = struct{}{}
+		}
+	}
+	clusters := make([]string, 0, len(names))
+	for cluster := range names {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// userIn returns true if any of names is the name or email of user.
+func userIn(user ClientUser, names []string) bool {
+	for _, n := range names {
+		if (user.Name != "" && strings.EqualFold(n, user.Name)) ||
+			(user.Email != "" && strings.EqualFold(n, user.Email)) {
+			return true
+		}
+	}
+	return false
+}
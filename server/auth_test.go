@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authTestManifest(owners ...string) *sous.Manifest {
+	spec := sous.DeploySpec{
+		Version: semv.MustParse("1.0.0"),
+		DeployConfig: sous.DeployConfig{
+			Resources: sous.Resources{"cpus": "0.1", "memory": "100", "ports": "1"},
+			Startup:   sous.Startup{CheckReadyProtocol: "HTTP", CheckReadyURIPath: "/health"},
+		},
+	}
+	return &sous.Manifest{
+		Source:      sous.SourceLocation{Repo: "gh"},
+		Owners:      owners,
+		Kind:        sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{"cluster-1": spec, "prod": spec.Clone()},
+	}
+}
+
+func TestNewAuthenticator_Header(t *testing.T) {
+	a, err := NewAuthenticator(config.AuthConfig{})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Sous-User-Name", "Sam")
+	req.Header.Set("Sous-User-Email", "sam@example.com")
+	user, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, ClientUser{Name: "Sam", Email: "sam@example.com"}, user)
+}
+
+func TestNewAuthenticator_Proxy(t *testing.T) {
+	a, err := NewAuthenticator(config.AuthConfig{Mode: "proxy", ProxyEmailHeader: "X-Auth-Email"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Sous-User-Email", "forged@example.com")
+	_, err = a.Authenticate(req)
+	assert.Error(t, err)
+
+	req.Header.Set("X-Auth-Email", "sam@example.com")
+	user, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "sam@example.com", user.Email)
+}
+
+func TestNewAuthenticator_Token(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "tokens.yaml")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("abc123:\n  Name: Sam\n  Email: sam@example.com\n"), 0600))
+
+	a, err := NewAuthenticator(config.AuthConfig{Mode: "token", TokenFile: tokenFile})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	_, err = a.Authenticate(req)
+	assert.Error(t, err)
+
+	req.Header.Set("Authorization", "Bearer wrong")
+	_, err = a.Authenticate(req)
+	assert.Error(t, err)
+
+	req.Header.Set("Authorization", "Bearer abc123")
+	user, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, ClientUser{Name: "Sam", Email: "sam@example.com"}, user)
+}
+
+func TestNewAuthenticator_TLSWithoutCertificate(t *testing.T) {
+	a, err := NewAuthenticator(config.AuthConfig{Mode: "tls"})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	_, err = a.Authenticate(req)
+	assert.Error(t, err)
+}
+
+func TestNewAuthorizer_Disabled(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{})
+	assert.Nil(t, a)
+	assert.NoError(t, a.AuthorizeManifest(ClientUser{}, authTestManifest("sam"), nil))
+}
+
+func TestAuthorizeManifest(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{
+		Authorize:        true,
+		Admins:           []string{"admin@example.com"},
+		ClusterDeployers: map[string][]string{"prod": {"judson@example.com"}},
+	})
+	sam := ClientUser{Name: "Sam", Email: "sam@example.com"}
+	judson := ClientUser{Name: "Judson", Email: "judson@example.com"}
+	mallory := ClientUser{Name: "Mallory", Email: "mallory@example.com"}
+	admin := ClientUser{Email: "admin@example.com"}
+
+	prior := authTestManifest("sam@example.com", "judson@example.com")
+	toCluster1 := prior.Clone()
+	toCluster1.Deployments["cluster-1"] = sous.DeploySpec{Version: semv.MustParse("1.1.0")}
+	toProd := prior.Clone()
+	toProd.Deployments["prod"] = sous.DeploySpec{Version: semv.MustParse("1.1.0")}
+	created := authTestManifest("mallory@example.com")
+	delete(created.Deployments, "prod")
+
+	assert.NoError(t, a.AuthorizeManifest(sam, prior, toCluster1))
+	assert.NoError(t, a.AuthorizeManifest(judson, prior, toProd))
+	assert.NoError(t, a.AuthorizeManifest(admin, prior, toProd))
+	assert.NoError(t, a.AuthorizeManifest(mallory, nil, created))
+
+	assert.Error(t, a.AuthorizeManifest(ClientUser{}, prior, toCluster1))
+	assert.Error(t, a.AuthorizeManifest(mallory, prior, toCluster1))
+	assert.Error(t, a.AuthorizeManifest(mallory, prior, nil))
+	assert.Error(t, a.AuthorizeManifest(sam, prior, toProd))
+	assert.Error(t, a.AuthorizeManifest(mallory, nil, authTestManifest("mallory@example.com")))
+}
+
+func TestAuthorizeManifest_Unowned(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{
+		Authorize: true,
+		Admins:    []string{"root"},
+		ClusterDeployers: map[string][]string{
+			"cluster-1": {"dana", "judson"},
+			"prod":      {"dana"},
+		},
+	})
+	dana := ClientUser{Name: "dana"}
+	judson := ClientUser{Name: "judson"}
+	mallory := ClientUser{Name: "mallory"}
+
+	prior := authTestManifest()
+	scaled := prior.Clone()
+	scaled.Deployments["cluster-1"] = sous.DeploySpec{Version: semv.MustParse("1.1.0")}
+	claimed := prior.Clone()
+	claimed.Owners = []string{"mallory"}
+
+	assert.NoError(t, a.AuthorizeManifest(dana, prior, scaled))
+	assert.NoError(t, a.AuthorizeManifest(ClientUser{Name: "root"}, prior, claimed))
+
+	assert.Error(t, a.AuthorizeManifest(mallory, prior, scaled))
+	assert.Error(t, a.AuthorizeManifest(mallory, prior, claimed))
+	assert.Error(t, a.AuthorizeManifest(dana, prior, claimed))
+	assert.Error(t, a.AuthorizeManifest(mallory, prior, nil))
+	// judson deploys to cluster-1, but not to prod, which the manifest also
+	// deploys to.
+	assert.Error(t, a.AuthorizeManifest(judson, prior, scaled))
+}
+
+func TestAuthorizeManifest_ManifestWide(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{
+		Authorize:        true,
+		ClusterDeployers: map[string][]string{"prod": {"judson"}},
+	})
+	sam := ClientUser{Name: "sam"}
+	judson := ClientUser{Name: "judson"}
+
+	prior := authTestManifest("sam", "judson")
+	rekinded := prior.Clone()
+	rekinded.Kind = sous.ManifestKindScheduled
+	reowned := prior.Clone()
+	reowned.Owners = []string{"sam"}
+	reordered := prior.Clone()
+	reordered.Owners = []string{"judson", "sam"}
+
+	// Both change the manifest in prod, where only judson deploys.
+	assert.Error(t, a.AuthorizeManifest(sam, prior, rekinded))
+	assert.Error(t, a.AuthorizeManifest(sam, prior, reowned))
+	assert.NoError(t, a.AuthorizeManifest(judson, prior, rekinded))
+	assert.NoError(t, a.AuthorizeManifest(judson, prior, reowned))
+	assert.NoError(t, a.AuthorizeManifest(sam, prior, reordered))
+}
+
+func TestAuthorizeManifests(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{Authorize: true})
+	prior := sous.NewManifests(authTestManifest("sam"))
+	next := prior.Clone()
+
+	assert.NoError(t, a.AuthorizeManifests(ClientUser{Name: "mallory"}, prior, next))
+
+	m, _ := next.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}})
+	m.Deployments["cluster-1"] = sous.DeploySpec{Version: semv.MustParse("2.0.0")}
+	assert.Error(t, a.AuthorizeManifests(ClientUser{Name: "mallory"}, prior, next))
+	assert.NoError(t, a.AuthorizeManifests(ClientUser{Name: "sam"}, prior, next))
+}
+
+func TestHandlesManifestPutForbidden(t *testing.T) {
+	q, err := url.ParseQuery("repo=gh")
+	require.NoError(t, err)
+	state := sous.NewState()
+	state.Manifests.Add(authTestManifest("sam"))
+	writer := &sous.DummyStateManager{State: state}
+
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(authTestManifest("mallory"))
+	req, err := http.NewRequest("PUT", "", buf)
+	require.NoError(t, err)
+
+	th := &PUTManifestHandler{
+		Request:     req,
+		StateWriter: writer,
+		State:       state,
+		QueryValues: restful.QueryValues{Values: q},
+		LogSink:     logging.Log,
+		User:        ClientUser{Name: "mallory"},
+		Authorizer:  NewAuthorizer(config.AuthConfig{Authorize: true}),
+	}
+
+	data, status := th.Exchange()
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, data, "is not an owner")
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{Authorize: true, Admins: []string{"root"}})
+	assert.NoError(t, a.AuthorizeAdmin(ClientUser{Name: "root"}, "repair storage"))
+	assert.Error(t, a.AuthorizeAdmin(ClientUser{Name: "sam"}, "repair storage"))
+	assert.Error(t, a.AuthorizeAdmin(ClientUser{}, "repair storage"))
+}
+
+func TestAuthorizeState(t *testing.T) {
+	a := NewAuthorizer(config.AuthConfig{Authorize: true, Admins: []string{"root"}})
+	prior := sous.NewState()
+	prior.Manifests.Add(authTestManifest("sam"))
+	next := prior.Clone()
+	next.Defs.Clusters = sous.Clusters{"cluster-1": &sous.Cluster{Name: "cluster-1"}}
+
+	assert.Error(t, a.AuthorizeState(ClientUser{Name: "sam"}, prior, next))
+	assert.NoError(t, a.AuthorizeState(ClientUser{Name: "root"}, prior, next))
+	assert.NoError(t, a.AuthorizeState(ClientUser{Name: "sam"}, prior, prior.Clone()))
+}
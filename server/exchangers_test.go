@@ -20,6 +20,7 @@ func TestResourcesFulfillInterfaces(t *testing.T) {
 	assert.Implements(t, (*restful.Putable)(nil), newManifestResource(ComponentLocator{}))
 	assert.Implements(t, (*restful.Deleteable)(nil), newManifestResource(ComponentLocator{}))
 
+	assert.Implements(t, (*restful.Getable)(nil), newArtifactResource(ComponentLocator{}))
 	assert.Implements(t, (*restful.Putable)(nil), newArtifactResource(ComponentLocator{}))
 
 	assert.Implements(t, (*restful.Getable)(nil), newServerListResource(ComponentLocator{}))
@@ -34,4 +35,6 @@ func TestResourcesFulfillInterfaces(t *testing.T) {
 	assert.Implements(t, (*restful.Getable)(nil), newDeployQueueResource(ComponentLocator{}))
 
 	assert.Implements(t, (*restful.Getable)(nil), newR11nResource(ComponentLocator{}))
+
+	assert.Implements(t, (*restful.Getable)(nil), newNotificationsResource(ComponentLocator{}))
 }
@@ -18,11 +18,22 @@ type (
 		context ComponentLocator
 	}
 
+	// GETArtifactHandler handles GET requests to /artifact
+	GETArtifactHandler struct {
+		restful.QueryValues
+		sous.Registry
+	}
+
 	// PUTArtifactHandler handles PUT requests to /artifact
 	PUTArtifactHandler struct {
 		*http.Request
 		restful.QueryValues
 		sous.Inserter
+		User       ClientUser
+		Authorizer *Authorizer
+		// State is the GDM, whose manifests say who may record artifacts for
+		// a source.
+		State *sous.State
 	}
 )
 
@@ -30,12 +41,27 @@ func newArtifactResource(ctx ComponentLocator) *ArtifactResource {
 	return &ArtifactResource{context: ctx}
 }
 
+// Get implements Getable on ArtifactResource, which marks it as accepting GET requests
+func (ar *ArtifactResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETArtifactHandler{
+		QueryValues: ar.ParseQuery(req),
+		Registry:    ar.context.Registry,
+	}
+}
+
 // Put implements Putable on ArtifactResource, which marks it as accepting PUT requests
 func (ar *ArtifactResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := ar.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &PUTArtifactHandler{
 		Request:     req,
 		QueryValues: ar.ParseQuery(req),
 		Inserter:    ar.context.Inserter,
+		User:        user,
+		Authorizer:  ar.context.Authorizer,
+		State:       ar.context.liveState(),
 	}
 }
 
@@ -53,7 +79,20 @@ func (pah *PUTArtifactHandler) Exchange() (interface{}, int) {
 		return err, http.StatusNotAcceptable
 	}
 
-	err = pah.Inserter.Insert(sid, ba.Name, "", ba.Qualities)
+	// Recording an artifact decides what is deployed for sid, so it needs
+	// the same permission as building it.
+	if pah.Authorizer != nil && pah.State == nil {
+		return "Error loading state from storage", http.StatusInternalServerError
+	}
+	if err := pah.Authorizer.AuthorizeBuild(pah.User, pah.State, sid.Location); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+
+	if si, ok := pah.Inserter.(sous.SBOMInserter); ok && ba.SBOM != nil {
+		err = si.InsertWithSBOM(sid, ba.Name, "", ba.Qualities, ba.SBOM)
+	} else {
+		err = pah.Inserter.Insert(sid, ba.Name, "", ba.Qualities)
+	}
 	if err != nil {
 		return err, http.StatusNotAcceptable
 	}
@@ -61,6 +100,30 @@ func (pah *PUTArtifactHandler) Exchange() (interface{}, int) {
 	return "", http.StatusOK
 }
 
+// Exchange implements Exchanger on GETArtifactHandler. The artifact returned
+// includes its SBOM, if the registry has one.
+func (gah *GETArtifactHandler) Exchange() (interface{}, int) {
+	if gah.Registry == nil {
+		return "No registry available.", http.StatusNotFound
+	}
+	sid, err := sourceIDFromValues(gah.QueryValues)
+	if err != nil {
+		return err, http.StatusNotAcceptable
+	}
+
+	ba, err := gah.Registry.GetArtifact(sid)
+	if err != nil {
+		return err, http.StatusNotFound
+	}
+	if sr, ok := gah.Registry.(sous.SBOMRegistry); ok {
+		ba.SBOM, err = sr.GetSBOM(sid)
+		if err != nil {
+			return err, http.StatusInternalServerError
+		}
+	}
+	return ba, http.StatusOK
+}
+
 func sourceIDFromValues(qv restful.QueryValues) (sous.SourceID, error) {
 	var r, o, vs string
 	var v semv.Version
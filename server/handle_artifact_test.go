@@ -7,6 +7,9 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/pkg/errors"
+
+	"github.com/opentable/sous/config"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/restful"
 )
@@ -60,3 +63,93 @@ func TestPUTArtifact(t *testing.T) {
 		t.Errorf("inserted artifact name was %s, should be test.reg.com/repo/test", inName)
 	}
 }
+
+func TestPUTArtifact_Forbidden(t *testing.T) {
+	state := sous.NewState()
+	m := authTestManifest("sam")
+	m.Source = sous.SourceLocation{Repo: "github.com/opentable/test"}
+	state.Manifests.Add(m)
+
+	put := func(user string) (interface{}, int, bool) {
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(sous.NewBuildArtifact("test.reg.com/repo/test", sous.Strpairs{}))
+		req, err := http.NewRequest("PUT", "", buf)
+		if err != nil {
+			t.Fatal("error building request", err)
+		}
+		q, err := url.ParseQuery("repo=github.com/opentable/test&offset=&version=1.2.3")
+		if err != nil {
+			t.Fatal("error parsing query", err)
+		}
+		inserted := false
+		pah := &PUTArtifactHandler{
+			Request:     req,
+			QueryValues: restful.QueryValues{Values: q},
+			Inserter: &artifactTestInserter{
+				insFunc: func(sous.SourceID, string, string, []sous.Quality) error {
+					inserted = true
+					return nil
+				},
+			},
+			User:       ClientUser{Name: user},
+			Authorizer: NewAuthorizer(config.AuthConfig{Authorize: true}),
+			State:      state,
+		}
+		body, status := pah.Exchange()
+		return body, status, inserted
+	}
+
+	if body, status, inserted := put("mallory"); status != 403 || inserted {
+		t.Errorf("status should be 403 with nothing inserted, was %d (%v), inserted: %t", status, body, inserted)
+	}
+	if body, status, inserted := put("sam"); status != 200 || !inserted {
+		t.Errorf("status should be 200 with the artifact inserted, was %d (%v), inserted: %t", status, body, inserted)
+	}
+}
+
+type artifactTestRegistry struct {
+	sous.Registry
+	artifact *sous.BuildArtifact
+	sbom     *sous.SBOM
+}
+
+func (atr *artifactTestRegistry) GetArtifact(sid sous.SourceID) (*sous.BuildArtifact, error) {
+	if atr.artifact == nil {
+		return nil, errors.Errorf("no artifact for %s", sid)
+	}
+	return atr.artifact, nil
+}
+
+func (atr *artifactTestRegistry) GetSBOM(sid sous.SourceID) (*sous.SBOM, error) {
+	return atr.sbom, nil
+}
+
+func TestGETArtifact(t *testing.T) {
+	q, err := url.ParseQuery("repo=github.com/opentable/test&offset=&version=1.2.3")
+	if err != nil {
+		t.Fatal("error parsing query", err)
+	}
+	sbom := &sous.SBOM{Repo: "github.com/opentable/test", Packages: []sous.SBOMPackage{{Name: "openssl"}}}
+	reg := &artifactTestRegistry{sbom: sbom}
+	gah := &GETArtifactHandler{
+		QueryValues: restful.QueryValues{Values: q},
+		Registry:    reg,
+	}
+
+	if _, status := gah.Exchange(); status != 404 {
+		t.Errorf("status should be 404 for a missing artifact, was %d", status)
+	}
+
+	reg.artifact = sous.NewBuildArtifact("test.reg.com/repo/test", sous.Strpairs{})
+	body, status := gah.Exchange()
+	if status != 200 {
+		t.Fatalf("status should be 200, was %d", status)
+	}
+	ba, ok := body.(*sous.BuildArtifact)
+	if !ok {
+		t.Fatalf("body should be a *sous.BuildArtifact, was %T", body)
+	}
+	if ba.SBOM != sbom {
+		t.Errorf("artifact SBOM was %v, should be %v", ba.SBOM, sbom)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// BuildResource provides the /build endpoint, which builds source on the
+	// server, for clients without Docker of their own.
+	BuildResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// PUTBuildHandler handles PUT requests to /build, which queue a
+	// sous.BuildRequest to be built.
+	PUTBuildHandler struct {
+		req            *http.Request
+		responseWriter http.ResponseWriter
+		routeMap       *restful.RouteMap
+		BuildQueue     *sous.BuildQueue
+		User           ClientUser
+		Authorizer     *Authorizer
+		State          *sous.State
+		// RepoHosts are the hosts whose repos may be built.
+		RepoHosts []string
+		log       logging.LogSink
+	}
+
+	// GETBuildHandler handles GET requests to /build, which report the
+	// progress of a build, and its output since the line given by "since".
+	GETBuildHandler struct {
+		restful.QueryValues
+		BuildQueue *sous.BuildQueue
+	}
+)
+
+func newBuildResource(ctx ComponentLocator) *BuildResource {
+	return &BuildResource{context: ctx}
+}
+
+// Put returns a configured PUTBuildHandler.
+func (r *BuildResource) Put(rm *restful.RouteMap, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	var hosts []string
+	if r.context.Config != nil {
+		hosts = r.context.Config.BuildRepoHosts
+	}
+	return &PUTBuildHandler{
+		req:            req,
+		responseWriter: rw,
+		routeMap:       rm,
+		BuildQueue:     r.context.BuildQueue,
+		User:           user,
+		Authorizer:     r.context.Authorizer,
+		State:          r.context.liveState(),
+		RepoHosts:      hosts,
+		log:            r.context.LogSink,
+	}
+}
+
+// Get returns a configured GETBuildHandler.
+func (r *BuildResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	if _, err := r.context.authenticate(req); err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &GETBuildHandler{
+		QueryValues: r.ParseQuery(req),
+		BuildQueue:  r.context.BuildQueue,
+	}
+}
+
+// Exchange queues the sous.BuildRequest in the request body, and returns the
+// queued sous.BuildJob, with its location in the Location header.
+func (h *PUTBuildHandler) Exchange() (interface{}, int) {
+	if h.BuildQueue == nil {
+		return "Remote builds are not enabled on this server.", http.StatusNotFound
+	}
+	var br sous.BuildRequest
+	if err := json.NewDecoder(h.req.Body).Decode(&br); err != nil {
+		return "Error parsing body: " + err.Error(), http.StatusBadRequest
+	}
+	if br.SourceID.Location.Repo == "" || br.SourceID.RevID() == "" {
+		return "A build needs a repo and a revision.", http.StatusBadRequest
+	}
+	if err := br.Validate(h.RepoHosts); err != nil {
+		return "Invalid build: " + err.Error(), http.StatusBadRequest
+	}
+	if err := h.Authorizer.AuthorizeBuild(h.User, h.State, br.SourceID.Location); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+
+	job, ok := h.BuildQueue.Push(br)
+	if !ok {
+		return "Build queue full, please try again later.", http.StatusConflict
+	}
+	messages.ReportLogFieldsMessage("Queued remote build", logging.InformationLevel, h.log, job.ID, br.SourceID, sous.User(h.User))
+
+	loc, err := h.routeMap.FullURIFor(h.req.Host, "build", nil, restful.KV{"id", string(job.ID)})
+	if err != nil {
+		return "Determining build URL: " + err.Error(), http.StatusInternalServerError
+	}
+	h.responseWriter.Header().Add("Location", loc)
+	return job, http.StatusCreated
+}
+
+// Exchange returns the sous.BuildJob with the ID given by "id".
+func (h *GETBuildHandler) Exchange() (interface{}, int) {
+	if h.BuildQueue == nil {
+		return "Remote builds are not enabled on this server.", http.StatusNotFound
+	}
+	// A missing ID is not found, rather than a bad request, so that PUT's
+	// check for an existing resource passes.
+	id, err := h.Single("id", "")
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	since := 0
+	if s, err := h.Single("since", "0"); err != nil {
+		return err, http.StatusBadRequest
+	} else if since, err = strconv.Atoi(s); err != nil {
+		return err, http.StatusBadRequest
+	}
+	job, ok := h.BuildQueue.Job(sous.BuildJobID(id), since)
+	if !ok {
+		return "No build with ID " + id + ".", http.StatusNotFound
+	}
+	return job, http.StatusOK
+}
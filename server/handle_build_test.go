@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildResource_RemoteBuilder(t *testing.T) {
+	var built []sous.BuildRequest
+	bq := sous.NewBuildQueue(1, 1, func(req sous.BuildRequest, out io.Writer) (*sous.BuildResult, error) {
+		built = append(built, req)
+		fmt.Fprintln(out, "Step 1/2 : FROM scratch")
+		fmt.Fprintln(out, "Successfully built")
+		if req.Strict {
+			return nil, fmt.Errorf("strict build encountered advisories")
+		}
+		return &sous.BuildResult{Products: []*sous.BuildProduct{{Source: req.SourceID, Kind: "app"}}}, nil
+	})
+	c := ComponentLocator{
+		LogSink:      logging.SilentLogSet(),
+		Config:       &config.Config{BuildRepoHosts: []string{"github.com"}},
+		StateManager: sous.NewDummyStateManager(),
+		BuildQueue:   bq,
+	}
+	client, err := restful.NewInMemoryClient(Handler(c, http.NotFoundHandler(), c.LogSink), c.LogSink)
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	rb := &sous.RemoteBuilder{Client: client, Out: out, PollInterval: 1}
+	sid := sous.MustNewSourceID("github.com/opentable/project", "", "1.2.3+abc1234")
+
+	result, err := rb.Build(sous.BuildRequest{SourceID: sid, Platforms: []string{"linux/arm64"}})
+	require.NoError(t, err)
+	require.Len(t, result.Products, 1)
+	assert.True(t, result.Products[0].Source.Equal(sid))
+	assert.Equal(t, "Step 1/2 : FROM scratch\nSuccessfully built\n", out.String())
+	require.Len(t, built, 1)
+	assert.Equal(t, "abc1234", built[0].SourceID.RevID())
+	assert.Equal(t, []string{"linux/arm64"}, built[0].Platforms)
+
+	_, err = rb.Build(sous.BuildRequest{SourceID: sid, Strict: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "strict build encountered advisories")
+}
+
+func TestPUTBuildHandler_Refused(t *testing.T) {
+	state := sous.NewState()
+	m := authTestManifest("sam")
+	m.Source = sous.SourceLocation{Repo: "github.com/opentable/project"}
+	state.Manifests.Add(m)
+	exchange := func(user string, sid sous.SourceID) (interface{}, int) {
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(sous.BuildRequest{SourceID: sid})
+		req, err := http.NewRequest("PUT", "http://sous.example.com/build", buf)
+		require.NoError(t, err)
+		h := &PUTBuildHandler{
+			req:            req,
+			responseWriter: httptest.NewRecorder(),
+			routeMap:       routemap(ComponentLocator{}),
+			BuildQueue:     sous.NewBuildQueue(1, 1, func(sous.BuildRequest, io.Writer) (*sous.BuildResult, error) { return nil, nil }),
+			User:           ClientUser{Name: user},
+			Authorizer:     NewAuthorizer(config.AuthConfig{Authorize: true, ClusterDeployers: map[string][]string{"prod": {"sam"}}}),
+			State:          state,
+			RepoHosts:      []string{"github.com"},
+			log:            logging.SilentLogSet(),
+		}
+		return h.Exchange()
+	}
+
+	_, status := exchange("sam", sous.MustNewSourceID("github.com/opentable/project", "", "1.2.3+abc1234"))
+	assert.Equal(t, http.StatusCreated, status)
+
+	for _, sid := range []sous.SourceID{
+		sous.MustNewSourceID("169.254.169.254/latest", "", "1.2.3+abc1234"),
+		sous.MustNewSourceID("github.com/opentable/project", "../..", "1.2.3+abc1234"),
+		sous.MustNewSourceID("github.com/opentable/project", "", "1.2.3+--orphan"),
+	} {
+		data, status := exchange("sam", sid)
+		assert.Equal(t, http.StatusBadRequest, status, "%v", data)
+	}
+
+	data, status := exchange("mallory", sous.MustNewSourceID("github.com/opentable/project", "", "1.2.3+abc1234"))
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, data, "is not an owner")
+
+	data, status = exchange("sam", sous.MustNewSourceID("github.com/opentable/other", "", "1.2.3+abc1234"))
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, data, "has no manifest")
+}
+
+func TestBuildResource_Disabled(t *testing.T) {
+	c := ComponentLocator{LogSink: logging.SilentLogSet()}
+	_, status := newBuildResource(c).Get(routemap(c), nil, makeRequestWithQuery(t, "id=abc"), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestBuildResource_Get(t *testing.T) {
+	c := ComponentLocator{LogSink: logging.SilentLogSet(), BuildQueue: sous.NewBuildQueue(1, 1, nil)}
+	br := newBuildResource(c)
+
+	_, status := br.Get(routemap(c), nil, makeRequestWithQuery(t, ""), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+
+	_, status = br.Get(routemap(c), nil, makeRequestWithQuery(t, "id=abc&since=x"), nil).Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	_, status = br.Get(routemap(c), nil, makeRequestWithQuery(t, "id=abc"), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -11,12 +11,13 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
 )
 
 type (
 	// GDMResource is the resource for the GDM
 	GDMResource struct {
-		userExtractor
+		restful.QueryParser
 		context ComponentLocator
 	}
 
@@ -24,6 +25,10 @@ type (
 	GETGDMHandler struct {
 		logging.LogSink
 		GDM *sous.State
+		// At names a snapshot, by ID or time, to return the GDM as it was in,
+		// rather than the live GDM.
+		At          string
+		Snapshotter *sous.Snapshotter
 	}
 
 	// PUTGDMHandler is an injectable request handler
@@ -33,6 +38,7 @@ type (
 		GDM          *sous.State
 		StateManager sous.StateManager
 		User         ClientUser
+		Authorizer   *Authorizer
 	}
 )
 
@@ -41,7 +47,14 @@ func newGDMResource(ctx ComponentLocator) *GDMResource {
 }
 
 // Get implements Getable on GDMResource
-func (gr *GDMResource) Get(_ *restful.RouteMap, writer http.ResponseWriter, _ *http.Request, _ httprouter.Params) restful.Exchanger {
+func (gr *GDMResource) Get(_ *restful.RouteMap, writer http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	if at, err := gr.ParseQuery(req).Single("at", ""); err == nil && at != "" {
+		return &GETGDMHandler{
+			LogSink:     gr.context.LogSink,
+			At:          at,
+			Snapshotter: gr.context.Snapshotter,
+		}
+	}
 	return &GETGDMHandler{
 		LogSink: gr.context.LogSink,
 		GDM:     gr.context.liveState(),
@@ -50,6 +63,13 @@ func (gr *GDMResource) Get(_ *restful.RouteMap, writer http.ResponseWriter, _ *h
 
 // Exchange implements the Handler interface
 func (h *GETGDMHandler) Exchange() (interface{}, int) {
+	if h.At != "" {
+		found, status := findSnapshot(h.Snapshotter, h.At)
+		if status != http.StatusOK {
+			return found, status
+		}
+		h.GDM = found.(*sous.StateSnapshot).State()
+	}
 	reportDebugHandleGDMMessage(fmt.Sprintf("Get GDM Handler Exchange with GDM: %v", h.GDM), nil, nil, h.LogSink)
 
 	data := dto.GDMWrapper{Deployments: make([]*sous.Deployment, 0)}
@@ -74,12 +94,17 @@ func (h *GETGDMHandler) Exchange() (interface{}, int) {
 
 // Put implements Putable on GDMResource
 func (gr *GDMResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := gr.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &PUTGDMHandler{
 		Request:      req,
 		LogSink:      gr.context.LogSink,
 		GDM:          gr.context.liveState(),
 		StateManager: gr.context.StateManager,
-		User:         gr.GetUser(req),
+		User:         user,
+		Authorizer:   gr.context.Authorizer,
 	}
 }
 
@@ -99,6 +124,7 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusInternalServerError
 	}
 
+	prior := state.Manifests.Clone()
 	state.Manifests, err = deps.PutbackManifests(state.Defs, state.Manifests)
 	if err != nil {
 		msg := "Error getting state"
@@ -106,6 +132,11 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusConflict
 	}
 
+	if err := h.Authorizer.AuthorizeManifests(h.User, prior, state.Manifests); err != nil {
+		reportHandleGDMMessage("Forbidden", nil, err, h.LogSink)
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+
 	flaws := state.Validate()
 	if len(flaws) > 0 {
 		msg := "Invalid GDM"
@@ -118,6 +149,14 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 	}
 
 	if err := h.StateManager.WriteState(state, sous.User(h.User)); err != nil {
+		if conflicts, is := errors.Cause(err).(sous.MergeConflicts); is {
+			reportHandleGDMMessage("Conflicting changes to state", flaws, err, h.LogSink)
+			return conflicts.Error(), http.StatusConflict
+		}
+		if stale, is := errors.Cause(err).(*sous.StaleStateError); is {
+			reportHandleGDMMessage("Stale state", flaws, err, h.LogSink)
+			return stale.Error(), http.StatusConflict
+		}
 		msg := "Error committing state"
 		reportHandleGDMMessage(msg, flaws, err, h.LogSink)
 		return msg, http.StatusInternalServerError
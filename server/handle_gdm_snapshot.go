@@ -0,0 +1,245 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// GDMSnapshotsResource provides the /gdm/snapshots endpoint, which lists
+	// the snapshots taken of the GDM.
+	GDMSnapshotsResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETGDMSnapshotsHandler handles GET requests to /gdm/snapshots.
+	GETGDMSnapshotsHandler struct {
+		restful.QueryValues
+		Snapshotter *sous.Snapshotter
+	}
+
+	// GDMSnapshotResource provides the /gdm/snapshot endpoint, which takes
+	// snapshots of the GDM, and returns them.
+	GDMSnapshotResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETGDMSnapshotHandler handles GET requests to /gdm/snapshot, which
+	// return the snapshot named by "at", either its ID or a time.
+	GETGDMSnapshotHandler struct {
+		restful.QueryValues
+		Snapshotter *sous.Snapshotter
+	}
+
+	// PUTGDMSnapshotHandler handles PUT requests to /gdm/snapshot, which take
+	// a snapshot of the GDM now.
+	PUTGDMSnapshotHandler struct {
+		req            *http.Request
+		responseWriter http.ResponseWriter
+		routeMap       *restful.RouteMap
+		Snapshotter    *sous.Snapshotter
+		User           ClientUser
+		log            logging.LogSink
+	}
+
+	// GDMRestoreResource provides the /gdm/restore endpoint, which restores
+	// the GDM from a snapshot.
+	GDMRestoreResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// PUTGDMRestoreHandler handles PUT requests to /gdm/restore, which write
+	// the state in the snapshot named by "at" back to the GDM.
+	PUTGDMRestoreHandler struct {
+		restful.QueryValues
+		req            *http.Request
+		responseWriter http.ResponseWriter
+		routeMap       *restful.RouteMap
+		Snapshotter    *sous.Snapshotter
+		StateManager   sous.StateManager
+		User           ClientUser
+		Authorizer     *Authorizer
+		log            logging.LogSink
+	}
+)
+
+func newGDMSnapshotsResource(ctx ComponentLocator) *GDMSnapshotsResource {
+	return &GDMSnapshotsResource{context: ctx}
+}
+
+func newGDMSnapshotResource(ctx ComponentLocator) *GDMSnapshotResource {
+	return &GDMSnapshotResource{context: ctx}
+}
+
+func newGDMRestoreResource(ctx ComponentLocator) *GDMRestoreResource {
+	return &GDMRestoreResource{context: ctx}
+}
+
+// Get returns a configured GETGDMSnapshotsHandler.
+func (r *GDMSnapshotsResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETGDMSnapshotsHandler{
+		QueryValues: r.ParseQuery(req),
+		Snapshotter: r.context.Snapshotter,
+	}
+}
+
+// Exchange returns a dto.SnapshotsResponse, listing at most "limit"
+// snapshots, newest first.
+func (h *GETGDMSnapshotsHandler) Exchange() (interface{}, int) {
+	if h.Snapshotter == nil {
+		return "No snapshots available.", http.StatusNotFound
+	}
+	l, err := h.Single("limit", "0")
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	infos, err := h.Snapshotter.Store.Snapshots(limit)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return dto.SnapshotsResponse{Snapshots: infos}, http.StatusOK
+}
+
+// Get returns a configured GETGDMSnapshotHandler.
+func (r *GDMSnapshotResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETGDMSnapshotHandler{
+		QueryValues: r.ParseQuery(req),
+		Snapshotter: r.context.Snapshotter,
+	}
+}
+
+// Put returns a configured PUTGDMSnapshotHandler.
+func (r *GDMSnapshotResource) Put(rm *restful.RouteMap, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &PUTGDMSnapshotHandler{
+		req:            req,
+		responseWriter: rw,
+		routeMap:       rm,
+		Snapshotter:    r.context.Snapshotter,
+		User:           user,
+		log:            r.context.LogSink,
+	}
+}
+
+// Exchange returns the sous.StateSnapshot named by "at".
+func (h *GETGDMSnapshotHandler) Exchange() (interface{}, int) {
+	// A missing "at" is not found, rather than a bad request, so that PUT's
+	// check for an existing resource passes.
+	at, err := h.Single("at", "")
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	return findSnapshot(h.Snapshotter, at)
+}
+
+// findSnapshot returns the snapshot named by at, or an error and the status
+// to return it with.
+func findSnapshot(sn *sous.Snapshotter, at string) (interface{}, int) {
+	if sn == nil {
+		return "No snapshots available.", http.StatusNotFound
+	}
+	ss, found, err := sn.Find(at)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if !found {
+		return "No snapshot at " + at + ".", http.StatusNotFound
+	}
+	return ss, http.StatusOK
+}
+
+// Exchange takes a snapshot of the GDM, and returns it, with its location in
+// the Location header.
+func (h *PUTGDMSnapshotHandler) Exchange() (interface{}, int) {
+	if h.Snapshotter == nil {
+		return "No snapshots available.", http.StatusNotFound
+	}
+	ss, err := h.Snapshotter.Take(sous.SnapshotOnDemand, sous.User(h.User))
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	messages.ReportLogFieldsMessage("Took snapshot of GDM", logging.InformationLevel, h.log, ss.SnapshotInfo)
+	return created(h.routeMap, h.req, h.responseWriter, ss)
+}
+
+// created adds the location of ss to the response, and returns ss.
+func created(rm *restful.RouteMap, req *http.Request, rw http.ResponseWriter, ss *sous.StateSnapshot) (interface{}, int) {
+	loc, err := rm.FullURIFor(req.Host, "gdm-snapshot", nil, restful.KV{"at", ss.ID})
+	if err != nil {
+		return "Determining snapshot URL: " + err.Error(), http.StatusInternalServerError
+	}
+	rw.Header().Add("Location", loc)
+	return ss, http.StatusCreated
+}
+
+// Put returns a configured PUTGDMRestoreHandler.
+func (r *GDMRestoreResource) Put(rm *restful.RouteMap, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &PUTGDMRestoreHandler{
+		QueryValues:    r.ParseQuery(req),
+		req:            req,
+		responseWriter: rw,
+		routeMap:       rm,
+		Snapshotter:    r.context.Snapshotter,
+		StateManager:   r.context.StateManager,
+		User:           user,
+		Authorizer:     r.context.Authorizer,
+		log:            r.context.LogSink,
+	}
+}
+
+// Exchange restores the GDM from the snapshot named by "at", as the user
+// making the request, and returns the snapshot of the state it replaced, with
+// its location in the Location header.
+func (h *PUTGDMRestoreHandler) Exchange() (interface{}, int) {
+	at, err := h.Single("at")
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	found, status := findSnapshot(h.Snapshotter, at)
+	if status != http.StatusOK {
+		return found, status
+	}
+	ss := found.(*sous.StateSnapshot)
+
+	prior, err := h.StateManager.ReadState()
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if err := h.Authorizer.AuthorizeState(h.User, prior, ss.State()); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+	backup, err := h.Snapshotter.Restore(ss, h.StateManager, sous.User(h.User))
+	if conflicts, is := errors.Cause(err).(sous.MergeConflicts); is {
+		return conflicts.Error(), http.StatusConflict
+	}
+	if stale, is := errors.Cause(err).(*sous.StaleStateError); is {
+		return stale.Error(), http.StatusConflict
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	messages.ReportLogFieldsMessage("Restored GDM from snapshot", logging.InformationLevel, h.log, ss.SnapshotInfo, backup.SnapshotInfo)
+	return created(h.routeMap, h.req, h.responseWriter, backup)
+}
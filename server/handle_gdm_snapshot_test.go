@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGDMSnapshots(t *testing.T) {
+	sm := sous.NewDummyStateManager()
+	sm.State = sous.DefaultStateFixture()
+	sn := sous.NewSnapshotter(sous.NewMemorySnapshotStore(), sm, 0, logging.SilentLogSet())
+	c := ComponentLocator{LogSink: logging.SilentLogSet(), StateManager: sm, Snapshotter: sn}
+	client, err := restful.NewInMemoryClient(Handler(c, http.NotFoundHandler(), c.LogSink), c.LogSink)
+	require.NoError(t, err)
+	user := sous.User{Name: "Test User", Email: "test@example.com"}
+
+	res, err := client.Create("./gdm/snapshot", nil, nil, user.HTTPHeaders())
+	require.NoError(t, err)
+	loc, err := url.Parse(res.Location())
+	require.NoError(t, err)
+	id := loc.Query().Get("at")
+	require.NotEmpty(t, id)
+
+	snapshots := &dto.SnapshotsResponse{}
+	_, err = client.Retrieve("./gdm/snapshots", nil, snapshots, nil)
+	require.NoError(t, err)
+	require.Len(t, snapshots.Snapshots, 1)
+	assert.Equal(t, id, snapshots.Snapshots[0].ID)
+	assert.Equal(t, user, snapshots.Snapshots[0].User)
+
+	live, err := sm.State.Deployments()
+	require.NoError(t, err)
+	sm.State = sous.NewState()
+
+	gdm := &dto.GDMWrapper{}
+	_, err = client.Retrieve("./gdm", map[string]string{"at": id}, gdm, nil)
+	require.NoError(t, err)
+	assert.Len(t, gdm.Deployments, live.Len())
+
+	_, err = client.Retrieve("./gdm", map[string]string{"at": "no-such-snapshot"}, gdm, nil)
+	assert.Error(t, err)
+
+	res, err = client.Create("./gdm/restore", map[string]string{"at": id}, nil, user.HTTPHeaders())
+	require.NoError(t, err)
+	restored, err := sm.State.Deployments()
+	require.NoError(t, err)
+	assert.Equal(t, live.Len(), restored.Len())
+
+	loc, err = url.Parse(res.Location())
+	require.NoError(t, err)
+	backup := &sous.StateSnapshot{}
+	_, err = client.Retrieve("./gdm/snapshot", map[string]string{"at": loc.Query().Get("at")}, backup, nil)
+	require.NoError(t, err)
+	assert.Equal(t, sous.SnapshotBeforeRestore, backup.Reason)
+	assert.Empty(t, backup.Manifests)
+}
@@ -1,12 +1,15 @@
 package server
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/opentable/sous/dto"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandlesGDMGet(t *testing.T) {
@@ -62,3 +65,20 @@ func TestReturnFlawMsg(t *testing.T) {
 	assert.Contains(t, flawsMsg, "Missing resource")
 
 }
+
+func TestHandlesGDMPut_stale(t *testing.T) {
+	sm := sous.NewDummyStateManager()
+	sm.WriteErr = &sous.StaleStateError{Etag: "old"}
+	req, err := http.NewRequest("PUT", "/gdm", strings.NewReader(`{"Deployments": []}`))
+	require.NoError(t, err)
+	req.Header.Set("Etag", "old")
+
+	th := &PUTGDMHandler{
+		Request:      req,
+		LogSink:      logging.SilentLogSet(),
+		StateManager: sm,
+	}
+	data, status := th.Exchange()
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, data, `state was read at "old"`)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// GDMWatchResource provides the /gdm/watch endpoint, which long-polls for
+	// changes to the GDM.
+	GDMWatchResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// GETGDMWatchHandler handles GET requests to /gdm/watch. It waits up to
+	// "timeout" for a change to the GDM other than the one numbered "seq",
+	// and returns the latest sous.StateChange, which has the same Seq if
+	// there was none.
+	GETGDMWatchHandler struct {
+		restful.QueryValues
+		Changes *sous.StateChanges
+	}
+)
+
+// maxGDMWatchTimeout limits how long a request to /gdm/watch is held open.
+const maxGDMWatchTimeout = 5 * time.Minute
+
+func newGDMWatchResource(ctx ComponentLocator) *GDMWatchResource {
+	return &GDMWatchResource{context: ctx}
+}
+
+// Get returns a configured GETGDMWatchHandler.
+func (r *GDMWatchResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETGDMWatchHandler{
+		QueryValues: r.ParseQuery(req),
+		Changes:     r.context.StateChanges,
+	}
+}
+
+// Exchange waits for a change to the GDM and returns it.
+func (h *GETGDMWatchHandler) Exchange() (interface{}, int) {
+	if h.Changes == nil {
+		return "This server cannot watch the GDM.", http.StatusNotFound
+	}
+	latest := h.Changes.Latest()
+	seq := latest.Seq
+	if s, err := h.Single("seq", ""); err != nil {
+		return err, http.StatusBadRequest
+	} else if s != "" {
+		if seq, err = strconv.Atoi(s); err != nil {
+			return err, http.StatusBadRequest
+		}
+	}
+	timeout := sous.GDMWatchTimeout
+	if t, err := h.Single("timeout", ""); err != nil {
+		return err, http.StatusBadRequest
+	} else if t != "" {
+		if timeout, err = time.ParseDuration(t); err != nil {
+			return err, http.StatusBadRequest
+		}
+	}
+	if timeout > maxGDMWatchTimeout {
+		timeout = maxGDMWatchTimeout
+	}
+	change, _ := h.Changes.Next(seq, timeout)
+	return change, http.StatusOK
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGDMWatchResource_Get(t *testing.T) {
+	sc := sous.NewStateChanges(nil, logging.SilentLogSet())
+	c := ComponentLocator{LogSink: logging.SilentLogSet(), StateChanges: sc}
+	wr := newGDMWatchResource(c)
+
+	body, status := wr.Get(routemap(c), nil, makeRequestWithQuery(t, "timeout=1ms"), nil).Exchange()
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 0, body.(sous.StateChange).Seq)
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		sc.Changed()
+	}()
+	body, status = wr.Get(routemap(c), nil, makeRequestWithQuery(t, "seq=0&timeout=1s"), nil).Exchange()
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, body.(sous.StateChange).Seq)
+
+	_, status = wr.Get(routemap(c), nil, makeRequestWithQuery(t, "seq=x"), nil).Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	c.StateChanges = nil
+	_, status = newGDMWatchResource(c).Get(routemap(c), nil, makeRequestWithQuery(t, ""), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestHTTPStateManagerWatch(t *testing.T) {
+	sc := sous.NewStateChanges(nil, logging.SilentLogSet())
+	c := ComponentLocator{LogSink: logging.SilentLogSet(), StateChanges: sc}
+	client, err := restful.NewInMemoryClient(Handler(c, http.NotFoundHandler(), c.LogSink), c.LogSink)
+	require.NoError(t, err)
+	hsm := sous.NewHTTPStateManager(client, nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	changes, err := hsm.Watch(done)
+	require.NoError(t, err)
+
+	sc.Changed()
+	select {
+	case change := <-changes:
+		assert.Equal(t, 1, change.Seq)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not reported")
+	}
+}
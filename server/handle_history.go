@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// HistoryResource describes resources for the history of GDM changes.
+	HistoryResource struct {
+		context ComponentLocator
+	}
+
+	// GETHistoryHandler handles GET exchanges for GDM history.
+	GETHistoryHandler struct {
+		History  sous.History
+		Filter   *sous.ResolveFilter
+		Limit    int
+		QueryErr error
+	}
+)
+
+func newHistoryResource(ctx ComponentLocator) *HistoryResource {
+	return &HistoryResource{context: ctx}
+}
+
+// Get returns a configured GETHistoryHandler.
+func (r *HistoryResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	qv := restful.QueryValues{Values: req.URL.Query()}
+	filter, err := resolveFilterFromValues(qv)
+	limit := 0
+	if err == nil {
+		var l string
+		l, err = qv.Single("limit", "0")
+		if err == nil {
+			limit, err = strconv.Atoi(l)
+		}
+	}
+	return &GETHistoryHandler{
+		History:  r.context.History,
+		Filter:   filter,
+		Limit:    limit,
+		QueryErr: err,
+	}
+}
+
+// Exchange returns a dto.HistoryResponse listing matching history entries.
+func (h *GETHistoryHandler) Exchange() (interface{}, int) {
+	if h.QueryErr != nil {
+		return h.QueryErr, http.StatusBadRequest
+	}
+	if h.History == nil {
+		return "No history available.", http.StatusNotFound
+	}
+	entries, err := h.History.Entries(h.Filter, h.Limit)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return dto.HistoryResponse{Entries: entries}, http.StatusOK
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryResource_Get(t *testing.T) {
+	h := sous.NewMemoryHistory()
+	c := ComponentLocator{History: h}
+	rm := routemap(c)
+	hr := newHistoryResource(c)
+
+	got := hr.Get(rm, nil, makeRequestWithQuery(t, "repo=github.com%2Fexample%2Fproject&offset=&limit=5"), nil).(*GETHistoryHandler)
+	require.NoError(t, got.QueryErr)
+	assert.Equal(t, h, got.History)
+	assert.Equal(t, 5, got.Limit)
+	assert.Equal(t, "github.com/example/project", got.Filter.Repo.ValueOr("*"))
+	assert.Equal(t, "", got.Filter.Offset.ValueOr("*"))
+	assert.True(t, got.Filter.Flavor.All())
+	assert.True(t, got.Filter.Cluster.All())
+
+	bad := hr.Get(rm, nil, makeRequestWithQuery(t, "limit=lots"), nil).(*GETHistoryHandler)
+	assert.Error(t, bad.QueryErr)
+	_, status := bad.Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestGETHistoryHandler_Exchange(t *testing.T) {
+	h := sous.NewMemoryHistory()
+	project := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}
+	other := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/other"}}
+	require.NoError(t, h.RecordChanges([]sous.HistoryEntry{
+		{ID: "project", ManifestID: project, Clusters: []string{"one"}},
+		{ID: "other", ManifestID: other, Clusters: []string{"one"}},
+	}))
+
+	handler := &GETHistoryHandler{
+		History: h,
+		Filter:  &sous.ResolveFilter{Repo: sous.NewResolveFieldMatcher("github.com/example/project")},
+	}
+	body, status := handler.Exchange()
+	require.Equal(t, http.StatusOK, status)
+	entries := body.(dto.HistoryResponse).Entries
+	require.Len(t, entries, 1)
+	assert.Equal(t, "project", entries[0].ID)
+}
@@ -15,7 +15,6 @@ import (
 type (
 	// ManifestResource describes resources for manifests
 	ManifestResource struct {
-		userExtractor
 		restful.QueryParser
 		context ComponentLocator
 	}
@@ -33,6 +32,7 @@ type (
 		*http.Request
 		restful.QueryValues
 		User        ClientUser
+		Authorizer  *Authorizer
 		StateWriter sous.StateWriter
 	}
 
@@ -40,6 +40,8 @@ type (
 	DELETEManifestHandler struct {
 		*sous.State
 		restful.QueryValues
+		User        ClientUser
+		Authorizer  *Authorizer
 		StateWriter sous.StateWriter
 	}
 )
@@ -58,21 +60,32 @@ func (mr *ManifestResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req
 
 // Put implements Putable for ManifestResource
 func (mr *ManifestResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := mr.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &PUTManifestHandler{
 		State:       mr.context.liveState(),
 		LogSink:     mr.context.LogSink,
 		Request:     req,
 		QueryValues: mr.ParseQuery(req),
-		User:        mr.GetUser(req),
+		User:        user,
+		Authorizer:  mr.context.Authorizer,
 		StateWriter: sous.StateWriter(mr.context.StateManager),
 	}
 }
 
 // Delete implements Deleteable for ManifestResource
 func (mr *ManifestResource) Delete(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := mr.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &DELETEManifestHandler{
 		State:       mr.context.liveState(),
 		QueryValues: mr.ParseQuery(req),
+		User:        user,
+		Authorizer:  mr.context.Authorizer,
 		StateWriter: sous.StateWriter(mr.context.StateManager),
 	}
 }
@@ -96,10 +109,13 @@ func (dmh *DELETEManifestHandler) Exchange() (interface{}, int) {
 	if err != nil {
 		return err, http.StatusNotFound
 	}
-	_, there := dmh.State.Manifests.Get(mid)
+	prior, there := dmh.State.Manifests.Get(mid)
 	if !there {
 		return nil, http.StatusNotFound
 	}
+	if err := dmh.Authorizer.AuthorizeManifest(dmh.User, prior, nil); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
 	dmh.State.Manifests.Remove(mid)
 
 	return nil, http.StatusNoContent
@@ -121,6 +137,10 @@ func (pmh *PUTManifestHandler) Exchange() (interface{}, int) {
 		messages.ReportLogFieldsMessageToConsole("Exchange contains flaws", logging.ExtraDebug1Level, pmh.LogSink, flaws)
 		return "Invalid manifest", http.StatusBadRequest
 	}
+	prior, _ := pmh.State.Manifests.Get(mid)
+	if err := pmh.Authorizer.AuthorizeManifest(pmh.User, prior, m); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
 	pmh.State.Manifests.Set(mid, m)
 	if err := pmh.StateWriter.WriteState(pmh.State, sous.User(pmh.User)); err != nil {
 		return errors.Wrapf(err, "state recording collision - retry"), http.StatusConflict
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// NotificationsResource describes resources for the delivery log of
+	// webhook notifications.
+	NotificationsResource struct {
+		context ComponentLocator
+	}
+
+	// GETNotificationsHandler handles GET exchanges for webhook deliveries.
+	GETNotificationsHandler struct {
+		Notifier *sous.Notifier
+	}
+)
+
+func newNotificationsResource(ctx ComponentLocator) *NotificationsResource {
+	return &NotificationsResource{context: ctx}
+}
+
+// Get returns a configured GETNotificationsHandler.
+func (r *NotificationsResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, _ *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETNotificationsHandler{Notifier: r.context.Notifier}
+}
+
+// Exchange returns a dto.NotificationsResponse listing recent deliveries.
+func (h *GETNotificationsHandler) Exchange() (interface{}, int) {
+	if h.Notifier == nil {
+		return "No notifier available.", http.StatusNotFound
+	}
+	return dto.NotificationsResponse{Deliveries: h.Notifier.Deliveries()}, http.StatusOK
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/firsterr"
+	"github.com/opentable/sous/util/restful"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// PromotionsResource describes resources for the status of promotion
+	// pipelines.
+	PromotionsResource struct {
+		context ComponentLocator
+	}
+
+	// GETPromotionsHandler handles GET exchanges for promotion pipelines.
+	GETPromotionsHandler struct {
+		GDM *sous.State
+	}
+
+	// PromotionPauseResource provides the /promotion-pause endpoint, which
+	// pauses and resumes promotion pipelines.
+	PromotionPauseResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// PUTPromotionPauseHandler handles PUT requests to /promotion-pause.
+	PUTPromotionPauseHandler struct {
+		restful.QueryValues
+		Promoter   *sous.Promoter
+		GDM        *sous.State
+		User       ClientUser
+		Authorizer *Authorizer
+	}
+
+	// PromotionApprovalResource provides the /promotion-approval endpoint,
+	// which approves pending promotions.
+	PromotionApprovalResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// PUTPromotionApprovalHandler handles PUT requests to /promotion-approval.
+	PUTPromotionApprovalHandler struct {
+		restful.QueryValues
+		Promoter   *sous.Promoter
+		User       ClientUser
+		Authorizer *Authorizer
+	}
+)
+
+func newPromotionsResource(ctx ComponentLocator) *PromotionsResource {
+	return &PromotionsResource{context: ctx}
+}
+
+// Get returns a configured GETPromotionsHandler.
+func (r *PromotionsResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, _ *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPromotionsHandler{
+		GDM: r.context.liveState(),
+	}
+}
+
+// Exchange returns a dto.PromotionsResponse with the status of each pipeline,
+// as recorded in the GDM.
+func (h *GETPromotionsHandler) Exchange() (interface{}, int) {
+	if h.GDM == nil {
+		return "Could not read the GDM.", http.StatusInternalServerError
+	}
+	return dto.PromotionsResponse{Promotions: h.GDM.Defs.Promotions.Statuses()}, http.StatusOK
+}
+
+func newPromotionPauseResource(ctx ComponentLocator) *PromotionPauseResource {
+	return &PromotionPauseResource{context: ctx}
+}
+
+// Put implements Putable on PromotionPauseResource.
+func (r *PromotionPauseResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &PUTPromotionPauseHandler{
+		QueryValues: r.ParseQuery(req),
+		Promoter:    r.context.Promoter,
+		GDM:         r.context.liveState(),
+		User:        user,
+		Authorizer:  r.context.Authorizer,
+	}
+}
+
+// Exchange pauses or resumes the pipeline named in the query.
+func (h *PUTPromotionPauseHandler) Exchange() (interface{}, int) {
+	if h.Promoter == nil {
+		return "No promoter running.", http.StatusNotFound
+	}
+	var pipeline, ps string
+	var paused bool
+	var err error
+	if err := firsterr.Returned(
+		func() error { pipeline, err = h.Single("pipeline"); return err },
+		func() error { ps, err = h.Single("paused", "true"); return err },
+		func() error { paused, err = strconv.ParseBool(ps); return err },
+	); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if h.GDM == nil {
+		return "Could not read the GDM.", http.StatusInternalServerError
+	}
+	promo, has := h.GDM.Defs.Promotions[pipeline]
+	if !has || promo == nil {
+		return fmt.Sprintf("No promotion pipeline %q.", pipeline), http.StatusNotFound
+	}
+	// Pausing or resuming a pipeline changes what is promoted into every
+	// stage but the first.
+	var targets []string
+	for i, stage := range promo.Stages {
+		if i > 0 {
+			targets = append(targets, stage.Cluster)
+		}
+	}
+	if err := h.Authorizer.AuthorizeClusters(h.User, targets...); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+	if err := h.Promoter.Pause(pipeline, paused, sous.User(h.User)); err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return "", http.StatusOK
+}
+
+func newPromotionApprovalResource(ctx ComponentLocator) *PromotionApprovalResource {
+	return &PromotionApprovalResource{context: ctx}
+}
+
+// Put implements Putable on PromotionApprovalResource.
+func (r *PromotionApprovalResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &PUTPromotionApprovalHandler{
+		QueryValues: r.ParseQuery(req),
+		Promoter:    r.context.Promoter,
+		User:        user,
+		Authorizer:  r.context.Authorizer,
+	}
+}
+
+// Exchange approves the pending promotion of the deployment named in the
+// query to its version.
+func (h *PUTPromotionApprovalHandler) Exchange() (interface{}, int) {
+	if h.Promoter == nil {
+		return "No promoter running.", http.StatusNotFound
+	}
+	var pipeline, vs string
+	var did sous.DeploymentID
+	var version semv.Version
+	var err error
+	if err := firsterr.Returned(
+		func() error { pipeline, err = h.Single("pipeline"); return err },
+		func() error { did, err = deploymentIDFromValues(h.QueryValues); return err },
+		func() error { vs, err = h.Single("version"); return err },
+		func() error { version, err = semv.Parse(vs); return err },
+	); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if err := h.Authorizer.AuthorizeClusters(h.User, did.Cluster); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+	if err := h.Promoter.Approve(pipeline, did, version, sous.User(h.User)); err != nil {
+		if _, pending := err.(*sous.NoPendingPromotionError); !pending {
+			return err, http.StatusInternalServerError
+		}
+		return err, http.StatusConflict
+	}
+	return "", http.StatusOK
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func promotionsTestLocator() (ComponentLocator, *sous.DummyStateManager) {
+	sm := sous.NewDummyStateManager()
+	sm.State.Defs.Clusters = sous.Clusters{"ci": {Name: "ci"}, "prod": {Name: "prod"}}
+	sm.State.Defs.Promotions = sous.Promotions{
+		"main": {Stages: []sous.PromotionStage{{Cluster: "ci"}, {Cluster: "prod"}}},
+	}
+	return ComponentLocator{
+		LogSink:      logging.SilentLogSet(),
+		StateManager: sm,
+		Promoter:     sous.NewPromoter(sm, logging.SilentLogSet()),
+	}, sm
+}
+
+func TestPromotionsResource_Get(t *testing.T) {
+	c, sm := promotionsTestLocator()
+	sm.State.Defs.Promotions["main"].Paused = true
+	sm.State.Defs.Promotions["main"].Pending = map[string]sous.PendingPromotion{
+		"prod": {Pipeline: "main", DeploymentID: sous.DeploymentID{Cluster: "prod"}},
+	}
+	rm := routemap(c)
+
+	body, status := newPromotionsResource(c).Get(rm, nil, makeRequestWithQuery(t, ""), nil).Exchange()
+	require.Equal(t, http.StatusOK, status)
+	promotions := body.(dto.PromotionsResponse).Promotions
+	require.Len(t, promotions, 1)
+	assert.Equal(t, "main", promotions[0].Name)
+	assert.True(t, promotions[0].Paused)
+	assert.Len(t, promotions[0].Pending, 1)
+
+	// Any server reports the pipelines, whether or not it promotes.
+	c.Promoter = nil
+	_, status = newPromotionsResource(c).Get(rm, nil, makeRequestWithQuery(t, ""), nil).Exchange()
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestPromotionPauseResource_Put(t *testing.T) {
+	c, sm := promotionsTestLocator()
+	rm := routemap(c)
+	pr := newPromotionPauseResource(c)
+
+	_, status := pr.Put(rm, nil, makeRequestWithQuery(t, "pipeline=main"), nil).Exchange()
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, sm.State.Defs.Promotions["main"].Paused)
+
+	_, status = pr.Put(rm, nil, makeRequestWithQuery(t, "pipeline=main&paused=false"), nil).Exchange()
+	require.Equal(t, http.StatusOK, status)
+	assert.False(t, sm.State.Defs.Promotions["main"].Paused)
+
+	_, status = pr.Put(rm, nil, makeRequestWithQuery(t, "paused=false"), nil).Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	_, status = pr.Put(rm, nil, makeRequestWithQuery(t, "pipeline=other"), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestPromotionResources_Authorize(t *testing.T) {
+	c, sm := promotionsTestLocator()
+	c.Authorizer = NewAuthorizer(config.AuthConfig{
+		Authorize:        true,
+		ClusterDeployers: map[string][]string{"prod": {"sam"}},
+	})
+	rm := routemap(c)
+	as := func(name, query string) *http.Request {
+		req := makeRequestWithQuery(t, query)
+		req.Header = http.Header{"Sous-User-Name": {name}}
+		return req
+	}
+
+	pr := newPromotionPauseResource(c)
+	_, status := pr.Put(rm, nil, as("", "pipeline=main"), nil).Exchange()
+	assert.Equal(t, http.StatusForbidden, status)
+	_, status = pr.Put(rm, nil, as("mallory", "pipeline=main"), nil).Exchange()
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.False(t, sm.State.Defs.Promotions["main"].Paused)
+	_, status = pr.Put(rm, nil, as("sam", "pipeline=main"), nil).Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, sm.State.Defs.Promotions["main"].Paused)
+
+	ar := newPromotionApprovalResource(c)
+	_, status = ar.Put(rm, nil, as("mallory", "pipeline=main&repo=github.com%2Fexample%2Fproject&cluster=prod&version=1.2.3"), nil).Exchange()
+	assert.Equal(t, http.StatusForbidden, status)
+	_, status = ar.Put(rm, nil, as("sam", "pipeline=main&repo=github.com%2Fexample%2Fproject&cluster=prod&version=1.2.3"), nil).Exchange()
+	assert.Equal(t, http.StatusConflict, status, "nothing pending approval")
+}
+
+func TestPromotionApprovalResource_Put(t *testing.T) {
+	c, sm := promotionsTestLocator()
+	rm := routemap(c)
+	ar := newPromotionApprovalResource(c)
+
+	_, status := ar.Put(rm, nil, makeRequestWithQuery(t, "pipeline=main&repo=github.com%2Fexample%2Fproject&cluster=prod&version=1.2.3"), nil).Exchange()
+	assert.Equal(t, http.StatusConflict, status, "nothing pending approval")
+
+	did := sous.DeploymentID{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/project"}}, Cluster: "prod"}
+	sm.State.Defs.Promotions["main"].Pending = map[string]sous.PendingPromotion{
+		did.String(): {Pipeline: "main", DeploymentID: did, To: semv.MustParse("1.2.3")},
+	}
+	_, status = ar.Put(rm, nil, makeRequestWithQuery(t, "pipeline=main&repo=github.com%2Fexample%2Fproject&cluster=prod&version=1.2.3"), nil).Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, sm.State.Defs.Promotions["main"].Pending[did.String()].Approved)
+
+	_, status = ar.Put(rm, nil, makeRequestWithQuery(t, "pipeline=main&repo=github.com%2Fexample%2Fproject&cluster=prod&version=latest"), nil).Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -25,8 +25,10 @@ type (
 	// ServerListUpdater handles PUT for /servers
 	ServerListUpdater struct {
 		*http.Request
-		Config *config.Config
-		Log    logging.LogSink
+		Config     *config.Config
+		Log        logging.LogSink
+		User       ClientUser
+		Authorizer *Authorizer
 	}
 )
 
@@ -43,10 +45,16 @@ func (slr *ServerListResource) Get(*restful.RouteMap, http.ResponseWriter, *http
 
 // Put implements Putable on ServerListResource, which marks is as accepting PUT requests
 func (slr *ServerListResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := slr.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &ServerListUpdater{
-		Config:  slr.context.Config,
-		Log:     slr.context.LogSink,
-		Request: req,
+		Config:     slr.context.Config,
+		Log:        slr.context.LogSink,
+		Request:    req,
+		User:       user,
+		Authorizer: slr.context.Authorizer,
 	}
 }
 
@@ -61,6 +69,10 @@ func (slh *ServerListHandler) Exchange() (interface{}, int) {
 
 // Exchange implements restful.Exchanger on ServerListUpdater
 func (slh *ServerListUpdater) Exchange() (interface{}, int) {
+	if err := slh.Authorizer.AuthorizeAdmin(slh.User, "change the server list"); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+
 	dec := json.NewDecoder(slh.Request.Body)
 	data := ServerListData{Servers: []NameData{}}
 	dec.Decode(&data)
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleServerList_Get(t *testing.T) {
@@ -34,3 +38,29 @@ func TestHandleServerList_Get(t *testing.T) {
 	assert.Equal(list.Servers[0].ClusterName, "left")
 	assert.Equal(list.Servers[1].ClusterName, "right")
 }
+
+func TestHandleServerList_PutAdminOnly(t *testing.T) {
+	put := func(user string) (*config.Config, int) {
+		body := strings.NewReader(`{"Servers": [{"ClusterName": "left", "URL": "https://evil.example.com"}]}`)
+		req, err := http.NewRequest("PUT", "/servers", body)
+		require.NoError(t, err)
+		cfg := &config.Config{SiblingURLs: map[string]string{"left": "https://left.sous.com"}}
+		h := &ServerListUpdater{
+			Request:    req,
+			Config:     cfg,
+			Log:        logging.SilentLogSet(),
+			User:       ClientUser{Name: user},
+			Authorizer: NewAuthorizer(config.AuthConfig{Authorize: true, Admins: []string{"root"}}),
+		}
+		_, status := h.Exchange()
+		return cfg, status
+	}
+
+	cfg, status := put("mallory")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "https://left.sous.com", cfg.SiblingURLs["left"])
+
+	cfg, status = put("root")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "https://evil.example.com", cfg.SiblingURLs["left"])
+}
@@ -27,6 +27,8 @@ type (
 		QueueSet    sous.QueueSet
 		routeMap    *restful.RouteMap
 		StateWriter sous.StateWriter
+		User        ClientUser
+		Authorizer  *Authorizer
 	}
 
 	// GETSingleDeploymentHandler retrieves manifests containing single deployment
@@ -38,7 +40,6 @@ type (
 	// SingleDeploymentHandler contains common data and methods to both
 	// the GET and PUT handlers.
 	SingleDeploymentHandler struct {
-		userExtractor
 		Body           SingleDeploymentBody
 		req            *http.Request
 		responseWriter http.ResponseWriter
@@ -74,6 +75,10 @@ func (sdh *SingleDeploymentHandler) depID() (sous.DeploymentID, error) {
 
 // Put returns a configured put single deployment handler.
 func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := sdr.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	gdm := sdr.context.liveState()
 	sdh := sdr.newSingleDeploymentHandler(req, rw, gdm)
 	return &PUTSingleDeploymentHandler{
@@ -81,6 +86,8 @@ func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, rw http.ResponseW
 		QueueSet:                sdr.context.QueueSet,
 		routeMap:                rm,
 		StateWriter:             sdr.context.StateManager,
+		User:                    user,
+		Authorizer:              sdr.context.Authorizer,
 	}
 }
 
@@ -168,11 +175,15 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.ok(200, nil)
 	}
 
-	m.Deployments[did.Cluster] = *psd.Body.Deployment
+	next := m.Clone()
+	next.Deployments[did.Cluster] = *psd.Body.Deployment
+	if err := psd.Authorizer.AuthorizeManifest(psd.User, m, next); err != nil {
+		return psd.err(403, "Forbidden: %s.", err)
+	}
 
-	user := sous.User(psd.GetUser(psd.req))
+	m.Deployments[did.Cluster] = *psd.Body.Deployment
 
-	if err := psd.StateWriter.WriteState(psd.GDM, user); err != nil {
+	if err := psd.StateWriter.WriteState(psd.GDM, sous.User(psd.User)); err != nil {
 		return psd.err(500, "Failed to write state: %s.", err)
 	}
 
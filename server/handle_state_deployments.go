@@ -8,13 +8,13 @@ import (
 	"github.com/opentable/sous/dto"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
 )
 
 type (
 	// A StateDeploymentResource provides for the /state/deployments resource family
 	StateDeploymentResource struct {
 		loc ComponentLocator
-		userExtractor
 	}
 
 	// A GETStateDeployments is the exchanger for GET /state/deployments
@@ -29,6 +29,10 @@ type (
 		clusterName string
 		req         *http.Request
 		User        ClientUser
+		// State is read to check that User may make the changes to the
+		// manifests which replacing the cluster's deployments would.
+		State      sous.StateReader
+		Authorizer *Authorizer
 	}
 )
 
@@ -46,11 +50,17 @@ func (res *StateDeploymentResource) Get(*restful.RouteMap, http.ResponseWriter,
 
 // Put implements restful.Putable on StateDeployments
 func (res *StateDeploymentResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := res.loc.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
 	return &PUTStateDeployments{
 		cluster:     res.loc.ClusterManager,
 		clusterName: res.loc.ResolveFilter.Cluster.ValueOr("no-cluster"),
 		req:         req,
-		User:        res.GetUser(req),
+		User:        user,
+		State:       res.loc.StateManager,
+		Authorizer:  res.loc.Authorizer,
 	}
 }
 
@@ -80,6 +90,13 @@ func (psd *PUTStateDeployments) Exchange() (interface{}, int) {
 
 	deps := sous.NewDeployments(data.Deployments...)
 
+	if status, err := psd.authorize(deps); err != nil {
+		if status == http.StatusForbidden {
+			return "Forbidden: " + err.Error(), status
+		}
+		return err, status
+	}
+
 	err = psd.cluster.WriteCluster(psd.clusterName, deps, sous.User(psd.User))
 	if err != nil {
 		return err, http.StatusInternalServerError
@@ -87,3 +104,46 @@ func (psd *PUTStateDeployments) Exchange() (interface{}, int) {
 
 	return nil, http.StatusAccepted
 }
+
+// authorize returns a status and an error if psd.User may not replace the
+// deployments of psd.clusterName with deps. The deployments of every other
+// cluster are put back into manifests along with deps, so that changes to
+// whole manifests, like their owners, are checked as well as those to the
+// cluster.
+func (psd *PUTStateDeployments) authorize(deps sous.Deployments) (int, error) {
+	if psd.Authorizer == nil {
+		return http.StatusOK, nil
+	}
+	if err := psd.Authorizer.AuthorizeClusters(psd.User, psd.clusterName); err != nil {
+		return http.StatusForbidden, err
+	}
+	for _, d := range deps.Snapshot() {
+		if d.ClusterName != psd.clusterName {
+			return http.StatusBadRequest, errors.Errorf("deployment %s is not in cluster %q", d.ID(), psd.clusterName)
+		}
+	}
+
+	state, err := psd.State.ReadState()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	all, err := state.Deployments()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	prior, err := all.PutbackManifests(state.Defs, state.Manifests)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	others := all.Filter(func(d *sous.Deployment) bool {
+		return d.ClusterName != psd.clusterName
+	})
+	next, err := others.Merge(deps).PutbackManifests(state.Defs, state.Manifests)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := psd.Authorizer.AuthorizeManifests(psd.User, prior, next); err != nil {
+		return http.StatusForbidden, err
+	}
+	return http.StatusOK, nil
+}
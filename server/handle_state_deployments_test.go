@@ -7,8 +7,11 @@ import (
 	"testing"
 
 	"github.com/nyarly/spies"
+	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/dto"
 	sous "github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetStateDeployments(t *testing.T) {
@@ -84,3 +87,60 @@ func TestPutStateDeployments(t *testing.T) {
 		t.Errorf("No calls to WriteCluster")
 	}
 }
+
+func TestPutStateDeployments_Authorized(t *testing.T) {
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{
+		"cluster-1": &sous.Cluster{Name: "cluster-1"},
+		"prod":      &sous.Cluster{Name: "prod"},
+	}
+	state.Manifests.Add(authTestManifest("sam"))
+
+	exchange := func(user string, edit func(*sous.Deployment)) (interface{}, int, *spies.Spy) {
+		all, err := state.Deployments()
+		require.NoError(t, err)
+		gdm := dto.GDMWrapper{}
+		for _, d := range all.Clone().Snapshot() {
+			if d.ClusterName == "prod" {
+				d := d.Clone()
+				edit(d)
+				gdm.Deployments = append(gdm.Deployments, d)
+			}
+		}
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(gdm)
+		req, err := http.NewRequest("PUT", "", buf)
+		require.NoError(t, err)
+
+		cm, ctrl := sous.NewClusterManagerSpy()
+		ctrl.MatchMethod("WriteCluster", spies.AnyArgs, nil)
+		ex := &PUTStateDeployments{
+			cluster:     cm,
+			clusterName: "prod",
+			req:         req,
+			User:        ClientUser{Name: user},
+			State:       &sous.DummyStateManager{State: state},
+			Authorizer:  NewAuthorizer(config.AuthConfig{Authorize: true, ClusterDeployers: map[string][]string{"prod": {"sam", "dana"}}}),
+		}
+		data, status := ex.Exchange()
+		return data, status, ctrl
+	}
+	scale := func(d *sous.Deployment) { d.NumInstances = 3 }
+
+	_, status, ctrl := exchange("sam", scale)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Len(t, ctrl.CallsTo("WriteCluster"), 1)
+
+	data, status, ctrl := exchange("mallory", scale)
+	assert.Equal(t, http.StatusForbidden, status, "%v", data)
+	assert.Len(t, ctrl.CallsTo("WriteCluster"), 0)
+
+	data, status, ctrl = exchange("dana", scale)
+	assert.Equal(t, http.StatusForbidden, status, "%v", data)
+	assert.Contains(t, data, "is not an owner")
+	assert.Len(t, ctrl.CallsTo("WriteCluster"), 0)
+
+	data, status, ctrl = exchange("sam", func(d *sous.Deployment) { d.ClusterName = "cluster-1" })
+	assert.Equal(t, http.StatusBadRequest, status, "%v", data)
+	assert.Len(t, ctrl.CallsTo("WriteCluster"), 0)
+}
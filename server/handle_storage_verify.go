@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/ext/storage"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// StorageVerifyResource provides the /storage/verify endpoint, which
+	// compares the secondary storage of the GDM with the primary.
+	StorageVerifyResource struct {
+		context ComponentLocator
+	}
+
+	// GETStorageVerifyHandler handles GET requests to /storage/verify.
+	GETStorageVerifyHandler struct {
+		Verifier *storage.StorageVerifier
+	}
+
+	// StorageRepairResource provides the /storage/repair endpoint, which
+	// rewrites the secondary storage of the GDM from the primary.
+	StorageRepairResource struct {
+		context ComponentLocator
+	}
+
+	// PUTStorageRepairHandler handles PUT requests to /storage/repair.
+	PUTStorageRepairHandler struct {
+		Verifier   *storage.StorageVerifier
+		Authorizer *Authorizer
+		User       ClientUser
+	}
+)
+
+func newStorageVerifyResource(ctx ComponentLocator) *StorageVerifyResource {
+	return &StorageVerifyResource{context: ctx}
+}
+
+func newStorageRepairResource(ctx ComponentLocator) *StorageRepairResource {
+	return &StorageRepairResource{context: ctx}
+}
+
+// Get implements Getable on StorageVerifyResource.
+func (r *StorageVerifyResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	if _, err := r.context.authenticate(req); err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &GETStorageVerifyHandler{Verifier: r.context.StorageVerifier}
+}
+
+// Exchange returns a storage.StorageReport describing the manifests which
+// differ between the primary and secondary storage.
+func (h *GETStorageVerifyHandler) Exchange() (interface{}, int) {
+	if h.Verifier == nil {
+		return "No secondary storage to verify.", http.StatusNotFound
+	}
+	report, err := h.Verifier.Check(false, sous.User{})
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return report, http.StatusOK
+}
+
+// Put implements Putable on StorageRepairResource.
+func (r *StorageRepairResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	user, err := r.context.authenticate(req)
+	if err != nil {
+		return unauthenticatedExchanger{err: err}
+	}
+	return &PUTStorageRepairHandler{
+		Verifier:   r.context.StorageVerifier,
+		Authorizer: r.context.Authorizer,
+		User:       user,
+	}
+}
+
+// Exchange rewrites the secondary storage from the primary if they differ,
+// and returns a storage.StorageReport of the differences it repaired. Only
+// admins may repair the storage.
+func (h *PUTStorageRepairHandler) Exchange() (interface{}, int) {
+	if err := h.Authorizer.AuthorizeAdmin(h.User, "repair the secondary storage"); err != nil {
+		return "Forbidden: " + err.Error(), http.StatusForbidden
+	}
+	if h.Verifier == nil {
+		return "No secondary storage to repair.", http.StatusNotFound
+	}
+	report, err := h.Verifier.Check(true, sous.User(h.User))
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return report, http.StatusOK
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/storage"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageVerify(t *testing.T) {
+	primary, secondary := sous.NewDummyStateManager(), sous.NewDummyStateManager()
+	primary.State = sous.DefaultStateFixture()
+	c := ComponentLocator{
+		LogSink:         logging.SilentLogSet(),
+		Authorizer:      NewAuthorizer(config.AuthConfig{Authorize: true, Admins: []string{"admin@example.com"}}),
+		StorageVerifier: storage.NewStorageVerifier(primary, secondary, "cluster0", 0, false, logging.SilentLogSet()),
+	}
+	client, err := restful.NewInMemoryClient(Handler(c, http.NotFoundHandler(), c.LogSink), c.LogSink)
+	require.NoError(t, err)
+
+	report := &storage.StorageReport{}
+	_, err = client.Retrieve("./storage/verify", nil, report, nil)
+	require.NoError(t, err)
+	assert.Len(t, report.Divergences, primary.State.Manifests.Len())
+	assert.Equal(t, storage.MissingFromSecondary, report.Divergences[0].Kind)
+
+	user := sous.User{Name: "Test User", Email: "test@example.com"}
+	_, err = client.Create("./storage/repair", nil, nil, user.HTTPHeaders())
+	assert.Error(t, err, "only admins should be able to repair the storage")
+
+	admin := sous.User{Name: "Admin", Email: "admin@example.com"}
+	_, err = client.Create("./storage/repair", nil, nil, admin.HTTPHeaders())
+	require.NoError(t, err)
+
+	report = &storage.StorageReport{}
+	_, err = client.Retrieve("./storage/verify", nil, report, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+
+	c.StorageVerifier = nil
+	_, status := newStorageVerifyResource(c).Get(nil, nil, makeRequestWithQuery(t, ""), nil).Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -56,3 +56,30 @@ func deploymentIDFromValues(qv restful.QueryValues) (sous.DeploymentID, error) {
 		Cluster:    cluster,
 	}, nil
 }
+
+// resolveFilterFromValues builds a filter from the optional repo, offset,
+// flavor and cluster fields. Absent fields match everything; present fields,
+// even if empty, match exactly.
+func resolveFilterFromValues(qv restful.QueryValues) (*sous.ResolveFilter, error) {
+	rf := &sous.ResolveFilter{}
+	fields := []struct {
+		name    string
+		matcher *sous.ResolveFieldMatcher
+	}{
+		{"repo", &rf.Repo},
+		{"offset", &rf.Offset},
+		{"flavor", &rf.Flavor},
+		{"cluster", &rf.Cluster},
+	}
+	for _, f := range fields {
+		if _, present := qv.Values[f.name]; !present {
+			continue
+		}
+		v, err := qv.Single(f.name)
+		if err != nil {
+			return nil, err
+		}
+		*f.matcher = sous.NewResolveFieldMatcher(v)
+	}
+	return rf, nil
+}
@@ -1,6 +1,9 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -26,6 +29,8 @@ type (
 		logging.LogSink
 		*config.Config
 		sous.Inserter
+		// Registry is used to look up build artifacts.
+		Registry sous.Registry
 		sous.StateManager
 		sous.ClusterManager    // xxx temporary?
 		sous.DeploymentManager // xxx temporary?
@@ -33,6 +38,25 @@ type (
 		*sous.AutoResolver
 		Version  semv.Version
 		QueueSet sous.QueueSet
+		History  sous.History
+		Promoter *sous.Promoter
+		Notifier *sous.Notifier
+		// Authenticator identifies the users making requests. If it is nil,
+		// the Sous-User-* headers sent by clients are trusted.
+		Authenticator Authenticator
+		// Authorizer checks changes to manifests. If it is nil, every change
+		// is allowed.
+		Authorizer *Authorizer
+		// BuildQueue performs builds for clients. If it is nil, remote builds
+		// are refused.
+		BuildQueue *sous.BuildQueue
+		// StateChanges reports changes to the GDM to watchers.
+		StateChanges *sous.StateChanges
+		// Snapshotter takes, finds and restores snapshots of the GDM.
+		Snapshotter *sous.Snapshotter
+		// StorageVerifier checks the secondary storage of the GDM against the
+		// primary. If it is nil, the storage cannot be checked.
+		StorageVerifier *storage.StorageVerifier
 	}
 )
 
@@ -48,6 +72,13 @@ func (ctx ComponentLocator) liveState() *sous.State {
 	return state
 }
 
+func (ctx ComponentLocator) authenticate(req *http.Request) (ClientUser, error) {
+	if ctx.Authenticator == nil {
+		return userExtractor{}.Authenticate(req)
+	}
+	return ctx.Authenticator.Authenticate(req)
+}
+
 func (userExtractor) GetUser(req *http.Request) ClientUser {
 	clu := ClientUser{
 		Name:  req.Header.Get("Sous-User-Name"),
@@ -65,6 +96,28 @@ func Run(laddr string, handler http.Handler) error {
 	return s.ListenAndServe()
 }
 
+// RunTLS starts a server up, listening for HTTPS. If clientCAFile is not
+// empty, client certificates are verified against it so that they can be used
+// to authenticate requests.
+func RunTLS(laddr string, handler http.Handler, certFile, keyFile, clientCAFile string) error {
+	s := &http.Server{Addr: laddr, Handler: handler}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return errors.Wrapf(err, "reading client CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates found in %q", clientCAFile)
+		}
+		s.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
+	}
+	return s.ListenAndServeTLS(certFile, keyFile)
+}
+
 // Handler builds the http.Handler for the Sous server httprouter.
 func Handler(sc ComponentLocator, metrics http.Handler, ls logging.LogSink) http.Handler {
 	handler := mux(sc, ls)
@@ -91,6 +144,12 @@ func mux(sc ComponentLocator, ls logging.LogSink) *http.ServeMux {
 func routemap(context ComponentLocator) *restful.RouteMap {
 	return restful.BuildRouteMap(func(re restful.RouteEntryBuilder) {
 		re("gdm", "/gdm", newGDMResource(context))
+		re("gdm-watch", "/gdm/watch", newGDMWatchResource(context))
+		re("gdm-snapshots", "/gdm/snapshots", newGDMSnapshotsResource(context))
+		re("gdm-snapshot", "/gdm/snapshot", newGDMSnapshotResource(context))
+		re("gdm-restore", "/gdm/restore", newGDMRestoreResource(context))
+		re("storage-verify", "/storage/verify", newStorageVerifyResource(context))
+		re("storage-repair", "/storage/repair", newStorageRepairResource(context))
 		re("defs", "/defs", newStateDefResource(context))
 		re("manifest", "/manifest", newManifestResource(context))
 		re("artifact", "/artifact", newArtifactResource(context))
@@ -102,6 +161,12 @@ func routemap(context ComponentLocator) *restful.RouteMap {
 		re("deploy-queue", "/deploy-queue", newDeployQueueResource(context))
 		re("deploy-queue-item", "/deploy-queue-item", newR11nResource(context))
 		re("single-deployment", "/single-deployment", newSingleDeploymentResource(context))
+		re("history", "/history", newHistoryResource(context))
+		re("promotions", "/promotions", newPromotionsResource(context))
+		re("promotion-pause", "/promotion-pause", newPromotionPauseResource(context))
+		re("promotion-approval", "/promotion-approval", newPromotionApprovalResource(context))
+		re("notifications", "/notifications", newNotificationsResource(context))
+		re("build", "/build", newBuildResource(context))
 	})
 }
 
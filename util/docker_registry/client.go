@@ -69,6 +69,9 @@ type (
 		LabelsForImageName(string) (map[string]string, error)
 		GetImageMetadata(imageName, etag string) (Metadata, error)
 		AllTags(repoName string) ([]string, error)
+		DeleteImage(imageName string) error
+		GetSignatures(imageName string) ([]Signature, error)
+		PutSignature(imageName string, sig Signature) error
 		Cancel()
 		BecomeFoolishlyTrusting()
 	}
@@ -82,6 +85,10 @@ type (
 		CanonicalName string
 		AllNames      []string
 		OnBuild       []string
+		// Platforms lists the platforms the image was built for, e.g.
+		// "linux/amd64". It is empty for single platform images whose config
+		// doesn't record their platform.
+		Platforms []string
 	}
 )
 
@@ -215,6 +222,28 @@ func (c *liveClient) AllTags(repoName string) ([]string, error) {
 	return rep.getRepoTags(ref)
 }
 
+// DeleteImage deletes the manifest of an image, named by digest, from its
+// registry. Deleting a manifest also deletes every tag which refers to it, so
+// an image named by tag is refused: the caller must first check that no tag
+// of the same digest is still needed. The registry must allow deletes, and
+// its own garbage collection removes the layers no longer referred to.
+func (c *liveClient) DeleteImage(imageName string) error {
+	regHost, ref, err := splitHost(imageName)
+	if err != nil {
+		return err
+	}
+	cref, ok := ref.(reference.Canonical)
+	if !ok {
+		return fmt.Errorf("refusing to delete %s: images can only be deleted by digest", imageName)
+	}
+
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return err
+	}
+	return rep.deleteManifest(cref)
+}
+
 func splitHost(in string) (url string, ref reference.Named, err error) {
 	ref, err = reference.ParseNamed(in)
 	if err != nil {
@@ -281,7 +310,10 @@ func (c *liveClient) registryForHostname(regHost string) (*registry, error) {
 }
 
 type stubConfig struct {
-	Config stubImage `json:"config"`
+	Config       stubImage `json:"config"`
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Variant      string    `json:"variant"`
 }
 
 type stubImage struct {
@@ -333,6 +365,27 @@ func (c *liveClient) metadataForImage(regHost string, ref reference.Named, etag
 	md.CanonicalName = ref.Name() + "@" + dg.String()
 	md.AllNames[1] = md.CanonicalName
 
+	// The canonical name of a multi-platform image is the digest of its
+	// index, but its metadata is that of one of the platforms.
+	if idx, ok := mani.(*imageIndex); ok {
+		md.Platforms = idx.Platforms()
+		var pd digest.Digest
+		pd, err = idx.platformManifest(DefaultPlatform)
+		if err != nil {
+			return
+		}
+		var pref reference.Canonical
+		pref, err = digestRef(ref, pd.String())
+		if err != nil {
+			return
+		}
+		mani, _, _, err = rep.getManifestWithEtag(c.ctx, pref, "")
+		if err != nil {
+			return
+		}
+	}
+
+	configDigest := digest.Digest("")
 	switch mani := mani.(type) {
 	case *schema1.SignedManifest:
 		history := mani.History
@@ -363,8 +416,22 @@ func (c *liveClient) metadataForImage(regHost string, ref reference.Named, etag
 		copy(md.OnBuild, historyEntry.CC.OnBuild)
 
 	case *schema2.DeserializedManifest:
+		configDigest = mani.Config.Digest
+	case *ociManifest:
+		configDigest = mani.Config.Digest
+
+	default:
+		// We shouldn't receive this, because we shouldn't include the Accept
+		// header that would trigger it. To begin work on this (because...?) start
+		// by adding schema2 as an import - it's a sibling of schema1. Schema2
+		// includes a 'config' key, which has a digest for a blob - see
+		// distribution/pull_v2 pullSchema2ImageConfig() (~ ln 677)
+		err = fmt.Errorf("Cripes! Don't know that format of manifest")
+	}
+
+	if configDigest != "" {
 		var cj []byte
-		cj, err = rep.getBlob(c.ctx, ref, mani.Config.Digest)
+		cj, err = rep.getBlob(c.ctx, ref, configDigest)
 		if err != nil {
 			return
 		}
@@ -388,13 +455,10 @@ func (c *liveClient) metadataForImage(regHost string, ref reference.Named, etag
 		md.OnBuild = make([]string, len(c.Config.OnBuild))
 		copy(md.OnBuild, c.Config.OnBuild)
 
-	default:
-		// We shouldn't receive this, because we shouldn't include the Accept
-		// header that would trigger it. To begin work on this (because...?) start
-		// by adding schema2 as an import - it's a sibling of schema1. Schema2
-		// includes a 'config' key, which has a digest for a blob - see
-		// distribution/pull_v2 pullSchema2ImageConfig() (~ ln 677)
-		err = fmt.Errorf("Cripes! Don't know that format of manifest")
+		if len(md.Platforms) == 0 && c.OS != "" && c.Architecture != "" {
+			p := &ociPlatform{OS: c.OS, Architecture: c.Architecture, Variant: c.Variant}
+			md.Platforms = []string{p.String()}
+		}
 	}
 
 	return
@@ -532,6 +596,46 @@ func (r *registry) getManifestWithEtag(ctx context.Context, ref reference.Named,
 	return
 }
 
+// resolveDigest returns ref by the digest of its manifest, as the registry
+// knows it, which may differ from the one computed for schema1 manifests.
+func (r *registry) resolveDigest(ctx context.Context, ref reference.Named) (reference.Canonical, error) {
+	if cref, ok := ref.(reference.Canonical); ok {
+		return cref, nil
+	}
+	_, d, h, err := r.getManifestWithEtag(ctx, ref, "")
+	if err != nil {
+		return nil, err
+	}
+	if hd := h.Get("Docker-Content-Digest"); hd != "" {
+		d = digest.Digest(hd)
+	}
+	return digestRef(ref, string(d))
+}
+
+func (r *registry) deleteManifest(ref reference.Named) error {
+	u, err := r.ub.BuildManifestURL(ref)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do("docker-manifest-delete", req)
+	defer safeCloseBody(resp)
+
+	if err != nil {
+		return err
+	}
+
+	if client.SuccessStatus(resp.StatusCode) {
+		return nil
+	}
+	return client.HandleErrorResponse(resp)
+}
+
 func safeCloseBody(r *http.Response) {
 	defer func() { recover() }()
 	r.Body.Close()
@@ -557,7 +661,7 @@ func (r *registry) manifestFromResponse(resp *http.Response) (distribution.Manif
 		case *schema1.SignedManifest:
 			//log.Print(string(v.Canonical))
 			d = digest.FromBytes(v.Canonical)
-		case *schema2.DeserializedManifest:
+		case *schema2.DeserializedManifest, *ociManifest, *imageIndex:
 			_, pl, err := m.Payload()
 			if err != nil {
 				return nil, "", err
@@ -1,10 +1,15 @@
 package docker_registry
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/docker/distribution/digest"
 	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegistries(t *testing.T) {
@@ -26,3 +31,60 @@ func TestNewClient(t *testing.T) {
 	assert.NotNil(c)
 	c.Cancel()
 }
+
+func TestDeleteImage(t *testing.T) {
+	manifest := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1470,
+		  "digest": "sha256:c73e3e1f4b2e0f1ea1c38e8e9d7e5a4f6d8b6d6c2c4b6a7e8f9a0b1c2d3e4f5a"},
+		"layers": []}`
+	dgst := digest.FromBytes([]byte(manifest)).String()
+
+	var deleted []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "GET":
+			rw.Header().Set("Content-Type", MediaTypeOCIManifest)
+			rw.Header().Set("Docker-Content-Digest", dgst)
+			rw.Write([]byte(manifest))
+		case "DELETE":
+			deleted = append(deleted, req.URL.Path)
+			rw.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(logging.SilentLogSet())
+	c.BecomeFoolishlyTrusting()
+
+	assert.Error(t, c.DeleteImage(host+"/repo/image:1.2.3"), "deleting by tag would delete every tag of its digest")
+	require.NoError(t, c.DeleteImage(host+"/repo/image@"+dgst))
+	assert.Equal(t, []string{"/v2/repo/image/manifests/" + dgst}, deleted)
+}
+
+func TestGetImageMetadata_ConfigPlatform(t *testing.T) {
+	config := `{"architecture": "arm64", "os": "linux", "variant": "v8", "config": {"Labels": {"a": "b"}}}`
+	manifest := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 91,
+		  "digest": "` + digest.FromBytes([]byte(config)).String() + `"},
+		"layers": []}`
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/blobs/") {
+			rw.Write([]byte(config))
+			return
+		}
+		rw.Header().Set("Content-Type", MediaTypeOCIManifest)
+		rw.Write([]byte(manifest))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(logging.SilentLogSet())
+	c.BecomeFoolishlyTrusting()
+
+	md, err := c.GetImageMetadata(host+"/repo/image:1.2.3", "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "b"}, md.Labels)
+	assert.Equal(t, []string{"linux/arm64/v8"}, md.Platforms)
+}
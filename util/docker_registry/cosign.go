@@ -0,0 +1,257 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/api/errcode"
+	"github.com/docker/distribution/registry/api/v2"
+	"github.com/docker/distribution/registry/client"
+	"golang.org/x/net/context"
+)
+
+// Media types and annotations of signatures stored the way cosign stores
+// them: as the layers of an OCI manifest tagged after the digest of the
+// image they sign.
+const (
+	// MediaTypeSimpleSigning is the media type of a signature layer, whose
+	// content is the signed payload.
+	MediaTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// SignatureAnnotation is the annotation of a signature layer which holds
+	// the base64 encoded signature of its payload.
+	SignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	mediaTypeOCIConfig = "application/vnd.oci.image.config.v1+json"
+)
+
+// A Signature is a signature of an image: a payload which identifies the
+// image, and the base64 encoded signature of that payload.
+type Signature struct {
+	Payload   []byte
+	Signature string
+}
+
+// SignatureTag returns the tag under which the signatures of the image with
+// digest dgst are stored, e.g. "sha256-abc123.sig".
+func SignatureTag(dgst string) string {
+	return strings.Replace(dgst, ":", "-", 1) + ".sig"
+}
+
+// GetSignatures returns the signatures of an image. An image named by tag
+// is looked up by the digest the tag refers to. An image without signatures
+// has none, rather than an error.
+func (c *liveClient) GetSignatures(imageName string) ([]Signature, error) {
+	rep, sigRef, err := c.signatureRef(imageName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := rep.getSignatureManifest(c.ctx, sigRef)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	var sigs []Signature
+	for _, l := range m.Layers {
+		if l.MediaType != MediaTypeSimpleSigning {
+			continue
+		}
+		payload, err := rep.getBlob(c.ctx, sigRef, l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, Signature{Payload: payload, Signature: l.Annotations[SignatureAnnotation]})
+	}
+	return sigs, nil
+}
+
+// PutSignature adds sig to the signatures of an image, unless it already has
+// that signature.
+func (c *liveClient) PutSignature(imageName string, sig Signature) error {
+	rep, sigRef, err := c.signatureRef(imageName)
+	if err != nil {
+		return err
+	}
+	m, err := rep.getSignatureManifest(c.ctx, sigRef)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m = &ociManifest{}
+	}
+
+	payloadDigest := digest.FromBytes(sig.Payload)
+	for _, l := range m.Layers {
+		if l.Digest == payloadDigest && l.Annotations[SignatureAnnotation] == sig.Signature {
+			return nil
+		}
+	}
+	if err := rep.putBlob(sigRef, sig.Payload); err != nil {
+		return err
+	}
+	m.Layers = append(m.Layers, ociDescriptor{
+		MediaType:   MediaTypeSimpleSigning,
+		Size:        int64(len(sig.Payload)),
+		Digest:      payloadDigest,
+		Annotations: map[string]string{SignatureAnnotation: sig.Signature},
+	})
+
+	var diffIDs []digest.Digest
+	for _, l := range m.Layers {
+		diffIDs = append(diffIDs, l.Digest)
+	}
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "",
+		"os":           "",
+		"config":       map[string]interface{}{},
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+	})
+	if err != nil {
+		return err
+	}
+	if err := rep.putBlob(sigRef, config); err != nil {
+		return err
+	}
+	m.SchemaVersion = 2
+	m.MediaType = MediaTypeOCIManifest
+	m.Config = ociDescriptor{MediaType: mediaTypeOCIConfig, Size: int64(len(config)), Digest: digest.FromBytes(config)}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return rep.putManifest(sigRef, MediaTypeOCIManifest, body)
+}
+
+// signatureRef returns the registry of an image, and the reference its
+// signatures are tagged with.
+func (c *liveClient) signatureRef(imageName string) (*registry, reference.Named, error) {
+	regHost, ref, err := splitHost(imageName)
+	if err != nil {
+		return nil, nil, err
+	}
+	rep, err := c.registryForHostname(regHost)
+	if err != nil {
+		return nil, nil, err
+	}
+	cref, err := rep.resolveDigest(c.ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	name, err := reference.ParseNamed(ref.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	sigRef, err := reference.WithTag(name, SignatureTag(cref.Digest().String()))
+	return rep, sigRef, err
+}
+
+// getSignatureManifest returns the manifest of the signatures tagged ref,
+// or nil if there are none.
+func (r *registry) getSignatureManifest(ctx context.Context, ref reference.Named) (*ociManifest, error) {
+	mf, _, _, err := r.getManifestWithEtag(ctx, ref, "")
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, ok := mf.(*ociManifest)
+	if !ok {
+		return nil, fmt.Errorf("signatures %s have unsupported manifest type %T", ref, mf)
+	}
+	return m, nil
+}
+
+// putBlob uploads content as a blob of the repository of ref, in a single
+// request.
+func (r *registry) putBlob(ref reference.Named, content []byte) error {
+	u, err := r.ub.BuildBlobUploadURL(ref)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do("docker-blob-upload", req)
+	defer safeCloseBody(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return client.HandleErrorResponse(resp)
+	}
+
+	location, err := sanitizeLocation(resp.Header.Get("Location"), u)
+	if err != nil {
+		return err
+	}
+	lu, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := lu.Query()
+	q.Set("digest", digest.FromBytes(content).String())
+	lu.RawQuery = q.Encode()
+
+	req, err = http.NewRequest("PUT", lu.String(), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	put, err := r.client.Do("docker-blob-put", req)
+	defer safeCloseBody(put)
+	if err != nil {
+		return err
+	}
+	if client.SuccessStatus(put.StatusCode) {
+		return nil
+	}
+	return client.HandleErrorResponse(put)
+}
+
+func (r *registry) putManifest(ref reference.Named, mediaType string, body []byte) error {
+	u, err := r.ub.BuildManifestURL(ref)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+	resp, err := r.client.Do("docker-manifest-put", req)
+	defer safeCloseBody(resp)
+	if err != nil {
+		return err
+	}
+	if client.SuccessStatus(resp.StatusCode) {
+		return nil
+	}
+	return client.HandleErrorResponse(resp)
+}
+
+// isNotFound returns true if err reports that a manifest doesn't exist.
+func isNotFound(err error) bool {
+	switch e := err.(type) {
+	case errcode.Errors:
+		for _, ee := range e {
+			if isNotFound(ee) {
+				return true
+			}
+		}
+	case errcode.Error:
+		return e.Code == v2.ErrorCodeManifestUnknown
+	case errcode.ErrorCode:
+		return e == v2.ErrorCodeManifestUnknown
+	case *client.UnexpectedHTTPResponseError:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package docker_registry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureTag(t *testing.T) {
+	assert.Equal(t, "sha256-abc123.sig", SignatureTag("sha256:abc123"))
+}
+
+func TestPutAndGetSignatures(t *testing.T) {
+	image := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 2,
+		  "digest": "` + digest.FromBytes([]byte("{}")).String() + `"},
+		"layers": []}`
+	imageDigest := digest.FromBytes([]byte(image)).String()
+
+	var lock sync.Mutex
+	manifests := map[string][]byte{"/v2/repo/image/manifests/1.2.3": []byte(image)}
+	blobs := map[string][]byte{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		body, _ := ioutil.ReadAll(req.Body)
+		switch {
+		case req.Method == "POST":
+			rw.Header().Set("Location", "/v2/repo/image/blobs/uploads/some-uuid")
+			rw.WriteHeader(http.StatusAccepted)
+		case req.Method == "PUT" && strings.Contains(req.URL.Path, "/blobs/uploads/"):
+			blobs[req.URL.Query().Get("digest")] = body
+			rw.WriteHeader(http.StatusCreated)
+		case req.Method == "PUT":
+			manifests[req.URL.Path] = body
+			rw.WriteHeader(http.StatusCreated)
+		case strings.Contains(req.URL.Path, "/blobs/"):
+			b, ok := blobs[req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]]
+			if !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.Write(b)
+		default:
+			m, ok := manifests[req.URL.Path]
+			if !ok {
+				rw.Header().Set("Content-Type", "application/json")
+				rw.WriteHeader(http.StatusNotFound)
+				rw.Write([]byte(`{"errors": [{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}]}`))
+				return
+			}
+			rw.Header().Set("Content-Type", MediaTypeOCIManifest)
+			rw.Write(m)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(logging.SilentLogSet())
+	c.BecomeFoolishlyTrusting()
+
+	sigs, err := c.GetSignatures(host + "/repo/image:1.2.3")
+	require.NoError(t, err)
+	assert.Empty(t, sigs)
+
+	first := Signature{Payload: []byte(`{"first": true}`), Signature: "Zmlyc3Q="}
+	second := Signature{Payload: []byte(`{"second": true}`), Signature: "c2Vjb25k"}
+	require.NoError(t, c.PutSignature(host+"/repo/image:1.2.3", first))
+	require.NoError(t, c.PutSignature(host+"/repo/image@"+imageDigest, second))
+	require.NoError(t, c.PutSignature(host+"/repo/image:1.2.3", first))
+
+	assert.Contains(t, manifests, "/v2/repo/image/manifests/"+SignatureTag(imageDigest))
+	sigs, err = c.GetSignatures(host + "/repo/image:1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, []Signature{first, second}, sigs)
+}
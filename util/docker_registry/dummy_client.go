@@ -68,6 +68,22 @@ func (drc *DummyRegistryClient) AllTags(rn string) (tags []string, err error) {
 	return res.Get(0).([]string), res.Error(1)
 }
 
+// DeleteImage fulfills part of Client
+func (drc *DummyRegistryClient) DeleteImage(in string) error {
+	return drc.Called(in).Error(0)
+}
+
+// GetSignatures fulfills part of Client
+func (drc *DummyRegistryClient) GetSignatures(in string) ([]Signature, error) {
+	res := drc.Called(in)
+	return res.GetOr(0, []Signature(nil)).([]Signature), res.Error(1)
+}
+
+// PutSignature fulfills part of Client
+func (drc *DummyRegistryClient) PutSignature(in string, sig Signature) error {
+	return drc.Called(in, sig).Error(0)
+}
+
 // LabelsForImageName fulfills part of Client
 func (drc *DummyRegistryClient) LabelsForImageName(in string) (labels map[string]string, err error) {
 	res := drc.Called(in)
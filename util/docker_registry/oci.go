@@ -0,0 +1,161 @@
+package docker_registry
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+)
+
+// Media types of the manifests which are not supported by the vendored
+// docker/distribution.
+const (
+	// MediaTypeOCIManifest is the media type of an OCI image manifest.
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeOCIIndex is the media type of an OCI image index.
+	MediaTypeOCIIndex = "application/vnd.oci.image.index.v1+json"
+	// MediaTypeManifestList is the media type of a Docker manifest list, the
+	// predecessor of the OCI image index.
+	MediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// DefaultPlatform is the platform whose manifest is inspected for the
+// metadata of a multi-platform image.
+const DefaultPlatform = "linux/amd64"
+
+type (
+	// ociDescriptor is a distribution.Descriptor, with the platform of the
+	// manifests listed in an index, and annotations.
+	ociDescriptor struct {
+		MediaType string        `json:"mediaType,omitempty"`
+		Size      int64         `json:"size,omitempty"`
+		Digest    digest.Digest `json:"digest,omitempty"`
+		Platform  *ociPlatform  `json:"platform,omitempty"`
+		// Annotations hold e.g. the signature of a cosign signature layer.
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	ociPlatform struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant,omitempty"`
+	}
+
+	// ociManifest is an OCI image manifest. It has the same shape as a
+	// schema2 manifest.
+	ociManifest struct {
+		SchemaVersion int             `json:"schemaVersion,omitempty"`
+		MediaType     string          `json:"mediaType,omitempty"`
+		Config        ociDescriptor   `json:"config"`
+		Layers        []ociDescriptor `json:"layers"`
+		payload       []byte
+	}
+
+	// imageIndex is an OCI image index, or a Docker manifest list, which
+	// lists the manifests of an image for each platform it was built for.
+	imageIndex struct {
+		MediaType string          `json:"mediaType,omitempty"`
+		Manifests []ociDescriptor `json:"manifests"`
+		payload   []byte
+	}
+)
+
+func init() {
+	register := func(mediaType string, unmarshal func([]byte) (distribution.Manifest, error)) {
+		err := distribution.RegisterManifestSchema(mediaType, func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+			m, err := unmarshal(b)
+			if err != nil {
+				return nil, distribution.Descriptor{}, err
+			}
+			return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: mediaType}, nil
+		})
+		if err != nil {
+			panic(fmt.Sprintf("Unable to register manifest: %s", err))
+		}
+	}
+	unmarshalManifest := func(b []byte) (distribution.Manifest, error) {
+		m := &ociManifest{payload: b}
+		return m, json.Unmarshal(b, m)
+	}
+	unmarshalIndex := func(b []byte) (distribution.Manifest, error) {
+		m := &imageIndex{payload: b}
+		return m, json.Unmarshal(b, m)
+	}
+	register(MediaTypeOCIManifest, unmarshalManifest)
+	register(MediaTypeOCIIndex, unmarshalIndex)
+	register(MediaTypeManifestList, unmarshalIndex)
+}
+
+func (d ociDescriptor) descriptor() distribution.Descriptor {
+	return distribution.Descriptor{MediaType: d.MediaType, Size: d.Size, Digest: d.Digest}
+}
+
+// String returns the platform as e.g. "linux/arm64/v8".
+func (p *ociPlatform) String() string {
+	if p == nil {
+		return ""
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// References implements distribution.Manifest on ociManifest.
+func (m *ociManifest) References() []distribution.Descriptor {
+	refs := []distribution.Descriptor{m.Config.descriptor()}
+	for _, l := range m.Layers {
+		refs = append(refs, l.descriptor())
+	}
+	return refs
+}
+
+// Payload implements distribution.Manifest on ociManifest.
+func (m *ociManifest) Payload() (string, []byte, error) {
+	return MediaTypeOCIManifest, m.payload, nil
+}
+
+// References implements distribution.Manifest on imageIndex.
+func (m *imageIndex) References() []distribution.Descriptor {
+	refs := make([]distribution.Descriptor, len(m.Manifests))
+	for i, d := range m.Manifests {
+		refs[i] = d.descriptor()
+	}
+	return refs
+}
+
+// Payload implements distribution.Manifest on imageIndex.
+func (m *imageIndex) Payload() (string, []byte, error) {
+	mt := m.MediaType
+	if mt == "" {
+		mt = MediaTypeOCIIndex
+	}
+	return mt, m.payload, nil
+}
+
+// Platforms returns the platforms listed in the index.
+func (m *imageIndex) Platforms() []string {
+	var ps []string
+	for _, d := range m.Manifests {
+		if p := d.Platform.String(); p != "" {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// platformManifest returns the digest of the manifest for platform, or of
+// the first manifest if none is for that platform.
+func (m *imageIndex) platformManifest(platform string) (digest.Digest, error) {
+	if len(m.Manifests) == 0 {
+		return "", fmt.Errorf("image index lists no manifests")
+	}
+	for _, d := range m.Manifests {
+		if d.Platform.String() == platform {
+			return d.Digest, nil
+		}
+	}
+	return m.Manifests[0].Digest, nil
+}
@@ -0,0 +1,68 @@
+package docker_registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIndex = `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 7143,
+     "digest": "sha256:e692418e4cbaf90ca69d05a66403747baa33ee08806650b51fab815ad7fc331f",
+     "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "size": 7682,
+     "digest": "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270",
+     "platform": {"architecture": "amd64", "os": "linux"}}
+  ]
+}`
+
+func TestImageIndex(t *testing.T) {
+	m, desc, err := distribution.UnmarshalManifest(MediaTypeOCIIndex, []byte(testIndex))
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromBytes([]byte(testIndex)), desc.Digest)
+
+	idx, ok := m.(*imageIndex)
+	require.True(t, ok, "expected an *imageIndex, got %T", m)
+	assert.Equal(t, []string{"linux/arm64/v8", "linux/amd64"}, idx.Platforms())
+	assert.Len(t, idx.References(), 2)
+
+	d, err := idx.platformManifest(DefaultPlatform)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", d.String())
+
+	d, err = idx.platformManifest("windows/amd64")
+	require.NoError(t, err)
+	assert.Equal(t, idx.Manifests[0].Digest, d, "should fall back to the first manifest")
+
+	_, err = (&imageIndex{}).platformManifest(DefaultPlatform)
+	assert.Error(t, err)
+}
+
+func TestManifestFromResponse_OCI(t *testing.T) {
+	manifest := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": {"mediaType": "application/vnd.oci.image.config.v1+json", "size": 1470,
+		  "digest": "sha256:c73e3e1f4b2e0f1ea1c38e8e9d7e5a4f6d8b6d6c2c4b6a7e8f9a0b1c2d3e4f5a"},
+		"layers": []}`
+	for mt, body := range map[string]string{MediaTypeOCIManifest: manifest, MediaTypeManifestList: testIndex} {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{mt}},
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		}
+		m, d, err := (&registry{}).manifestFromResponse(resp)
+		require.NoError(t, err, mt)
+		assert.Equal(t, digest.FromBytes([]byte(body)), d, mt)
+		if om, ok := m.(*ociManifest); ok {
+			assert.Equal(t, "sha256:c73e3e1f4b2e0f1ea1c38e8e9d7e5a4f6d8b6d6c2c4b6a7e8f9a0b1c2d3e4f5a", om.Config.Digest.String())
+		}
+	}
+}
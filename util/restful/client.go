@@ -3,6 +3,8 @@ package restful
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,6 +31,18 @@ type (
 		commonHeaders http.Header
 	}
 
+	// Credentials are presented by a LiveHTTPClient to the server with every
+	// request.
+	Credentials struct {
+		// BearerToken is sent in the Authorization header.
+		BearerToken string
+		// CertFile and KeyFile are a client certificate and its key.
+		CertFile, KeyFile string
+		// CAFile is a bundle of CAs trusted to sign the server's certificate,
+		// as well as the system's.
+		CAFile string
+	}
+
 	resourceState struct {
 		client       *LiveHTTPClient
 		path, etag   string
@@ -135,6 +149,44 @@ func NewClient(serverURL string, ls logging.LogSink, headers ...map[string]strin
 	return client, errors.Wrapf(err, "new Sous REST client")
 }
 
+// SetCredentials has client present creds with each request.
+func (client *LiveHTTPClient) SetCredentials(creds Credentials) error {
+	if creds.BearerToken != "" {
+		client.commonHeaders.Set("Authorization", "Bearer "+creds.BearerToken)
+	}
+	if creds.CertFile == "" && creds.CAFile == "" {
+		return nil
+	}
+	transport, ok := client.Client.Transport.(*http.Transport)
+	if !ok {
+		return errors.Errorf("cannot configure TLS on a %T", client.Client.Transport)
+	}
+	tlsConfig := &tls.Config{}
+	if creds.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(creds.CertFile, creds.KeyFile)
+		if err != nil {
+			return errors.Wrapf(err, "loading client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if creds.CAFile != "" {
+		pem, err := ioutil.ReadFile(creds.CAFile)
+		if err != nil {
+			return errors.Wrapf(err, "reading CA file")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates in CA file %q", creds.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return nil
+}
+
 // NewInMemoryClient wraps a MemoryListener in a restful.Client
 func NewInMemoryClient(handler http.Handler, ls logging.LogSink, headers ...map[string]string) (HTTPClient, error) {
 	u, err := url.Parse("http://in.memory.server")
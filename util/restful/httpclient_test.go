@@ -3,9 +3,11 @@ package restful
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/pem"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -129,6 +131,33 @@ func TestClientRetrieve(t *testing.T) {
 	logging.AssertMessageFieldlist(t, tstmsg, variableFields, fixedFields)
 }
 
+func TestClientCredentials(t *testing.T) {
+	var authz string
+	s := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		authz = req.Header.Get("Authorization")
+		rw.Write([]byte("{}"))
+	}))
+	defer s.Close()
+
+	caFile, err := ioutil.TempFile("", "sous-ca")
+	require.NoError(t, err)
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	caFile.Close()
+
+	c, err := NewClient(s.URL, logging.SilentLogSet())
+	require.NoError(t, err)
+	_, err = c.Retrieve("/path", nil, &map[string]interface{}{}, nil)
+	assert.Error(t, err, "server certificate not trusted")
+
+	require.NoError(t, c.SetCredentials(Credentials{BearerToken: "s3cret", CAFile: caFile.Name()}))
+	_, err = c.Retrieve("/path", nil, &map[string]interface{}{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer s3cret", authz)
+
+	assert.Error(t, c.SetCredentials(Credentials{CertFile: "/no/such/cert", KeyFile: "/no/such/key"}))
+}
+
 func dig(m interface{}, index ...interface{}) interface{} {
 	var res interface{}
 	has := true
@@ -3,6 +3,7 @@ package shell
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/nyarly/spies"
@@ -131,3 +132,20 @@ func (c *TestCommandController) ResultSuccess(out, err string) {
 	c.MatchMethod("Stdout", spies.AnyArgs, out, nil)
 	c.MatchMethod("Stderr", spies.AnyArgs, err, nil)
 }
+
+// ResultFailure sets up the TestCommand to behave like it ran and exited with
+// a non-zero status, with particular stdout/stderr.
+func (c *TestCommandController) ResultFailure(out, err string, status int) {
+	ob := &Output{bytes.NewBufferString(out)}
+	eb := &Output{bytes.NewBufferString(err)}
+	cb := &Output{bytes.NewBufferString(out + err)}
+	failed := fmt.Errorf("exit status %d", status)
+	res := &Result{Command: c.cmd, Stdout: ob, Stderr: eb, Combined: cb, Err: failed, ExitCode: status}
+
+	c.MatchMethod("Result", spies.AnyArgs, res, nil)
+	c.MatchMethod("SucceedResult", spies.AnyArgs, res, failed)
+	c.MatchMethod("Succeed", spies.AnyArgs, failed)
+	c.MatchMethod("ExitCode", spies.AnyArgs, status, nil)
+	c.MatchMethod("Stdout", spies.AnyArgs, out, failed)
+	c.MatchMethod("Stderr", spies.AnyArgs, err, failed)
+}